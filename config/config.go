@@ -2,19 +2,81 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
 
+const (
+	// TransportSES delivers emails through AWS SES.
+	TransportSES = "ses"
+	// TransportSMTP delivers emails through an SMTP server.
+	TransportSMTP = "smtp"
+)
+
+const (
+	// RateLimitBackendMemory keeps the rate limits in process, so they only hold within a single replica.
+	RateLimitBackendMemory = "memory"
+	// RateLimitBackendRedis keeps the rate limits in Redis, so they hold across replicas.
+	RateLimitBackendRedis = "redis"
+)
+
+const (
+	// IdempotencyBackendMemory keeps the idempotency keys in process, so retries are only deduplicated within a single replica.
+	IdempotencyBackendMemory = "memory"
+	// IdempotencyBackendRedis keeps the idempotency keys in Redis, so retries are deduplicated across replicas.
+	IdempotencyBackendRedis = "redis"
+)
+
+const (
+	// FormTokenBackendMemory keeps the used form tokens in process, so replays are only rejected within a single replica.
+	FormTokenBackendMemory = "memory"
+	// FormTokenBackendRedis keeps the used form tokens in Redis, so replays are rejected across replicas.
+	FormTokenBackendRedis = "redis"
+)
+
+const (
+	// TemplateEngineSES renders the email templates inside AWS SES.
+	TemplateEngineSES = "ses"
+	// TemplateEngineLocal renders the email templates in process with the Go template packages.
+	TemplateEngineLocal = "local"
+)
+
 // Config holds the configuration for the email service.
 // It includes nested configurations for the service itself and email settings.
 //
 // Fields:
 //   - Service: The Service struct containing the service-related configuration.
 //   - Email: The Email struct containing the email-related configuration.
+//   - Storage: The Storage struct containing the configuration for the embedded data stores.
+//   - Outbox: The Outbox struct containing the configuration for asynchronous email delivery.
+//   - Events: The Events struct containing the configuration for ingesting SES delivery events.
+//   - Suppression: The Suppression struct containing the configuration for the suppression list.
+//   - Templates: The Templates struct containing the sources of the email templates.
+//   - Attachments: The Attachments struct containing the limits enforced on uploaded files.
+//   - HTMLForm: The HTMLForm struct containing the configuration for plain HTML form submissions.
+//   - Forms: The Forms struct containing the configuration of the form registry.
+//   - Captcha: The Captcha struct containing the CAPTCHA provider verifying submissions.
+//   - RateLimit: The RateLimit struct containing the limits submissions are throttled with.
+//   - Idempotency: The Idempotency struct containing the configuration of the keys retried submissions are deduplicated by.
+//   - FormTokens: The FormTokens struct containing the configuration of the tokens forms embed when rendered.
+//   - Spam: The Spam struct containing the rules and thresholds submissions are screened for spam with.
 type Config struct {
-	Service Service
-	Email   Email
+	Service     Service
+	Email       Email
+	Storage     Storage
+	Outbox      Outbox
+	Events      Events
+	Suppression Suppression
+	Templates   Templates
+	Attachments Attachments
+	HTMLForm    HTMLForm
+	Forms       Forms
+	Captcha     Captcha
+	RateLimit   RateLimit
+	Idempotency Idempotency
+	FormTokens  FormTokens
+	Spam        Spam
 }
 
 // Service holds the configuration for the service, including the port and listen address.
@@ -22,23 +84,322 @@ type Config struct {
 // Fields:
 //   - Port: The port on which the email service will listen. It is loaded from the environment variable "EMAIL_SERVICE_PORT" with a default value of 8080.
 //   - ListenAddress: The address on which the email service will listen. It is loaded from the environment variable "EMAIL_SERVICE_LISTEN_ADDRESS" with a default value of "0.0.0.0".
+//   - AdminToken: The bearer token required by administrative RPCs. Administrative RPCs are disabled when empty. It is loaded from the environment variable "EMAIL_SERVICE_ADMIN_TOKEN".
+//   - CORS: The CORS struct containing the cross-origin policy of the HTTP gateway.
 type Service struct {
 	ListenAddress string `env:"EMAIL_SERVICE_LISTEN_ADDRESS" envDefault:"0.0.0.0"`
 	Port          int    `env:"EMAIL_SERVICE_PORT" envDefault:"8080"`
 	GRPCHost      string `env:"EMAIL_SERVICE_GRPC_HOST" envDefault:"127.0.0.1"`
 	GRPCPort      int    `env:"EMAIL_SERVICE_GRPC_PORT" envDefault:"8081"`
+	AdminToken    string `env:"EMAIL_SERVICE_ADMIN_TOKEN"`
+	CORS          CORS
+}
+
+// CORS holds the cross-origin resource sharing policy of the HTTP gateway.
+//
+// Fields:
+//   - AllowedOrigins: A comma separated list of the origins allowed to call the gateway. An origin may contain one "*" wildcard, e.g. "https://*.example.com", and "*" allows every origin. No cross-origin requests are allowed when empty. It is loaded from the environment variable "EMAIL_SERVICE_CORS_ALLOWED_ORIGINS".
+//   - AllowedMethods: A comma separated list of the methods cross-origin requests may use. It is loaded from the environment variable "EMAIL_SERVICE_CORS_ALLOWED_METHODS" with a default value of "GET,POST,OPTIONS".
+//   - AllowedHeaders: A comma separated list of the headers cross-origin requests may send. "Origin", "Accept", "Content-Type" and "X-Requested-With" are allowed when empty, and "Idempotency-Key" always. It is loaded from the environment variable "EMAIL_SERVICE_CORS_ALLOWED_HEADERS".
+//   - AllowCredentials: Whether cross-origin requests may include cookies and authorization headers. It is loaded from the environment variable "EMAIL_SERVICE_CORS_ALLOW_CREDENTIALS" with a default value of false.
+//   - MaxAge: How long browsers may cache the result of a preflight request, rounded down to seconds. Browsers apply their own default when zero. It is loaded from the environment variable "EMAIL_SERVICE_CORS_MAX_AGE".
+type CORS struct {
+	AllowedOrigins   []string      `env:"EMAIL_SERVICE_CORS_ALLOWED_ORIGINS" envSeparator:","`
+	AllowedMethods   []string      `env:"EMAIL_SERVICE_CORS_ALLOWED_METHODS" envSeparator:"," envDefault:"GET,POST,OPTIONS"`
+	AllowedHeaders   []string      `env:"EMAIL_SERVICE_CORS_ALLOWED_HEADERS" envSeparator:","`
+	AllowCredentials bool          `env:"EMAIL_SERVICE_CORS_ALLOW_CREDENTIALS" envDefault:"false"`
+	MaxAge           time.Duration `env:"EMAIL_SERVICE_CORS_MAX_AGE"`
 }
 
 // Email holds the configuration for email settings, including the sender and forward addresses.
 //
 // Fields:
 //   - From: The email address from which emails will be sent. It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_FROM".
-//   - Forward: The email address to which incoming emails will be forwarded. It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_FORWARD".
-//   - ThankYouTemplate: A base64 standard encoded html template for your thank you email.
+//   - Forward: The comma-separated email addresses to which incoming emails will be forwarded. It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_FORWARD".
+//   - Cc: The comma-separated email addresses incoming emails are copied to. It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_CC".
+//   - Bcc: The comma-separated email addresses incoming emails are blind copied to. It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_BCC".
+//   - ThankYouTemplate: A base64 standard encoded html template for your thank you email. Deprecated: use "EMAIL_SERVICE_TEMPLATE_THANK_YOU_HTML", which takes precedence.
+//   - Site: The name of the site the service forwards submissions for, available to ForwardSubject as "{{site}}". It is loaded from the environment variable "EMAIL_SERVICE_SITE".
+//   - ForwardSubject: The pattern of the subject of forwarded submissions, with the placeholders "{{site}}", "{{subject}}", "{{name}}", "{{email}}" and "{{form}}". The subject of the forward template is used when set empty. It is loaded from the environment variable "EMAIL_SERVICE_FORWARD_SUBJECT" with a default value of "{{subject}} — {{name}}".
+//   - Transport: The transport used to deliver emails, either "ses" or "smtp". It is loaded from the environment variable "EMAIL_SERVICE_EMAIL_TRANSPORT" with a default value of "ses".
+//   - SMTP: The SMTP struct containing the SMTP server configuration, used when Transport is "smtp".
+//   - AutoReply: The AutoReply struct containing the configuration for the thank you email sent to the original sender.
 type Email struct {
-	From             string `env:"EMAIL_SERVICE_EMAIL_FROM"`
-	Forward          string `env:"EMAIL_SERVICE_EMAIL_FORWARD"`
-	ThankYouTemplate string `env:"EMAIL_SERVICE_EMAIL_THANK_YOU_TEMPLATE"`
+	From             string   `env:"EMAIL_SERVICE_EMAIL_FROM"`
+	Forward          []string `env:"EMAIL_SERVICE_EMAIL_FORWARD" envSeparator:","`
+	Cc               []string `env:"EMAIL_SERVICE_EMAIL_CC" envSeparator:","`
+	Bcc              []string `env:"EMAIL_SERVICE_EMAIL_BCC" envSeparator:","`
+	ThankYouTemplate string   `env:"EMAIL_SERVICE_EMAIL_THANK_YOU_TEMPLATE"`
+	Site             string   `env:"EMAIL_SERVICE_SITE"`
+	ForwardSubject   string   `env:"EMAIL_SERVICE_FORWARD_SUBJECT" envDefault:"{{subject}} — {{name}}"`
+	Transport        string   `env:"EMAIL_SERVICE_EMAIL_TRANSPORT" envDefault:"ses"`
+	SMTP             SMTP
+	AutoReply        AutoReply
+}
+
+// AutoReply holds the configuration for the thank you email sent to the sender of a submission.
+//
+// Fields:
+//   - Enabled: Whether the thank you email is sent. It is loaded from the environment variable "EMAIL_SERVICE_AUTO_REPLY_ENABLED" with a default value of false.
+//   - Limit: The maximum number of thank you emails a single address receives within Window, 0 for unlimited. It is loaded from the environment variable "EMAIL_SERVICE_AUTO_REPLY_LIMIT" with a default value of 3.
+//   - Window: The period Limit applies to. It is loaded from the environment variable "EMAIL_SERVICE_AUTO_REPLY_WINDOW" with a default value of 24h.
+type AutoReply struct {
+	Enabled bool          `env:"EMAIL_SERVICE_AUTO_REPLY_ENABLED" envDefault:"false"`
+	Limit   int           `env:"EMAIL_SERVICE_AUTO_REPLY_LIMIT" envDefault:"3"`
+	Window  time.Duration `env:"EMAIL_SERVICE_AUTO_REPLY_WINDOW" envDefault:"24h"`
+}
+
+// SMTP holds the configuration for delivering emails through an SMTP server.
+//
+// Fields:
+//   - Host: The hostname of the SMTP server. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_HOST".
+//   - Port: The port of the SMTP server. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_PORT" with a default value of 587.
+//   - Username: The username used to authenticate. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_USERNAME".
+//   - Password: The password used to authenticate. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_PASSWORD".
+//   - Auth: The authentication mechanism, one of "none", "plain", "login" or "cram-md5". It is loaded from the environment variable "EMAIL_SERVICE_SMTP_AUTH" with a default value of "plain".
+//   - TLS: The TLS mode, one of "none", "starttls" or "tls". It is loaded from the environment variable "EMAIL_SERVICE_SMTP_TLS" with a default value of "starttls".
+//   - LocalName: The hostname sent with the EHLO command. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_LOCAL_NAME".
+//   - Timeout: The timeout of an SMTP session, from connecting to the server to sending the message. It is loaded from the environment variable "EMAIL_SERVICE_SMTP_TIMEOUT" with a default value of 10s.
+type SMTP struct {
+	Host      string        `env:"EMAIL_SERVICE_SMTP_HOST"`
+	Port      int           `env:"EMAIL_SERVICE_SMTP_PORT" envDefault:"587"`
+	Username  string        `env:"EMAIL_SERVICE_SMTP_USERNAME"`
+	Password  string        `env:"EMAIL_SERVICE_SMTP_PASSWORD"`
+	Auth      string        `env:"EMAIL_SERVICE_SMTP_AUTH" envDefault:"plain"`
+	TLS       string        `env:"EMAIL_SERVICE_SMTP_TLS" envDefault:"starttls"`
+	LocalName string        `env:"EMAIL_SERVICE_SMTP_LOCAL_NAME"`
+	Timeout   time.Duration `env:"EMAIL_SERVICE_SMTP_TIMEOUT" envDefault:"10s"`
+}
+
+// Storage holds the configuration for the embedded, file-backed data stores used by the service.
+//
+// Fields:
+//   - DataDir: The directory the service persists its data in. It must survive restarts, e.g. a persistent volume, or queued emails are lost. It is loaded from the environment variable "EMAIL_SERVICE_DATA_DIR" with a default value of "/var/lib/mail-service".
+type Storage struct {
+	DataDir string `env:"EMAIL_SERVICE_DATA_DIR" envDefault:"/var/lib/mail-service"`
+}
+
+// Outbox holds the configuration for the outbox that persists and asynchronously delivers emails.
+//
+// Fields:
+//   - Workers: The number of concurrent delivery workers. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_WORKERS" with a default value of 4.
+//   - PollInterval: How often the outbox looks for emails due for delivery. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_POLL_INTERVAL" with a default value of 1s.
+//   - MaxAttempts: The number of delivery attempts before an email is moved to the dead-letter state. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_MAX_ATTEMPTS" with a default value of 8.
+//   - InitialBackoff: The delay before the first retry, doubled for every following retry. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_INITIAL_BACKOFF" with a default value of 5s.
+//   - MaxBackoff: The upper bound for the delay between two attempts. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_MAX_BACKOFF" with a default value of 10m.
+//   - AttemptTimeout: How long a single delivery attempt may take before it is abandoned and retried. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_ATTEMPT_TIMEOUT" with a default value of 30s.
+//   - Retention: How long sent and dead-lettered emails are kept after their last change. They are kept forever when zero. It is loaded from the environment variable "EMAIL_SERVICE_OUTBOX_RETENTION" with a default value of 720h.
+type Outbox struct {
+	Workers        int           `env:"EMAIL_SERVICE_OUTBOX_WORKERS" envDefault:"4"`
+	PollInterval   time.Duration `env:"EMAIL_SERVICE_OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	MaxAttempts    int           `env:"EMAIL_SERVICE_OUTBOX_MAX_ATTEMPTS" envDefault:"8"`
+	InitialBackoff time.Duration `env:"EMAIL_SERVICE_OUTBOX_INITIAL_BACKOFF" envDefault:"5s"`
+	MaxBackoff     time.Duration `env:"EMAIL_SERVICE_OUTBOX_MAX_BACKOFF" envDefault:"10m"`
+	AttemptTimeout time.Duration `env:"EMAIL_SERVICE_OUTBOX_ATTEMPT_TIMEOUT" envDefault:"30s"`
+	Retention      time.Duration `env:"EMAIL_SERVICE_OUTBOX_RETENTION" envDefault:"720h"`
+}
+
+// Events holds the configuration for the endpoint that receives SES bounce, complaint and delivery events through an SNS HTTP(S) subscription.
+//
+// Fields:
+//   - Enabled: Whether the events endpoint is served. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_ENABLED" with a default value of false.
+//   - Path: The HTTP path of the events endpoint. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_PATH" with a default value of "/v1/events/ses".
+//   - TopicARNs: A comma separated list of SNS topic ARNs accepted by the endpoint. At least one is required when the endpoint is enabled. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_TOPIC_ARNS".
+//   - CertFile: A PEM encoded certificate used to verify SNS signatures instead of downloading the signing certificate. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_CERT_FILE".
+//   - CertHostPattern: A regular expression the host of signing certificate and subscription URLs must match. The AWS SNS endpoints are matched when empty. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_CERT_HOST_PATTERN".
+//   - AutoConfirm: Whether SNS subscriptions are confirmed automatically. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_AUTO_CONFIRM" with a default value of false.
+//   - MaxAge: How old an SNS message may be before it is rejected as a replay. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_MAX_AGE" with a default value of 1h.
+//   - Timeout: The timeout of the requests downloading signing certificates and confirming subscriptions. It is loaded from the environment variable "EMAIL_SERVICE_EVENTS_TIMEOUT" with a default value of 10s.
+type Events struct {
+	Enabled         bool          `env:"EMAIL_SERVICE_EVENTS_ENABLED" envDefault:"false"`
+	Path            string        `env:"EMAIL_SERVICE_EVENTS_PATH" envDefault:"/v1/events/ses"`
+	TopicARNs       []string      `env:"EMAIL_SERVICE_EVENTS_TOPIC_ARNS" envSeparator:","`
+	CertFile        string        `env:"EMAIL_SERVICE_EVENTS_CERT_FILE"`
+	CertHostPattern string        `env:"EMAIL_SERVICE_EVENTS_CERT_HOST_PATTERN"`
+	AutoConfirm     bool          `env:"EMAIL_SERVICE_EVENTS_AUTO_CONFIRM" envDefault:"false"`
+	MaxAge          time.Duration `env:"EMAIL_SERVICE_EVENTS_MAX_AGE" envDefault:"1h"`
+	Timeout         time.Duration `env:"EMAIL_SERVICE_EVENTS_TIMEOUT" envDefault:"10s"`
+}
+
+// Suppression holds the configuration for the list of addresses that are never mailed.
+//
+// Fields:
+//   - SyncSES: Whether the suppression list is mirrored to and imported from the AWS SES account-level suppression list. Only used with the "ses" transport. It is loaded from the environment variable "EMAIL_SERVICE_SUPPRESSION_SYNC_SES" with a default value of false.
+//   - SyncInterval: How often expired suppressions are pruned and the AWS SES suppression list is imported. It is loaded from the environment variable "EMAIL_SERVICE_SUPPRESSION_SYNC_INTERVAL" with a default value of 1h.
+type Suppression struct {
+	SyncSES      bool          `env:"EMAIL_SERVICE_SUPPRESSION_SYNC_SES" envDefault:"false"`
+	SyncInterval time.Duration `env:"EMAIL_SERVICE_SUPPRESSION_SYNC_INTERVAL" envDefault:"1h"`
+}
+
+// Templates holds the sources of the email templates. Every part of a template is taken from the first of the following
+// that provides it: the inline value, the file, the template directory, and finally the compiled-in default.
+//
+// Fields:
+//   - Dir: A directory holding template files named "<key>.subject.txt", "<key>.html" and "<key>.txt", where key is "thank_you" or "forward". It is loaded from the environment variable "EMAIL_SERVICE_TEMPLATE_DIR".
+//   - ThankYou: The TemplateSource of the thank you email, loaded from environment variables prefixed with "EMAIL_SERVICE_TEMPLATE_THANK_YOU_".
+//   - Forward: The TemplateSource of the forward email, loaded from environment variables prefixed with "EMAIL_SERVICE_TEMPLATE_FORWARD_".
+//   - Engine: The engine used to render the templates, either "ses" or "local". It is loaded from the environment variable "EMAIL_SERVICE_TEMPLATE_ENGINE" with a default value of "ses".
+//   - PartialsDir: A directory of layouts and partials available to the "local" engine. It is loaded from the environment variable "EMAIL_SERVICE_TEMPLATE_PARTIALS_DIR".
+//   - Preserve: Whether templates that already exist in AWS SES are left untouched at startup, so edits made through the TemplateService survive a restart. It is loaded from the environment variable "EMAIL_SERVICE_TEMPLATE_PRESERVE" with a default value of false.
+type Templates struct {
+	Dir         string         `env:"EMAIL_SERVICE_TEMPLATE_DIR"`
+	ThankYou    TemplateSource `envPrefix:"EMAIL_SERVICE_TEMPLATE_THANK_YOU_"`
+	Forward     TemplateSource `envPrefix:"EMAIL_SERVICE_TEMPLATE_FORWARD_"`
+	Engine      string         `env:"EMAIL_SERVICE_TEMPLATE_ENGINE" envDefault:"ses"`
+	PartialsDir string         `env:"EMAIL_SERVICE_TEMPLATE_PARTIALS_DIR"`
+	Preserve    bool           `env:"EMAIL_SERVICE_TEMPLATE_PRESERVE" envDefault:"false"`
+}
+
+// TemplateSource holds the configured parts of a single email template. Inline values take precedence over files.
+//
+// Fields:
+//   - Subject: The subject line. It is loaded from the environment variable "<prefix>SUBJECT".
+//   - HTML: The base64 standard encoded HTML body. It is loaded from the environment variable "<prefix>HTML".
+//   - Text: The base64 standard encoded plain text body. It is loaded from the environment variable "<prefix>TEXT".
+//   - SubjectFile: The path of a file holding the subject line. It is loaded from the environment variable "<prefix>SUBJECT_FILE".
+//   - HTMLFile: The path of a file holding the HTML body. It is loaded from the environment variable "<prefix>HTML_FILE".
+//   - TextFile: The path of a file holding the plain text body. It is loaded from the environment variable "<prefix>TEXT_FILE".
+type TemplateSource struct {
+	Subject     string `env:"SUBJECT"`
+	HTML        string `env:"HTML"`
+	Text        string `env:"TEXT"`
+	SubjectFile string `env:"SUBJECT_FILE"`
+	HTMLFile    string `env:"HTML_FILE"`
+	TextFile    string `env:"TEXT_FILE"`
+}
+
+// Attachments holds the limits enforced on the files uploaded with a submission.
+//
+// Fields:
+//   - UploadPath: The path of the gateway route accepting multipart/form-data submissions. It is loaded from the environment variable "EMAIL_SERVICE_ATTACHMENTS_UPLOAD_PATH" with a default value of "/v1/mail/upload".
+//   - AllowedTypes: A comma separated list of the media types uploaded files may have, e.g. "image/*,application/pdf". Every type is allowed when empty. It is loaded from the environment variable "EMAIL_SERVICE_ATTACHMENTS_ALLOWED_TYPES".
+//   - MaxCount: The maximum number of attachments per submission. Attachments are rejected when zero. It is loaded from the environment variable "EMAIL_SERVICE_ATTACHMENTS_MAX_COUNT" with a default value of 5.
+//   - MaxFileSize: The maximum size of a single attachment in bytes. It is loaded from the environment variable "EMAIL_SERVICE_ATTACHMENTS_MAX_FILE_SIZE" with a default value of 5242880 (5 MiB).
+//   - MaxTotalSize: The maximum combined size of the attachments of a submission in bytes. Base64 encoding grows attachments by a third, so keep it well below the 10 MB message limit of AWS SES. It is loaded from the environment variable "EMAIL_SERVICE_ATTACHMENTS_MAX_TOTAL_SIZE" with a default value of 7340032 (7 MiB).
+type Attachments struct {
+	UploadPath   string   `env:"EMAIL_SERVICE_ATTACHMENTS_UPLOAD_PATH" envDefault:"/v1/mail/upload"`
+	AllowedTypes []string `env:"EMAIL_SERVICE_ATTACHMENTS_ALLOWED_TYPES" envSeparator:"," envDefault:"application/pdf,image/png,image/jpeg,image/gif,image/webp,text/plain,application/msword,application/vnd.openxmlformats-officedocument.wordprocessingml.document"`
+	MaxCount     int      `env:"EMAIL_SERVICE_ATTACHMENTS_MAX_COUNT" envDefault:"5"`
+	MaxFileSize  int64    `env:"EMAIL_SERVICE_ATTACHMENTS_MAX_FILE_SIZE" envDefault:"5242880"`
+	MaxTotalSize int64    `env:"EMAIL_SERVICE_ATTACHMENTS_MAX_TOTAL_SIZE" envDefault:"7340032"`
+}
+
+// HTMLForm holds the configuration for submissions from plain HTML forms, which are answered with a redirect.
+//
+// Fields:
+//   - Path: The path of the gateway route accepting application/x-www-form-urlencoded submissions. It is loaded from the environment variable "EMAIL_SERVICE_HTML_FORM_PATH" with a default value of "/v1/mail/form".
+//   - SuccessURL: The page submissions are redirected to once accepted, unless their site has its own. It is loaded from the environment variable "EMAIL_SERVICE_HTML_FORM_SUCCESS_URL".
+//   - ErrorURL: The page submissions are redirected to when rejected, unless their site has its own. It is loaded from the environment variable "EMAIL_SERVICE_HTML_FORM_ERROR_URL".
+//   - Sites: A comma separated list of per-site redirect pages formatted as "<host>=<success url> <error url>", matched against the Origin or Referer of a submission. It is loaded from the environment variable "EMAIL_SERVICE_HTML_FORM_SITES".
+type HTMLForm struct {
+	Path       string   `env:"EMAIL_SERVICE_HTML_FORM_PATH" envDefault:"/v1/mail/form"`
+	SuccessURL string   `env:"EMAIL_SERVICE_HTML_FORM_SUCCESS_URL"`
+	ErrorURL   string   `env:"EMAIL_SERVICE_HTML_FORM_ERROR_URL"`
+	Sites      []string `env:"EMAIL_SERVICE_HTML_FORM_SITES" envSeparator:","`
+}
+
+// Forms holds the configuration of the registry of contact forms, each with its own recipients, templates and anti-abuse settings.
+//
+// Fields:
+//   - File: The path of a YAML file defining the forms. Settings a form leaves out are taken from the service-wide configuration. A single form is served, built from the service-wide configuration, when empty. It is loaded from the environment variable "EMAIL_SERVICE_FORMS_FILE".
+type Forms struct {
+	File string `env:"EMAIL_SERVICE_FORMS_FILE"`
+}
+
+// Captcha holds the configuration of the CAPTCHA provider verifying the submissions of forms that do not configure their own.
+//
+// Fields:
+//   - Provider: The provider, one of "none", "hcaptcha", "turnstile" or "recaptcha". It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_PROVIDER" with a default value of "none".
+//   - Secret: The secret key issued by the provider. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_SECRET".
+//   - Endpoint: The siteverify endpoint of the provider, e.g. to point at a stand-in during tests. The public endpoint of the provider is used when empty. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_ENDPOINT".
+//   - MinScore: The minimum score a token must have, between 0 and 1, e.g. 0.5 for reCAPTCHA v3. Scores are not checked when zero. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_MIN_SCORE".
+//   - Action: The action a token must have been issued for. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_ACTION".
+//   - Hostnames: A comma separated list of the hostnames a token may have been solved on. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_HOSTNAMES".
+//   - Timeout: The timeout of a verification request. It is loaded from the environment variable "EMAIL_SERVICE_CAPTCHA_TIMEOUT" with a default value of 5s.
+type Captcha struct {
+	Provider  string        `env:"EMAIL_SERVICE_CAPTCHA_PROVIDER" envDefault:"none"`
+	Secret    string        `env:"EMAIL_SERVICE_CAPTCHA_SECRET"`
+	Endpoint  string        `env:"EMAIL_SERVICE_CAPTCHA_ENDPOINT"`
+	MinScore  float64       `env:"EMAIL_SERVICE_CAPTCHA_MIN_SCORE"`
+	Action    string        `env:"EMAIL_SERVICE_CAPTCHA_ACTION"`
+	Hostnames []string      `env:"EMAIL_SERVICE_CAPTCHA_HOSTNAMES" envSeparator:","`
+	Timeout   time.Duration `env:"EMAIL_SERVICE_CAPTCHA_TIMEOUT" envDefault:"5s"`
+}
+
+// RateLimit holds the configuration of the token buckets throttling submissions. A limit is written as
+// "<requests>/<duration>", e.g. "10/1h" allows bursts of 10 submissions refilled over an hour, and is disabled when empty.
+//
+// Fields:
+//   - Backend: Where the token buckets are kept, either "memory" or "redis". It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_BACKEND" with a default value of "memory".
+//   - RedisURL: The URL of the Redis server used by the "redis" backend, e.g. "redis://redis:6379/0". It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_REDIS_URL".
+//   - IP: The limit of every client IP address. It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_IP" with a default value of "10/1h".
+//   - Email: The limit of every submitter email address. It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_EMAIL" with a default value of "5/1h".
+//   - Form: The limit of every form, shared by all of its submitters. It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_FORM" with a default value of "100/1h".
+//   - TrustedHops: The number of proxies in front of the service, e.g. 1 behind an ingress controller, whose X-Forwarded-For entries are trusted. It also picks the submitter IP address passed to CAPTCHA providers. It is loaded from the environment variable "EMAIL_SERVICE_RATE_LIMIT_TRUSTED_HOPS" with a default value of 0.
+type RateLimit struct {
+	Backend     string `env:"EMAIL_SERVICE_RATE_LIMIT_BACKEND" envDefault:"memory"`
+	RedisURL    string `env:"EMAIL_SERVICE_RATE_LIMIT_REDIS_URL"`
+	IP          string `env:"EMAIL_SERVICE_RATE_LIMIT_IP" envDefault:"10/1h"`
+	Email       string `env:"EMAIL_SERVICE_RATE_LIMIT_EMAIL" envDefault:"5/1h"`
+	Form        string `env:"EMAIL_SERVICE_RATE_LIMIT_FORM" envDefault:"100/1h"`
+	TrustedHops int    `env:"EMAIL_SERVICE_RATE_LIMIT_TRUSTED_HOPS" envDefault:"0"`
+}
+
+// Idempotency holds the configuration of the idempotency keys submissions may carry. Retries of a submission carrying
+// the same key within the window are answered with the response of the first instead of being sent again.
+//
+// Fields:
+//   - Backend: Where the keys are kept, either "memory" or "redis". It is loaded from the environment variable "EMAIL_SERVICE_IDEMPOTENCY_BACKEND" with a default value of "memory".
+//   - RedisURL: The URL of the Redis server used by the "redis" backend, e.g. "redis://redis:6379/0". It is loaded from the environment variable "EMAIL_SERVICE_IDEMPOTENCY_REDIS_URL".
+//   - Window: How long the response of a submission is replayed to its retries. Idempotency keys are ignored when zero. It is loaded from the environment variable "EMAIL_SERVICE_IDEMPOTENCY_WINDOW" with a default value of 24h.
+type Idempotency struct {
+	Backend  string        `env:"EMAIL_SERVICE_IDEMPOTENCY_BACKEND" envDefault:"memory"`
+	RedisURL string        `env:"EMAIL_SERVICE_IDEMPOTENCY_REDIS_URL"`
+	Window   time.Duration `env:"EMAIL_SERVICE_IDEMPOTENCY_WINDOW" envDefault:"24h"`
+}
+
+// FormTokens holds the configuration of the signed tokens forms embed when rendered, which show when a form was
+// rendered and that a submission is not replayed. Submissions must carry a token when a secret is set.
+//
+// Fields:
+//   - Secret: The key the tokens are signed with. Form tokens are disabled when empty. It is loaded from the environment variable "EMAIL_SERVICE_FORM_TOKEN_SECRET".
+//   - MinAge: The minimum time between rendering a form and submitting it. Faster submissions are dropped as automated. It is loaded from the environment variable "EMAIL_SERVICE_FORM_TOKEN_MIN_AGE" with a default value of 3s.
+//   - MaxAge: The time after which a token is no longer accepted. It is loaded from the environment variable "EMAIL_SERVICE_FORM_TOKEN_MAX_AGE" with a default value of 1h.
+//   - Backend: Where the used tokens are kept, either "memory" or "redis". It is loaded from the environment variable "EMAIL_SERVICE_FORM_TOKEN_BACKEND" with a default value of "memory".
+//   - RedisURL: The URL of the Redis server used by the "redis" backend, e.g. "redis://redis:6379/0". It is loaded from the environment variable "EMAIL_SERVICE_FORM_TOKEN_REDIS_URL".
+type FormTokens struct {
+	Secret   string        `env:"EMAIL_SERVICE_FORM_TOKEN_SECRET"`
+	MinAge   time.Duration `env:"EMAIL_SERVICE_FORM_TOKEN_MIN_AGE" envDefault:"3s"`
+	MaxAge   time.Duration `env:"EMAIL_SERVICE_FORM_TOKEN_MAX_AGE" envDefault:"1h"`
+	Backend  string        `env:"EMAIL_SERVICE_FORM_TOKEN_BACKEND" envDefault:"memory"`
+	RedisURL string        `env:"EMAIL_SERVICE_FORM_TOKEN_REDIS_URL"`
+}
+
+// Spam holds the configuration of the spam filter, which scores submissions with a pipeline of rules before they
+// are forwarded. A threshold of zero disables its verdict.
+//
+// Fields:
+//   - Enabled: Whether submissions are screened for spam. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_ENABLED" with a default value of false.
+//   - TagThreshold: The score from which the subject of a forwarded submission is tagged with "[SPAM?]". It is loaded from the environment variable "EMAIL_SERVICE_SPAM_TAG_THRESHOLD" with a default value of 3.
+//   - QuarantineThreshold: The score from which a submission is held for review. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_QUARANTINE_THRESHOLD" with a default value of 6.
+//   - DropThreshold: The score from which a submission is dropped. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_DROP_THRESHOLD" with a default value of 10.
+//   - MaxLinks: The number of links a submission may contain before every further link adds to its score. Negative disables the rule. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_MAX_LINKS" with a default value of 2.
+//   - Shorteners: A comma separated list of the URL shortener domains whose links add to the score. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_SHORTENERS" with a default value of common shorteners, e.g. "bit.ly" and "tinyurl.com".
+//   - Keywords: A comma separated list of the words and phrases, matched case-insensitively, that add to the score. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_KEYWORDS".
+//   - Patterns: A newline separated list of the regular expressions, matched case-insensitively, that add to the score. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_PATTERNS".
+//   - Scripts: A comma separated list of the Unicode scripts submissions are expected in, e.g. "Latin". Submissions mostly written in other scripts add to the score. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_SCRIPTS".
+//   - RepeatWindow: How long identical messages are remembered. Messages repeated within the window add to the score. It is loaded from the environment variable "EMAIL_SERVICE_SPAM_REPEAT_WINDOW" with a default value of 24h.
+type Spam struct {
+	Enabled             bool          `env:"EMAIL_SERVICE_SPAM_ENABLED" envDefault:"false"`
+	TagThreshold        float64       `env:"EMAIL_SERVICE_SPAM_TAG_THRESHOLD" envDefault:"3"`
+	QuarantineThreshold float64       `env:"EMAIL_SERVICE_SPAM_QUARANTINE_THRESHOLD" envDefault:"6"`
+	DropThreshold       float64       `env:"EMAIL_SERVICE_SPAM_DROP_THRESHOLD" envDefault:"10"`
+	MaxLinks            int           `env:"EMAIL_SERVICE_SPAM_MAX_LINKS" envDefault:"2"`
+	Shorteners          []string      `env:"EMAIL_SERVICE_SPAM_SHORTENERS" envSeparator:"," envDefault:"bit.ly,tinyurl.com,t.co,goo.gl,ow.ly,is.gd,buff.ly,rebrand.ly,cutt.ly,shorturl.at"`
+	Keywords            []string      `env:"EMAIL_SERVICE_SPAM_KEYWORDS" envSeparator:","`
+	Patterns            []string      `env:"EMAIL_SERVICE_SPAM_PATTERNS" envSeparator:"\n"`
+	Scripts             []string      `env:"EMAIL_SERVICE_SPAM_SCRIPTS" envSeparator:","`
+	RepeatWindow        time.Duration `env:"EMAIL_SERVICE_SPAM_REPEAT_WINDOW" envDefault:"24h"`
 }
 
 // Load loads the configuration from environment variables using the env package.
@@ -53,5 +414,9 @@ func Load() (*Config, error) {
 		return &cfg, fmt.Errorf("failed to load environment: %s", err.Error())
 	}
 
+	if cfg.Templates.ThankYou.HTML == "" {
+		cfg.Templates.ThankYou.HTML = cfg.Email.ThankYouTemplate
+	}
+
 	return &cfg, nil
 }
@@ -23,6 +23,29 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MailServiceClient interface {
 	SendMail(ctx context.Context, in *SendMailRequest, opts ...grpc.CallOption) (*SendMailResponse, error)
+	// IssueFormToken issues the signed token a form embeds when it is rendered, so submissions can prove when the
+	// form was rendered and that they were not replayed.
+	IssueFormToken(ctx context.Context, in *IssueFormTokenRequest, opts ...grpc.CallOption) (*FormToken, error)
+	// GetMessageStatus reports the delivery state of a message returned by SendMail. Requires the admin token.
+	GetMessageStatus(ctx context.Context, in *GetMessageStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error)
+	// ListMessages lists the messages known to the service, newest first. Requires the admin token.
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	// AddSuppression adds an address to the suppression list, so it is never mailed again. Requires the admin token.
+	AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error)
+	// RemoveSuppression removes an address from the suppression list. Requires the admin token.
+	RemoveSuppression(ctx context.Context, in *RemoveSuppressionRequest, opts ...grpc.CallOption) (*RemoveSuppressionResponse, error)
+	// ListSuppressions lists the suppressed addresses, ordered by address. Requires the admin token.
+	ListSuppressions(ctx context.Context, in *ListSuppressionsRequest, opts ...grpc.CallOption) (*ListSuppressionsResponse, error)
+	// TrainSpamFilter trains the spam classifier with a message marked as spam or not. Requires the admin token.
+	TrainSpamFilter(ctx context.Context, in *TrainSpamFilterRequest, opts ...grpc.CallOption) (*TrainSpamFilterResponse, error)
+	// ListQuarantine lists the submissions held back for review as likely spam, newest first. Requires the admin token.
+	ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error)
+	// ReleaseQuarantined forwards a quarantined submission and trains the spam classifier with it as legitimate.
+	// Requires the admin token.
+	ReleaseQuarantined(ctx context.Context, in *ReleaseQuarantinedRequest, opts ...grpc.CallOption) (*ReleaseQuarantinedResponse, error)
+	// DiscardQuarantined deletes a quarantined submission and trains the spam classifier with it as spam. Requires the
+	// admin token.
+	DiscardQuarantined(ctx context.Context, in *DiscardQuarantinedRequest, opts ...grpc.CallOption) (*DiscardQuarantinedResponse, error)
 }
 
 type mailServiceClient struct {
@@ -42,11 +65,124 @@ func (c *mailServiceClient) SendMail(ctx context.Context, in *SendMailRequest, o
 	return out, nil
 }
 
+func (c *mailServiceClient) IssueFormToken(ctx context.Context, in *IssueFormTokenRequest, opts ...grpc.CallOption) (*FormToken, error) {
+	out := new(FormToken)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/IssueFormToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) GetMessageStatus(ctx context.Context, in *GetMessageStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error) {
+	out := new(MessageStatus)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/GetMessageStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/ListMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error) {
+	out := new(Suppression)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/AddSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) RemoveSuppression(ctx context.Context, in *RemoveSuppressionRequest, opts ...grpc.CallOption) (*RemoveSuppressionResponse, error) {
+	out := new(RemoveSuppressionResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/RemoveSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) ListSuppressions(ctx context.Context, in *ListSuppressionsRequest, opts ...grpc.CallOption) (*ListSuppressionsResponse, error) {
+	out := new(ListSuppressionsResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/ListSuppressions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) TrainSpamFilter(ctx context.Context, in *TrainSpamFilterRequest, opts ...grpc.CallOption) (*TrainSpamFilterResponse, error) {
+	out := new(TrainSpamFilterResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/TrainSpamFilter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error) {
+	out := new(ListQuarantineResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/ListQuarantine", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) ReleaseQuarantined(ctx context.Context, in *ReleaseQuarantinedRequest, opts ...grpc.CallOption) (*ReleaseQuarantinedResponse, error) {
+	out := new(ReleaseQuarantinedResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/ReleaseQuarantined", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) DiscardQuarantined(ctx context.Context, in *DiscardQuarantinedRequest, opts ...grpc.CallOption) (*DiscardQuarantinedResponse, error) {
+	out := new(DiscardQuarantinedResponse)
+	err := c.cc.Invoke(ctx, "/mailservice.MailService/DiscardQuarantined", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MailServiceServer is the server API for MailService service.
 // All implementations must embed UnimplementedMailServiceServer
 // for forward compatibility
 type MailServiceServer interface {
 	SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error)
+	// IssueFormToken issues the signed token a form embeds when it is rendered, so submissions can prove when the
+	// form was rendered and that they were not replayed.
+	IssueFormToken(context.Context, *IssueFormTokenRequest) (*FormToken, error)
+	// GetMessageStatus reports the delivery state of a message returned by SendMail. Requires the admin token.
+	GetMessageStatus(context.Context, *GetMessageStatusRequest) (*MessageStatus, error)
+	// ListMessages lists the messages known to the service, newest first. Requires the admin token.
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	// AddSuppression adds an address to the suppression list, so it is never mailed again. Requires the admin token.
+	AddSuppression(context.Context, *AddSuppressionRequest) (*Suppression, error)
+	// RemoveSuppression removes an address from the suppression list. Requires the admin token.
+	RemoveSuppression(context.Context, *RemoveSuppressionRequest) (*RemoveSuppressionResponse, error)
+	// ListSuppressions lists the suppressed addresses, ordered by address. Requires the admin token.
+	ListSuppressions(context.Context, *ListSuppressionsRequest) (*ListSuppressionsResponse, error)
+	// TrainSpamFilter trains the spam classifier with a message marked as spam or not. Requires the admin token.
+	TrainSpamFilter(context.Context, *TrainSpamFilterRequest) (*TrainSpamFilterResponse, error)
+	// ListQuarantine lists the submissions held back for review as likely spam, newest first. Requires the admin token.
+	ListQuarantine(context.Context, *ListQuarantineRequest) (*ListQuarantineResponse, error)
+	// ReleaseQuarantined forwards a quarantined submission and trains the spam classifier with it as legitimate.
+	// Requires the admin token.
+	ReleaseQuarantined(context.Context, *ReleaseQuarantinedRequest) (*ReleaseQuarantinedResponse, error)
+	// DiscardQuarantined deletes a quarantined submission and trains the spam classifier with it as spam. Requires the
+	// admin token.
+	DiscardQuarantined(context.Context, *DiscardQuarantinedRequest) (*DiscardQuarantinedResponse, error)
 	mustEmbedUnimplementedMailServiceServer()
 }
 
@@ -57,6 +193,36 @@ type UnimplementedMailServiceServer struct {
 func (UnimplementedMailServiceServer) SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendMail not implemented")
 }
+func (UnimplementedMailServiceServer) IssueFormToken(context.Context, *IssueFormTokenRequest) (*FormToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueFormToken not implemented")
+}
+func (UnimplementedMailServiceServer) GetMessageStatus(context.Context, *GetMessageStatusRequest) (*MessageStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessageStatus not implemented")
+}
+func (UnimplementedMailServiceServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedMailServiceServer) AddSuppression(context.Context, *AddSuppressionRequest) (*Suppression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSuppression not implemented")
+}
+func (UnimplementedMailServiceServer) RemoveSuppression(context.Context, *RemoveSuppressionRequest) (*RemoveSuppressionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveSuppression not implemented")
+}
+func (UnimplementedMailServiceServer) ListSuppressions(context.Context, *ListSuppressionsRequest) (*ListSuppressionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSuppressions not implemented")
+}
+func (UnimplementedMailServiceServer) TrainSpamFilter(context.Context, *TrainSpamFilterRequest) (*TrainSpamFilterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TrainSpamFilter not implemented")
+}
+func (UnimplementedMailServiceServer) ListQuarantine(context.Context, *ListQuarantineRequest) (*ListQuarantineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuarantine not implemented")
+}
+func (UnimplementedMailServiceServer) ReleaseQuarantined(context.Context, *ReleaseQuarantinedRequest) (*ReleaseQuarantinedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseQuarantined not implemented")
+}
+func (UnimplementedMailServiceServer) DiscardQuarantined(context.Context, *DiscardQuarantinedRequest) (*DiscardQuarantinedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscardQuarantined not implemented")
+}
 func (UnimplementedMailServiceServer) mustEmbedUnimplementedMailServiceServer() {}
 
 // UnsafeMailServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +254,186 @@ func _MailService_SendMail_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MailService_IssueFormToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueFormTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).IssueFormToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/IssueFormToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).IssueFormToken(ctx, req.(*IssueFormTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_GetMessageStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).GetMessageStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/GetMessageStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).GetMessageStatus(ctx, req.(*GetMessageStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).ListMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/ListMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).ListMessages(ctx, req.(*ListMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_AddSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).AddSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/AddSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).AddSuppression(ctx, req.(*AddSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_RemoveSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).RemoveSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/RemoveSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).RemoveSuppression(ctx, req.(*RemoveSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_ListSuppressions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSuppressionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).ListSuppressions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/ListSuppressions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).ListSuppressions(ctx, req.(*ListSuppressionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_TrainSpamFilter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrainSpamFilterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).TrainSpamFilter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/TrainSpamFilter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).TrainSpamFilter(ctx, req.(*TrainSpamFilterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_ListQuarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).ListQuarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/ListQuarantine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).ListQuarantine(ctx, req.(*ListQuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_ReleaseQuarantined_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseQuarantinedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).ReleaseQuarantined(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/ReleaseQuarantined",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).ReleaseQuarantined(ctx, req.(*ReleaseQuarantinedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_DiscardQuarantined_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscardQuarantinedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).DiscardQuarantined(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailservice.MailService/DiscardQuarantined",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).DiscardQuarantined(ctx, req.(*DiscardQuarantinedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MailService_ServiceDesc is the grpc.ServiceDesc for MailService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +445,46 @@ var MailService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendMail",
 			Handler:    _MailService_SendMail_Handler,
 		},
+		{
+			MethodName: "IssueFormToken",
+			Handler:    _MailService_IssueFormToken_Handler,
+		},
+		{
+			MethodName: "GetMessageStatus",
+			Handler:    _MailService_GetMessageStatus_Handler,
+		},
+		{
+			MethodName: "ListMessages",
+			Handler:    _MailService_ListMessages_Handler,
+		},
+		{
+			MethodName: "AddSuppression",
+			Handler:    _MailService_AddSuppression_Handler,
+		},
+		{
+			MethodName: "RemoveSuppression",
+			Handler:    _MailService_RemoveSuppression_Handler,
+		},
+		{
+			MethodName: "ListSuppressions",
+			Handler:    _MailService_ListSuppressions_Handler,
+		},
+		{
+			MethodName: "TrainSpamFilter",
+			Handler:    _MailService_TrainSpamFilter_Handler,
+		},
+		{
+			MethodName: "ListQuarantine",
+			Handler:    _MailService_ListQuarantine_Handler,
+		},
+		{
+			MethodName: "ReleaseQuarantined",
+			Handler:    _MailService_ReleaseQuarantined_Handler,
+		},
+		{
+			MethodName: "DiscardQuarantined",
+			Handler:    _MailService_DiscardQuarantined_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "v1/mail-service.proto",
@@ -0,0 +1,186 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// maxLength is the maximum length of an address in octets, the longest path SMTP accepts minus its angle brackets.
+	maxLength = 254
+	// maxLocalLength is the maximum length of the local part of an address in octets.
+	maxLocalLength = 64
+	// specials are the characters, besides letters and digits, allowed in the atoms of an unquoted local part.
+	specials = "!#$%&'*+-/=?^_`{|}~"
+)
+
+// ErrInvalid is returned when an email address is malformed. Errors returned by Normalize wrap it with the reason the
+// address was rejected.
+var ErrInvalid = errors.New("invalid email address")
+
+// domains converts domain names to their ASCII form with the IDNA2008 lookup rules, rejecting labels that are not
+// valid host names.
+var domains = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// Normalize validates an email address and returns its canonical form. Addresses must be a bare addr-spec as defined
+// by RFC 5322, e.g. "jane@example.com", where the local part may contain UTF-8 as allowed by RFC 6531. Display names,
+// comments and address literals are rejected, and the domain must be a fully qualified domain name.
+//
+// The canonical form has surrounding whitespace removed, the local part in Unicode normalization form C and lower
+// case, and the domain in its lower case ASCII form, internationalized domains being converted to punycode. Most
+// providers treat local parts case-insensitively, so submissions differing only in case are treated as the same address.
+//
+// Parameters:
+//   - raw: The address to validate.
+//
+// Returns:
+//   - string: The normalized address.
+//   - error: An error wrapping ErrInvalid if the address is malformed.
+func Normalize(raw string) (string, error) {
+	addr := strings.TrimSpace(raw)
+	if addr == "" {
+		return "", invalid("the address is empty")
+	}
+
+	if !utf8.ValidString(addr) {
+		return "", invalid("the address is not valid UTF-8")
+	}
+
+	// Domains cannot contain an @, so the last one separates the domain from a local part that may quote one.
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return "", invalid("the address is missing an @")
+	}
+
+	local, err := normalizeLocal(addr[:at])
+	if err != nil {
+		return "", err
+	}
+
+	domain, err := normalizeDomain(addr[at+1:])
+	if err != nil {
+		return "", err
+	}
+
+	addr = local + "@" + domain
+	if len(addr) > maxLength {
+		return "", invalid(fmt.Sprintf("the address is longer than %d octets", maxLength))
+	}
+
+	return addr, nil
+}
+
+// normalizeLocal validates the local part of an address, either a dot-atom or a quoted string, and normalizes it.
+func normalizeLocal(local string) (string, error) {
+	if local == "" {
+		return "", invalid("the local part is empty")
+	}
+
+	local = strings.ToLower(norm.NFC.String(local))
+	if len(local) > maxLocalLength {
+		return "", invalid(fmt.Sprintf("the local part is longer than %d octets", maxLocalLength))
+	}
+
+	if strings.HasPrefix(local, `"`) {
+		return local, validateQuoted(local)
+	}
+
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return "", invalid("the local part has a leading, trailing or repeated dot")
+		}
+
+		for _, r := range atom {
+			if !isAtext(r) {
+				return "", invalid(fmt.Sprintf("the local part contains %q, which must be quoted", r))
+			}
+		}
+	}
+
+	return local, nil
+}
+
+// validateQuoted validates a quoted local part, e.g. "jane doe". Only printable characters and spaces may be quoted.
+func validateQuoted(local string) error {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return invalid("the local part has an unterminated quote")
+	}
+
+	escaped := false
+	for _, r := range local[1 : len(local)-1] {
+		switch {
+		case !isQtext(r) && r != '"' && r != '\\':
+			return invalid(fmt.Sprintf("the local part contains %q", r))
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return invalid("the local part contains an unescaped quote")
+		}
+	}
+
+	if escaped {
+		return invalid("the local part has an unterminated quote")
+	}
+
+	return nil
+}
+
+// normalizeDomain validates the domain of an address and converts it to its ASCII form.
+func normalizeDomain(domain string) (string, error) {
+	if domain == "" {
+		return "", invalid("the domain is empty")
+	}
+
+	if strings.HasPrefix(domain, "[") {
+		return "", invalid("address literals are not accepted")
+	}
+
+	// The lookup rules accept the trailing dot of an absolute name, which addresses cannot carry.
+	ascii, err := domains.ToASCII(domain)
+	if err != nil || strings.HasSuffix(ascii, ".") {
+		return "", invalid(fmt.Sprintf("the domain %q is not a valid domain name", domain))
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", invalid("the domain must be fully qualified")
+	}
+
+	if tld := labels[len(labels)-1]; strings.Trim(tld, "0123456789") == "" {
+		return "", invalid("the top-level domain must not be numeric")
+	}
+
+	return ascii, nil
+}
+
+// isAtext reports whether a character may appear in an atom: letters, digits, the specials and, following RFC 6531,
+// any printable non-ASCII character.
+func isAtext(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune(specials, r)
+	}
+
+	return unicode.IsGraphic(r) && !unicode.IsSpace(r)
+}
+
+// isQtext reports whether a character may appear unescaped in a quoted string: printable ASCII characters but the
+// quote and backslash, spaces and, following RFC 6531, any printable non-ASCII character.
+func isQtext(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r == ' ' || '!' <= r && r <= '~' && r != '"' && r != '\\'
+	}
+
+	return unicode.IsGraphic(r)
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, reason)
+}
@@ -0,0 +1,79 @@
+package address
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		address      string
+	}
+
+	valid := func(address string) want {
+		return want{
+			errAssertion: func(t *testing.T, err error) {
+				assert.Empty(t, err)
+			},
+			address: address,
+		}
+	}
+
+	invalid := func(reason string) want {
+		return want{
+			errAssertion: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrInvalid)
+				assert.ErrorContains(t, err, reason)
+			},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{"accepts address", "jane@example.com", valid("jane@example.com")},
+		{"trims whitespace and lowers case", "  Jane.Doe@Example.COM\n", valid("jane.doe@example.com")},
+		{"accepts specials", "jane+contact/form=1@example.com", valid("jane+contact/form=1@example.com")},
+		{"accepts quoted local part", `"jane doe"@example.com`, valid(`"jane doe"@example.com`)},
+		{"accepts quoted at sign", `"jane@home"@example.com`, valid(`"jane@home"@example.com`)},
+		{"accepts internationalized local part", "Jürgen@example.com", valid("jürgen@example.com")},
+		{"normalizes unicode", "jürgen@example.com", valid("jürgen@example.com")},
+		{"converts internationalized domain to punycode", "jane@Bücher.example", valid("jane@xn--bcher-kva.example")},
+		{"accepts punycode domain", "jane@xn--bcher-kva.example", valid("jane@xn--bcher-kva.example")},
+		{"accepts fully internationalized address", "用户@例子.广告", valid("用户@xn--fsqu00a.xn--4rr70v")},
+		{"handles empty address", " ", invalid("the address is empty")},
+		{"handles missing at sign", "jane.example.com", invalid("missing an @")},
+		{"handles empty local part", "@example.com", invalid("the local part is empty")},
+		{"handles empty domain", "jane@", invalid("the domain is empty")},
+		{"handles display name", "Jane <jane@example.com>", invalid("must be quoted")},
+		{"handles header injection", "jane@example.com\r\nBcc: victim@example.com", invalid("must be quoted")},
+		{"handles space in local part", "jane doe@example.com", invalid("must be quoted")},
+		{"handles leading dot", ".jane@example.com", invalid("leading, trailing or repeated dot")},
+		{"handles repeated dot", "jane..doe@example.com", invalid("leading, trailing or repeated dot")},
+		{"handles unterminated quote", `"jane@example.com`, invalid("unterminated quote")},
+		{"handles unescaped quote", `"ja"ne"@example.com`, invalid("unescaped quote")},
+		{"handles control character", "ja\x00ne@example.com", invalid("must be quoted")},
+		{"handles long local part", strings.Repeat("a", 65) + "@example.com", invalid("longer than 64 octets")},
+		{"handles long address", strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("a", 62)+".", 3) + "com", invalid("longer than 254 octets")},
+		{"handles address literal", "jane@[192.0.2.1]", invalid("address literals are not accepted")},
+		{"handles ip address", "jane@192.0.2.1", invalid("must not be numeric")},
+		{"handles unqualified domain", "jane@localhost", invalid("must be fully qualified")},
+		{"handles invalid domain", "jane@exa_mple.com", invalid("not a valid domain name")},
+		{"handles trailing dot", "jane@example.com.", invalid("not a valid domain name")},
+		{"handles invalid punycode", "jane@xn--a.example", invalid("not a valid domain name")},
+		{"handles invalid utf-8", "ja\xffne@example.com", invalid("not valid UTF-8")},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			tt.want.errAssertion(t, err)
+			assert.Equal(t, tt.want.address, got)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AdminInterceptor returns a unary interceptor that protects administrative RPCs with a shared bearer token.
+// Calls to any of the given methods must carry an "authorization: Bearer <token>" header, which the gRPC-Gateway
+// forwards from the HTTP Authorization header. When no token is configured the administrative RPCs are disabled.
+// Calls to all other methods pass through untouched.
+//
+// Parameters:
+//   - token: The admin token callers must present.
+//   - methods: The full gRPC method names, e.g. "/mailservice.MailService/ListMessages", that require the token.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: The interceptor enforcing the token.
+func AdminInterceptor(token string, methods ...string) grpc.UnaryServerInterceptor {
+	protected := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		protected[m] = struct{}{}
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := protected[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		if token == "" {
+			return nil, status.Error(codes.PermissionDenied, "administrative api is disabled")
+		}
+
+		if !validToken(ctx, token) {
+			return nil, status.Error(codes.Unauthenticated, "a valid admin token is required")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func validToken(ctx context.Context, token string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	for _, v := range md.Get("authorization") {
+		scheme, presented, ok := strings.Cut(v, " ")
+		if ok && strings.EqualFold(scheme, "bearer") && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
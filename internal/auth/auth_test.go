@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminInterceptorUnit(t *testing.T) {
+	type input struct {
+		token         string
+		method        string
+		authorization string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  codes.Code
+	}{
+		{
+			"passes through unprotected methods",
+			input{token: "secret", method: "/mailservice.MailService/SendMail"},
+			codes.OK,
+		},
+		{
+			"rejects protected methods when no token is configured",
+			input{method: "/mailservice.MailService/ListMessages", authorization: "Bearer "},
+			codes.PermissionDenied,
+		},
+		{
+			"rejects a missing token",
+			input{token: "secret", method: "/mailservice.MailService/ListMessages"},
+			codes.Unauthenticated,
+		},
+		{
+			"rejects a wrong token",
+			input{token: "secret", method: "/mailservice.MailService/ListMessages", authorization: "Bearer guess"},
+			codes.Unauthenticated,
+		},
+		{
+			"is successful with the admin token",
+			input{token: "secret", method: "/mailservice.MailService/ListMessages", authorization: "bearer secret"},
+			codes.OK,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.input.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.input.authorization))
+			}
+
+			interceptor := AdminInterceptor(tt.input.token, "/mailservice.MailService/ListMessages")
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.input.method}, func(ctx context.Context, req any) (any, error) {
+				return "ok", nil
+			})
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+}
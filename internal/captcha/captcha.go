@@ -0,0 +1,223 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ProviderNone disables CAPTCHA verification.
+	ProviderNone = "none"
+	// ProviderHCaptcha verifies hCaptcha tokens.
+	ProviderHCaptcha = "hcaptcha"
+	// ProviderTurnstile verifies Cloudflare Turnstile tokens.
+	ProviderTurnstile = "turnstile"
+	// ProviderReCAPTCHA verifies Google reCAPTCHA v2 and v3 tokens.
+	ProviderReCAPTCHA = "recaptcha"
+)
+
+// endpoints are the siteverify endpoints of the providers.
+var endpoints = map[string]string{
+	ProviderHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	ProviderTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	ProviderReCAPTCHA: "https://www.google.com/recaptcha/api/siteverify",
+}
+
+// maxResponseSize bounds the siteverify responses read from a provider.
+const maxResponseSize = 64 << 10
+
+// ErrRejected is returned, wrapped, when a provider does not accept a token. Any other error means the token could
+// not be verified, e.g. because the provider is unreachable.
+var ErrRejected = errors.New("captcha rejected")
+
+// Verifier verifies the CAPTCHA token solved by the submitter of a form.
+//
+// Methods:
+//   - Verify: Verifies a token with the provider. It returns an error wrapping ErrRejected if the token is not accepted.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// Config holds the configuration of a CAPTCHA provider.
+//
+// Fields:
+//   - Provider: The provider, one of "none", "hcaptcha", "turnstile" or "recaptcha". Verification is disabled when empty or "none".
+//   - Secret: The secret key issued by the provider.
+//   - Endpoint: The siteverify endpoint of the provider. The public endpoint of the provider is used when empty.
+//   - MinScore: The minimum score a token must have, between 0 and 1. Scores are only checked when set, e.g. for reCAPTCHA v3.
+//   - Action: The action a token must have been issued for, e.g. "contact". Not checked when empty.
+//   - Hostnames: The hostnames a token may have been solved on. Not checked when empty.
+//   - Timeout: The timeout of a verification request. Defaults to 5 seconds.
+//   - HTTPClient: The http.Client used to call the provider. Defaults to a client with Timeout.
+type Config struct {
+	Provider   string
+	Secret     string
+	Endpoint   string
+	MinScore   float64
+	Action     string
+	Hostnames  []string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+// siteVerifier verifies tokens with the siteverify protocol shared by hCaptcha, Turnstile and reCAPTCHA: the secret
+// and the token are posted as a form, and the provider answers with a JSON verdict.
+type siteVerifier struct {
+	provider  string
+	secret    string
+	endpoint  string
+	minScore  float64
+	action    string
+	hostnames []string
+	client    *http.Client
+}
+
+// siteVerifyResponse is the verdict of a provider. Score and action are only reported by score based providers,
+// e.g. reCAPTCHA v3, and Turnstile reports the action of every token.
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	Action     string   `json:"action"`
+	Hostname   string   `json:"hostname"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// New creates the Verifier of the configured provider.
+//
+// Parameters:
+//   - cfg: The Config object containing the provider and its secret.
+//
+// Returns:
+//   - Verifier: The Verifier, or nil if verification is disabled.
+//   - error: An error if the provider is unknown, the secret is missing, or the endpoint or minimum score is invalid.
+func New(cfg Config) (Verifier, error) {
+	if cfg.Provider == "" || cfg.Provider == ProviderNone {
+		return nil, nil
+	}
+
+	endpoint, ok := endpoints[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha provider %q", cfg.Provider)
+	}
+
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("captcha provider %q has no secret", cfg.Provider)
+	}
+
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid captcha endpoint %q: must be an absolute http or https url", cfg.Endpoint)
+		}
+		endpoint = cfg.Endpoint
+	}
+
+	if cfg.MinScore < 0 || cfg.MinScore > 1 {
+		return nil, fmt.Errorf("invalid captcha minimum score %v: must be between 0 and 1", cfg.MinScore)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	hostnames := make([]string, 0, len(cfg.Hostnames))
+	for _, h := range cfg.Hostnames {
+		hostnames = append(hostnames, strings.ToLower(strings.TrimSpace(h)))
+	}
+
+	return &siteVerifier{
+		provider:  cfg.Provider,
+		secret:    cfg.Secret,
+		endpoint:  endpoint,
+		minScore:  cfg.MinScore,
+		action:    cfg.Action,
+		hostnames: hostnames,
+		client:    client,
+	}, nil
+}
+
+// Verify verifies a token with the provider. The token must be successful and, when configured, reach the minimum
+// score and match the action and hostnames.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - token: The token solved by the submitter.
+//   - remoteIP: The IP address of the submitter, passed to the provider as a hint. Left out when empty.
+//
+// Returns:
+//   - error: An error wrapping ErrRejected if the token is not accepted, or any other error if it could not be verified.
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("%w: no token", ErrRejected)
+	}
+
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", v.provider, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", v.provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to call %s: unexpected status %s", v.provider, resp.Status)
+	}
+
+	var verdict siteVerifyResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&verdict); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", v.provider, err)
+	}
+
+	return v.check(verdict)
+}
+
+func (v *siteVerifier) check(verdict siteVerifyResponse) error {
+	if !verdict.Success {
+		return fmt.Errorf("%w: %s", ErrRejected, strings.Join(verdict.ErrorCodes, ", "))
+	}
+
+	if v.minScore > 0 {
+		if verdict.Score == nil {
+			return fmt.Errorf("%w: no score", ErrRejected)
+		}
+
+		if *verdict.Score < v.minScore {
+			return fmt.Errorf("%w: score %.2f is below %.2f", ErrRejected, *verdict.Score, v.minScore)
+		}
+	}
+
+	if v.action != "" && verdict.Action != v.action {
+		return fmt.Errorf("%w: action %q does not match", ErrRejected, verdict.Action)
+	}
+
+	if len(v.hostnames) > 0 && !slices.Contains(v.hostnames, strings.ToLower(verdict.Hostname)) {
+		return fmt.Errorf("%w: hostname %q is not allowed", ErrRejected, verdict.Hostname)
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyUnit(t *testing.T) {
+	type input struct {
+		cfg    Config
+		token  string
+		status int
+		body   string
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"accepts hcaptcha token",
+			input{
+				cfg:   Config{Provider: ProviderHCaptcha},
+				token: "token",
+				body:  `{"success":true,"hostname":"www.example.com"}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"accepts turnstile token with matching action and hostname",
+			input{
+				cfg:   Config{Provider: ProviderTurnstile, Action: "contact", Hostnames: []string{"WWW.example.com"}},
+				token: "token",
+				body:  `{"success":true,"action":"contact","hostname":"www.example.com"}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"accepts recaptcha v2 token",
+			input{
+				cfg:   Config{Provider: ProviderReCAPTCHA},
+				token: "token",
+				body:  `{"success":true}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"accepts recaptcha v3 token above the minimum score",
+			input{
+				cfg:   Config{Provider: ProviderReCAPTCHA, MinScore: 0.5},
+				token: "token",
+				body:  `{"success":true,"score":0.9,"action":"submit"}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"rejects recaptcha v3 token below the minimum score",
+			input{
+				cfg:   Config{Provider: ProviderReCAPTCHA, MinScore: 0.5},
+				token: "token",
+				body:  `{"success":true,"score":0.1}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "score 0.10 is below 0.50")
+				},
+			},
+		},
+		{
+			"rejects token without score when a score is required",
+			input{
+				cfg:   Config{Provider: ProviderReCAPTCHA, MinScore: 0.5},
+				token: "token",
+				body:  `{"success":true}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "no score")
+				},
+			},
+		},
+		{
+			"rejects unsuccessful token",
+			input{
+				cfg:   Config{Provider: ProviderHCaptcha},
+				token: "token",
+				body:  `{"success":false,"error-codes":["invalid-input-response"]}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "invalid-input-response")
+				},
+			},
+		},
+		{
+			"rejects mismatched action",
+			input{
+				cfg:   Config{Provider: ProviderTurnstile, Action: "contact"},
+				token: "token",
+				body:  `{"success":true,"action":"login"}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+				},
+			},
+		},
+		{
+			"rejects mismatched hostname",
+			input{
+				cfg:   Config{Provider: ProviderHCaptcha, Hostnames: []string{"www.example.com"}},
+				token: "token",
+				body:  `{"success":true,"hostname":"evil.example"}`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+				},
+			},
+		},
+		{
+			"rejects missing token without calling the provider",
+			input{
+				cfg:    Config{Provider: ProviderHCaptcha},
+				status: http.StatusInternalServerError,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "no token")
+				},
+			},
+		},
+		{
+			"handles provider failure",
+			input{
+				cfg:    Config{Provider: ProviderTurnstile},
+				token:  "token",
+				status: http.StatusBadGateway,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.NotErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "unexpected status 502")
+				},
+			},
+		},
+		{
+			"handles invalid response",
+			input{
+				cfg:   Config{Provider: ProviderTurnstile},
+				token: "token",
+				body:  `<html>`,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.NotErrorIs(t, err, ErrRejected)
+					assert.ErrorContains(t, err, "failed to decode turnstile response")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+				assert.Equal(t, "secret", r.PostFormValue("secret"))
+				assert.Equal(t, tt.input.token, r.PostFormValue("response"))
+				assert.Equal(t, "203.0.113.7", r.PostFormValue("remoteip"))
+
+				if tt.input.status != 0 {
+					w.WriteHeader(tt.input.status)
+					return
+				}
+				w.Write([]byte(tt.input.body))
+			}))
+			defer srv.Close()
+
+			cfg := tt.input.cfg
+			cfg.Secret = "secret"
+			cfg.Endpoint = srv.URL
+
+			v, err := New(cfg)
+			require.Empty(t, err)
+
+			err = v.Verify(context.Background(), tt.input.token, "203.0.113.7")
+			tt.want.errAssertion(t, err)
+		})
+	}
+}
+
+func TestNewUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		disabled     bool
+	}
+
+	cases := []struct {
+		name  string
+		input Config
+		want  want
+	}{
+		{
+			"disables verification without a provider",
+			Config{},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				disabled: true,
+			},
+		},
+		{
+			"disables verification with the none provider",
+			Config{Provider: ProviderNone, Secret: "secret"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				disabled: true,
+			},
+		},
+		{
+			"uses the public endpoint",
+			Config{Provider: ProviderReCAPTCHA, Secret: "secret"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"handles unknown provider",
+			Config{Provider: "friendlycaptcha", Secret: "secret"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `unknown captcha provider "friendlycaptcha"`)
+				},
+			},
+		},
+		{
+			"handles missing secret",
+			Config{Provider: ProviderHCaptcha},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "has no secret")
+				},
+			},
+		},
+		{
+			"handles invalid endpoint",
+			Config{Provider: ProviderHCaptcha, Secret: "secret", Endpoint: "/siteverify"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "invalid captcha endpoint")
+				},
+			},
+		},
+		{
+			"handles invalid minimum score",
+			Config{Provider: ProviderReCAPTCHA, Secret: "secret", MinScore: 5},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "invalid captcha minimum score")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(tt.input)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.disabled, v == nil)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DefaultSNSHostPattern matches the hostnames AWS serves SNS signing certificates and subscription URLs from.
+const DefaultSNSHostPattern = `^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`
+
+// DefaultTimeout bounds the requests made to AWS SNS when no http.Client is provided.
+const DefaultTimeout = 10 * time.Second
+
+// CertificateSource resolves the certificate referenced by the SigningCertURL of an SNS message.
+//
+// Methods:
+//   - Certificate: Returns the certificate for the given signing certificate URL.
+type CertificateSource interface {
+	Certificate(ctx context.Context, certURL string) (*x509.Certificate, error)
+}
+
+type httpCertificateSource struct {
+	client *http.Client
+	hosts  *regexp.Regexp
+
+	mu    sync.Mutex
+	cache map[string]*x509.Certificate
+}
+
+// NewHTTPCertificateSource creates a CertificateSource that downloads signing certificates over HTTPS.
+// Only URLs whose host matches the host pattern are fetched, which prevents a forged message from pointing
+// the service at a certificate controlled by an attacker. Downloaded certificates are cached.
+//
+// Parameters:
+//   - client: The http.Client used to download certificates. A client with DefaultTimeout is used when nil.
+//   - hostPattern: A regular expression the certificate URL host must match. Defaults to DefaultSNSHostPattern when empty.
+//
+// Returns:
+//   - CertificateSource: The newly created certificate source.
+//   - error: An error if the host pattern is not a valid regular expression.
+func NewHTTPCertificateSource(client *http.Client, hostPattern string) (CertificateSource, error) {
+	if hostPattern == "" {
+		hostPattern = DefaultSNSHostPattern
+	}
+
+	hosts, err := regexp.Compile(hostPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sns host pattern: %w", err)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &httpCertificateSource{
+		client: client,
+		hosts:  hosts,
+		cache:  map[string]*x509.Certificate{},
+	}, nil
+}
+
+// Certificate downloads and parses the PEM encoded certificate at certURL.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - certURL: The SigningCertURL of the SNS message.
+//
+// Returns:
+//   - *x509.Certificate: The signing certificate.
+//   - error: An error if the URL is not trusted or the certificate could not be downloaded or parsed.
+func (s *httpCertificateSource) Certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if err := checkURL(certURL, s.hosts); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cert, ok := s.cache[certURL]
+	s.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download signing certificate: unexpected status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+
+	cert, err = parseCertificate(b)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[certURL] = cert
+	s.mu.Unlock()
+
+	return cert, nil
+}
+
+type staticCertificateSource struct {
+	cert *x509.Certificate
+}
+
+// NewStaticCertificateSource creates a CertificateSource that always returns the given certificate,
+// regardless of the SigningCertURL. It is intended for local development and tests with a local signing key.
+//
+// Parameters:
+//   - cert: The certificate used to verify every message.
+//
+// Returns:
+//   - CertificateSource: The newly created certificate source.
+func NewStaticCertificateSource(cert *x509.Certificate) CertificateSource {
+	return staticCertificateSource{cert: cert}
+}
+
+// NewFileCertificateSource creates a static CertificateSource from a PEM encoded certificate file.
+//
+// Parameters:
+//   - path: The path of the PEM encoded certificate.
+//
+// Returns:
+//   - CertificateSource: The newly created certificate source.
+//   - error: An error if the file could not be read or parsed.
+func NewFileCertificateSource(path string) (CertificateSource, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+
+	cert, err := parseCertificate(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStaticCertificateSource(cert), nil
+}
+
+// Certificate returns the static certificate.
+func (s staticCertificateSource) Certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	return s.cert, nil
+}
+
+func parseCertificate(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("signing certificate is not a pem encoded certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// checkURL verifies that rawURL is an https URL whose host matches the trusted host pattern.
+func checkURL(rawURL string, hosts *regexp.Regexp) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("malformed sns url: %w", err)
+	}
+
+	if u.Scheme != "https" || !hosts.MatchString(u.Hostname()) {
+		return fmt.Errorf("untrusted sns url %q", rawURL)
+	}
+
+	return nil
+}
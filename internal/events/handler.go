@@ -0,0 +1,302 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"go.uber.org/zap"
+)
+
+// maxBodySize is the maximum size of an SNS message accepted by the handler. SNS messages are at most 256 KiB.
+const maxBodySize = 256 << 10
+
+// DefaultMaxAge is how old an SNS message may be when the handler receives it, unless configured otherwise. It covers
+// the retries of the default SNS delivery policy with room to spare.
+const DefaultMaxAge = time.Hour
+
+// recorder is an interface that defines the methods from the outbox that are used by the handler to apply delivery events.
+type recorder interface {
+	RecordEvent(providerMessageID string, e outbox.Event) (outbox.Record, error)
+}
+
+// suppressor is an interface that defines the methods from the suppression list that are used by the handler to
+// suppress recipients that hard-bounced or complained.
+type suppressor interface {
+	Add(ctx context.Context, e suppression.Entry) (suppression.Entry, error)
+}
+
+// Config holds the configuration required to initialize the SNS handler.
+//
+// Fields:
+//   - Certificates: The CertificateSource used to verify SNS message signatures.
+//   - Recorder: The outbox the parsed SES events are applied to.
+//   - Suppressions: An optional suppression list the recipients of permanent bounces and complaints are added to.
+//   - TopicARNs: The SNS topics the handler accepts messages from. At least one is required, as any AWS account can
+//     publish validly signed messages.
+//   - HostPattern: A regular expression the host of SubscribeURL must match. Defaults to DefaultSNSHostPattern.
+//   - AutoConfirm: Whether subscriptions are confirmed automatically by visiting the SubscribeURL.
+//   - MaxAge: How old a message may be, by its Timestamp, before it is rejected as a replay. Defaults to DefaultMaxAge.
+//   - Client: The http.Client used to confirm subscriptions. A client with DefaultTimeout is used when nil.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Certificates CertificateSource
+	Recorder     recorder
+	Suppressions suppressor
+	TopicARNs    []string
+	HostPattern  string
+	AutoConfirm  bool
+	MaxAge       time.Duration
+	Client       *http.Client
+	Logger       *zap.Logger
+}
+
+type handler struct {
+	certs       CertificateSource
+	recorder    recorder
+	suppressor  suppressor
+	topics      map[string]struct{}
+	hosts       *regexp.Regexp
+	autoConfirm bool
+	maxAge      time.Duration
+	client      *http.Client
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewHandler creates an http.Handler that receives SES events through an SNS HTTP(S) subscription.
+// It verifies the signature of every message, confirms subscriptions, and applies SES Bounce, Complaint,
+// Delivery, Open and Click events to the messages in the outbox.
+//
+// Parameters:
+//   - cfg: The Config object containing the certificate source, outbox and accepted topics.
+//
+// Returns:
+//   - http.Handler: The newly created handler.
+//   - error: An error if no topic is accepted or the host pattern is not a valid regular expression.
+func NewHandler(cfg Config) (http.Handler, error) {
+	if len(cfg.TopicARNs) == 0 {
+		return nil, errors.New("at least one sns topic arn must be accepted")
+	}
+
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+
+	if cfg.HostPattern == "" {
+		cfg.HostPattern = DefaultSNSHostPattern
+	}
+
+	hosts, err := regexp.Compile(cfg.HostPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sns host pattern: %w", err)
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	topics := make(map[string]struct{}, len(cfg.TopicARNs))
+	for _, t := range cfg.TopicARNs {
+		topics[t] = struct{}{}
+	}
+
+	return &handler{
+		certs:       cfg.Certificates,
+		recorder:    cfg.Recorder,
+		suppressor:  cfg.Suppressions,
+		topics:      topics,
+		hosts:       hosts,
+		autoConfirm: cfg.AutoConfirm,
+		maxAge:      cfg.MaxAge,
+		client:      cfg.Client,
+		logger:      cfg.Logger,
+		now:         time.Now,
+	}, nil
+}
+
+// ServeHTTP handles a single SNS message.
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, "malformed sns message", http.StatusBadRequest)
+		return
+	}
+
+	logger := h.logger.With(zap.String("sns_message_id", msg.MessageID), zap.String("topic_arn", msg.TopicArn), zap.String("type", msg.Type))
+
+	if _, ok := h.topics[msg.TopicArn]; !ok {
+		logger.Warn("Rejected SNS message from unexpected topic.")
+		http.Error(w, "unexpected topic", http.StatusForbidden)
+		return
+	}
+
+	if err := h.checkTimestamp(msg.Timestamp); err != nil {
+		logger.With(zap.Error(err)).Warn("Rejected stale SNS message.")
+		http.Error(w, "stale message", http.StatusForbidden)
+		return
+	}
+
+	if err := msg.Verify(r.Context(), h.certs); err != nil {
+		logger.With(zap.Error(err)).Warn("Rejected SNS message with invalid signature.")
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	switch msg.Type {
+	case TypeSubscriptionConfirmation:
+		if err := h.confirm(r.Context(), msg); err != nil {
+			logger.With(zap.Error(err)).Error("Failed to confirm SNS subscription.")
+			http.Error(w, "failed to confirm subscription", http.StatusBadGateway)
+			return
+		}
+	case TypeUnsubscribeConfirmation:
+		logger.Info("SNS subscription removed.")
+	case TypeNotification:
+		if err := h.notify(r.Context(), msg, logger); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "unsupported sns message type", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkTimestamp checks that a message was sent within the maximum age, so captured messages cannot be replayed later.
+// The signature covers the timestamp, so it cannot be changed.
+func (h handler) checkTimestamp(timestamp string) error {
+	sent, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q: %w", timestamp, err)
+	}
+
+	if age := h.now().Sub(sent); age > h.maxAge || age < -h.maxAge {
+		return fmt.Errorf("message sent at %s is outside the window of %s", timestamp, h.maxAge)
+	}
+
+	return nil
+}
+
+// confirm confirms a subscription by visiting its SubscribeURL, or logs the URL when auto confirmation is disabled.
+func (h handler) confirm(ctx context.Context, msg Message) error {
+	if !h.autoConfirm {
+		h.logger.With(zap.String("topic_arn", msg.TopicArn), zap.String("subscribe_url", msg.SubscribeURL)).Info("SNS subscription awaiting manual confirmation.")
+		return nil
+	}
+
+	if err := checkURL(msg.SubscribeURL, h.hosts); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, msg.SubscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create confirmation request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm subscription: unexpected status %d", resp.StatusCode)
+	}
+
+	h.logger.With(zap.String("topic_arn", msg.TopicArn)).Info("SNS subscription confirmed.")
+	return nil
+}
+
+// notify applies the SES event carried by the notification and suppresses the recipients of permanent bounces and
+// complaints. Events that cannot be applied because they are unsupported or refer to unknown messages are acknowledged
+// so SNS does not retry them. The outbox holds events for unknown messages for a few minutes, in case they were
+// reported before it recorded the message as sent.
+func (h handler) notify(ctx context.Context, msg Message, logger *zap.Logger) error {
+	ev, err := ParseSESEvent([]byte(msg.Message))
+	if err != nil {
+		logger.With(zap.Error(err)).Info("Ignored SNS notification.")
+		return nil
+	}
+
+	logger = logger.With(zap.String("provider_message_id", ev.ProviderMessageID), zap.String("event", string(ev.Event.Type)))
+
+	if err := h.suppress(ctx, ev, logger); err != nil {
+		return err
+	}
+
+	r, err := h.recorder.RecordEvent(ev.ProviderMessageID, ev.Event)
+	if err != nil {
+		if errors.Is(err, outbox.ErrNotFound) {
+			logger.Info("Held SES event for unknown message.")
+			return nil
+		}
+
+		logger.With(zap.Error(err)).Error("Failed to record SES event.")
+		return errors.New("failed to record event")
+	}
+
+	logger.With(zap.String("message_id", r.ID), zap.String("state", string(r.State))).Info("Recorded SES event.")
+	return nil
+}
+
+// suppress adds the recipients of permanent bounces and complaints to the suppression list.
+func (h handler) suppress(ctx context.Context, ev SESEvent, logger *zap.Logger) error {
+	if h.suppressor == nil {
+		return nil
+	}
+
+	var reason suppression.Reason
+	switch {
+	case ev.Event.Type == outbox.EventBounce && ev.BounceType == BounceTypePermanent:
+		reason = suppression.ReasonBounce
+	case ev.Event.Type == outbox.EventComplaint:
+		reason = suppression.ReasonComplaint
+	default:
+		return nil
+	}
+
+	for _, addr := range ev.Event.Recipients {
+		if _, err := h.suppressor.Add(ctx, suppression.Entry{
+			Address:   addr,
+			Reason:    reason,
+			Detail:    ev.Event.Detail,
+			CreatedAt: ev.Event.Timestamp,
+		}); err != nil {
+			if errors.Is(err, suppression.ErrInvalidAddress) {
+				continue
+			}
+
+			logger.With(zap.Error(err), zap.String("address", addr)).Error("Failed to suppress recipient.")
+			return errors.New("failed to suppress recipient")
+		}
+
+		logger.With(zap.String("address", addr), zap.String("reason", string(reason))).Info("Suppressed recipient.")
+	}
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package events
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTopicARN = "arn:aws:sns:us-east-1:123456789012:ses-events"
+
+// testNow is the time the handlers under test receive messages at, shortly after the test messages were sent.
+func testNow() time.Time {
+	return time.Date(2024, 5, 2, 18, 45, 0, 0, time.UTC)
+}
+
+func TestHandlerUnit(t *testing.T) {
+	key, cert := newSigningKey(t)
+
+	type input struct {
+		msg         Message
+		tamper      bool
+		method      string
+		recorderErr error
+		now         time.Time
+	}
+
+	type want struct {
+		status     int
+		events     []outbox.EventType
+		suppressed []string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"records bounce notification",
+			input{msg: notification(t, "bounce.json")},
+			want{status: http.StatusOK, events: []outbox.EventType{outbox.EventBounce}, suppressed: []string{"recipient@example.com:bounce"}},
+		},
+		{
+			"does not suppress recipients of transient bounces",
+			input{msg: func() Message {
+				m := notification(t, "bounce.json")
+				m.Message = strings.Replace(m.Message, `"Permanent"`, `"Transient"`, 1)
+				return m
+			}()},
+			want{status: http.StatusOK, events: []outbox.EventType{outbox.EventBounce}},
+		},
+		{
+			"records delivery notification",
+			input{msg: notification(t, "delivery.json")},
+			want{status: http.StatusOK, events: []outbox.EventType{outbox.EventDelivery}},
+		},
+		{
+			"acknowledges unsupported event",
+			input{msg: notification(t, "send.json")},
+			want{status: http.StatusOK},
+		},
+		{
+			"acknowledges event for unknown message",
+			input{msg: notification(t, "complaint.json"), recorderErr: outbox.ErrNotFound},
+			want{status: http.StatusOK, events: []outbox.EventType{outbox.EventComplaint}, suppressed: []string{"recipient@example.com:complaint"}},
+		},
+		{
+			"fails when event could not be recorded so sns retries",
+			input{msg: notification(t, "complaint.json"), recorderErr: errors.New("disk full")},
+			want{status: http.StatusInternalServerError, events: []outbox.EventType{outbox.EventComplaint}, suppressed: []string{"recipient@example.com:complaint"}},
+		},
+		{
+			"rejects invalid signature",
+			input{msg: notification(t, "bounce.json"), tamper: true},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"rejects unexpected topic",
+			input{msg: func() Message {
+				m := notification(t, "bounce.json")
+				m.TopicArn = "arn:aws:sns:us-east-1:123456789012:other"
+				return m
+			}()},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"rejects message older than the maximum age",
+			input{msg: notification(t, "bounce.json"), now: testNow().Add(2 * time.Hour)},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"rejects message from the future",
+			input{msg: notification(t, "bounce.json"), now: testNow().Add(-2 * time.Hour)},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"rejects message without timestamp",
+			input{msg: func() Message {
+				m := notification(t, "bounce.json")
+				m.Timestamp = ""
+				return m
+			}()},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"rejects non post requests",
+			input{msg: notification(t, "bounce.json"), method: http.MethodGet},
+			want{status: http.StatusMethodNotAllowed},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &mockRecorder{err: tt.input.recorderErr}
+			sup := &mockSuppressor{}
+			h, err := NewHandler(Config{
+				Certificates: NewStaticCertificateSource(cert),
+				Recorder:     rec,
+				Suppressions: sup,
+				TopicARNs:    []string{testTopicARN},
+			})
+			require.Empty(t, err)
+
+			now := tt.input.now
+			if now.IsZero() {
+				now = testNow()
+			}
+			h.(*handler).now = func() time.Time { return now }
+
+			msg := sign(t, key, tt.input.msg)
+			if tt.input.tamper {
+				msg.Message = strings.Replace(msg.Message, "Permanent", "Transient", 1)
+			}
+
+			method := tt.input.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, "/v1/events/ses", body(t, msg)))
+
+			assert.Equal(t, tt.want.status, w.Code)
+			assert.Equal(t, tt.want.events, rec.events)
+			assert.Equal(t, tt.want.suppressed, sup.added)
+		})
+	}
+}
+
+func TestHandlerMalformedUnit(t *testing.T) {
+	_, cert := newSigningKey(t)
+
+	h, err := NewHandler(Config{Certificates: NewStaticCertificateSource(cert), Recorder: &mockRecorder{}, TopicARNs: []string{testTopicARN}})
+	require.Empty(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/events/ses", strings.NewReader("not json")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNewHandlerRequiresTopicUnit(t *testing.T) {
+	_, cert := newSigningKey(t)
+
+	_, err := NewHandler(Config{Certificates: NewStaticCertificateSource(cert), Recorder: &mockRecorder{}})
+	assert.ErrorContains(t, err, "at least one sns topic arn must be accepted")
+}
+
+func TestHandlerSubscriptionConfirmationUnit(t *testing.T) {
+	key, cert := newSigningKey(t)
+
+	var confirmed atomic.Int32
+	sns := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ConfirmSubscription", r.URL.Query().Get("Action"))
+		confirmed.Add(1)
+	}))
+	defer sns.Close()
+
+	cases := []struct {
+		name        string
+		autoConfirm bool
+		hostPattern string
+		status      int
+		confirmed   int32
+	}{
+		{"confirms subscription", true, `^127\.0\.0\.1$`, http.StatusOK, 1},
+		{"does not confirm subscription when auto confirm is disabled", false, `^127\.0\.0\.1$`, http.StatusOK, 0},
+		{"refuses to confirm subscription on untrusted host", true, DefaultSNSHostPattern, http.StatusBadGateway, 0},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmed.Store(0)
+
+			h, err := NewHandler(Config{
+				Certificates: NewStaticCertificateSource(cert),
+				Recorder:     &mockRecorder{},
+				TopicARNs:    []string{testTopicARN},
+				HostPattern:  tt.hostPattern,
+				AutoConfirm:  tt.autoConfirm,
+				Client:       sns.Client(),
+			})
+			require.Empty(t, err)
+			h.(*handler).now = testNow
+
+			msg := sign(t, key, Message{
+				Type:         TypeSubscriptionConfirmation,
+				MessageID:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+				Token:        "2336412f37",
+				TopicArn:     testTopicARN,
+				Message:      "You have chosen to subscribe to the topic " + testTopicARN + ".",
+				SubscribeURL: sns.URL + "/?Action=ConfirmSubscription&TopicArn=" + testTopicARN + "&Token=2336412f37",
+				Timestamp:    "2024-05-02T18:00:00.000Z",
+			})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/events/ses", body(t, msg)))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.confirmed, confirmed.Load())
+		})
+	}
+}
+
+type mockRecorder struct {
+	err    error
+	events []outbox.EventType
+}
+
+func (m *mockRecorder) RecordEvent(providerMessageID string, e outbox.Event) (outbox.Record, error) {
+	m.events = append(m.events, e.Type)
+	if m.err != nil {
+		return outbox.Record{}, m.err
+	}
+
+	return outbox.Record{ID: "message-id", ProviderMessageID: providerMessageID, Events: []outbox.Event{e}}, nil
+}
+
+type mockSuppressor struct {
+	added []string
+}
+
+func (m *mockSuppressor) Add(ctx context.Context, e suppression.Entry) (suppression.Entry, error) {
+	m.added = append(m.added, e.Address+":"+string(e.Reason))
+	return e, nil
+}
+
+func newSigningKey(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Empty(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.us-east-1.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Empty(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Empty(t, err)
+
+	return key, cert
+}
+
+func notification(t *testing.T, file string) Message {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", file))
+	require.Empty(t, err)
+
+	return Message{
+		Type:           TypeNotification,
+		MessageID:      "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:       testTopicARN,
+		Message:        string(b),
+		Timestamp:      "2024-05-02T18:41:04.512Z",
+		SigningCertURL: "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem",
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, m Message) Message {
+	t.Helper()
+
+	m.SignatureVersion = "2"
+	sum := sha256.Sum256([]byte(m.StringToSign()))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	require.Empty(t, err)
+	m.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	return m
+}
+
+func body(t *testing.T, m Message) *strings.Reader {
+	t.Helper()
+
+	b, err := json.Marshal(m)
+	require.Empty(t, err)
+
+	return strings.NewReader(string(b))
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+)
+
+// BounceTypePermanent is the SES bounce type for hard bounces, which will never be delivered.
+const BounceTypePermanent = "Permanent"
+
+// ErrUnsupportedEvent is returned for SES events that do not map to a message status update, such as Send or Reject.
+var ErrUnsupportedEvent = errors.New("unsupported ses event")
+
+// SESEvent is an SES event parsed from an SNS notification.
+//
+// Fields:
+//   - ProviderMessageID: The SES message ID of the message the event was reported for.
+//   - Event: The event to apply to the message.
+//   - BounceType: The SES bounce type ("Permanent", "Transient" or "Undetermined") of bounce events.
+type SESEvent struct {
+	ProviderMessageID string
+	Event             outbox.Event
+	BounceType        string
+}
+
+// sesNotification is the JSON document SES publishes to SNS, either through a configuration set event
+// destination ("eventType") or through identity feedback notifications ("notificationType").
+type sesNotification struct {
+	EventType        string `json:"eventType"`
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce *struct {
+		BounceType        string         `json:"bounceType"`
+		BounceSubType     string         `json:"bounceSubType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+		Timestamp         time.Time      `json:"timestamp"`
+	} `json:"bounce"`
+	Complaint *struct {
+		ComplainedRecipients  []sesRecipient `json:"complainedRecipients"`
+		ComplaintFeedbackType string         `json:"complaintFeedbackType"`
+		Timestamp             time.Time      `json:"timestamp"`
+	} `json:"complaint"`
+	Delivery *struct {
+		Recipients   []string  `json:"recipients"`
+		SMTPResponse string    `json:"smtpResponse"`
+		Timestamp    time.Time `json:"timestamp"`
+	} `json:"delivery"`
+	Open *struct {
+		UserAgent string    `json:"userAgent"`
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"open"`
+	Click *struct {
+		Link      string    `json:"link"`
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"click"`
+}
+
+type sesRecipient struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+// ParseSESEvent parses the SES event JSON carried in the Message of an SNS notification.
+// Bounce, Complaint, Delivery, Open and Click events are supported.
+//
+// Parameters:
+//   - b: The SES event JSON.
+//
+// Returns:
+//   - SESEvent: The parsed event.
+//   - error: ErrUnsupportedEvent for other event types, or an error if the JSON is malformed.
+func ParseSESEvent(b []byte) (SESEvent, error) {
+	var n sesNotification
+	if err := json.Unmarshal(b, &n); err != nil {
+		return SESEvent{}, fmt.Errorf("malformed ses event: %w", err)
+	}
+
+	eventType := n.EventType
+	if eventType == "" {
+		eventType = n.NotificationType
+	}
+
+	ev := SESEvent{ProviderMessageID: n.Mail.MessageID}
+	switch {
+	case eventType == string(outbox.EventBounce) && n.Bounce != nil:
+		ev.BounceType = n.Bounce.BounceType
+		ev.Event = outbox.Event{
+			Type:       outbox.EventBounce,
+			Timestamp:  n.Bounce.Timestamp,
+			Recipients: addresses(n.Bounce.BouncedRecipients),
+			Detail:     n.Bounce.BounceType + "/" + n.Bounce.BounceSubType,
+		}
+	case eventType == string(outbox.EventComplaint) && n.Complaint != nil:
+		ev.Event = outbox.Event{
+			Type:       outbox.EventComplaint,
+			Timestamp:  n.Complaint.Timestamp,
+			Recipients: addresses(n.Complaint.ComplainedRecipients),
+			Detail:     n.Complaint.ComplaintFeedbackType,
+		}
+	case eventType == string(outbox.EventDelivery) && n.Delivery != nil:
+		ev.Event = outbox.Event{
+			Type:       outbox.EventDelivery,
+			Timestamp:  n.Delivery.Timestamp,
+			Recipients: n.Delivery.Recipients,
+			Detail:     n.Delivery.SMTPResponse,
+		}
+	case eventType == string(outbox.EventOpen) && n.Open != nil:
+		ev.Event = outbox.Event{
+			Type:      outbox.EventOpen,
+			Timestamp: n.Open.Timestamp,
+			Detail:    n.Open.UserAgent,
+		}
+	case eventType == string(outbox.EventClick) && n.Click != nil:
+		ev.Event = outbox.Event{
+			Type:      outbox.EventClick,
+			Timestamp: n.Click.Timestamp,
+			Detail:    n.Click.Link,
+		}
+	default:
+		return SESEvent{}, fmt.Errorf("%w: %q", ErrUnsupportedEvent, eventType)
+	}
+
+	if ev.ProviderMessageID == "" {
+		return SESEvent{}, errors.New("malformed ses event: missing mail.messageId")
+	}
+
+	return ev, nil
+}
+
+func addresses(recipients []sesRecipient) []string {
+	out := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		out = append(out, r.EmailAddress)
+	}
+
+	return out
+}
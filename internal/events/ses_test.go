@@ -0,0 +1,152 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSESEventUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		event        SESEvent
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"parses bounce event",
+			"bounce.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				event: SESEvent{
+					ProviderMessageID: "0100018f3a4bb2c5-1d7a3e4f-8c2e-4b4c-9d0b-6f7c5a2e1b3d-000000",
+					BounceType:        BounceTypePermanent,
+					Event: outbox.Event{
+						Type:       outbox.EventBounce,
+						Timestamp:  time.Date(2024, 5, 2, 18, 41, 4, 10000000, time.UTC),
+						Recipients: []string{"recipient@example.com"},
+						Detail:     "Permanent/General",
+					},
+				},
+			},
+		},
+		{
+			"parses complaint notification",
+			"complaint.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				event: SESEvent{
+					ProviderMessageID: "0100018f3a5f0a4c-2b3c4d5e-6f7a-8b9c-0d1e-2f3a4b5c6d7e-000000",
+					Event: outbox.Event{
+						Type:       outbox.EventComplaint,
+						Timestamp:  time.Date(2024, 5, 2, 19, 2, 11, 0, time.UTC),
+						Recipients: []string{"recipient@example.com"},
+						Detail:     "abuse",
+					},
+				},
+			},
+		},
+		{
+			"parses delivery event",
+			"delivery.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				event: SESEvent{
+					ProviderMessageID: "0100018f3a41b3e2-8d9e0f1a-2b3c-4d5e-6f7a-8b9c0d1e2f3a-000000",
+					Event: outbox.Event{
+						Type:       outbox.EventDelivery,
+						Timestamp:  time.Date(2024, 5, 2, 18, 30, 1, 627000000, time.UTC),
+						Recipients: []string{"owner@example.com"},
+						Detail:     "250 2.0.0 OK  1714674601 a1-20020a05620a1234000000b0078f0d4e2bd4si1234567qkn.123 - gsmtp",
+					},
+				},
+			},
+		},
+		{
+			"parses open event",
+			"open.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				event: SESEvent{
+					ProviderMessageID: "0100018f3a41b3e2-8d9e0f1a-2b3c-4d5e-6f7a-8b9c0d1e2f3a-000000",
+					Event: outbox.Event{
+						Type:      outbox.EventOpen,
+						Timestamp: time.Date(2024, 5, 2, 18, 35, 12, 0, time.UTC),
+						Detail:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)",
+					},
+				},
+			},
+		},
+		{
+			"parses click event",
+			"click.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				event: SESEvent{
+					ProviderMessageID: "0100018f3a41b3e2-8d9e0f1a-2b3c-4d5e-6f7a-8b9c0d1e2f3a-000000",
+					Event: outbox.Event{
+						Type:      outbox.EventClick,
+						Timestamp: time.Date(2024, 5, 2, 18, 36, 40, 0, time.UTC),
+						Detail:    "https://www.example.com/contact",
+					},
+				},
+			},
+		},
+		{
+			"handles unsupported event",
+			"send.json",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrUnsupportedEvent)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", tt.input))
+			require.Empty(t, err)
+
+			ev, err := ParseSESEvent(b)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.event.ProviderMessageID, ev.ProviderMessageID)
+			assert.Equal(t, tt.want.event.BounceType, ev.BounceType)
+			assert.Equal(t, tt.want.event.Event.Type, ev.Event.Type)
+			assert.True(t, tt.want.event.Event.Timestamp.Equal(ev.Event.Timestamp))
+			assert.Equal(t, tt.want.event.Event.Recipients, ev.Event.Recipients)
+			assert.Equal(t, tt.want.event.Event.Detail, ev.Event.Detail)
+		})
+	}
+}
+
+func TestParseSESEventMalformedUnit(t *testing.T) {
+	_, err := ParseSESEvent([]byte("{"))
+	assert.ErrorContains(t, err, "malformed ses event")
+
+	_, err = ParseSESEvent([]byte(`{"eventType":"Delivery","delivery":{}}`))
+	assert.ErrorContains(t, err, "missing mail.messageId")
+}
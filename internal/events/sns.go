@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TypeNotification is the SNS message type carrying a published message.
+	TypeNotification = "Notification"
+	// TypeSubscriptionConfirmation is the SNS message type sent when the endpoint is subscribed to a topic.
+	TypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	// TypeUnsubscribeConfirmation is the SNS message type sent when the endpoint is unsubscribed from a topic.
+	TypeUnsubscribeConfirmation = "UnsubscribeConfirmation"
+)
+
+// ErrInvalidSignature is returned when the signature of an SNS message cannot be verified.
+var ErrInvalidSignature = errors.New("invalid sns message signature")
+
+// Message is an SNS HTTP(S) endpoint message as documented at
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html.
+type Message struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token,omitempty"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject,omitempty"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL,omitempty"`
+	UnsubscribeURL   string `json:"UnsubscribeURL,omitempty"`
+}
+
+// Verify checks the signature of the message against the signing certificate provided by the certificate source.
+// Both SHA1withRSA (SignatureVersion 1) and SHA256withRSA (SignatureVersion 2) signatures are supported.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - certs: The CertificateSource used to resolve the signing certificate.
+//
+// Returns:
+//   - error: ErrInvalidSignature if the signature does not match, or an error if the certificate could not be resolved.
+func (m Message) Verify(ctx context.Context, certs CertificateSource) error {
+	var (
+		hash   crypto.Hash
+		digest []byte
+	)
+	switch m.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(m.StringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(m.StringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrInvalidSignature, m.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	cert, err := certs.Certificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("failed to resolve signing certificate: %w", err)
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: signing certificate does not hold an rsa key", ErrInvalidSignature)
+	}
+
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// StringToSign builds the canonical string SNS signs for the message type.
+//
+// Returns:
+//   - string: The canonical string to sign.
+func (m Message) StringToSign() string {
+	var b strings.Builder
+	add := func(k, v string) {
+		b.WriteString(k)
+		b.WriteString("\n")
+		b.WriteString(v)
+		b.WriteString("\n")
+	}
+
+	add("Message", m.Message)
+	add("MessageId", m.MessageID)
+	if m.Type == TypeNotification {
+		if m.Subject != "" {
+			add("Subject", m.Subject)
+		}
+	} else {
+		add("SubscribeURL", m.SubscribeURL)
+	}
+	add("Timestamp", m.Timestamp)
+	if m.Type != TypeNotification {
+		add("Token", m.Token)
+	}
+	add("TopicArn", m.TopicArn)
+	add("Type", m.Type)
+
+	return b.String()
+}
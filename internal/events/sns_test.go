@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageVerifyUnit(t *testing.T) {
+	key, cert := newSigningKey(t)
+	certs := NewStaticCertificateSource(cert)
+
+	cases := []struct {
+		name         string
+		msg          func() Message
+		errAssertion func(t *testing.T, err error)
+	}{
+		{
+			"verifies signature version 2",
+			func() Message { return sign(t, key, notification(t, "bounce.json")) },
+			func(t *testing.T, err error) {
+				assert.Empty(t, err)
+			},
+		},
+		{
+			"verifies signature version 1",
+			func() Message {
+				m := notification(t, "bounce.json")
+				m.SignatureVersion = "1"
+				sum := sha1.Sum([]byte(m.StringToSign()))
+				sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, sum[:])
+				require.Empty(t, err)
+				m.Signature = base64.StdEncoding.EncodeToString(sig)
+				return m
+			},
+			func(t *testing.T, err error) {
+				assert.Empty(t, err)
+			},
+		},
+		{
+			"rejects modified message",
+			func() Message {
+				m := sign(t, key, notification(t, "bounce.json"))
+				m.TopicArn = "arn:aws:sns:us-east-1:123456789012:other"
+				return m
+			},
+			func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			},
+		},
+		{
+			"rejects unsupported signature version",
+			func() Message {
+				m := sign(t, key, notification(t, "bounce.json"))
+				m.SignatureVersion = "3"
+				return m
+			},
+			func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			},
+		},
+		{
+			"rejects malformed signature",
+			func() Message {
+				m := sign(t, key, notification(t, "bounce.json"))
+				m.Signature = "%%%"
+				return m
+			},
+			func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.errAssertion(t, tt.msg().Verify(context.Background(), certs))
+		})
+	}
+}
+
+func TestHTTPCertificateSourceUnit(t *testing.T) {
+	certs, err := NewHTTPCertificateSource(nil, "")
+	require.Empty(t, err)
+	assert.Equal(t, DefaultTimeout, certs.(*httpCertificateSource).client.Timeout, "certificates are not downloaded without a timeout")
+
+	for _, u := range []string{
+		"http://sns.us-east-1.amazonaws.com/cert.pem",
+		"https://sns.us-east-1.amazonaws.com.attacker.example/cert.pem",
+		"https://attacker.example/sns.us-east-1.amazonaws.com/cert.pem",
+	} {
+		_, err := certs.Certificate(context.Background(), u)
+		assert.ErrorContains(t, err, "untrusted sns url", u)
+	}
+}
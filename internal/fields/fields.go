@@ -0,0 +1,427 @@
+package fields
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brice-aldrich/mail-service/internal/sanitize"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Type is the type of the value of a field.
+type Type string
+
+const (
+	// TypeString accepts a string.
+	TypeString Type = "string"
+	// TypeNumber accepts a number, or a string holding one as plain HTML forms submit.
+	TypeNumber Type = "number"
+	// TypeBoolean accepts a boolean, or one of the strings "true", "false", "on", "off", "yes", "no", "1" and "0".
+	TypeBoolean Type = "boolean"
+	// TypeList accepts a list of strings, e.g. the values of a group of checkboxes, or a single string.
+	TypeList Type = "list"
+)
+
+// namePattern restricts field names to identifiers, so templates can look fields up by name, e.g. {{fields.company}}.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Field declares a custom field of a form.
+//
+// Fields:
+//   - Name: The name the field is submitted under, e.g. "company".
+//   - Label: The name of the field shown in emails, e.g. "Company". Defaults to Name.
+//   - Type: The Type of the value. Defaults to TypeString.
+//   - Required: Whether the field must be submitted with a value. A required boolean must be true, e.g. to accept terms.
+//   - Pattern: A regular expression strings, and every item of lists, must match.
+//   - MaxLength: The maximum length of strings, and of every item of lists, in characters. Unlimited when zero.
+type Field struct {
+	Name      string
+	Label     string
+	Type      Type
+	Required  bool
+	Pattern   string
+	MaxLength int
+}
+
+// Violation describes a submitted field that does not match its declaration.
+//
+// Fields:
+//   - Field: The name of the field.
+//   - Description: Why the field is invalid, e.g. "company is required".
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Entry is a submitted field, ready to be shown in an email.
+//
+// Fields:
+//   - Name: The name of the field.
+//   - Label: The label of the field.
+//   - Value: The value of the field, formatted as text.
+type Entry struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Schema validates the custom fields of submissions against the fields declared by a form. A nil Schema declares no
+// fields.
+type Schema struct {
+	fields []field
+	byName map[string]int
+}
+
+// field is a Field with its pattern compiled.
+type field struct {
+	Field
+	pattern *regexp.Regexp
+}
+
+// New creates a Schema declaring the given fields, in the order they are shown in emails.
+//
+// Parameters:
+//   - fields: The declared fields.
+//
+// Returns:
+//   - *Schema: The newly created Schema.
+//   - error: An error if a name is invalid or declared twice, a type is unknown or a pattern does not compile.
+func New(fields ...Field) (*Schema, error) {
+	s := &Schema{
+		fields: make([]field, 0, len(fields)),
+		byName: make(map[string]int, len(fields)),
+	}
+
+	for _, f := range fields {
+		if !namePattern.MatchString(f.Name) {
+			return nil, fmt.Errorf("invalid field name %q: must be a letter or underscore followed by up to 63 letters, digits or underscores", f.Name)
+		}
+
+		if _, ok := s.byName[f.Name]; ok {
+			return nil, fmt.Errorf("field %q is declared twice", f.Name)
+		}
+
+		if f.Label == "" {
+			f.Label = f.Name
+		}
+
+		switch f.Type {
+		case "":
+			f.Type = TypeString
+		case TypeString, TypeNumber, TypeBoolean, TypeList:
+		default:
+			return nil, fmt.Errorf("field %q has an unknown type %q", f.Name, f.Type)
+		}
+
+		compiled := field{Field: f}
+		if f.Pattern != "" {
+			if f.Type != TypeString && f.Type != TypeList {
+				return nil, fmt.Errorf("field %q has a pattern, which only string and list fields support", f.Name)
+			}
+
+			pattern, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("field %q has an invalid pattern: %w", f.Name, err)
+			}
+			compiled.pattern = pattern
+		}
+
+		s.byName[f.Name] = len(s.fields)
+		s.fields = append(s.fields, compiled)
+	}
+
+	return s, nil
+}
+
+// Validate validates submitted fields against the schema and normalizes them in place: strings are cleaned with
+// sanitize.Text, numbers and booleans submitted as strings are converted, single strings submitted to list fields
+// become lists, and null and empty values are removed.
+//
+// Parameters:
+//   - values: The submitted fields, keyed by name.
+//
+// Returns:
+//   - []Violation: The fields that are unknown, missing or invalid, in the order they are declared. Unknown fields come last.
+func (s *Schema) Validate(values map[string]*structpb.Value) []Violation {
+	var violations []Violation
+
+	var declared []field
+	if s != nil {
+		declared = s.fields
+	}
+
+	for _, f := range declared {
+		v, err := f.normalize(values[f.Name])
+		if err != nil {
+			violations = append(violations, Violation{Field: f.Name, Description: err.Error()})
+			continue
+		}
+
+		if v == nil {
+			delete(values, f.Name)
+			continue
+		}
+		values[f.Name] = v
+	}
+
+	var unknown []string
+	for name := range values {
+		if !s.declares(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		violations = append(violations, Violation{Field: name, Description: "unknown field"})
+	}
+
+	return violations
+}
+
+// Names returns the names of the declared fields, in the order they are declared.
+//
+// Returns:
+//   - []string: The names of the fields.
+func (s *Schema) Names() []string {
+	if s == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+// Entries returns the submitted fields in the order they are declared, formatted as text. Fields the schema does not
+// declare are left out.
+//
+// Parameters:
+//   - values: The validated fields, keyed by name.
+//
+// Returns:
+//   - []Entry: The submitted fields.
+func (s *Schema) Entries(values map[string]*structpb.Value) []Entry {
+	if s == nil {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(values))
+	for _, f := range s.fields {
+		if v, ok := values[f.Name]; ok {
+			entries = append(entries, Entry{Name: f.Name, Label: f.Label, Value: Format(v.AsInterface())})
+		}
+	}
+
+	return entries
+}
+
+// Format formats the value of a field as text: lists are joined with commas, booleans become "yes" or "no", and
+// numbers are written without exponents.
+//
+// Parameters:
+//   - v: The value, as returned by structpb.Value.AsInterface.
+//
+// Returns:
+//   - string: The formatted value.
+func Format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, Format(item))
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// declares reports whether the schema declares a field with the given name.
+func (s *Schema) declares(name string) bool {
+	if s == nil {
+		return false
+	}
+
+	_, ok := s.byName[name]
+	return ok
+}
+
+// normalize validates a submitted value and converts it to the type of the field. It returns a nil value when the
+// field is empty.
+func (f field) normalize(v *structpb.Value) (*structpb.Value, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		v = nil
+	}
+
+	var (
+		out *structpb.Value
+		err error
+	)
+	if v != nil {
+		switch f.Type {
+		case TypeNumber:
+			out, err = f.number(v)
+		case TypeBoolean:
+			out, err = f.boolean(v)
+		case TypeList:
+			out, err = f.list(v)
+		default:
+			out, err = f.string(v)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Required && (empty(out) || !out.GetBoolValue() && f.Type == TypeBoolean) {
+		return nil, fmt.Errorf("%s is required", f.Name)
+	}
+
+	if empty(out) {
+		return nil, nil
+	}
+
+	return out, nil
+}
+
+func (f field) string(v *structpb.Value) (*structpb.Value, error) {
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", f.Name)
+	}
+
+	text, err := f.text(s.StringValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStringValue(text), nil
+}
+
+func (f field) number(v *structpb.Value) (*structpb.Value, error) {
+	var n float64
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		n = kind.NumberValue
+	case *structpb.Value_StringValue:
+		s := strings.TrimSpace(kind.StringValue)
+		if s == "" {
+			return nil, nil
+		}
+
+		var err error
+		if n, err = strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("%s must be a number", f.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a number", f.Name)
+	}
+
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("%s must be a number", f.Name)
+	}
+
+	return structpb.NewNumberValue(n), nil
+}
+
+func (f field) boolean(v *structpb.Value) (*structpb.Value, error) {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return structpb.NewBoolValue(kind.BoolValue), nil
+	case *structpb.Value_StringValue:
+		switch strings.ToLower(strings.TrimSpace(kind.StringValue)) {
+		case "true", "on", "yes", "1":
+			return structpb.NewBoolValue(true), nil
+		case "false", "off", "no", "0":
+			return structpb.NewBoolValue(false), nil
+		case "":
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s must be a boolean", f.Name)
+}
+
+func (f field) list(v *structpb.Value) (*structpb.Value, error) {
+	var items []*structpb.Value
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		items = []*structpb.Value{v}
+	case *structpb.Value_ListValue:
+		items = kind.ListValue.GetValues()
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", f.Name)
+	}
+
+	out := make([]*structpb.Value, 0, len(items))
+	for _, item := range items {
+		s, ok := item.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", f.Name)
+		}
+
+		text, err := f.text(s.StringValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if text != "" {
+			out = append(out, structpb.NewStringValue(text))
+		}
+	}
+
+	return structpb.NewListValue(&structpb.ListValue{Values: out}), nil
+}
+
+// text cleans a string and checks it against the maximum length and the pattern of the field.
+func (f field) text(s string) (string, error) {
+	s = strings.TrimSpace(sanitize.Text(s))
+	if s == "" {
+		return "", nil
+	}
+
+	if f.MaxLength > 0 && utf8.RuneCountInString(s) > f.MaxLength {
+		return "", fmt.Errorf("%s must be at most %d characters", f.Name, f.MaxLength)
+	}
+
+	if f.pattern != nil && !f.pattern.MatchString(s) {
+		return "", fmt.Errorf("%s has an invalid format", f.Name)
+	}
+
+	return s, nil
+}
+
+// empty reports whether a normalized value holds nothing: no value, or an empty string or list.
+func empty(v *structpb.Value) bool {
+	switch kind := v.GetKind().(type) {
+	case nil:
+		return true
+	case *structpb.Value_StringValue:
+		return kind.StringValue == ""
+	case *structpb.Value_ListValue:
+		return len(kind.ListValue.GetValues()) == 0
+	default:
+		return false
+	}
+}
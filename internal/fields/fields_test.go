@@ -0,0 +1,205 @@
+package fields
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestValidateUnit(t *testing.T) {
+	schema, err := New(
+		Field{Name: "company", Label: "Company", Required: true, MaxLength: 10},
+		Field{Name: "phone", Pattern: `^\+?[0-9 ]{6,20}$`},
+		Field{Name: "budget", Type: TypeNumber},
+		Field{Name: "newsletter", Type: TypeBoolean},
+		Field{Name: "terms", Type: TypeBoolean, Required: true},
+		Field{Name: "interests", Type: TypeList, MaxLength: 5},
+	)
+	require.Empty(t, err)
+
+	type want struct {
+		values     map[string]any
+		violations []Violation
+	}
+
+	cases := []struct {
+		name  string
+		input map[string]any
+		want  want
+	}{
+		{
+			"normalizes values",
+			map[string]any{
+				"company":    " Acme\x00 ",
+				"phone":      "+1 555 0100",
+				"budget":     "5000.50",
+				"newsletter": "on",
+				"terms":      true,
+				"interests":  "seo",
+			},
+			want{
+				values: map[string]any{
+					"company":    "Acme",
+					"phone":      "+1 555 0100",
+					"budget":     5000.5,
+					"newsletter": true,
+					"terms":      true,
+					"interests":  []any{"seo"},
+				},
+			},
+		},
+		{
+			"removes empty values",
+			map[string]any{
+				"company":    "Acme",
+				"phone":      "  ",
+				"budget":     nil,
+				"newsletter": false,
+				"terms":      "yes",
+				"interests":  []any{"", "ads"},
+			},
+			want{
+				values: map[string]any{
+					"company":    "Acme",
+					"newsletter": false,
+					"terms":      true,
+					"interests":  []any{"ads"},
+				},
+			},
+		},
+		{
+			"handles missing required fields",
+			map[string]any{"company": "", "terms": "off"},
+			want{
+				values: map[string]any{"company": "", "terms": "off"},
+				violations: []Violation{
+					{Field: "company", Description: "company is required"},
+					{Field: "terms", Description: "terms is required"},
+				},
+			},
+		},
+		{
+			"handles invalid values",
+			map[string]any{
+				"company":    "Acme Corporation",
+				"phone":      "call me",
+				"budget":     "a lot",
+				"newsletter": "maybe",
+				"terms":      true,
+				"interests":  []any{"seo", 1.0},
+			},
+			want{
+				values: map[string]any{
+					"company":    "Acme Corporation",
+					"phone":      "call me",
+					"budget":     "a lot",
+					"newsletter": "maybe",
+					"terms":      true,
+					"interests":  []any{"seo", 1.0},
+				},
+				violations: []Violation{
+					{Field: "company", Description: "company must be at most 10 characters"},
+					{Field: "phone", Description: "phone has an invalid format"},
+					{Field: "budget", Description: "budget must be a number"},
+					{Field: "newsletter", Description: "newsletter must be a boolean"},
+					{Field: "interests", Description: "interests must be a list of strings"},
+				},
+			},
+		},
+		{
+			"handles values of the wrong type",
+			map[string]any{"company": 42.0, "terms": true, "interests": map[string]any{"a": "b"}},
+			want{
+				values: map[string]any{"company": 42.0, "terms": true, "interests": map[string]any{"a": "b"}},
+				violations: []Violation{
+					{Field: "company", Description: "company must be a string"},
+					{Field: "interests", Description: "interests must be a list of strings"},
+				},
+			},
+		},
+		{
+			"handles unknown fields",
+			map[string]any{"company": "Acme", "terms": true, "zip": "12345", "age": 30.0},
+			want{
+				values: map[string]any{"company": "Acme", "terms": true, "zip": "12345", "age": 30.0},
+				violations: []Violation{
+					{Field: "age", Description: "unknown field"},
+					{Field: "zip", Description: "unknown field"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := structpb.NewStruct(tt.input)
+			require.Empty(t, err)
+
+			violations := schema.Validate(values.Fields)
+			assert.Equal(t, tt.want.violations, violations)
+			assert.Equal(t, tt.want.values, values.AsMap())
+		})
+	}
+}
+
+func TestValidateNilSchemaUnit(t *testing.T) {
+	var schema *Schema
+	assert.Empty(t, schema.Validate(nil))
+	assert.Equal(t, []Violation{{Field: "company", Description: "unknown field"}}, schema.Validate(map[string]*structpb.Value{"company": structpb.NewStringValue("Acme")}))
+	assert.Empty(t, schema.Entries(map[string]*structpb.Value{"company": structpb.NewStringValue("Acme")}))
+}
+
+func TestEntriesUnit(t *testing.T) {
+	schema, err := New(
+		Field{Name: "company", Label: "Company"},
+		Field{Name: "budget", Type: TypeNumber},
+		Field{Name: "newsletter", Type: TypeBoolean},
+		Field{Name: "interests", Type: TypeList},
+		Field{Name: "phone"},
+	)
+	require.Empty(t, err)
+
+	values, err := structpb.NewStruct(map[string]any{
+		"interests":  []any{"seo", "ads"},
+		"newsletter": false,
+		"budget":     1500000.0,
+		"company":    "Acme",
+	})
+	require.Empty(t, err)
+
+	assert.Equal(t, []Entry{
+		{Name: "company", Label: "Company", Value: "Acme"},
+		{Name: "budget", Label: "budget", Value: "1500000"},
+		{Name: "newsletter", Label: "newsletter", Value: "no"},
+		{Name: "interests", Label: "interests", Value: "seo, ads"},
+	}, schema.Entries(values.Fields))
+}
+
+func TestNewUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input []Field
+		want  string
+	}{
+		{"is successful", []Field{{Name: "company"}, {Name: "budget", Type: TypeNumber}}, ""},
+		{"handles invalid name", []Field{{Name: "first-name"}}, `invalid field name "first-name"`},
+		{"handles duplicate name", []Field{{Name: "company"}, {Name: "company"}}, `field "company" is declared twice`},
+		{"handles unknown type", []Field{{Name: "company", Type: "text"}}, `field "company" has an unknown type "text"`},
+		{"handles invalid pattern", []Field{{Name: "company", Pattern: "("}}, `field "company" has an invalid pattern`},
+		{"handles pattern of number", []Field{{Name: "budget", Type: TypeNumber, Pattern: "^1"}}, `field "budget" has a pattern`},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.input...)
+			if tt.want == "" {
+				assert.Empty(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
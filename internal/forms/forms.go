@@ -0,0 +1,392 @@
+package forms
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// idPattern restricts form IDs to characters AWS SES accepts in template names, as they are appended to them.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Form holds the settings of a single contact form. Settings a form leaves out are inherited from the service-wide
+// configuration.
+//
+// Fields:
+//   - ID: The ID submissions select the form with.
+//   - Forward: The addresses submissions are forwarded to.
+//   - Cc: The addresses submissions are copied to.
+//   - Bcc: The addresses submissions are blind copied to.
+//   - Routes: The Route list picking other recipients for the submissions they match. The first matching route wins.
+//   - From: The address emails are sent from.
+//   - Site: The name of the site the form belongs to, available to ForwardSubject as "{{site}}".
+//   - ForwardSubject: The pattern of the subject of forwarded submissions, e.g. "[{{site}}] {{subject}} — {{name}}".
+//   - Origins: The origins the form may be submitted from. An origin may contain one "*" wildcard, e.g. "https://*.example.com".
+//   - Templates: The Templates of the emails sent for the form.
+//   - AutoReply: The AutoReply settings of the thank you email sent to the submitter.
+//   - Attachments: The Attachments limits of the form. They may only tighten the service-wide limits.
+//   - Captcha: The Captcha provider verifying the submissions of the form.
+//   - Fields: The Field declarations of the custom fields submissions to the form may carry.
+type Form struct {
+	ID             string      `yaml:"id"`
+	Forward        []string    `yaml:"forward"`
+	Cc             []string    `yaml:"cc"`
+	Bcc            []string    `yaml:"bcc"`
+	Routes         []Route     `yaml:"routes"`
+	From           string      `yaml:"from"`
+	Site           string      `yaml:"site"`
+	ForwardSubject string      `yaml:"forward_subject"`
+	Origins        []string    `yaml:"origins"`
+	Templates      Templates   `yaml:"templates"`
+	AutoReply      AutoReply   `yaml:"auto_reply"`
+	Attachments    Attachments `yaml:"attachments"`
+	Captcha        Captcha     `yaml:"captcha"`
+	Fields         []Field     `yaml:"fields"`
+}
+
+// Route forwards the submissions of a form it matches to its own recipients instead of those of the form.
+//
+// Fields:
+//   - Name: The name of the route, logged with the submissions it routes.
+//   - Match: The RouteMatch conditions a submission must meet for the route to apply.
+//   - Forward: The addresses the submissions are forwarded to.
+//   - Cc: The addresses the submissions are copied to.
+//   - Bcc: The addresses the submissions are blind copied to.
+type Route struct {
+	Name    string     `yaml:"name"`
+	Match   RouteMatch `yaml:"match"`
+	Forward []string   `yaml:"forward"`
+	Cc      []string   `yaml:"cc"`
+	Bcc     []string   `yaml:"bcc"`
+}
+
+// RouteMatch holds the conditions of a Route. A submission must meet every condition that is set, and meets a
+// condition when it matches any of its values. A route without conditions matches every submission.
+//
+// Fields:
+//   - SubjectKeywords: The words and phrases, matched case-insensitively, one of which the subject must contain.
+//   - Departments: The departments, matched case-insensitively, one of which the submitter must have selected.
+//   - EmailDomains: The domains the address of the submitter must belong to, including their subdomains.
+type RouteMatch struct {
+	SubjectKeywords []string `yaml:"subject_keywords"`
+	Departments     []string `yaml:"departments"`
+	EmailDomains    []string `yaml:"email_domains"`
+}
+
+// Field declares a custom field of a form.
+//
+// Fields:
+//   - Name: The name the field is submitted under, e.g. "company".
+//   - Label: The name of the field shown in emails, e.g. "Company". Defaults to Name.
+//   - Type: The type of the value, one of "string", "number", "boolean" or "list". Defaults to "string".
+//   - Required: Whether the field must be submitted with a value. A required boolean must be true.
+//   - Pattern: A regular expression strings, and every item of lists, must match.
+//   - MaxLength: The maximum length of strings, and of every item of lists, in characters.
+type Field struct {
+	Name      string `yaml:"name"`
+	Label     string `yaml:"label"`
+	Type      string `yaml:"type"`
+	Required  bool   `yaml:"required"`
+	Pattern   string `yaml:"pattern"`
+	MaxLength int    `yaml:"max_length"`
+}
+
+// Templates holds the sources of the email templates of a form. A template the form configures replaces the
+// service-wide template as a whole, so inline parts configured for the service never shadow the files of a form.
+//
+// Fields:
+//   - Dir: A directory holding template files named "<key>.subject.txt", "<key>.html" and "<key>.txt".
+//   - ThankYou: The TemplateSource of the thank you email.
+//   - Forward: The TemplateSource of the forward email.
+type Templates struct {
+	Dir      string         `yaml:"dir"`
+	ThankYou TemplateSource `yaml:"thank_you"`
+	Forward  TemplateSource `yaml:"forward"`
+}
+
+// TemplateSource holds the configured parts of a single email template. It converts to a templates.Source.
+//
+// Fields:
+//   - Subject: The subject line.
+//   - HTML: The base64 standard encoded HTML body.
+//   - Text: The base64 standard encoded plain text body.
+//   - SubjectFile: The path of a file holding the subject line.
+//   - HTMLFile: The path of a file holding the HTML body.
+//   - TextFile: The path of a file holding the plain text body.
+type TemplateSource struct {
+	Subject     string `yaml:"subject"`
+	HTML        string `yaml:"html"`
+	Text        string `yaml:"text"`
+	SubjectFile string `yaml:"subject_file"`
+	HTMLFile    string `yaml:"html_file"`
+	TextFile    string `yaml:"text_file"`
+}
+
+// AutoReply holds the settings of the thank you email sent to the submitter of a form.
+//
+// Fields:
+//   - Enabled: Whether the thank you email is sent.
+//   - Limit: The maximum number of thank you emails a single address receives within Window, 0 for unlimited.
+//   - Window: The period Limit applies to, e.g. "24h".
+type AutoReply struct {
+	Enabled bool          `yaml:"enabled"`
+	Limit   int           `yaml:"limit"`
+	Window  time.Duration `yaml:"window"`
+}
+
+// Attachments holds the limits enforced on the files uploaded with a submission.
+//
+// Fields:
+//   - MaxCount: The maximum number of attachments per submission. Attachments are rejected when zero.
+//   - MaxFileSize: The maximum size of a single attachment in bytes.
+//   - MaxTotalSize: The maximum combined size of the attachments of a submission in bytes.
+type Attachments struct {
+	MaxCount     int   `yaml:"max_count"`
+	MaxFileSize  int64 `yaml:"max_file_size"`
+	MaxTotalSize int64 `yaml:"max_total_size"`
+}
+
+// Captcha holds the CAPTCHA provider of a form. A form that configures a provider replaces the service-wide provider
+// as a whole, as secrets are issued per site.
+//
+// Fields:
+//   - Provider: The provider, one of "none", "hcaptcha", "turnstile" or "recaptcha".
+//   - Secret: The secret key issued by the provider.
+//   - Endpoint: The siteverify endpoint of the provider. The public endpoint of the provider is used when empty.
+//   - MinScore: The minimum score a token must have, e.g. 0.5 for reCAPTCHA v3.
+//   - Action: The action a token must have been issued for.
+//   - Hostnames: The hostnames a token may have been solved on.
+type Captcha struct {
+	Provider  string   `yaml:"provider"`
+	Secret    string   `yaml:"secret"`
+	Endpoint  string   `yaml:"endpoint"`
+	MinScore  float64  `yaml:"min_score"`
+	Action    string   `yaml:"action"`
+	Hostnames []string `yaml:"hostnames"`
+}
+
+// Registry holds the forms served by the service.
+type Registry struct {
+	forms map[string]Form
+	def   string
+}
+
+// file is the layout of a forms file.
+type file struct {
+	Default string      `yaml:"default"`
+	Forms   []yaml.Node `yaml:"forms"`
+}
+
+// Load reads the forms from a YAML file. Every form starts out as a copy of base, the form built from the
+// service-wide configuration, and overrides the settings it configures:
+//
+//	default: portfolio
+//	forms:
+//	  - id: portfolio
+//	    forward: [me@example.com]
+//	    origins: [https://www.example.com]
+//	  - id: shop
+//	    forward: [sales@shop.example, support@shop.example]
+//	    from: noreply@shop.example
+//	    routes:
+//	      - name: support
+//	        match:
+//	          departments: [support]
+//	        forward: [support@shop.example]
+//	    templates:
+//	      dir: /etc/mail-service/templates/shop
+//	    auto_reply:
+//	      enabled: true
+//
+// Parameters:
+//   - path: The path of the YAML file.
+//   - base: The Form holding the service-wide settings.
+//
+// Returns:
+//   - *Registry: The forms, ready to be looked up by ID.
+//   - error: An error if the file could not be read or parsed, or a form is invalid.
+func Load(path string, base Form) (*Registry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forms file: %w", err)
+	}
+
+	return Parse(b, base)
+}
+
+// Parse parses the forms from YAML, as described by Load.
+//
+// Parameters:
+//   - b: The YAML document.
+//   - base: The Form holding the service-wide settings.
+//
+// Returns:
+//   - *Registry: The forms, ready to be looked up by ID.
+//   - error: An error if the document could not be parsed or a form is invalid.
+func Parse(b []byte, base Form) (*Registry, error) {
+	// Nodes are decoded leniently, so the document is decoded strictly once to reject unknown settings.
+	var strict struct {
+		Default string `yaml:"default"`
+		Forms   []Form `yaml:"forms"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&strict); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse forms file: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse forms file: %w", err)
+	}
+
+	forms := make([]Form, 0, len(f.Forms))
+	for i := range f.Forms {
+		form, err := decodeForm(&f.Forms[i], base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse form %d: %w", i, err)
+		}
+
+		forms = append(forms, form)
+	}
+
+	return New(f.Default, base, forms...)
+}
+
+// decodeForm decodes a form on top of a copy of base.
+func decodeForm(node *yaml.Node, base Form) (Form, error) {
+	var own Form
+	if err := node.Decode(&own); err != nil {
+		return Form{}, err
+	}
+
+	form := base
+	form.ID = ""
+	if err := node.Decode(&form); err != nil {
+		return Form{}, err
+	}
+
+	if own.Templates.ThankYou != (TemplateSource{}) {
+		form.Templates.ThankYou = own.Templates.ThankYou
+	}
+	if own.Templates.Forward != (TemplateSource{}) {
+		form.Templates.Forward = own.Templates.Forward
+	}
+	if !reflect.ValueOf(own.Captcha).IsZero() {
+		form.Captcha = own.Captcha
+	}
+
+	return form, nil
+}
+
+// New creates a Registry holding the given forms.
+//
+// Parameters:
+//   - def: The ID of the form used by submissions that do not select one. Submissions must select a form when empty.
+//   - base: The Form holding the service-wide settings, bounding the attachment limits of every form.
+//   - forms: The forms.
+//
+// Returns:
+//   - *Registry: The forms, ready to be looked up by ID.
+//   - error: An error if a form is invalid, an ID is used twice or the default form does not exist.
+func New(def string, base Form, forms ...Form) (*Registry, error) {
+	r := &Registry{
+		forms: make(map[string]Form, len(forms)),
+		def:   def,
+	}
+
+	for _, f := range forms {
+		if err := validate(f, base); err != nil {
+			return nil, err
+		}
+
+		if _, ok := r.forms[f.ID]; ok {
+			return nil, fmt.Errorf("form %q is defined twice", f.ID)
+		}
+
+		r.forms[f.ID] = f
+	}
+
+	if _, ok := r.forms[def]; def != "" && !ok {
+		return nil, fmt.Errorf("default form %q does not exist", def)
+	}
+
+	return r, nil
+}
+
+func validate(f Form, base Form) error {
+	if !idPattern.MatchString(f.ID) {
+		return fmt.Errorf("invalid form id %q: must be 1 to 64 letters, digits, underscores or hyphens", f.ID)
+	}
+
+	if len(f.Forward) == 0 {
+		return fmt.Errorf("form %q has no forward address", f.ID)
+	}
+
+	addrs := append(append(append([]string{f.From}, f.Forward...), f.Cc...), f.Bcc...)
+	for i, r := range f.Routes {
+		if len(r.Forward) == 0 {
+			return fmt.Errorf("route %d of form %q has no forward address", i, f.ID)
+		}
+		addrs = append(append(append(addrs, r.Forward...), r.Cc...), r.Bcc...)
+	}
+
+	for _, addr := range addrs {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("form %q has an invalid address %q: %w", f.ID, addr, err)
+		}
+	}
+
+	if f.Attachments.MaxCount > base.Attachments.MaxCount ||
+		f.Attachments.MaxFileSize > base.Attachments.MaxFileSize ||
+		f.Attachments.MaxTotalSize > base.Attachments.MaxTotalSize {
+		return fmt.Errorf("form %q has attachment limits above the service-wide limits", f.ID)
+	}
+
+	return nil
+}
+
+// Get returns the form with the given ID, or the default form when id is empty.
+//
+// Parameters:
+//   - id: The ID of the form.
+//
+// Returns:
+//   - Form: The form.
+//   - bool: Whether the form exists.
+func (r *Registry) Get(id string) (Form, bool) {
+	if id == "" {
+		id = r.def
+	}
+
+	f, ok := r.forms[id]
+	return f, ok
+}
+
+// Default returns the ID of the form used by submissions that do not select one, or an empty string if there is none.
+func (r *Registry) Default() string {
+	return r.def
+}
+
+// Forms returns every form, ordered by ID.
+func (r *Registry) Forms() []Form {
+	out := make([]Form, 0, len(r.forms))
+	for _, f := range r.forms {
+		out = append(out, f)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+
+	return out
+}
@@ -0,0 +1,297 @@
+package forms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnit(t *testing.T) {
+	base := Form{
+		Forward: []string{"owner@example.com"},
+		From:    "noreply@example.com",
+		Templates: Templates{
+			Dir:      "/etc/templates",
+			ThankYou: TemplateSource{Subject: "Thanks"},
+			Forward:  TemplateSource{HTML: "PHA+aGk8L3A+"},
+		},
+		AutoReply:   AutoReply{Enabled: true, Limit: 3, Window: 24 * time.Hour},
+		Attachments: Attachments{MaxCount: 5, MaxFileSize: 100, MaxTotalSize: 200},
+		Captcha:     Captcha{Provider: "recaptcha", Secret: "service-secret", MinScore: 0.5, Action: "contact"},
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		def          string
+		forms        []Form
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"inherits the service-wide settings",
+			`
+default: portfolio
+forms:
+  - id: portfolio
+    origins: [https://www.example.com]
+  - id: shop
+    forward: [sales@shop.example, support@shop.example]
+    from: noreply@shop.example
+    cc: [manager@shop.example]
+    bcc: [archive@shop.example]
+    routes:
+      - name: support
+        match:
+          subject_keywords: [broken, refund]
+          departments: [support]
+          email_domains: [partner.example]
+        forward: [support@shop.example]
+        cc: [lead@shop.example]
+    templates:
+      forward:
+        html_file: /etc/shop/forward.html
+    auto_reply:
+      limit: 1
+      window: 1h
+    attachments:
+      max_count: 0
+    captcha:
+      provider: turnstile
+      secret: shop-secret
+    fields:
+      - name: company
+        label: Company
+        required: true
+        max_length: 100
+      - name: budget
+        type: number
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				def: "portfolio",
+				forms: []Form{
+					{
+						ID:          "portfolio",
+						Forward:     []string{"owner@example.com"},
+						From:        "noreply@example.com",
+						Origins:     []string{"https://www.example.com"},
+						Templates:   base.Templates,
+						AutoReply:   base.AutoReply,
+						Attachments: base.Attachments,
+						Captcha:     base.Captcha,
+					},
+					{
+						ID:      "shop",
+						Forward: []string{"sales@shop.example", "support@shop.example"},
+						Cc:      []string{"manager@shop.example"},
+						Bcc:     []string{"archive@shop.example"},
+						Routes: []Route{
+							{
+								Name: "support",
+								Match: RouteMatch{
+									SubjectKeywords: []string{"broken", "refund"},
+									Departments:     []string{"support"},
+									EmailDomains:    []string{"partner.example"},
+								},
+								Forward: []string{"support@shop.example"},
+								Cc:      []string{"lead@shop.example"},
+							},
+						},
+						From: "noreply@shop.example",
+						Templates: Templates{
+							Dir:      "/etc/templates",
+							ThankYou: TemplateSource{Subject: "Thanks"},
+							Forward:  TemplateSource{HTMLFile: "/etc/shop/forward.html"},
+						},
+						AutoReply:   AutoReply{Enabled: true, Limit: 1, Window: time.Hour},
+						Attachments: Attachments{MaxCount: 0, MaxFileSize: 100, MaxTotalSize: 200},
+						Captcha:     Captcha{Provider: "turnstile", Secret: "shop-secret"},
+						Fields: []Field{
+							{Name: "company", Label: "Company", Required: true, MaxLength: 100},
+							{Name: "budget", Type: "number"},
+						},
+					},
+				},
+			},
+		},
+		{
+			"handles unknown settings",
+			`
+forms:
+  - id: shop
+    forwards: [sales@shop.example]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "field forwards not found")
+				},
+			},
+		},
+		{
+			"handles invalid id",
+			`
+forms:
+  - id: my shop
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `invalid form id "my shop"`)
+				},
+			},
+		},
+		{
+			"handles missing id",
+			`
+forms:
+  - forward: [sales@shop.example]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `invalid form id ""`)
+				},
+			},
+		},
+		{
+			"handles duplicate id",
+			`
+forms:
+  - id: shop
+  - id: shop
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `form "shop" is defined twice`)
+				},
+			},
+		},
+		{
+			"handles invalid address",
+			`
+forms:
+  - id: shop
+    forward: [not an address]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `form "shop" has an invalid address "not an address"`)
+				},
+			},
+		},
+		{
+			"handles invalid copy address",
+			`
+forms:
+  - id: shop
+    bcc: [not an address]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `form "shop" has an invalid address "not an address"`)
+				},
+			},
+		},
+		{
+			"handles route without forward address",
+			`
+forms:
+  - id: shop
+    routes:
+      - match:
+          departments: [sales]
+        cc: [sales@shop.example]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `route 0 of form "shop" has no forward address`)
+				},
+			},
+		},
+		{
+			"handles invalid route address",
+			`
+forms:
+  - id: shop
+    routes:
+      - forward: [sales@shop.example]
+        bcc: [not an address]
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `form "shop" has an invalid address "not an address"`)
+				},
+			},
+		},
+		{
+			"handles attachment limits above the service-wide limits",
+			`
+forms:
+  - id: shop
+    attachments:
+      max_file_size: 101
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "above the service-wide limits")
+				},
+			},
+		},
+		{
+			"handles missing default form",
+			`
+default: blog
+forms:
+  - id: shop
+`,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, `default form "blog" does not exist`)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input), base)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.def, got.Default())
+			assert.Equal(t, tt.want.forms, got.Forms())
+		})
+	}
+}
+
+func TestRegistryGetUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.yaml")
+	require.Empty(t, os.WriteFile(path, []byte("default: shop\nforms:\n  - id: shop\n  - id: blog\n"), 0o600))
+
+	r, err := Load(path, Form{Forward: []string{"owner@example.com"}, From: "noreply@example.com"})
+	require.Empty(t, err)
+
+	f, ok := r.Get("")
+	assert.True(t, ok)
+	assert.Equal(t, "shop", f.ID)
+
+	f, ok = r.Get("blog")
+	assert.True(t, ok)
+	assert.Equal(t, "blog", f.ID)
+
+	_, ok = r.Get("portfolio")
+	assert.False(t, ok)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"), Form{})
+	assert.ErrorContains(t, err, "failed to read forms file")
+}
@@ -0,0 +1,264 @@
+package formtoken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned when a token is missing, malformed, not signed with the secret or issued for another form.
+	ErrInvalid = errors.New("invalid form token")
+	// ErrTooFast is returned when a form is submitted sooner after it was rendered than a person could fill it.
+	ErrTooFast = errors.New("form submitted too fast")
+	// ErrExpired is returned when a token is older than the maximum age.
+	ErrExpired = errors.New("form token expired")
+	// ErrReplayed is returned when a token was already used by another submission.
+	ErrReplayed = errors.New("form token replayed")
+)
+
+// Signer issues and verifies the HMAC-signed tokens recording when a form was rendered.
+//
+// Methods:
+//   - Issue: Issues a token for a form. It returns the token and the time after which it is no longer accepted.
+//   - Verify: Verifies the token of a submission to a form, without using it up.
+//   - Use: Atomically marks the token of a submission as used before it is sent, so concurrent submissions with the
+//     same token cannot all be sent.
+//   - Release: Releases a token marked as used whose submission failed, so it can be corrected and submitted again.
+type Signer interface {
+	Issue(formID string) (string, time.Time, error)
+	Verify(ctx context.Context, token, formID string) error
+	Use(ctx context.Context, token string) error
+	Release(ctx context.Context, token string) error
+}
+
+// Store holds the nonces of the tokens already used. Stores shared between replicas, e.g. Redis, reject tokens
+// replayed to any of them.
+//
+// Methods:
+//   - Used: Reports whether a nonce was used.
+//   - Use: Marks a nonce as used until ttl elapses. It reports whether the nonce was not used yet.
+//   - Release: Forgets a used nonce.
+type Store interface {
+	Used(ctx context.Context, nonce string) (bool, error)
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, nonce string) error
+}
+
+// Config holds the configuration of the form tokens.
+//
+// Fields:
+//   - Secret: The key the tokens are signed with. It should be at least 32 bytes long.
+//   - MinAge: The minimum time between rendering a form and submitting it.
+//   - MaxAge: The time after which a token is no longer accepted.
+//   - Store: The Store holding the nonces of the tokens already used.
+type Config struct {
+	Secret []byte
+	MinAge time.Duration
+	MaxAge time.Duration
+	Store  Store
+}
+
+// signer signs tokens of the form "<payload>.<signature>", both base64url encoded, where the payload is
+// "<form id>:<issued at in unix milliseconds>:<nonce>". Used nonces are remembered in the store until their token
+// expires, so a token can only be submitted once.
+type signer struct {
+	secret []byte
+	minAge time.Duration
+	maxAge time.Duration
+	store  Store
+	now    func() time.Time
+}
+
+// New creates a Signer with the provided configuration.
+//
+// Parameters:
+//   - cfg: The Config object containing the secret, the age limits and the store of the tokens.
+//
+// Returns:
+//   - Signer: The newly created Signer.
+//   - error: An error if the secret or the store is missing or the age limits are invalid.
+func New(cfg Config) (Signer, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("form token secret is required")
+	}
+
+	if cfg.Store == nil {
+		return nil, errors.New("form token store is required")
+	}
+
+	if cfg.MinAge < 0 || cfg.MaxAge <= cfg.MinAge {
+		return nil, fmt.Errorf("invalid form token ages: the maximum age %s must be greater than the minimum age %s", cfg.MaxAge, cfg.MinAge)
+	}
+
+	return &signer{
+		secret: cfg.Secret,
+		minAge: cfg.MinAge,
+		maxAge: cfg.MaxAge,
+		store:  cfg.Store,
+		now:    time.Now,
+	}, nil
+}
+
+// Issue issues a token for a form.
+//
+// Parameters:
+//   - formID: The ID of the form the token is issued for.
+//
+// Returns:
+//   - string: The token.
+//   - time.Time: The time after which the token is no longer accepted.
+//   - error: An error if no random nonce could be generated.
+func (s *signer) Issue(formID string) (string, time.Time, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate form token nonce: %w", err)
+	}
+
+	now := s.now()
+	payload := formID + ":" + strconv.FormatInt(now.UnixMilli(), 10) + ":" + hex.EncodeToString(nonce)
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+
+	return token, now.Add(s.maxAge), nil
+}
+
+// Verify verifies the token of a submission to a form. The token is not used up, see Use.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - token: The token submitted with the form.
+//   - formID: The ID of the form the submission was made to.
+//
+// Returns:
+//   - error: ErrInvalid, ErrTooFast, ErrExpired or ErrReplayed if the token is not accepted, or an error if the store
+//     could not be reached.
+func (s *signer) Verify(ctx context.Context, token, formID string) error {
+	t, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+
+	if t.formID != formID {
+		return ErrInvalid
+	}
+
+	age := s.now().Sub(t.issuedAt)
+	switch {
+	case age < s.minAge:
+		return ErrTooFast
+	case age > s.maxAge:
+		return ErrExpired
+	}
+
+	used, err := s.store.Used(ctx, t.nonce)
+	if err != nil {
+		return fmt.Errorf("failed to check form token: %w", err)
+	}
+
+	if used {
+		return ErrReplayed
+	}
+
+	return nil
+}
+
+// Use marks the token of a submission as used until it expires. Only one of concurrent calls with the same token
+// succeeds.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - token: The token submitted with the form, verified with Verify.
+//
+// Returns:
+//   - error: ErrInvalid if the token is malformed, ErrReplayed if it was already used, or an error if the store could
+//     not be reached.
+func (s *signer) Use(ctx context.Context, token string) error {
+	t, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.store.Use(ctx, t.nonce, t.issuedAt.Add(s.maxAge).Sub(s.now()))
+	if err != nil {
+		return fmt.Errorf("failed to use form token: %w", err)
+	}
+
+	if !ok {
+		return ErrReplayed
+	}
+
+	return nil
+}
+
+// Release releases a token marked as used by Use, so it can be submitted again.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - token: The token submitted with the form, marked as used with Use.
+//
+// Returns:
+//   - error: ErrInvalid if the token is malformed, or an error if the store could not be reached.
+func (s *signer) Release(ctx context.Context, token string) error {
+	t, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Release(ctx, t.nonce); err != nil {
+		return fmt.Errorf("failed to release form token: %w", err)
+	}
+
+	return nil
+}
+
+// parsedToken is the payload of a token whose signature was verified.
+type parsedToken struct {
+	formID   string
+	issuedAt time.Time
+	nonce    string
+}
+
+// parse verifies the signature of a token and returns its payload.
+func (s *signer) parse(token string) (parsedToken, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return parsedToken{}, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return parsedToken{}, ErrInvalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.sign(string(payload))) {
+		return parsedToken{}, ErrInvalid
+	}
+
+	// Form IDs cannot contain colons, so the payload splits unambiguously.
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 {
+		return parsedToken{}, ErrInvalid
+	}
+
+	issuedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return parsedToken{}, ErrInvalid
+	}
+
+	return parsedToken{formID: parts[0], issuedAt: time.UnixMilli(issuedAt), nonce: parts[2]}, nil
+}
+
+func (s *signer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,180 @@
+package formtoken
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyUnit(t *testing.T) {
+	type input struct {
+		token   func(t *testing.T, s *signer) string
+		formID  string
+		elapsed time.Duration
+	}
+
+	issue := func(formID string) func(t *testing.T, s *signer) string {
+		return func(t *testing.T, s *signer) string {
+			token, _, err := s.Issue(formID)
+			require.Empty(t, err)
+			return token
+		}
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  error
+	}{
+		{"accepts token", input{issue("shop"), "shop", 10 * time.Second}, nil},
+		{"rejects token submitted too fast", input{issue("shop"), "shop", time.Second}, ErrTooFast},
+		{"rejects expired token", input{issue("shop"), "shop", 2 * time.Hour}, ErrExpired},
+		{"rejects token of another form", input{issue("blog"), "shop", 10 * time.Second}, ErrInvalid},
+		{"rejects missing token", input{func(*testing.T, *signer) string { return "" }, "shop", 10 * time.Second}, ErrInvalid},
+		{
+			"rejects tampered token",
+			input{
+				func(t *testing.T, s *signer) string {
+					payload, signature, _ := strings.Cut(issue("shop")(t, s), ".")
+					return payload + "." + strings.Repeat("A", len(signature))
+				},
+				"shop",
+				10 * time.Second,
+			},
+			ErrInvalid,
+		},
+		{
+			"rejects token signed with another secret",
+			input{
+				func(t *testing.T, s *signer) string {
+					other, err := New(Config{Secret: []byte("other"), MinAge: 3 * time.Second, MaxAge: time.Hour, Store: NewMemoryStore()})
+					require.Empty(t, err)
+					other.(*signer).now = s.now
+					token, _, err := other.Issue("shop")
+					require.Empty(t, err)
+					return token
+				},
+				"shop",
+				10 * time.Second,
+			},
+			ErrInvalid,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(Config{Secret: []byte("secret"), MinAge: 3 * time.Second, MaxAge: time.Hour, Store: NewMemoryStore()})
+			require.Empty(t, err)
+
+			s := v.(*signer)
+			now := time.Unix(1700000000, 0)
+			s.now = func() time.Time { return now }
+
+			token := tt.input.token(t, s)
+			now = now.Add(tt.input.elapsed)
+
+			assert.ErrorIs(t, s.Verify(context.Background(), token, tt.input.formID), tt.want)
+		})
+	}
+}
+
+func TestVerifyReplayUnit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, "test:"),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			v, err := New(Config{Secret: []byte("secret"), MinAge: 3 * time.Second, MaxAge: time.Hour, Store: store})
+			require.Empty(t, err)
+
+			s := v.(*signer)
+			now := time.Now()
+			s.now = func() time.Time { return now }
+
+			token, expiresAt, err := s.Issue("shop")
+			require.Empty(t, err)
+			assert.Equal(t, now.Add(time.Hour), expiresAt)
+
+			now = now.Add(10 * time.Second)
+			assert.Empty(t, s.Verify(ctx, token, "shop"))
+			assert.Empty(t, s.Verify(ctx, token, "shop"), "verifying a token does not use it up")
+
+			require.Empty(t, s.Use(ctx, token))
+			assert.ErrorIs(t, s.Verify(ctx, token, "shop"), ErrReplayed)
+			assert.ErrorIs(t, s.Use(ctx, token), ErrReplayed)
+
+			require.Empty(t, s.Release(ctx, token))
+			assert.Empty(t, s.Verify(ctx, token, "shop"), "a released token can be submitted again")
+			require.Empty(t, s.Use(ctx, token))
+
+			other, _, err := s.Issue("shop")
+			require.Empty(t, err)
+			now = now.Add(10 * time.Second)
+			assert.Empty(t, s.Verify(ctx, other, "shop"))
+		})
+	}
+}
+
+func TestUseConcurrentUnit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, "test:"),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			s, err := New(Config{Secret: []byte("secret"), MaxAge: time.Hour, Store: store})
+			require.Empty(t, err)
+
+			token, _, err := s.Issue("shop")
+			require.Empty(t, err)
+
+			var (
+				wg       sync.WaitGroup
+				accepted atomic.Int32
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if s.Use(context.Background(), token) == nil {
+						accepted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, int32(1), accepted.Load())
+		})
+	}
+}
+
+func TestNewUnit(t *testing.T) {
+	_, err := New(Config{MinAge: time.Second, MaxAge: time.Hour})
+	assert.ErrorContains(t, err, "secret is required")
+
+	_, err = New(Config{Secret: []byte("secret"), MinAge: time.Second, MaxAge: time.Hour})
+	assert.ErrorContains(t, err, "store is required")
+
+	_, err = New(Config{Secret: []byte("secret"), MinAge: time.Hour, MaxAge: time.Minute, Store: NewMemoryStore()})
+	assert.ErrorContains(t, err, "invalid form token ages")
+}
@@ -0,0 +1,101 @@
+package formtoken
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the nonces of the tokens that have expired.
+const sweepInterval = time.Minute
+
+// memoryStore holds the used nonces in process. It only rejects tokens replayed to the same replica.
+type memoryStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates a Store holding the used nonces in process.
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		used: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Used reports whether a nonce was used.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//
+// Returns:
+//   - bool: Whether the nonce was used.
+//   - error: Always nil.
+func (s *memoryStore) Used(_ context.Context, nonce string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.used[nonce]
+	return ok && s.now().Before(expires), nil
+}
+
+// Use marks a nonce as used.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//   - ttl: How long the nonce is remembered, i.e. until its token expires.
+//
+// Returns:
+//   - bool: Whether the nonce was not used yet.
+//   - error: Always nil.
+func (s *memoryStore) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if expires, ok := s.used[nonce]; ok && now.Before(expires) {
+		return false, nil
+	}
+
+	s.used[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// Release forgets a used nonce.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//
+// Returns:
+//   - error: Always nil.
+func (s *memoryStore) Release(_ context.Context, nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.used, nonce)
+	return nil
+}
+
+// sweep drops the nonces that have expired.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for nonce, expires := range s.used {
+		if !now.Before(expires) {
+			delete(s.used, nonce)
+		}
+	}
+}
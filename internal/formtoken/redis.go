@@ -0,0 +1,83 @@
+package formtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore holds the used nonces in Redis, so tokens cannot be replayed to another replica.
+type redisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore creates a Store holding the used nonces in Redis.
+//
+// Parameters:
+//   - client: The Redis client, e.g. a *redis.Client.
+//   - prefix: The prefix of the keys, e.g. "mail-service:formtoken:".
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewRedisStore(client redis.Cmdable, prefix string) Store {
+	return &redisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Used reports whether a nonce was used.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//
+// Returns:
+//   - bool: Whether the nonce was used.
+//   - error: An error if Redis could not be reached.
+func (s *redisStore) Used(ctx context.Context, nonce string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+nonce).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to look up nonce %q: %w", nonce, err)
+	}
+
+	return n > 0, nil
+}
+
+// Use marks a nonce as used. The nonce is set only if it is not set yet, so concurrent replicas cannot both use it.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//   - ttl: How long the nonce is remembered, i.e. until its token expires.
+//
+// Returns:
+//   - bool: Whether the nonce was not used yet.
+//   - error: An error if Redis could not be reached.
+func (s *redisStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	ok, err := s.client.SetNX(ctx, s.prefix+nonce, 1, max(ttl, time.Millisecond)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to use nonce %q: %w", nonce, err)
+	}
+
+	return ok, nil
+}
+
+// Release forgets a used nonce.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - nonce: The nonce of the token.
+//
+// Returns:
+//   - error: An error if Redis could not be reached.
+func (s *redisStore) Release(ctx context.Context, nonce string) error {
+	if err := s.client.Del(ctx, s.prefix+nonce).Err(); err != nil {
+		return fmt.Errorf("failed to release nonce %q: %w", nonce, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// errorHandler writes gRPC errors as HTTP responses with runtime.DefaultHTTPErrorHandler. Errors carrying an
+// errdetails.RetryInfo, e.g. those of rate limited submissions, also set the Retry-After header in seconds.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+				seconds := int64(math.Ceil(info.RetryDelay.AsDuration().Seconds()))
+				w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+				break
+			}
+		}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
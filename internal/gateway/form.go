@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// genericErrorMessage replaces the message of errors caused by the service rather than the submission,
+// so internal details never end up in a redirect URL.
+const genericErrorMessage = "the message could not be sent, please try again later"
+
+// FormRedirect holds the pages a plain HTML form submission is redirected to.
+//
+// Fields:
+//   - SuccessURL: The page a successful submission is redirected to. The message ID is added as the "message_id" query parameter.
+//   - ErrorURL: The page a failed submission is redirected to. The error is described by the "error", "message" and "field.<name>" query parameters.
+type FormRedirect struct {
+	SuccessURL string
+	ErrorURL   string
+}
+
+// FormConfig holds the configuration of the application/x-www-form-urlencoded form route.
+//
+// Fields:
+//   - Path: The path the route is registered at, e.g. "/v1/mail/form".
+//   - Sites: The FormRedirect of each site, keyed by the host of the page the form was submitted from.
+//   - Default: The FormRedirect used for sites without their own. Without a redirect URL, the route responds like "/v1/mail/send".
+type FormConfig struct {
+	Path    string
+	Sites   map[string]FormRedirect
+	Default FormRedirect
+}
+
+// formHandler accepts plain HTML form submissions and answers them with a redirect, so forms work without JavaScript.
+type formHandler struct {
+	cfg    FormConfig
+	client mailservice_v1.MailServiceClient
+	mux    *runtime.ServeMux
+}
+
+// ParseFormSites parses the redirect pages of each site from entries formatted as "<host>=<success url> <error url>".
+// The error URL may be left out.
+//
+// Parameters:
+//   - entries: The entries to parse, e.g. "example.com=https://example.com/thanks https://example.com/oops".
+//
+// Returns:
+//   - map[string]FormRedirect: The FormRedirect of each site, keyed by host.
+//   - error: An error if an entry is malformed or holds an invalid URL.
+func ParseFormSites(entries []string) (map[string]FormRedirect, error) {
+	sites := make(map[string]FormRedirect, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, urls, ok := strings.Cut(entry, "=")
+		fields := strings.Fields(urls)
+		if !ok || host == "" || len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid form site %q: want <host>=<success url> [error url]", entry)
+		}
+
+		redirect := FormRedirect{SuccessURL: fields[0]}
+		if len(fields) == 2 {
+			redirect.ErrorURL = fields[1]
+		}
+
+		for _, u := range []string{redirect.SuccessURL, redirect.ErrorURL} {
+			if err := validateRedirectURL(u); err != nil {
+				return nil, fmt.Errorf("invalid form site %q: %w", entry, err)
+			}
+		}
+
+		sites[strings.ToLower(strings.TrimSpace(host))] = redirect
+	}
+
+	return sites, nil
+}
+
+// RegisterForm registers a route accepting application/x-www-form-urlencoded submissions from plain HTML forms.
+// The form fields "name", "email", "subject", "message", "department" and "form_id" map onto the SendMailRequest, and
+// fields named "fields.<name>" onto its custom fields. The route answers with a 303 redirect to the success or error
+// page of the site the form was submitted from.
+//
+// Parameters:
+//   - ctx: The context.Context object that bounds the lifetime of the gRPC connection.
+//   - cfg: The FormConfig object containing the route path and the redirect pages.
+//   - opts: Additional grpc.DialOption options for the gRPC connection.
+//
+// Returns:
+//   - error: An error if the gRPC connection could not be set up, a redirect URL is invalid or the path pattern is invalid.
+func (g gateway) RegisterForm(ctx context.Context, cfg FormConfig, opts ...grpc.DialOption) error {
+	for _, u := range []string{cfg.Default.SuccessURL, cfg.Default.ErrorURL} {
+		if err := validateRedirectURL(u); err != nil {
+			return err
+		}
+	}
+
+	client, err := g.dial(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	h := &formHandler{
+		cfg:    cfg,
+		client: client,
+		mux:    g.mux,
+	}
+
+	return g.mux.HandlePath(http.MethodPost, cfg.Path, h.serve)
+}
+
+func (h *formHandler) serve(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	_, outbound := runtime.MarshalerForRequest(h.mux, r)
+	redirect := h.redirect(r)
+
+	ctx, err := runtime.AnnotateContext(r.Context(), h.mux, r, "/mailservice.MailService/SendMail", runtime.WithHTTPPathPattern(h.cfg.Path))
+	if err != nil {
+		runtime.HTTPError(r.Context(), h.mux, outbound, w, r, err)
+		return
+	}
+
+	resp, err := h.send(ctx, w, r)
+	if err != nil {
+		if redirect.ErrorURL == "" {
+			runtime.HTTPError(ctx, h.mux, outbound, w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, withQuery(redirect.ErrorURL, errorQuery(err)), http.StatusSeeOther)
+		return
+	}
+
+	if redirect.SuccessURL == "" {
+		runtime.ForwardResponseMessage(ctx, h.mux, outbound, w, r, resp)
+		return
+	}
+
+	http.Redirect(w, r, withQuery(redirect.SuccessURL, url.Values{"message_id": {resp.MessageId}}), http.StatusSeeOther)
+}
+
+func (h *formHandler) send(ctx context.Context, w http.ResponseWriter, r *http.Request) (*mailservice_v1.SendMailResponse, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return nil, status.Error(codes.InvalidArgument, "the request must be application/x-www-form-urlencoded")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFieldsSize)
+	if err := r.ParseForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, status.Errorf(codes.InvalidArgument, "the request is larger than the limit of %d bytes", maxBytesErr.Limit)
+		}
+
+		return nil, status.Errorf(codes.InvalidArgument, "invalid form: %v", err)
+	}
+
+	req := &mailservice_v1.SendMailRequest{}
+	for name, values := range r.PostForm {
+		// Custom fields keep every value, e.g. of a group of checkboxes. Other fields only take the first.
+		if !strings.HasPrefix(name, customFieldPrefix) {
+			values = values[:1]
+		}
+
+		for _, v := range values {
+			if v != "" {
+				setField(req, name, v)
+			}
+		}
+	}
+
+	return h.client.SendMail(ctx, req)
+}
+
+// redirect returns the redirect pages of the site the request was submitted from, as identified by its Origin
+// or Referer header. Pages the site does not configure are taken from the defaults.
+func (h *formHandler) redirect(r *http.Request) FormRedirect {
+	redirect := h.cfg.Default
+
+	for _, v := range []string{r.Header.Get("Origin"), r.Referer()} {
+		u, err := url.Parse(v)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		site, ok := h.cfg.Sites[strings.ToLower(u.Host)]
+		if !ok {
+			site, ok = h.cfg.Sites[strings.ToLower(u.Hostname())]
+		}
+		if !ok {
+			continue
+		}
+
+		if site.SuccessURL != "" {
+			redirect.SuccessURL = site.SuccessURL
+		}
+		if site.ErrorURL != "" {
+			redirect.ErrorURL = site.ErrorURL
+		}
+		break
+	}
+
+	return redirect
+}
+
+// errorQuery describes an error as query parameters: its code, a message safe to show to the submitter and the
+// description of every invalid field.
+func errorQuery(err error) url.Values {
+	st := status.Convert(err)
+
+	message := st.Message()
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.ResourceExhausted,
+		codes.PermissionDenied, codes.Unauthenticated, codes.AlreadyExists:
+	default:
+		message = genericErrorMessage
+	}
+
+	q := url.Values{
+		"error":   {strings.ToLower(codeName(st.Code()))},
+		"message": {message},
+	}
+
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				q.Add("field."+v.GetField(), v.GetDescription())
+			}
+		}
+	}
+
+	return q
+}
+
+// codeName returns the canonical name of a code, e.g. INVALID_ARGUMENT.
+func codeName(c codes.Code) string {
+	var b strings.Builder
+	for i, r := range c.String() {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+
+	return strings.ToUpper(b.String())
+}
+
+// withQuery adds the query parameters to a URL, keeping the parameters it already has.
+func withQuery(rawURL string, q url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	existing := u.Query()
+	for k, values := range q {
+		for _, v := range values {
+			existing.Add(k, v)
+		}
+	}
+	u.RawQuery = existing.Encode()
+
+	return u.String()
+}
+
+func validateRedirectURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid redirect url %q: %w", rawURL, err)
+	}
+
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid redirect url %q: must be an absolute http or https url", rawURL)
+	}
+
+	return nil
+}
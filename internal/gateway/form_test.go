@@ -0,0 +1,281 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFormUnit(t *testing.T) {
+	invalidEmail, err := status.New(codes.InvalidArgument, "invalid email").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "email", Description: "must be a valid email address"}},
+	})
+	require.Empty(t, err)
+
+	cfg := FormConfig{
+		Path: "/v1/mail/form",
+		Sites: map[string]FormRedirect{
+			"example.org": {SuccessURL: "https://example.org/thanks", ErrorURL: "https://example.org/contact?failed=1"},
+			"shop.test":   {SuccessURL: "https://shop.test/thanks"},
+		},
+		Default: FormRedirect{SuccessURL: "https://example.com/thanks", ErrorURL: "https://example.com/oops"},
+	}
+
+	type input struct {
+		cfg         FormConfig
+		contentType string
+		form        url.Values
+		headers     map[string]string
+		err         error
+	}
+
+	type want struct {
+		status   int
+		location string
+		query    url.Values
+		body     string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"redirects to the success page",
+			input{
+				cfg:  cfg,
+				form: url.Values{"name": {"Jane"}, "email": {"jane@example.com"}, "subject": {"Hi"}, "message": {"Hello"}},
+			},
+			want{
+				status:   http.StatusSeeOther,
+				location: "https://example.com/thanks",
+				query:    url.Values{"message_id": {"message-id"}},
+			},
+		},
+		{
+			"redirects to the error page with field errors",
+			input{
+				cfg:  cfg,
+				form: url.Values{"email": {"jane"}, "message": {"Hello"}},
+				err:  invalidEmail.Err(),
+			},
+			want{
+				status:   http.StatusSeeOther,
+				location: "https://example.com/oops",
+				query: url.Values{
+					"error":       {"invalid_argument"},
+					"message":     {"invalid email"},
+					"field.email": {"must be a valid email address"},
+				},
+			},
+		},
+		{
+			"hides internal errors",
+			input{
+				cfg:  cfg,
+				form: url.Values{"message": {"Hello"}},
+				err:  status.Error(codes.Internal, "failed to reach ses: connection refused"),
+			},
+			want{
+				status:   http.StatusSeeOther,
+				location: "https://example.com/oops",
+				query:    url.Values{"error": {"internal"}, "message": {genericErrorMessage}},
+			},
+		},
+		{
+			"picks the site by origin",
+			input{
+				cfg:     cfg,
+				form:    url.Values{"message": {"Hello"}},
+				headers: map[string]string{"Origin": "https://EXAMPLE.org"},
+				err:     status.Error(codes.InvalidArgument, "message is required"),
+			},
+			want{
+				status:   http.StatusSeeOther,
+				location: "https://example.org/contact",
+				query:    url.Values{"failed": {"1"}, "error": {"invalid_argument"}, "message": {"message is required"}},
+			},
+		},
+		{
+			"picks the site by referer and falls back to the default error page",
+			input{
+				cfg:     cfg,
+				form:    url.Values{"message": {"Hello"}},
+				headers: map[string]string{"Referer": "https://shop.test:8443/contact"},
+				err:     status.Error(codes.InvalidArgument, "message is required"),
+			},
+			want{
+				status:   http.StatusSeeOther,
+				location: "https://example.com/oops",
+				query:    url.Values{"error": {"invalid_argument"}, "message": {"message is required"}},
+			},
+		},
+		{
+			"responds like the api without redirect pages",
+			input{
+				cfg:  FormConfig{Path: "/v1/mail/form"},
+				form: url.Values{"message": {"Hello"}},
+				err:  status.Error(codes.InvalidArgument, "message is required"),
+			},
+			want{
+				status: http.StatusBadRequest,
+				body:   "message is required",
+			},
+		},
+		{
+			"handles non form content type",
+			input{
+				cfg:         FormConfig{Path: "/v1/mail/form"},
+				contentType: "application/json",
+			},
+			want{
+				status: http.StatusBadRequest,
+				body:   "application/x-www-form-urlencoded",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockMailServiceClient{err: tt.input.err}
+			h := &formHandler{cfg: tt.input.cfg, client: client, mux: runtime.NewServeMux()}
+
+			contentType := tt.input.contentType
+			if contentType == "" {
+				contentType = "application/x-www-form-urlencoded"
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/mail/form", strings.NewReader(tt.input.form.Encode()))
+			req.Header.Set("Content-Type", contentType)
+			for k, v := range tt.input.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.serve(rec, req, nil)
+
+			assert.Equal(t, tt.want.status, rec.Code, rec.Body.String())
+			if tt.want.status != http.StatusSeeOther {
+				assert.Contains(t, rec.Body.String(), tt.want.body)
+				return
+			}
+
+			location, err := url.Parse(rec.Header().Get("Location"))
+			require.Empty(t, err)
+			assert.Equal(t, tt.want.location, location.Scheme+"://"+location.Host+location.Path)
+			assert.Equal(t, tt.want.query, location.Query())
+
+			require.NotNil(t, client.req)
+			assert.Equal(t, tt.input.form.Get("name"), client.req.Name)
+			assert.Equal(t, tt.input.form.Get("email"), client.req.Email)
+			assert.Equal(t, tt.input.form.Get("subject"), client.req.GetSubject())
+			assert.Equal(t, tt.input.form.Get("message"), client.req.Message)
+		})
+	}
+}
+
+func TestParseFormSitesUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		sites        map[string]FormRedirect
+	}
+
+	cases := []struct {
+		name  string
+		input []string
+		want  want
+	}{
+		{
+			"parses sites",
+			[]string{"Example.com=https://example.com/thanks https://example.com/oops", " shop.test=https://shop.test/thanks ", ""},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				sites: map[string]FormRedirect{
+					"example.com": {SuccessURL: "https://example.com/thanks", ErrorURL: "https://example.com/oops"},
+					"shop.test":   {SuccessURL: "https://shop.test/thanks"},
+				},
+			},
+		},
+		{
+			"handles missing urls",
+			[]string{"example.com="},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "want <host>=<success url> [error url]")
+				},
+			},
+		},
+		{
+			"handles relative urls",
+			[]string{"example.com=/thanks"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "must be an absolute http or https url")
+				},
+			},
+		},
+		{
+			"handles unsupported schemes",
+			[]string{"example.com=javascript:alert(1)"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "must be an absolute http or https url")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormSites(tt.input)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.sites, got)
+		})
+	}
+}
+
+func TestFormCustomFieldsUnit(t *testing.T) {
+	client := &mockMailServiceClient{}
+	h := &formHandler{
+		cfg:    FormConfig{Path: "/v1/mail/form", Default: FormRedirect{SuccessURL: "https://example.com/thanks"}},
+		client: client,
+		mux:    runtime.NewServeMux(),
+	}
+
+	form := url.Values{
+		"email":            {"jane@example.com"},
+		"fields.company":   {"Acme"},
+		"fields.interests": {"seo", "", "ads"},
+		"fields.":          {"ignored"},
+		"name":             {"Jane", "John"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mail/form", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.serve(rec, req, nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code, rec.Body.String())
+
+	require.NotNil(t, client.req)
+	assert.Equal(t, "Jane", client.req.Name)
+	assert.Equal(t, map[string]any{
+		"company":   "Acme",
+		"interests": []any{"seo", "ads"},
+	}, (&structpb.Struct{Fields: client.req.Fields}).AsMap())
+}
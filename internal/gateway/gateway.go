@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/idempotency"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/cors"
 	"google.golang.org/grpc"
@@ -19,11 +23,29 @@ import (
 //   - Port: The port number for the HTTP server.
 //   - GRPCHost: The host address for the gRPC server.
 //   - GRPCPort: The port number for the gRPC server.
+//   - CORS: The CORSConfig object containing the cross-origin policy of the HTTP server.
 type Config struct {
 	Host     string
 	Port     int
 	GRPCHost string
 	GRPCPort int
+	CORS     CORSConfig
+}
+
+// CORSConfig holds the cross-origin resource sharing policy of the HTTP server.
+//
+// Fields:
+//   - AllowedOrigins: The origins allowed to make cross-origin requests. An origin may contain one "*" wildcard, e.g. "https://*.example.com". No cross-origin requests are allowed when empty.
+//   - AllowedMethods: The methods cross-origin requests may use.
+//   - AllowedHeaders: The headers cross-origin requests may send. "Origin", "Accept", "Content-Type" and "X-Requested-With" are allowed when empty. "Idempotency-Key" is always allowed.
+//   - AllowCredentials: Whether cross-origin requests may include cookies and authorization headers.
+//   - MaxAge: How long browsers may cache the result of a preflight request. Browsers apply their own default when zero.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
 }
 
 // gateway represents the gRPC-Gateway server.
@@ -34,12 +56,14 @@ type Config struct {
 //   - port: The port number for the HTTP server.
 //   - grpcHost: The host address for the gRPC server.
 //   - grpcPort: The port number for the gRPC server.
+//   - cors: The cross-origin policy applied to every request.
 //   - mux: The runtime.ServeMux for routing HTTP requests to gRPC handlers.
 type gateway struct {
 	host     string
 	port     int
 	grpcHost string
 	grpcPort int
+	cors     CORSConfig
 	mux      *runtime.ServeMux
 }
 
@@ -57,11 +81,27 @@ func New(cfg Config) *gateway {
 		port:     cfg.Port,
 		grpcHost: cfg.GRPCHost,
 		grpcPort: cfg.GRPCPort,
-		mux:      runtime.NewServeMux(),
+		cors:     cfg.CORS,
+		mux:      newServeMux(),
 	}
 }
 
-// Register registers the MailService handler with the gRPC-Gateway mux.
+// newServeMux creates the mux routing HTTP requests to gRPC handlers. Errors are rendered with errorHandler, and the
+// Idempotency-Key header is forwarded to the gRPC server along with the headers forwarded by default.
+func newServeMux() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithErrorHandler(errorHandler),
+		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+			if strings.EqualFold(key, "Idempotency-Key") {
+				return idempotency.MetadataKey, true
+			}
+
+			return runtime.DefaultHeaderMatcher(key)
+		}),
+	)
+}
+
+// Register registers the MailService and TemplateService handlers with the gRPC-Gateway mux.
 // It connects the mux to the gRPC server endpoint.
 //
 // Parameters:
@@ -71,24 +111,88 @@ func New(cfg Config) *gateway {
 // Returns:
 //   - error: An error if any occurred during the registration of the handler.
 func (g gateway) Register(ctx context.Context, opts ...grpc.DialOption) error {
-	return mailservice_v1.RegisterMailServiceHandlerFromEndpoint(ctx, g.mux, fmt.Sprintf("%s:%d", g.grpcHost, g.grpcPort), opts)
+	endpoint := fmt.Sprintf("%s:%d", g.grpcHost, g.grpcPort)
+	if err := mailservice_v1.RegisterMailServiceHandlerFromEndpoint(ctx, g.mux, endpoint, opts); err != nil {
+		return err
+	}
+
+	return mailservice_v1.RegisterTemplateServiceHandlerFromEndpoint(ctx, g.mux, endpoint, opts)
+}
+
+// dial connects a MailService client to the gRPC server, for routes that are not generated by the gRPC-Gateway.
+// The connection is closed once ctx is done.
+func (g gateway) dial(ctx context.Context, opts ...grpc.DialOption) (mailservice_v1.MailServiceClient, error) {
+	conn, err := grpc.NewClient(fmt.Sprintf("%s:%d", g.grpcHost, g.grpcPort), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to grpc server: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	return mailservice_v1.NewMailServiceClient(conn), nil
+}
+
+// Handle registers a plain HTTP handler on the gateway mux, alongside the generated gRPC-Gateway routes.
+//
+// Parameters:
+//   - method: The HTTP method the handler is registered for.
+//   - path: The path pattern the handler is registered for.
+//   - h: The http.Handler that serves matching requests.
+//
+// Returns:
+//   - error: An error if the path pattern is invalid.
+func (g gateway) Handle(method, path string, h http.Handler) error {
+	return g.mux.HandlePath(method, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		h.ServeHTTP(w, r)
+	})
 }
 
 // Serve starts the HTTP server and listens for incoming requests.
-// It applies CORS settings to allow cross-origin requests.
+// It applies the configured CORS policy to allow cross-origin requests.
 //
 // Returns:
 //   - error: An error if any occurred during the server startup or while listening for requests.
 func (g gateway) Serve() error {
-	withCors := cors.New(cors.Options{
-		AllowedOrigins: []string{"https://www.bricealdrich.com", "http://localhost:3000"},
-		AllowedMethods: []string{http.MethodPost, http.MethodOptions, http.MethodGet},
-	}).Handler(g.mux)
-
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", g.host, g.port),
-		Handler: withCors,
+		Handler: g.handler(),
 	}
 
 	return server.ListenAndServe()
 }
+
+// handler returns the mux wrapped in the CORS policy. Without allowed origins, no cross-origin requests are allowed.
+func (g gateway) handler() http.Handler {
+	opts := cors.Options{
+		AllowedOrigins:   g.cors.AllowedOrigins,
+		AllowedMethods:   g.cors.AllowedMethods,
+		AllowedHeaders:   allowedHeaders(g.cors.AllowedHeaders),
+		AllowCredentials: g.cors.AllowCredentials,
+		MaxAge:           int(g.cors.MaxAge / time.Second),
+	}
+	// The cors package allows every origin when none are listed.
+	if len(opts.AllowedOrigins) == 0 {
+		opts.AllowOriginFunc = func(string) bool { return false }
+	}
+
+	return cors.New(opts).Handler(g.mux)
+}
+
+// allowedHeaders returns the headers cross-origin requests may send: the configured headers, or the headers the cors
+// package allows by default when none are configured, along with the Idempotency-Key header, so browsers can send it.
+func allowedHeaders(headers []string) []string {
+	if len(headers) == 0 {
+		headers = []string{"Origin", "Accept", "Content-Type", "X-Requested-With"}
+	}
+
+	for _, h := range headers {
+		if h == "*" || strings.EqualFold(h, "Idempotency-Key") {
+			return headers
+		}
+	}
+
+	return append(slices.Clone(headers), "Idempotency-Key")
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestCORSUnit(t *testing.T) {
+	g := New(Config{
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"https://www.example.com", "https://*.shop.example"},
+			AllowedMethods:   []string{http.MethodPost},
+			AllowedHeaders:   []string{"Content-Type", "Authorization"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	})
+
+	type want struct {
+		origin      string
+		credentials string
+		maxAge      string
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"allows listed origin",
+			"https://www.example.com",
+			want{origin: "https://www.example.com", credentials: "true", maxAge: "600"},
+		},
+		{
+			"allows wildcard subdomain",
+			"https://eu.shop.example",
+			want{origin: "https://eu.shop.example", credentials: "true", maxAge: "600"},
+		},
+		{
+			"rejects other origin",
+			"https://evil.example",
+			want{},
+		},
+		{
+			"rejects lookalike of wildcard",
+			"https://shop.example.evil.example",
+			want{},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/v1/mail/send", nil)
+			req.Header.Set("Origin", tt.input)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "authorization,content-type")
+			rec := httptest.NewRecorder()
+			g.handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.want.credentials, rec.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, tt.want.maxAge, rec.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func TestCORSWithoutOriginsUnit(t *testing.T) {
+	g := New(Config{CORS: CORSConfig{AllowedMethods: []string{http.MethodPost}}})
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/mail/send", nil)
+	req.Header.Set("Origin", "https://www.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	g.handler().ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSIdempotencyKeyUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input []string
+	}{
+		{"allows the header by default", nil},
+		{"allows the header with configured headers", []string{"Content-Type"}},
+		{"keeps the header when configured", []string{"Content-Type", "idempotency-key"}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(Config{CORS: CORSConfig{
+				AllowedOrigins: []string{"https://www.example.com"},
+				AllowedMethods: []string{http.MethodPost},
+				AllowedHeaders: tt.input,
+			}})
+
+			req := httptest.NewRequest(http.MethodOptions, "/v1/mail/send", nil)
+			req.Header.Set("Origin", "https://www.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "content-type,idempotency-key")
+			rec := httptest.NewRecorder()
+			g.handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, "https://www.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "content-type,idempotency-key", rec.Header().Get("Access-Control-Allow-Headers"))
+		})
+	}
+}
+
+func TestRetryAfterUnit(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "too many submissions").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+	require.Empty(t, err)
+
+	h := &uploadHandler{client: &mockMailServiceClient{err: st.Err()}, mux: New(Config{}).mux, cfg: UploadConfig{MaxFiles: 1, MaxFileSize: 1 << 10, MaxTotalSize: 1 << 10}}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.Empty(t, mw.WriteField("email", "jane@example.com"))
+	require.Empty(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mail/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.serve(rec, req, nil)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+}
+
+func TestFieldViolationsUnit(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "invalid submission: email: email is required").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "email", Description: "email is required"}},
+	})
+	require.Empty(t, err)
+
+	h := &uploadHandler{client: &mockMailServiceClient{err: st.Err()}, mux: New(Config{}).mux, cfg: UploadConfig{MaxFiles: 1, MaxFileSize: 1 << 10, MaxTotalSize: 1 << 10}}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.Empty(t, mw.WriteField("message", "Hello there"))
+	require.Empty(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mail/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.serve(rec, req, nil)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"code": 3,
+		"message": "invalid submission: email: email is required",
+		"details": [{
+			"@type": "type.googleapis.com/google.rpc.BadRequest",
+			"fieldViolations": [{"field": "email", "description": "email is required"}]
+		}]
+	}`, rec.Body.String())
+}
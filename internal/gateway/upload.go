@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// maxFieldSize is the maximum size of a single non-file form field.
+	maxFieldSize = 64 << 10
+	// maxFieldsSize is the maximum combined size of the non-file form fields, on top of the files.
+	maxFieldsSize = 1 << 20
+	// maxFilenameLength is the maximum length of a sanitised file name in bytes.
+	maxFilenameLength = 255
+	// sniffLength is the number of bytes http.DetectContentType considers.
+	sniffLength = 512
+	// customFieldPrefix prefixes the names of the form fields that map onto the custom fields of the SendMailRequest,
+	// e.g. "fields.company".
+	customFieldPrefix = "fields."
+)
+
+// genericTypes are the types http.DetectContentType reports for content it cannot identify more precisely.
+// They never contradict the declared type of a file; e.g. Office documents are sniffed as application/zip.
+var genericTypes = map[string]bool{
+	"application/octet-stream": true,
+	"text/plain":               true,
+	"application/zip":          true,
+}
+
+// UploadConfig holds the configuration of the multipart form upload route.
+//
+// Fields:
+//   - Path: The path the route is registered at, e.g. "/v1/mail/upload".
+//   - AllowedTypes: The media types files may have. A type may end in "/*" to allow a whole family, e.g. "image/*". Every type is allowed when empty.
+//   - MaxFiles: The maximum number of files per submission.
+//   - MaxFileSize: The maximum size of a single file in bytes.
+//   - MaxTotalSize: The maximum combined size of the files in bytes.
+type UploadConfig struct {
+	Path         string
+	AllowedTypes []string
+	MaxFiles     int
+	MaxFileSize  int64
+	MaxTotalSize int64
+}
+
+// uploadHandler accepts multipart/form-data submissions and forwards them, along with their files, to the SendMail RPC.
+type uploadHandler struct {
+	cfg    UploadConfig
+	client mailservice_v1.MailServiceClient
+	mux    *runtime.ServeMux
+}
+
+// RegisterUpload registers a route accepting multipart/form-data submissions, so browser forms can upload files.
+// The form fields "name", "email", "subject", "message", "department" and "form_id" map onto the SendMailRequest, and
+// fields named "fields.<name>" onto its custom fields. Every file becomes an attachment.
+// Files are read part by part and never buffered beyond the configured limits.
+//
+// Parameters:
+//   - ctx: The context.Context object that bounds the lifetime of the gRPC connection.
+//   - cfg: The UploadConfig object containing the route path and the upload limits.
+//   - opts: Additional grpc.DialOption options for the gRPC connection.
+//
+// Returns:
+//   - error: An error if the gRPC connection could not be set up or the path pattern is invalid.
+func (g gateway) RegisterUpload(ctx context.Context, cfg UploadConfig, opts ...grpc.DialOption) error {
+	client, err := g.dial(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	h := &uploadHandler{
+		cfg:    cfg,
+		client: client,
+		mux:    g.mux,
+	}
+
+	return g.mux.HandlePath(http.MethodPost, cfg.Path, h.serve)
+}
+
+func (h *uploadHandler) serve(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	_, outbound := runtime.MarshalerForRequest(h.mux, r)
+
+	ctx, err := runtime.AnnotateContext(r.Context(), h.mux, r, "/mailservice.MailService/SendMail", runtime.WithHTTPPathPattern(h.cfg.Path))
+	if err != nil {
+		runtime.HTTPError(r.Context(), h.mux, outbound, w, r, err)
+		return
+	}
+
+	req, err := h.parse(w, r)
+	if err != nil {
+		runtime.HTTPError(ctx, h.mux, outbound, w, r, err)
+		return
+	}
+
+	resp, err := h.client.SendMail(ctx, req)
+	if err != nil {
+		runtime.HTTPError(ctx, h.mux, outbound, w, r, err)
+		return
+	}
+
+	runtime.ForwardResponseMessage(ctx, h.mux, outbound, w, r, resp)
+}
+
+// parse reads the multipart form into a SendMailRequest, enforcing the upload limits as the parts are read.
+func (h *uploadHandler) parse(w http.ResponseWriter, r *http.Request) (*mailservice_v1.SendMailRequest, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, status.Error(codes.InvalidArgument, "the request must be multipart/form-data")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxTotalSize+maxFieldsSize)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid multipart form: %v", err)
+	}
+
+	var (
+		req        = &mailservice_v1.SendMailRequest{}
+		total      int64
+		fieldsSize int64
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return req, nil
+		}
+		if err != nil {
+			return nil, readError(err)
+		}
+
+		if part.FileName() == "" {
+			v, ok, err := readLimited(part, maxFieldSize)
+			if err != nil {
+				return nil, readError(err)
+			}
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "form field %q is larger than %d bytes", part.FormName(), maxFieldSize)
+			}
+
+			fieldsSize += int64(len(v))
+			if fieldsSize > maxFieldsSize {
+				return nil, status.Error(codes.InvalidArgument, "form fields are too large")
+			}
+
+			setField(req, part.FormName(), string(v))
+			continue
+		}
+
+		if len(req.Attachments) >= h.cfg.MaxFiles {
+			return nil, status.Errorf(codes.InvalidArgument, "too many files: at most %d are allowed", h.cfg.MaxFiles)
+		}
+
+		a, err := h.readFile(part)
+		if err != nil {
+			return nil, err
+		}
+
+		total += int64(len(a.Content))
+		if total > h.cfg.MaxTotalSize {
+			return nil, status.Errorf(codes.InvalidArgument, "files exceed the total size limit of %d bytes", h.cfg.MaxTotalSize)
+		}
+
+		req.Attachments = append(req.Attachments, a)
+	}
+}
+
+// readFile reads a file part, sanitising its name and checking its type against the allow list.
+func (h *uploadHandler) readFile(part *multipart.Part) (*mailservice_v1.Attachment, error) {
+	filename := sanitizeFilename(part.FileName())
+
+	content, ok, err := readLimited(part, h.cfg.MaxFileSize)
+	if err != nil {
+		return nil, readError(err)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "file %q is larger than the limit of %d bytes", filename, h.cfg.MaxFileSize)
+	}
+	if len(content) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "file %q is empty", filename)
+	}
+
+	contentType := declaredType(part.Header.Get("Content-Type"), filename)
+	sniffed := baseType(http.DetectContentType(content[:min(len(content), sniffLength)]))
+	if contentType == "" {
+		contentType = sniffed
+	}
+
+	if !h.allowed(contentType) {
+		return nil, status.Errorf(codes.InvalidArgument, "file %q has a type that is not allowed: %s", filename, contentType)
+	}
+
+	// The declared type is chosen by the client, so the content must not identify as a type outside the allow list.
+	if !genericTypes[sniffed] && !h.allowed(sniffed) {
+		return nil, status.Errorf(codes.InvalidArgument, "the content of file %q has a type that is not allowed: %s", filename, sniffed)
+	}
+
+	return &mailservice_v1.Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Content:     content,
+	}, nil
+}
+
+func (h *uploadHandler) allowed(contentType string) bool {
+	if len(h.cfg.AllowedTypes) == 0 {
+		return true
+	}
+
+	for _, t := range h.cfg.AllowedTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == contentType {
+			return true
+		}
+
+		if family, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(contentType, family+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sanitizeFilename reduces a client supplied file name to a safe base name: directories, control characters and
+// characters reserved on common file systems are removed and the name is shortened to 255 bytes, keeping its extension.
+// It returns "attachment" if nothing is left of the name.
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f || r == utf8.RuneError:
+			return -1
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		default:
+			return r
+		}
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	if len(name) > maxFilenameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+
+		base := name[:maxFilenameLength-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = base + ext
+	}
+
+	if name == "" {
+		return "attachment"
+	}
+
+	return name
+}
+
+// declaredType returns the media type declared for a part, falling back to the type registered for its extension.
+func declaredType(header, filename string) string {
+	if t := baseType(header); t != "" && t != "application/octet-stream" {
+		return t
+	}
+
+	return baseType(mime.TypeByExtension(filepath.Ext(filename)))
+}
+
+// baseType returns the lower case media type without parameters, or an empty string if it cannot be parsed.
+func baseType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return t
+}
+
+// readLimited reads at most limit bytes. It reports false, without an error, when the reader holds more than that.
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buf.Bytes(), n <= limit, nil
+}
+
+func readError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return status.Errorf(codes.InvalidArgument, "the request is larger than the limit of %d bytes", maxBytesErr.Limit)
+	}
+
+	return status.Errorf(codes.InvalidArgument, "invalid multipart form: %v", err)
+}
+
+func setField(req *mailservice_v1.SendMailRequest, name, value string) {
+	if field, ok := strings.CutPrefix(name, customFieldPrefix); ok && field != "" {
+		addCustomField(req, field, value)
+		return
+	}
+
+	switch name {
+	case "name":
+		req.Name = value
+	case "email":
+		req.Email = value
+	case "subject":
+		req.Subject = &value
+	case "message":
+		req.Message = value
+	case "form_id":
+		req.FormId = value
+	case "department":
+		req.Department = value
+	// The CAPTCHA widgets add their token to the form under the name of the provider.
+	case "captcha_token", "h-captcha-response", "cf-turnstile-response", "g-recaptcha-response":
+		req.CaptchaToken = value
+	case "honeypot":
+		req.Honeypot = value
+	case "form_rendered_at":
+		req.FormRenderedAt = value
+	case "idempotency_key":
+		req.IdempotencyKey = value
+	}
+}
+
+// addCustomField adds a value to a custom field of the request. A field submitted several times, e.g. by a group of
+// checkboxes, becomes a list.
+func addCustomField(req *mailservice_v1.SendMailRequest, name, value string) {
+	if req.Fields == nil {
+		req.Fields = map[string]*structpb.Value{}
+	}
+
+	v := structpb.NewStringValue(value)
+	switch prev := req.Fields[name].GetKind().(type) {
+	case *structpb.Value_StringValue:
+		v = structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue(prev.StringValue), v}})
+	case *structpb.Value_ListValue:
+		prev.ListValue.Values = append(prev.ListValue.Values, v)
+		return
+	}
+
+	req.Fields[name] = v
+}
@@ -0,0 +1,272 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUploadUnit(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n")
+
+	type file struct {
+		field       string
+		filename    string
+		contentType string
+		content     []byte
+	}
+
+	type input struct {
+		fields map[string]string
+		files  []file
+		err    error
+	}
+
+	type want struct {
+		status      int
+		message     string
+		attachments []*mailservice_v1.Attachment
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"forwards fields and files",
+			input{
+				fields: map[string]string{"name": "Jane", "email": "jane@example.com", "subject": "Hi", "message": "See attached", "form_id": "shop", "department": "sales", "cf-turnstile-response": "token", "form_rendered_at": "rendered", "idempotency_key": "key"},
+				files: []file{
+					{"resume", `C:\Users\jane\résumé<1>.pdf`, "application/pdf", pdf},
+					{"screenshot", "screen.png", "", []byte("\x89PNG\r\n\x1a\n")},
+				},
+			},
+			want{
+				status: http.StatusOK,
+				attachments: []*mailservice_v1.Attachment{
+					{Filename: "résumé_1_.pdf", ContentType: "application/pdf", Content: pdf},
+					{Filename: "screen.png", ContentType: "image/png", Content: []byte("\x89PNG\r\n\x1a\n")},
+				},
+			},
+		},
+		{
+			"handles disallowed type",
+			input{
+				files: []file{{"file", "run.exe", "application/x-msdownload", []byte("MZ")}},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: "has a type that is not allowed: application/x-msdownload",
+			},
+		},
+		{
+			"handles content that contradicts the declared type",
+			input{
+				files: []file{{"file", "page.pdf", "application/pdf", []byte("<html><script>alert(1)</script></html>")}},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: "the content of file \"page.pdf\" has a type that is not allowed: text/html",
+			},
+		},
+		{
+			"handles file over the size limit",
+			input{
+				files: []file{{"file", "big.pdf", "application/pdf", bytes.Repeat([]byte("a"), 65)}},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: `file "big.pdf" is larger than the limit of 64 bytes`,
+			},
+		},
+		{
+			"handles files over the total size limit",
+			input{
+				files: []file{
+					{"a", "a.pdf", "application/pdf", bytes.Repeat([]byte("a"), 60)},
+					{"b", "b.pdf", "application/pdf", bytes.Repeat([]byte("b"), 60)},
+				},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: "total size limit of 100 bytes",
+			},
+		},
+		{
+			"handles too many files",
+			input{
+				files: []file{
+					{"a", "a.pdf", "application/pdf", pdf},
+					{"b", "b.pdf", "application/pdf", pdf},
+					{"c", "c.pdf", "application/pdf", pdf},
+				},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: "too many files: at most 2 are allowed",
+			},
+		},
+		{
+			"handles empty file",
+			input{
+				files: []file{{"a", "a.pdf", "application/pdf", nil}},
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: `file "a.pdf" is empty`,
+			},
+		},
+		{
+			"handles rpc failure",
+			input{
+				fields: map[string]string{"message": "Hello"},
+				err:    status.Error(codes.FailedPrecondition, "the forward address is on the suppression list"),
+			},
+			want{
+				status:  http.StatusBadRequest,
+				message: "the forward address is on the suppression list",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			for k, v := range tt.input.fields {
+				require.Empty(t, mw.WriteField(k, v))
+			}
+			for _, f := range tt.input.files {
+				h := textproto.MIMEHeader{}
+				h.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+strings.ReplaceAll(f.filename, `\`, `\\`)+`"`)
+				if f.contentType != "" {
+					h.Set("Content-Type", f.contentType)
+				}
+				w, err := mw.CreatePart(h)
+				require.Empty(t, err)
+				_, err = w.Write(f.content)
+				require.Empty(t, err)
+			}
+			require.Empty(t, mw.Close())
+
+			client := &mockMailServiceClient{err: tt.input.err}
+			h := &uploadHandler{
+				cfg: UploadConfig{
+					Path:         "/v1/mail/upload",
+					AllowedTypes: []string{"application/pdf", "image/*"},
+					MaxFiles:     2,
+					MaxFileSize:  64,
+					MaxTotalSize: 100,
+				},
+				client: client,
+				mux:    newServeMux(),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/mail/upload", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("Idempotency-Key", "header-key")
+			rec := httptest.NewRecorder()
+			h.serve(rec, req, nil)
+
+			assert.Equal(t, tt.want.status, rec.Code, rec.Body.String())
+			if tt.want.status != http.StatusOK {
+				var resp struct {
+					Message string `json:"message"`
+				}
+				require.Empty(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Contains(t, resp.Message, tt.want.message)
+				return
+			}
+
+			require.NotNil(t, client.req)
+			assert.Equal(t, tt.input.fields["name"], client.req.Name)
+			assert.Equal(t, tt.input.fields["email"], client.req.Email)
+			assert.Equal(t, tt.input.fields["subject"], client.req.GetSubject())
+			assert.Equal(t, tt.input.fields["message"], client.req.Message)
+			assert.Equal(t, tt.input.fields["form_id"], client.req.FormId)
+			assert.Equal(t, tt.input.fields["department"], client.req.Department)
+			assert.Equal(t, tt.input.fields["cf-turnstile-response"], client.req.CaptchaToken)
+			assert.Equal(t, tt.input.fields["form_rendered_at"], client.req.FormRenderedAt)
+			assert.Equal(t, tt.input.fields["idempotency_key"], client.req.IdempotencyKey)
+			require.Len(t, client.req.Attachments, len(tt.want.attachments))
+			for i, want := range tt.want.attachments {
+				assert.Equal(t, want.Filename, client.req.Attachments[i].Filename)
+				assert.Equal(t, want.ContentType, client.req.Attachments[i].ContentType)
+				assert.Equal(t, want.Content, client.req.Attachments[i].Content)
+			}
+			assert.Equal(t, []string{"Bearer token"}, client.md.Get("authorization"))
+			assert.Equal(t, []string{"header-key"}, client.md.Get("idempotency-key"))
+			assert.Contains(t, rec.Body.String(), "message-id")
+		})
+	}
+}
+
+func TestUploadRejectsNonMultipartUnit(t *testing.T) {
+	h := &uploadHandler{client: &mockMailServiceClient{}, mux: runtime.NewServeMux()}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mail/upload", strings.NewReader(`{"message":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.serve(rec, req, nil)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "multipart/form-data")
+}
+
+func TestSanitizeFilenameUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"keeps simple names", "resume.pdf", "resume.pdf"},
+		{"strips unix directories", "../../etc/passwd", "passwd"},
+		{"strips windows directories", `C:\Users\jane\resume.pdf`, "resume.pdf"},
+		{"replaces reserved characters", `a<b>c:d"e|f?g*h.pdf`, "a_b_c_d_e_f_g_h.pdf"},
+		{"removes control characters", "re\r\nsume\x00.pdf", "resume.pdf"},
+		{"removes leading dots", "...hidden", "hidden"},
+		{"falls back when nothing is left", "..", "attachment"},
+		{"shortens long names keeping the extension", strings.Repeat("a", 300) + ".pdf", strings.Repeat("a", 251) + ".pdf"},
+		{"shortens on rune boundaries", strings.Repeat("é", 200), strings.Repeat("é", 127)},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeFilename(tt.input))
+		})
+	}
+}
+
+type mockMailServiceClient struct {
+	mailservice_v1.MailServiceClient
+	err error
+	req *mailservice_v1.SendMailRequest
+	md  metadata.MD
+}
+
+func (m *mockMailServiceClient) SendMail(ctx context.Context, in *mailservice_v1.SendMailRequest, opts ...grpc.CallOption) (*mailservice_v1.SendMailResponse, error) {
+	m.req = in
+	m.md, _ = metadata.FromOutgoingContext(ctx)
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &mailservice_v1.SendMailResponse{MessageId: "message-id"}, nil
+}
@@ -0,0 +1,253 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// MetadataKey is the gRPC metadata key the idempotency key of a call may be sent under, e.g. by the gRPC-Gateway
+	// from the Idempotency-Key HTTP header.
+	MetadataKey = "idempotency-key"
+
+	// maxKeyLength is the maximum length of an idempotency key in bytes.
+	maxKeyLength = 255
+
+	// pendingTTL is how long a call holds its key while it runs. It bounds how long retries wait for a replica that
+	// stopped before the call completed.
+	pendingTTL = time.Minute
+
+	// pollInterval is how often a retry checks whether the call holding its key has completed.
+	pollInterval = 100 * time.Millisecond
+)
+
+// Record is the state of an idempotency key.
+//
+// Fields:
+//   - Fingerprint: The hash of the request the key was first used with, so a key cannot be reused for another request.
+//   - Response: The response of the call, marshaled as a google.protobuf.Any. It is empty while the call runs.
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	Response    []byte `json:"response,omitempty"`
+}
+
+// Store holds the idempotency keys. Stores shared between replicas, e.g. Redis, deduplicate retries across all of them.
+//
+// Methods:
+//   - Reserve: Stores the record under the key for ttl if the key is free, reporting whether it was. When the key is
+//     taken, it returns the record stored under it instead.
+//   - Save: Stores the record under the key for ttl, replacing the reservation.
+//   - Release: Removes the key, so the call may be retried.
+type Store interface {
+	Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error)
+	Save(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// Config holds the configuration of the idempotency interceptor.
+//
+// Fields:
+//   - Store: The Store holding the idempotency keys.
+//   - Window: How long the response of a call is replayed to retries carrying its key.
+//   - Methods: The full gRPC method names, e.g. "/mailservice.MailService/SendMail", that accept idempotency keys.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Store   Store
+	Window  time.Duration
+	Methods []string
+	Logger  *zap.Logger
+}
+
+// keyed is implemented by the requests that carry an idempotency key.
+type keyed interface {
+	GetIdempotencyKey() string
+}
+
+type deduplicator struct {
+	cfg     Config
+	methods map[string]struct{}
+}
+
+// Interceptor returns a unary interceptor that deduplicates calls to the given methods by idempotency key. The key is
+// read from the idempotency_key field of the request, or from the idempotency-key metadata when the field is empty.
+// The first call with a key runs, and its response is replayed to the calls retrying it within the window. Retries
+// arriving while it runs wait for its response. Failed calls are not recorded, so they may be retried. Reusing a key
+// for another request fails with an InvalidArgument error. Calls pass through when the Store fails, so an outage of a
+// shared store does not take the service down. Calls without a key, and calls to all other methods, pass through
+// untouched.
+//
+// Parameters:
+//   - cfg: The Config object containing the store, the window and the deduplicated methods.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: The interceptor deduplicating the calls.
+func Interceptor(cfg Config) grpc.UnaryServerInterceptor {
+	d := &deduplicator{
+		cfg:     cfg,
+		methods: make(map[string]struct{}, len(cfg.Methods)),
+	}
+	for _, m := range cfg.Methods {
+		d.methods[m] = struct{}{}
+	}
+
+	return d.intercept
+}
+
+func (d *deduplicator) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if _, ok := d.methods[info.FullMethod]; !ok || d.cfg.Window <= 0 {
+		return handler(ctx, req)
+	}
+
+	idempotencyKey := Key(ctx, req)
+	if idempotencyKey == "" {
+		return handler(ctx, req)
+	}
+
+	if len(idempotencyKey) > maxKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "the idempotency key must be at most %d characters", maxKeyLength)
+	}
+
+	fingerprint, err := fingerprint(req)
+	if err != nil {
+		d.cfg.Logger.Warn("Idempotency key ignored, the request could not be hashed", zap.Error(err))
+		return handler(ctx, req)
+	}
+
+	key := info.FullMethod + ":" + idempotencyKey
+	for {
+		rec, reserved, err := d.cfg.Store.Reserve(ctx, key, Record{Fingerprint: fingerprint}, pendingTTL)
+		if err != nil {
+			d.cfg.Logger.Warn("Idempotency key ignored, the store failed", zap.String("key", key), zap.Error(err))
+			return handler(ctx, req)
+		}
+
+		if reserved {
+			return d.run(ctx, key, fingerprint, req, handler)
+		}
+
+		if rec.Fingerprint != fingerprint {
+			return nil, status.Error(codes.InvalidArgument, "the idempotency key was already used for another request")
+		}
+
+		if len(rec.Response) > 0 {
+			d.cfg.Logger.Info("Replaying response to retried call", zap.String("key", key))
+			return replay(rec.Response)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// run runs the call holding the key and records its response. The key is released when the call fails.
+func (d *deduplicator) run(ctx context.Context, key, fingerprint string, req any, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+
+	// The key is stored even when the client has gone away, so its retries find the response.
+	storeCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		if releaseErr := d.cfg.Store.Release(storeCtx, key); releaseErr != nil {
+			d.cfg.Logger.Warn("Failed to release idempotency key", zap.String("key", key), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return resp, nil
+	}
+
+	packed, err := anypb.New(msg)
+	if err == nil {
+		var b []byte
+		if b, err = proto.Marshal(packed); err == nil {
+			err = d.cfg.Store.Save(storeCtx, key, Record{Fingerprint: fingerprint, Response: b}, d.cfg.Window)
+		}
+	}
+	if err != nil {
+		d.cfg.Logger.Warn("Failed to record response of idempotent call", zap.String("key", key), zap.Error(err))
+	}
+
+	return resp, nil
+}
+
+// Key returns the idempotency key of a request: its idempotency_key field, or the idempotency-key metadata of the
+// call when the field is empty.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The request.
+//
+// Returns:
+//   - string: The idempotency key, or an empty string if the call has none.
+func Key(ctx context.Context, req any) string {
+	if k, ok := req.(keyed); ok && k.GetIdempotencyKey() != "" {
+		return k.GetIdempotencyKey()
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// ignoredFields are the fields of a request left out of its fingerprint: the idempotency key, so retries sending the
+// key in the field and in the metadata match, and the single-use tokens a browser fetches again for every attempt.
+var ignoredFields = []protoreflect.Name{"idempotency_key", "captcha_token", "form_rendered_at", "honeypot"}
+
+// fingerprint hashes a request without its ignoredFields, so retries of a submission match.
+func fingerprint(req any) (string, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("request %T is not a protobuf message", req)
+	}
+
+	msg = proto.Clone(msg)
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	for _, name := range ignoredFields {
+		if fd := fields.ByName(name); fd != nil {
+			msg.ProtoReflect().Clear(fd)
+		}
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replay unmarshals a recorded response.
+func replay(b []byte) (any, error) {
+	packed := &anypb.Any{}
+	if err := proto.Unmarshal(b, packed); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read recorded response: %v", err)
+	}
+
+	resp, err := packed.UnmarshalNew()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read recorded response: %v", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,287 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStoreUnit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Unix(1700000000, 0)
+	memory := NewMemoryStore()
+	memory.(*memoryStore).now = func() time.Time { return now }
+
+	stores := map[string]struct {
+		store   Store
+		advance func(time.Duration)
+	}{
+		"memory": {memory, func(d time.Duration) { now = now.Add(d) }},
+		"redis":  {NewRedisStore(client, "test:"), mr.FastForward},
+	}
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pending := Record{Fingerprint: "abc"}
+			done := Record{Fingerprint: "abc", Response: []byte("response")}
+
+			_, ok, err := s.store.Reserve(ctx, "key", pending, time.Minute)
+			require.Empty(t, err)
+			assert.True(t, ok)
+
+			rec, ok, err := s.store.Reserve(ctx, "key", Record{Fingerprint: "def"}, time.Minute)
+			require.Empty(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, pending, rec)
+
+			require.Empty(t, s.store.Save(ctx, "key", done, time.Hour))
+			s.advance(30 * time.Minute)
+
+			rec, ok, err = s.store.Reserve(ctx, "key", pending, time.Minute)
+			require.Empty(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, done, rec)
+
+			s.advance(time.Hour)
+			_, ok, err = s.store.Reserve(ctx, "key", pending, time.Minute)
+			require.Empty(t, err)
+			assert.True(t, ok, "the key is free once the record expires")
+
+			require.Empty(t, s.store.Release(ctx, "key"))
+			_, ok, err = s.store.Reserve(ctx, "key", pending, time.Minute)
+			require.Empty(t, err)
+			assert.True(t, ok, "the key is free once released")
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Reserve(context.Context, string, Record, time.Duration) (Record, bool, error) {
+	return Record{}, false, errors.New("connection refused")
+}
+
+func (failingStore) Save(context.Context, string, Record, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Release(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+type call struct {
+	md  metadata.MD
+	req *mailservice_v1.SendMailRequest
+	err error
+}
+
+func TestInterceptorUnit(t *testing.T) {
+	type input struct {
+		store Store
+		calls []call
+	}
+
+	type want struct {
+		sent      int
+		responses []string
+		code      codes.Code
+	}
+
+	submission := func(key string) *mailservice_v1.SendMailRequest {
+		return &mailservice_v1.SendMailRequest{Email: "jane@example.com", Message: "Hello", IdempotencyKey: key}
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"replays response to retries",
+			input{calls: []call{{req: submission("k1")}, {req: submission("k1")}, {req: submission("k1")}}},
+			want{sent: 1, responses: []string{"msg-1", "msg-1", "msg-1"}},
+		},
+		{
+			"reads key from metadata",
+			input{calls: []call{
+				{req: submission(""), md: metadata.Pairs(MetadataKey, "k1")},
+				{req: submission("k1")},
+			}},
+			want{sent: 1, responses: []string{"msg-1", "msg-1"}},
+		},
+		{
+			"keeps keys apart",
+			input{calls: []call{{req: submission("k1")}, {req: submission("k2")}}},
+			want{sent: 2, responses: []string{"msg-1", "msg-2"}},
+		},
+		{
+			"sends calls without key",
+			input{calls: []call{{req: submission("")}, {req: submission("")}}},
+			want{sent: 2, responses: []string{"msg-1", "msg-2"}},
+		},
+		{
+			"runs retries of failed calls",
+			input{calls: []call{{req: submission("k1"), err: status.Error(codes.Unavailable, "down")}, {req: submission("k1")}}},
+			want{sent: 2, responses: []string{"", "msg-2"}},
+		},
+		{
+			"replays response to retries with fresh tokens",
+			input{calls: []call{
+				{req: &mailservice_v1.SendMailRequest{Email: "jane@example.com", Message: "Hello", IdempotencyKey: "k1", CaptchaToken: "c1", FormRenderedAt: "t1"}},
+				{req: &mailservice_v1.SendMailRequest{Email: "jane@example.com", Message: "Hello", IdempotencyKey: "k1", CaptchaToken: "c2", FormRenderedAt: "t2"}},
+			}},
+			want{sent: 1, responses: []string{"msg-1", "msg-1"}},
+		},
+		{
+			"rejects key reused for another request",
+			input{calls: []call{
+				{req: submission("k1")},
+				{req: &mailservice_v1.SendMailRequest{Email: "john@example.com", Message: "Hi", IdempotencyKey: "k1"}},
+			}},
+			want{sent: 1, responses: []string{"msg-1"}, code: codes.InvalidArgument},
+		},
+		{
+			"rejects key that is too long",
+			input{calls: []call{{req: submission(strings.Repeat("k", 256))}}},
+			want{code: codes.InvalidArgument},
+		},
+		{
+			"sends calls when the store fails",
+			input{store: failingStore{}, calls: []call{{req: submission("k1")}, {req: submission("k1")}}},
+			want{sent: 2, responses: []string{"msg-1", "msg-2"}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.input.store
+			if store == nil {
+				store = NewMemoryStore()
+			}
+
+			intercept := Interceptor(Config{
+				Store:   store,
+				Window:  time.Hour,
+				Methods: []string{"/mailservice.MailService/SendMail"},
+				Logger:  zap.NewNop(),
+			})
+			info := &grpc.UnaryServerInfo{FullMethod: "/mailservice.MailService/SendMail"}
+
+			var (
+				sent      int
+				responses []string
+				err       error
+			)
+			for _, c := range tt.input.calls {
+				handler := func(context.Context, any) (any, error) {
+					sent++
+					if c.err != nil {
+						return nil, c.err
+					}
+					return &mailservice_v1.SendMailResponse{MessageId: "msg-" + strconv.Itoa(sent)}, nil
+				}
+
+				ctx := metadata.NewIncomingContext(context.Background(), c.md)
+
+				var resp any
+				if resp, err = intercept(ctx, c.req, info, handler); err != nil && status.Code(err) != codes.Unavailable {
+					break
+				}
+
+				r, _ := resp.(*mailservice_v1.SendMailResponse)
+				responses = append(responses, r.GetMessageId())
+			}
+
+			assert.Equal(t, tt.want.sent, sent)
+			assert.Equal(t, tt.want.responses, responses)
+			if tt.want.code != codes.OK {
+				assert.Equal(t, tt.want.code, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestInterceptorConcurrentRetriesUnit(t *testing.T) {
+	intercept := Interceptor(Config{
+		Store:   NewMemoryStore(),
+		Window:  time.Hour,
+		Methods: []string{"/mailservice.MailService/SendMail"},
+		Logger:  zap.NewNop(),
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: "/mailservice.MailService/SendMail"}
+
+	var sent atomic.Int32
+	release := make(chan struct{})
+	handler := func(context.Context, any) (any, error) {
+		sent.Add(1)
+		<-release
+		return &mailservice_v1.SendMailResponse{MessageId: "msg-1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	responses := make([]any, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := &mailservice_v1.SendMailRequest{Email: "jane@example.com", IdempotencyKey: "k1"}
+			resp, err := intercept(context.Background(), req, info, handler)
+			assert.Empty(t, err)
+			responses[i] = resp
+		}()
+	}
+
+	time.Sleep(3 * pollInterval)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), sent.Load())
+	for _, resp := range responses {
+		assert.True(t, proto.Equal(&mailservice_v1.SendMailResponse{MessageId: "msg-1"}, resp.(proto.Message)))
+	}
+}
+
+func TestInterceptorSkipsOtherMethodsUnit(t *testing.T) {
+	intercept := Interceptor(Config{
+		Store:   NewMemoryStore(),
+		Window:  time.Hour,
+		Methods: []string{"/mailservice.MailService/SendMail"},
+		Logger:  zap.NewNop(),
+	})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "k1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/mailservice.MailService/GetMessageStatus"}
+
+	var calls int
+	handler := func(context.Context, any) (any, error) {
+		calls++
+		return &mailservice_v1.MessageStatus{}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := intercept(ctx, &mailservice_v1.GetMessageStatusRequest{}, info, handler)
+		assert.Empty(t, err)
+	}
+	assert.Equal(t, 3, calls)
+}
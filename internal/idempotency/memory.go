@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the keys that have expired.
+const sweepInterval = time.Minute
+
+// memoryStore holds the idempotency keys in process. It only deduplicates retries reaching the same replica.
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type entry struct {
+	rec     Record
+	expires time.Time
+}
+
+// NewMemoryStore creates a Store holding the idempotency keys in process.
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]entry),
+		now:     time.Now,
+	}
+}
+
+// Reserve stores the record under the key if the key is free.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key, e.g. "/mailservice.MailService/SendMail:6f1c...".
+//   - rec: The Record to store.
+//   - ttl: How long the record is kept.
+//
+// Returns:
+//   - Record: The record stored under the key, when it was taken.
+//   - bool: Whether the key was free.
+//   - error: Always nil.
+func (s *memoryStore) Reserve(_ context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+
+	s.entries[key] = entry{rec: rec, expires: now.Add(ttl)}
+	return Record{}, true, nil
+}
+
+// Save stores the record under the key, replacing the reservation.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key.
+//   - rec: The Record to store.
+//   - ttl: How long the record is kept.
+//
+// Returns:
+//   - error: Always nil.
+func (s *memoryStore) Save(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = entry{rec: rec, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release removes the key.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key.
+//
+// Returns:
+//   - error: Always nil.
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops the keys that have expired.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveScript stores a record under a key unless the key is taken, in which case it returns the record stored
+// under it. It runs atomically, so concurrent replicas cannot both reserve a key.
+var reserveScript = redis.NewScript(`
+local existing = redis.call("GET", KEYS[1])
+if existing then
+  return existing
+end
+
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return false
+`)
+
+// redisStore holds the idempotency keys in Redis, so retries are deduplicated across replicas.
+type redisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore creates a Store holding the idempotency keys in Redis.
+//
+// Parameters:
+//   - client: The Redis client, e.g. a *redis.Client.
+//   - prefix: The prefix of the keys, e.g. "mail-service:idempotency:".
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewRedisStore(client redis.Cmdable, prefix string) Store {
+	return &redisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Reserve stores the record under the key if the key is free.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key, e.g. "/mailservice.MailService/SendMail:6f1c...".
+//   - rec: The Record to store.
+//   - ttl: How long the record is kept.
+//
+// Returns:
+//   - Record: The record stored under the key, when it was taken.
+//   - bool: Whether the key was free.
+//   - error: An error if Redis could not be reached or the stored record could not be read.
+func (s *redisStore) Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to marshal record of %q: %w", key, err)
+	}
+
+	existing, err := reserveScript.Run(ctx, s.client, []string{s.prefix + key}, b, max(ttl.Milliseconds(), 1)).Text()
+	if errors.Is(err, redis.Nil) {
+		return Record{}, true, nil
+	}
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to reserve %q: %w", key, err)
+	}
+
+	var stored Record
+	if err := json.Unmarshal([]byte(existing), &stored); err != nil {
+		return Record{}, false, fmt.Errorf("failed to unmarshal record of %q: %w", key, err)
+	}
+
+	return stored, false, nil
+}
+
+// Save stores the record under the key, replacing the reservation.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key.
+//   - rec: The Record to store.
+//   - ttl: How long the record is kept.
+//
+// Returns:
+//   - error: An error if Redis could not be reached.
+func (s *redisStore) Save(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record of %q: %w", key, err)
+	}
+
+	if err := s.client.Set(ctx, s.prefix+key, b, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// Release removes the key.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key.
+//
+// Returns:
+//   - error: An error if Redis could not be reached.
+func (s *redisStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to release %q: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AttachmentLimits holds the limits enforced on the attachments of a submission.
+//
+// Fields:
+//   - MaxCount: The maximum number of attachments. Attachments are rejected when zero.
+//   - MaxFileSize: The maximum size of a single attachment in bytes.
+//   - MaxTotalSize: The maximum combined size of all attachments in bytes.
+type AttachmentLimits struct {
+	MaxCount     int
+	MaxFileSize  int64
+	MaxTotalSize int64
+}
+
+// attachments validates the attachments of a submission against the limits of the form and converts them for the transport.
+// Attachments without a content type get one detected from their file name or, failing that, their content.
+//
+// Parameters:
+//   - in: The attachments of the submission.
+//
+// Returns:
+//   - []transport.Attachment: The attachments ready to be sent.
+//   - error: An InvalidArgument error if an attachment is empty, too large or has an invalid content type, or there are too many.
+func (f *form) attachments(in []*mailservice_v1.Attachment) ([]transport.Attachment, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	if len(in) > f.attachmentLimits.MaxCount {
+		return nil, status.Errorf(codes.InvalidArgument, "too many attachments: at most %d are allowed", f.attachmentLimits.MaxCount)
+	}
+
+	var (
+		out   = make([]transport.Attachment, 0, len(in))
+		total int64
+	)
+	for i, a := range in {
+		if a.Filename == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "attachments[%d].filename is required", i)
+		}
+
+		size := int64(len(a.Content))
+		if size == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "attachment %q is empty", a.Filename)
+		}
+
+		if size > f.attachmentLimits.MaxFileSize {
+			return nil, status.Errorf(codes.InvalidArgument, "attachment %q is %s, larger than the limit of %s", a.Filename, formatSize(size), formatSize(f.attachmentLimits.MaxFileSize))
+		}
+
+		total += size
+		if total > f.attachmentLimits.MaxTotalSize {
+			return nil, status.Errorf(codes.InvalidArgument, "attachments exceed the total size limit of %s", formatSize(f.attachmentLimits.MaxTotalSize))
+		}
+
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = detectContentType(a.Filename, a.Content)
+		} else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "attachment %q has an invalid content type: %v", a.Filename, err)
+		}
+
+		out = append(out, transport.Attachment{
+			Filename:    a.Filename,
+			ContentType: contentType,
+			Data:        a.Content,
+		})
+	}
+
+	return out, nil
+}
+
+func detectContentType(filename string, content []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(filename)); t != "" {
+		return t
+	}
+
+	return http.DetectContentType(content)
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	switch {
+	case n >= unit*unit:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(unit*unit))
+	case n >= unit:
+		return fmt.Sprintf("%.1f KiB", float64(n)/unit)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,182 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAttachmentsUnit(t *testing.T) {
+	limits := AttachmentLimits{MaxCount: 2, MaxFileSize: 10, MaxTotalSize: 15}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		attachments  []transport.Attachment
+	}
+
+	cases := []struct {
+		name  string
+		input []*mailservice_v1.Attachment
+		want  want
+	}{
+		{
+			"accepts no attachments",
+			nil,
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"detects content types",
+			[]*mailservice_v1.Attachment{
+				{Filename: "notes.txt", Content: []byte("notes")},
+				{Filename: "image", Content: []byte("\x89PNG\r\n\x1a\n")},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				attachments: []transport.Attachment{
+					{Filename: "notes.txt", ContentType: "text/plain; charset=utf-8", Data: []byte("notes")},
+					{Filename: "image", ContentType: "image/png", Data: []byte("\x89PNG\r\n\x1a\n")},
+				},
+			},
+		},
+		{
+			"keeps content type",
+			[]*mailservice_v1.Attachment{{Filename: "resume.pdf", ContentType: "application/pdf", Content: []byte("%PDF")}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				attachments: []transport.Attachment{{Filename: "resume.pdf", ContentType: "application/pdf", Data: []byte("%PDF")}},
+			},
+		},
+		{
+			"handles too many attachments",
+			[]*mailservice_v1.Attachment{
+				{Filename: "a", Content: []byte("a")},
+				{Filename: "b", Content: []byte("b")},
+				{Filename: "c", Content: []byte("c")},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "too many attachments")
+				},
+			},
+		},
+		{
+			"handles missing filename",
+			[]*mailservice_v1.Attachment{{Content: []byte("a")}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "attachments[0].filename is required")
+				},
+			},
+		},
+		{
+			"handles empty attachment",
+			[]*mailservice_v1.Attachment{{Filename: "a"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "is empty")
+				},
+			},
+		},
+		{
+			"handles attachment over the file size limit",
+			[]*mailservice_v1.Attachment{{Filename: "a", Content: bytes.Repeat([]byte("a"), 11)}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), `attachment "a" is 11 B, larger than the limit of 10 B`)
+				},
+			},
+		},
+		{
+			"handles attachments over the total size limit",
+			[]*mailservice_v1.Attachment{
+				{Filename: "a", Content: bytes.Repeat([]byte("a"), 10)},
+				{Filename: "b", Content: bytes.Repeat([]byte("b"), 10)},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "total size limit of 15 B")
+				},
+			},
+		},
+		{
+			"handles invalid content type",
+			[]*mailservice_v1.Attachment{{Filename: "a", ContentType: "not a type", Content: []byte("a")}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "invalid content type")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&form{attachmentLimits: limits}).attachments(tt.input)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.attachments, got)
+		})
+	}
+}
+
+func TestSendMailAttachmentsUnit(t *testing.T) {
+	thankYou, forward := loadTemplates(t)
+
+	outbox := &mockQueue{}
+	o := orchestrator{
+		outbox: outbox,
+		forms: map[string]*form{
+			DefaultFormID: {
+				forwardEmails:    []string{"owner@example.com"},
+				fromEmail:        "noreply@example.com",
+				attachmentLimits: AttachmentLimits{MaxCount: 1, MaxFileSize: 1 << 10, MaxTotalSize: 1 << 10},
+				thankYou:         thankYou,
+				forward:          forward,
+			},
+		},
+		defaultForm: DefaultFormID,
+		logger:      zap.NewNop(),
+	}
+
+	_, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+		Email:       "jane@example.com",
+		Message:     "See attached",
+		Attachments: []*mailservice_v1.Attachment{{Filename: "resume.pdf", Content: []byte("%PDF")}},
+	})
+	require.Empty(t, err)
+	require.Len(t, outbox.messages, 1)
+	assert.Equal(t, []transport.Attachment{{Filename: "resume.pdf", ContentType: "application/pdf", Data: []byte("%PDF")}}, outbox.messages[0].Attachments)
+
+	_, err = o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+		Email:       "jane@example.com",
+		Message:     "See attached",
+		Attachments: []*mailservice_v1.Attachment{{Filename: "a.pdf", Content: []byte("a")}, {Filename: "b.pdf", Content: []byte("b")}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Len(t, outbox.messages, 1)
+}
@@ -0,0 +1,124 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"go.uber.org/zap"
+)
+
+// AutoReplyConfig holds the configuration for the thank-you auto-reply sent to the submitter of a message.
+//
+// Fields:
+//   - Enabled: Whether the auto-reply is sent.
+//   - Limit: The maximum number of auto-replies a single address receives within Window. Unlimited when zero.
+//   - Window: The period Limit applies to.
+type AutoReplyConfig struct {
+	Enabled bool
+	Limit   int
+	Window  time.Duration
+}
+
+// replyLimiter limits how many auto-replies a single address receives within a sliding window,
+// so the contact form cannot be used to flood a third party's inbox.
+type replyLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	sent map[string][]time.Time
+}
+
+func newReplyLimiter(limit int, window time.Duration) *replyLimiter {
+	return &replyLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		sent:   map[string][]time.Time{},
+	}
+}
+
+// allow reports whether another auto-reply may be sent to the address, and records it if so.
+func (l *replyLimiter) allow(address string) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+	key := strings.ToLower(address)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.sent[key][:0]
+	for _, t := range l.sent[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.sent[key] = recent
+		return false
+	}
+
+	l.sent[key] = append(recent, now)
+
+	// Drop addresses that have not been replied to within the window to keep the map bounded.
+	for k, times := range l.sent {
+		if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+			delete(l.sent, k)
+		}
+	}
+
+	return true
+}
+
+// sendAutoReply queues the thank-you email to the submitter of the request. It never returns an error:
+// failures are logged and reported through the returned status, so they never fail the forward delivery.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - f: The form the request was made from.
+//   - req: The SendMailRequest object containing the submitter's name and email address.
+//
+// Returns:
+//   - mailservice_v1.AutoReplyStatus: What happened to the auto-reply.
+//   - string: The ID of the queued auto-reply, if any.
+func (o orchestrator) sendAutoReply(ctx context.Context, f *form, req *mailservice_v1.SendMailRequest) (mailservice_v1.AutoReplyStatus, string) {
+	if !f.autoReply.Enabled {
+		return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED, ""
+	}
+
+	// The address was validated and normalized with the rest of the submission.
+	to := o.deliverable([]string{req.Email})
+	if len(to) == 0 {
+		o.logger.Info("Thank you email not queued, the submitter address is suppressed", zap.String("to", req.Email))
+		return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_SUPPRESSED, ""
+	}
+
+	if !f.replyLimiter.allow(req.Email) {
+		o.logger.Info("Thank you email not queued, rate limit reached", zap.String("to", req.Email))
+		return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_RATE_LIMITED, ""
+	}
+
+	thankYou, err := f.newMessage(f.thankYou, to, constructThankYouTemplateData(req, f.schema))
+	if err != nil {
+		o.logger.Error("Failed to prepare thank you email", zap.Error(err))
+		return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_FAILED, ""
+	}
+
+	id, err := o.outbox.Enqueue(ctx, thankYou)
+	if err != nil {
+		o.logger.Error("Failed to queue thank you email", zap.Error(err), zap.String("to", req.Email))
+		return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_FAILED, ""
+	}
+
+	o.logger.Info("Thank you email queued", zap.String("to", req.Email), zap.String("message_id", id))
+	return mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED, id
+}
@@ -0,0 +1,226 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/render"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSendMailFieldsUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{
+		Sources: map[string]templates.Source{
+			templates.Forward: {
+				Subject: "Inquiry from {{fields.company}}",
+				Text:    base64.StdEncoding.EncodeToString([]byte("{{#each field_list}}{{label}}={{value}};{{/each}}")),
+			},
+		},
+	})
+	require.Empty(t, err)
+
+	local, err := templates.Load(templates.Config{
+		Sources: map[string]templates.Source{
+			templates.Forward: {
+				Subject: "Inquiry from {{fields.company}}",
+				Text:    base64.StdEncoding.EncodeToString([]byte("{{range .field_list}}{{label}}={{value}};{{end}}{{fields.phone}}")),
+			},
+		},
+	})
+	require.Empty(t, err)
+
+	engine, err := render.New(render.Config{Templates: local})
+	require.Empty(t, err)
+
+	declared := []fields.Field{
+		{Name: "company", Label: "Company", Required: true, MaxLength: 50},
+		{Name: "budget", Label: "Budget", Type: fields.TypeNumber},
+		{Name: "phone", Pattern: `^\+?[0-9 ]+$`},
+	}
+
+	type input struct {
+		fields   map[string]any
+		renderer renderer
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		violations   []string
+		subject      string
+		text         string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"forwards fields to ses templates",
+			input{fields: map[string]any{"company": "Acme", "budget": "5000"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				subject: "Inquiry from Acme",
+				text:    "Company=Acme;Budget=5000;",
+			},
+		},
+		{
+			"forwards fields to the local engine",
+			input{fields: map[string]any{"budget": 5000.0, "company": "Acme"}, renderer: engine},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				subject: "Inquiry from Acme",
+				text:    "Company=Acme;Budget=5000;",
+			},
+		},
+		{
+			"handles invalid fields",
+			input{fields: map[string]any{"budget": "a lot", "phone": "call me", "fax": "123"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+				violations: []string{
+					"fields.company: company is required",
+					"fields.budget: budget must be a number",
+					"fields.phone: phone has an invalid format",
+					"fields.fax: unknown field",
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &mockQueue{}
+			o, err := New(context.Background(), Config{
+				Outbox: outbox,
+				Forms: []FormConfig{{
+					ID:            "shop",
+					ForwardEmails: []string{"owner@example.com"},
+					FromEmail:     "noreply@example.com",
+					Templates:     all,
+					Renderer:      tt.input.renderer,
+					Fields:        declared,
+				}},
+				DefaultForm: "shop",
+				Logger:      zap.NewNop(),
+			})
+			require.Empty(t, err)
+
+			values, err := structpb.NewStruct(tt.input.fields)
+			require.Empty(t, err)
+
+			_, err = o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+				Email:   "jane@example.com",
+				Message: "Hello there",
+				Fields:  values.Fields,
+			})
+			tt.want.errAssertion(t, err)
+
+			var violations []string
+			for _, d := range status.Convert(err).Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.FieldViolations {
+						violations = append(violations, v.Field+": "+v.Description)
+					}
+				}
+			}
+			assert.Equal(t, tt.want.violations, violations)
+			if err != nil {
+				assert.Empty(t, outbox.messages)
+				return
+			}
+
+			require.Len(t, outbox.messages, 1)
+			forwarded := outbox.messages[0]
+			assert.Equal(t, tt.want.subject, forwarded.Subject)
+			assert.Equal(t, tt.want.text, forwarded.Text)
+			if tt.input.renderer != nil {
+				return
+			}
+
+			var data struct {
+				Fields    map[string]string   `json:"fields"`
+				FieldList []map[string]string `json:"field_list"`
+			}
+			require.Empty(t, json.Unmarshal([]byte(forwarded.Template.Data), &data))
+			assert.Equal(t, map[string]string{"company": "Acme", "budget": "5000", "phone": ""}, data.Fields)
+			assert.Equal(t, []map[string]string{
+				{"name": "company", "label": "Company", "value": "Acme"},
+				{"name": "budget", "label": "Budget", "value": "5000"},
+			}, data.FieldList)
+		})
+	}
+}
+
+func TestSendMailDefaultForwardFieldsUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	engine, err := render.New(render.Config{Templates: all})
+	require.Empty(t, err)
+
+	cases := []struct {
+		name     string
+		renderer renderer
+	}{
+		{"renders fields in the copy of the ses template", nil},
+		{"renders fields with the local engine", engine},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &mockQueue{}
+			o, err := New(context.Background(), Config{
+				Outbox: outbox,
+				Forms: []FormConfig{{
+					ID:            "shop",
+					ForwardEmails: []string{"owner@example.com"},
+					FromEmail:     "noreply@example.com",
+					Templates:     all,
+					Renderer:      tt.renderer,
+					Fields:        []fields.Field{{Name: "company", Label: "Company"}, {Name: "budget", Label: "Budget", Type: fields.TypeNumber}},
+				}},
+				DefaultForm: "shop",
+				Logger:      zap.NewNop(),
+			})
+			require.Empty(t, err)
+
+			values, err := structpb.NewStruct(map[string]any{"company": "Acme & Co", "budget": 5000.0})
+			require.Empty(t, err)
+
+			subject := "Project"
+			_, err = o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+				Name:    "Jane Doe",
+				Email:   "jane@example.com",
+				Subject: &subject,
+				Message: "Hello there",
+				Fields:  values.Fields,
+			})
+			require.Empty(t, err)
+			require.Len(t, outbox.messages, 1)
+
+			forwarded := outbox.messages[0]
+			assert.Equal(t, "Name: Jane Doe\nFrom: jane@example.com\nSubject: Project\nCompany: Acme & Co\nBudget: 5000\n\nHello there", forwarded.Text)
+			assert.Contains(t, forwarded.HTML, "<p><strong>Company:</strong> Acme &amp; Co</p>")
+			assert.Contains(t, forwarded.HTML, "<p><strong>Budget:</strong> 5000</p>")
+			assert.Contains(t, forwarded.HTML, "<p><strong>Subject:</strong> Project</p>")
+		})
+	}
+}
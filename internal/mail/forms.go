@@ -0,0 +1,164 @@
+package mail
+
+import (
+	"fmt"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/routing"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultFormID is the ID of the form built from the top-level Config fields when no forms are configured.
+const DefaultFormID = "default"
+
+// FormConfig holds the settings of a single contact form.
+//
+// Fields:
+//   - ID: The ID submissions select the form with.
+//   - ForwardEmails: The email addresses to which submissions are forwarded.
+//   - CcEmails: The email addresses submissions are copied to.
+//   - BccEmails: The email addresses submissions are blind copied to.
+//   - Routes: The routing.Rule list picking other recipients for the submissions they match. The first matching rule wins, and submissions matching none go to ForwardEmails, CcEmails and BccEmails.
+//   - FromEmail: The email address from which emails are sent.
+//   - Origins: The origins the form may be submitted from, checked against the Origin header of the request. The form may be submitted from anywhere when empty.
+//   - AutoReply: The AutoReplyConfig controlling the thank you email sent to the original sender.
+//   - Templates: The email templates loaded by templates.Load, keyed by template key. Their names must not be shared with other forms.
+//   - Attachments: The AttachmentLimits enforced on the files uploaded with a submission.
+//   - Renderer: Renders the emails of the form in process. Leave nil to render the templates with the AWS SES template engine.
+//   - Fields: The fields.Field declarations of the custom fields of the form. Submissions with custom fields are rejected when empty.
+//   - Site: The name of the site the form belongs to, available to the forward subject as {{site}}.
+//   - ForwardSubject: The pattern of the forward email's subject line, e.g. "[{{site}}] {{subject}} - {{name}}". The subject of the forward template is used when empty.
+type FormConfig struct {
+	ID             string
+	ForwardEmails  []string
+	CcEmails       []string
+	BccEmails      []string
+	Routes         []routing.Rule
+	FromEmail      string
+	Origins        []string
+	AutoReply      AutoReplyConfig
+	Templates      map[string]templates.Template
+	Attachments    AttachmentLimits
+	Renderer       renderer
+	Fields         []fields.Field
+	Site           string
+	ForwardSubject string
+}
+
+// form holds the resolved settings of a single contact form.
+type form struct {
+	id               string
+	forwardEmails    []string
+	ccEmails         []string
+	bccEmails        []string
+	router           *routing.Router
+	fromEmail        string
+	origins          []string
+	autoReply        AutoReplyConfig
+	replyLimiter     *replyLimiter
+	attachmentLimits AttachmentLimits
+	thankYou         emailTemplate
+	forward          emailTemplate
+	renderer         renderer
+	schema           *fields.Schema
+	site             string
+	forwardSubject   string
+}
+
+// newForm resolves the templates of a form and sets up its auto-reply rate limit.
+//
+// Parameters:
+//   - cfg: The FormConfig object containing the settings of the form.
+//
+// Returns:
+//   - *form: The form, ready to handle submissions.
+//   - error: An error if a template is missing, or a routing rule or field declaration is invalid.
+func newForm(cfg FormConfig) (*form, error) {
+	thankYou, err := lookupTemplate(cfg.Templates, templates.ThankYou)
+	if err != nil {
+		return nil, fmt.Errorf("form %q: %w", cfg.ID, err)
+	}
+
+	forward, err := lookupTemplate(cfg.Templates, templates.Forward)
+	if err != nil {
+		return nil, fmt.Errorf("form %q: %w", cfg.ID, err)
+	}
+
+	router, err := routing.New(cfg.Routes...)
+	if err != nil {
+		return nil, fmt.Errorf("form %q: %w", cfg.ID, err)
+	}
+
+	schema, err := fields.New(cfg.Fields...)
+	if err != nil {
+		return nil, fmt.Errorf("form %q: %w", cfg.ID, err)
+	}
+
+	return &form{
+		id:               cfg.ID,
+		forwardEmails:    cfg.ForwardEmails,
+		ccEmails:         cfg.CcEmails,
+		bccEmails:        cfg.BccEmails,
+		router:           router,
+		fromEmail:        cfg.FromEmail,
+		origins:          cfg.Origins,
+		autoReply:        cfg.AutoReply,
+		replyLimiter:     newReplyLimiter(cfg.AutoReply.Limit, cfg.AutoReply.Window),
+		attachmentLimits: cfg.Attachments,
+		thankYou:         thankYou,
+		forward:          forward,
+		renderer:         cfg.Renderer,
+		schema:           schema,
+		site:             cfg.Site,
+		forwardSubject:   cfg.ForwardSubject,
+	}, nil
+}
+
+// resolveForm returns the form a submission was made from. Submissions that do not select a form use the default form.
+//
+// Parameters:
+//   - id: The form ID of the submission.
+//
+// Returns:
+//   - *form: The form.
+//   - error: An InvalidArgument error if the form does not exist, or no form is selected and there is no default.
+func (o orchestrator) resolveForm(id string) (*form, error) {
+	if id == "" {
+		id = o.defaultForm
+	}
+
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "form_id is required")
+	}
+
+	f, ok := o.forms[id]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown form %q", id)
+	}
+
+	return f, nil
+}
+
+// recipients picks the recipients of a submission: those of the first routing rule it matches, or those of the form.
+//
+// Parameters:
+//   - req: The mailservice_v1.SendMailRequest object containing the submission.
+//
+// Returns:
+//   - routing.Recipients: The recipients of the forward email.
+//   - string: The name of the matched routing rule, empty when the submission matches none.
+func (f *form) recipients(req *mailservice_v1.SendMailRequest) (routing.Recipients, string) {
+	rule, ok := f.router.Route(routing.Submission{
+		Subject:    req.GetSubject(),
+		Department: req.Department,
+		Email:      req.Email,
+	})
+	if !ok {
+		return routing.Recipients{To: f.forwardEmails, Cc: f.ccEmails, Bcc: f.bccEmails}, ""
+	}
+
+	return rule.Recipients, rule.Name
+}
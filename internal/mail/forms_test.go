@@ -0,0 +1,313 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/routing"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestSendMailFormsUnit(t *testing.T) {
+	portfolio, err := templates.Load(templates.Config{NameSuffix: "_portfolio"})
+	require.Empty(t, err)
+
+	shop, err := templates.Load(templates.Config{
+		NameSuffix: "_shop",
+		Sources:    map[string]templates.Source{templates.Forward: {Subject: "New shop inquiry"}},
+	})
+	require.Empty(t, err)
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		from         string
+		to           []string
+		template     string
+		subject      string
+	}
+
+	cases := []struct {
+		name        string
+		defaultForm string
+		input       string
+		want        want
+	}{
+		{
+			"uses the default form",
+			"portfolio",
+			"",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				from:     "noreply@portfolio.example",
+				to:       []string{"me@portfolio.example"},
+				template: "ForwardTemplate_portfolio",
+				subject:  "You have an inquiry",
+			},
+		},
+		{
+			"uses the selected form",
+			"portfolio",
+			"shop",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				from:     "noreply@shop.example",
+				to:       []string{"sales@shop.example", "support@shop.example"},
+				template: "ForwardTemplate_shop",
+				subject:  "New shop inquiry",
+			},
+		},
+		{
+			"handles unknown form",
+			"portfolio",
+			"blog",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), `unknown form "blog"`)
+				},
+			},
+		},
+		{
+			"handles missing form without a default",
+			"",
+			"",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "form_id is required")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &mockQueue{}
+			o, err := New(context.Background(), Config{
+				Outbox: outbox,
+				Forms: []FormConfig{
+					{
+						ID:            "portfolio",
+						ForwardEmails: []string{"me@portfolio.example"},
+						FromEmail:     "noreply@portfolio.example",
+						Templates:     portfolio,
+					},
+					{
+						ID:            "shop",
+						ForwardEmails: []string{"sales@shop.example", "support@shop.example"},
+						FromEmail:     "noreply@shop.example",
+						Templates:     shop,
+					},
+				},
+				DefaultForm: tt.defaultForm,
+				Logger:      zap.NewNop(),
+			})
+			require.Empty(t, err)
+
+			_, err = o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+				Email:   "jane@example.com",
+				Message: "Hello",
+				FormId:  tt.input,
+			})
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				assert.Empty(t, outbox.messages)
+				return
+			}
+
+			require.Len(t, outbox.messages, 1)
+			assert.Equal(t, tt.want.from, outbox.messages[0].From)
+			assert.Equal(t, tt.want.to, outbox.messages[0].To)
+			assert.Equal(t, tt.want.template, outbox.messages[0].Template.Name)
+			assert.Equal(t, tt.want.subject, outbox.messages[0].Subject)
+		})
+	}
+}
+
+func TestNewFormsUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	_, err = New(context.Background(), Config{
+		Forms:  []FormConfig{{ID: "a", Templates: all}, {ID: "a", Templates: all}},
+		Logger: zap.NewNop(),
+	})
+	assert.ErrorContains(t, err, `form "a" is defined twice`)
+
+	_, err = New(context.Background(), Config{
+		Forms:       []FormConfig{{ID: "a", Templates: all}},
+		DefaultForm: "b",
+		Logger:      zap.NewNop(),
+	})
+	assert.ErrorContains(t, err, `default form "b" does not exist`)
+
+	_, err = New(context.Background(), Config{
+		Forms:  []FormConfig{{ID: "a"}},
+		Logger: zap.NewNop(),
+	})
+	assert.ErrorContains(t, err, `form "a": missing thank_you template`)
+
+	_, err = New(context.Background(), Config{
+		Forms:  []FormConfig{{ID: "a", Templates: all, Routes: []routing.Rule{{Name: "sales"}}}},
+		Logger: zap.NewNop(),
+	})
+	assert.ErrorContains(t, err, `form "a": routing rule "sales" has no forward address`)
+
+	_, err = New(context.Background(), Config{
+		Forms:  []FormConfig{{ID: "a", Templates: all, Fields: []fields.Field{{Name: "company"}, {Name: "company"}}}},
+		Logger: zap.NewNop(),
+	})
+	assert.ErrorContains(t, err, `form "a": field "company" is declared twice`)
+}
+
+func TestSendMailRoutingUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	subject := func(s string) *string { return &s }
+
+	type want struct {
+		to  []string
+		cc  []string
+		bcc []string
+	}
+
+	cases := []struct {
+		name  string
+		input *mailservice_v1.SendMailRequest
+		want  want
+	}{
+		{
+			"uses the recipients of the form",
+			&mailservice_v1.SendMailRequest{Email: "jane@example.com", Subject: subject("Hello")},
+			want{to: []string{"owner@example.com"}, cc: []string{"team@example.com"}, bcc: []string{"archive@example.com"}},
+		},
+		{
+			"routes by subject keyword and leaves out suppressed recipients",
+			&mailservice_v1.SendMailRequest{Email: "jane@example.com", Subject: subject("Pricing for 10 seats")},
+			want{to: []string{"sales@example.com"}, cc: []string{"manager@example.com"}},
+		},
+		{
+			"routes by department",
+			&mailservice_v1.SendMailRequest{Email: "jane@example.com", Department: "Support"},
+			want{to: []string{"support@example.com"}, bcc: []string{"tickets@example.com"}},
+		},
+		{
+			"routes by email domain before later rules",
+			&mailservice_v1.SendMailRequest{Email: "jane@partner.example", Department: "support"},
+			want{to: []string{"partners@example.com"}},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			suppressions, err := suppression.New(suppression.Config{})
+			require.Empty(t, err)
+			_, err = suppressions.Add(context.Background(), suppression.Entry{Address: "lead@example.com"})
+			require.Empty(t, err)
+
+			outbox := &mockQueue{}
+			o, err := New(context.Background(), Config{
+				Outbox:       outbox,
+				Suppressions: suppressions,
+				Forms: []FormConfig{
+					{
+						ID:            "shop",
+						ForwardEmails: []string{"owner@example.com"},
+						CcEmails:      []string{"team@example.com"},
+						BccEmails:     []string{"archive@example.com"},
+						FromEmail:     "noreply@example.com",
+						Templates:     all,
+						Routes: []routing.Rule{
+							{
+								Name:       "partners",
+								Match:      routing.Match{EmailDomains: []string{"partner.example"}},
+								Recipients: routing.Recipients{To: []string{"partners@example.com"}},
+							},
+							{
+								Name:       "sales",
+								Match:      routing.Match{SubjectKeywords: []string{"pricing", "quote"}},
+								Recipients: routing.Recipients{To: []string{"sales@example.com"}, Cc: []string{"lead@example.com", "manager@example.com"}},
+							},
+							{
+								Name:       "support",
+								Match:      routing.Match{Departments: []string{"support"}},
+								Recipients: routing.Recipients{To: []string{"support@example.com"}, Bcc: []string{"tickets@example.com"}},
+							},
+						},
+					},
+				},
+				DefaultForm: "shop",
+				Logger:      zap.NewNop(),
+			})
+			require.Empty(t, err)
+
+			tt.input.Message = "Hello there"
+			_, err = o.SendMail(context.Background(), tt.input)
+			require.Empty(t, err)
+			require.Len(t, outbox.messages, 1)
+
+			assert.Equal(t, tt.want.to, outbox.messages[0].To)
+			assert.ElementsMatch(t, tt.want.cc, outbox.messages[0].Cc)
+			assert.ElementsMatch(t, tt.want.bcc, outbox.messages[0].Bcc)
+		})
+	}
+}
+
+func TestSendMailOriginUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	cases := []struct {
+		name  string
+		input metadata.MD
+		want  codes.Code
+	}{
+		{"accepts listed origin", metadata.Pairs("grpcgateway-origin", "https://www.example.com"), codes.OK},
+		{"accepts wildcard origin", metadata.Pairs("grpcgateway-origin", "https://EU.shop.example"), codes.OK},
+		{"accepts origin of native clients", metadata.Pairs("origin", "https://www.example.com"), codes.OK},
+		{"accepts requests without origin", metadata.MD{}, codes.OK},
+		{"rejects other origin", metadata.Pairs("grpcgateway-origin", "https://evil.example"), codes.PermissionDenied},
+		{"rejects lookalike origin", metadata.Pairs("grpcgateway-origin", "https://shop.example.evil.example"), codes.PermissionDenied},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &mockQueue{}
+			o, err := New(context.Background(), Config{
+				Outbox: outbox,
+				Forms: []FormConfig{{
+					ID:            "shop",
+					ForwardEmails: []string{"sales@shop.example"},
+					Origins:       []string{"https://www.example.com", "https://*.shop.example"},
+					Templates:     all,
+				}},
+				DefaultForm: "shop",
+				Logger:      zap.NewNop(),
+			})
+			require.Empty(t, err)
+
+			_, err = o.SendMail(metadata.NewIncomingContext(context.Background(), tt.input), &mailservice_v1.SendMailRequest{
+				Email:   "jane@example.com",
+				Message: "Hello",
+			})
+			assert.Equal(t, tt.want, status.Code(err))
+			if tt.want != codes.OK {
+				assert.Empty(t, outbox.messages)
+			}
+		})
+	}
+}
@@ -5,93 +5,213 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/render"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/brice-aldrich/mail-service/internal/transport"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// sesClient is an interface that defines the methods from the AWS SES client that are used by the Orchestrator.
+// sesClient is an interface that defines the methods from the AWS SES client that are used by the Orchestrator
+// to manage the email templates stored in AWS SES.
 type sesClient interface {
 	GetEmailTemplate(ctx context.Context, params *sesv2.GetEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailTemplateOutput, error)
 	CreateEmailTemplate(ctx context.Context, params *sesv2.CreateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.CreateEmailTemplateOutput, error)
 	UpdateEmailTemplate(ctx context.Context, params *sesv2.UpdateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.UpdateEmailTemplateOutput, error)
-	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
 }
 
-// Orchestrator defines the interface for sending emails.
-// It includes a single method, SendMail, which is responsible for sending an email based on the provided request.
+// Orchestrator defines the interface for sending emails and reporting on their delivery.
 //
 // Methods:
-//   - SendMail: Sends an email based on the provided request. It forwards the email to a predefined address and sends a thank you email to the original sender.
+//   - SendMail: Sends an email based on the provided request. It forwards the email to a predefined address and, when enabled, sends a thank you email to the original sender.
+//   - GetMessageStatus: Returns the delivery status of a message previously returned by SendMail.
+//   - ListMessages: Returns a page of messages known to the service, newest first.
+//   - AddSuppression: Adds an address to the suppression list.
+//   - RemoveSuppression: Removes an address from the suppression list.
+//   - ListSuppressions: Returns a page of suppressed addresses.
+//   - TrainSpamFilter: Trains the spam classifier with a message marked as spam or ham.
+//   - ListQuarantine: Returns a page of the submissions held back for review.
+//   - ReleaseQuarantined: Forwards a quarantined submission and trains the spam classifier with it as ham.
+//   - DiscardQuarantined: Deletes a quarantined submission and trains the spam classifier with it as spam.
+//   - DecoyResponse: Builds the response of a submission dropped as automated, looking like an accepted one.
 //
 // Parameters:
 //   - ctx: The context.Context object for the request.
-//   - req: The SendMailRequest object containing the email message and recipient information.
+//   - req: The request object for the method.
 //
 // Returns:
-//   - *mailservice_v1.SendMailResponse: The response object indicating the result of the send mail operation.
-//   - error: An error if any occurred during the preparation of template data or sending of emails.
+//   - The response object for the method.
+//   - error: An error if any occurred while handling the request.
 type Orchestrator interface {
 	SendMail(ctx context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error)
+	GetMessageStatus(ctx context.Context, req *mailservice_v1.GetMessageStatusRequest) (*mailservice_v1.MessageStatus, error)
+	ListMessages(ctx context.Context, req *mailservice_v1.ListMessagesRequest) (*mailservice_v1.ListMessagesResponse, error)
+	AddSuppression(ctx context.Context, req *mailservice_v1.AddSuppressionRequest) (*mailservice_v1.Suppression, error)
+	RemoveSuppression(ctx context.Context, req *mailservice_v1.RemoveSuppressionRequest) (*mailservice_v1.RemoveSuppressionResponse, error)
+	ListSuppressions(ctx context.Context, req *mailservice_v1.ListSuppressionsRequest) (*mailservice_v1.ListSuppressionsResponse, error)
+	TrainSpamFilter(ctx context.Context, req *mailservice_v1.TrainSpamFilterRequest) (*mailservice_v1.TrainSpamFilterResponse, error)
+	ListQuarantine(ctx context.Context, req *mailservice_v1.ListQuarantineRequest) (*mailservice_v1.ListQuarantineResponse, error)
+	ReleaseQuarantined(ctx context.Context, req *mailservice_v1.ReleaseQuarantinedRequest) (*mailservice_v1.ReleaseQuarantinedResponse, error)
+	DiscardQuarantined(ctx context.Context, req *mailservice_v1.DiscardQuarantinedRequest) (*mailservice_v1.DiscardQuarantinedResponse, error)
+	DecoyResponse(ctx context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error)
+}
+
+// renderer is an interface that defines the methods from the render.Engine that are used by the Orchestrator
+// to render emails in process.
+type renderer interface {
+	Render(key string, data any) (render.Content, error)
+}
+
+// queue is an interface that defines the methods from the outbox that are used by the Orchestrator to hand off emails
+// for delivery and to report on their delivery status.
+type queue interface {
+	Enqueue(ctx context.Context, msg *transport.Message) (string, error)
+	Get(id string) (outbox.Record, error)
+	List(q outbox.Query) ([]outbox.Record, string, error)
 }
 
 // Config holds the configuration required to initialize the Orchestrator.
-// It includes the SES client for sending emails, the forward email address, and the from email address.
+// It includes the outbox used for sending emails and the forms submissions are made from. When Forms is empty,
+// a single default form is built from the ForwardEmails, CcEmails, BccEmails, FromEmail, AutoReply, Templates, Attachments, Renderer, Site
+// and ForwardSubject fields.
 //
 // Fields:
-//   - SES: The sesv2.Client object used to interact with AWS SES for sending emails.
-//   - ForwardEmail: The email address to which incoming emails will be forwarded.
+//   - Outbox: The outbox.Outbox used to persist and asynchronously deliver emails.
+//   - SES: An optional sesv2.Client object used to store the email templates in AWS SES. Leave nil when not delivering through AWS SES.
+//   - Suppressions: The suppression.List checked before every email is queued.
+//   - ForwardEmails: The email addresses to which incoming emails will be forwarded.
+//   - CcEmails: The email addresses incoming emails are copied to.
+//   - BccEmails: The email addresses incoming emails are blind copied to.
 //   - FromEmail: The email address from which emails will be sent.
+//   - AutoReply: The AutoReplyConfig controlling the thank you email sent to the original sender.
+//   - Templates: The email templates loaded by templates.Load, keyed by template key.
+//   - Attachments: The AttachmentLimits enforced on the files uploaded with a submission.
+//   - Site: The name of the site the default form belongs to, available to the forward subject as {{site}}.
+//   - ForwardSubject: The pattern of the default form's forward subject line. The subject of the forward template is used when empty.
+//   - Forms: The FormConfig of every form submissions can select with their form ID.
+//   - DefaultForm: The ID of the form used by submissions that do not select one. Submissions must select a form when empty.
+//   - Spam: The spam.Filter screening submissions before they are forwarded. Submissions are not screened when nil.
+//   - Quarantine: The quarantine.Queue holding submissions back for review. Submissions to hold back are forwarded tagged when nil.
 //   - Logger: The zap.Logger object used for logging.
 type Config struct {
-	SES          sesClient
-	ForwardEmail string
-	FromEmail    string
-	Logger       *zap.Logger
+	Outbox        queue
+	SES           sesClient
+	Suppressions  suppressionList
+	ForwardEmails []string
+	CcEmails      []string
+	BccEmails     []string
+	FromEmail     string
+	AutoReply     AutoReplyConfig
+	Templates     map[string]templates.Template
+	Attachments   AttachmentLimits
+	// Renderer renders the emails in process. When set, emails are sent as rendered content and the templates
+	// are not stored in AWS SES. Leave nil to render the templates with the AWS SES template engine.
+	Renderer       renderer
+	Site           string
+	ForwardSubject string
+	Forms          []FormConfig
+	DefaultForm    string
+	Spam           spamFilter
+	Quarantine     quarantineQueue
+	// PreserveTemplates leaves templates that already exist in AWS SES untouched, so edits made through the
+	// TemplateService survive a restart. Only missing templates are created.
+	PreserveTemplates bool
+	Logger            *zap.Logger
 }
 
 type orchestrator struct {
+	outbox       queue
 	ses          sesClient
-	forwardEmail string
-	fromEmail    string
+	suppressions suppressionList
+	forms        map[string]*form
+	defaultForm  string
+	spam         spamFilter
+	quarantine   quarantineQueue
+	preserve     bool
 	logger       *zap.Logger
 }
 
 // New creates a new instance of the Orchestrator with the provided configuration.
-// It initializes the orchestrator with the SES client, forward email address, and from email address from the configuration.
-// It also initializes or updates the email templates in AWS SES.
+// It initializes the orchestrator with the outbox and the forms from the configuration.
+// When an SES client is provided, it also initializes or updates the email templates of the forms without a Renderer in AWS SES.
 //
 // Parameters:
 //   - ctx: The context.Context object for the request.
-//   - cfg: The Config object containing the SES client, forward email address, and from email address.
+//   - cfg: The Config object containing the outbox, SES client and forms.
 //
 // Returns:
 //   - Orchestrator: The newly created Orchestrator instance.
-//   - error: An error if any occurred during the initialization of the email templates.
+//   - error: An error if a template is missing, a form is defined twice, the default form does not exist or any occurred during the initialization of the email templates.
 func New(ctx context.Context, cfg Config) (Orchestrator, error) {
+	forms, defaultForm := cfg.Forms, cfg.DefaultForm
+	if len(forms) == 0 {
+		forms = []FormConfig{{
+			ID:             DefaultFormID,
+			ForwardEmails:  cfg.ForwardEmails,
+			CcEmails:       cfg.CcEmails,
+			BccEmails:      cfg.BccEmails,
+			FromEmail:      cfg.FromEmail,
+			AutoReply:      cfg.AutoReply,
+			Templates:      cfg.Templates,
+			Attachments:    cfg.Attachments,
+			Renderer:       cfg.Renderer,
+			Site:           cfg.Site,
+			ForwardSubject: cfg.ForwardSubject,
+		}}
+		defaultForm = DefaultFormID
+	}
+
 	o := &orchestrator{
+		outbox:       cfg.Outbox,
 		ses:          cfg.SES,
-		forwardEmail: cfg.ForwardEmail,
-		fromEmail:    cfg.FromEmail,
+		suppressions: cfg.Suppressions,
+		forms:        make(map[string]*form, len(forms)),
+		defaultForm:  defaultForm,
+		spam:         cfg.Spam,
+		quarantine:   cfg.Quarantine,
+		preserve:     cfg.PreserveTemplates,
 		logger:       cfg.Logger,
 	}
 
-	if err := o.initTemplates(ctx); err != nil {
-		return nil, err
+	for _, fc := range forms {
+		if _, ok := o.forms[fc.ID]; ok {
+			return nil, fmt.Errorf("form %q is defined twice", fc.ID)
+		}
+
+		f, err := newForm(fc)
+		if err != nil {
+			return nil, err
+		}
+		o.forms[fc.ID] = f
+	}
+
+	if _, ok := o.forms[defaultForm]; defaultForm != "" && !ok {
+		return nil, fmt.Errorf("default form %q does not exist", defaultForm)
+	}
+
+	if o.ses != nil {
+		if err := o.initTemplates(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	return o, nil
 }
 
-// initTemplates initializes or updates email templates in AWS SES based on the provided templates.
-// It iterates over a predefined list of templates and performs the following actions for each template:
+// initTemplates initializes or updates email templates in AWS SES based on the configured templates.
+// It iterates over the thank you and forward templates of every form and performs the following actions for each template:
 // 1. Checks if the template already exists in AWS SES.
 // 2. If the template does not exist, it creates the template in AWS SES.
-// 3. If the template exists, it updates the template in AWS SES.
+// 3. If the template exists, it updates the template in AWS SES, unless templates are preserved.
 //
 // Parameters:
 //   - ctx: The context.Context object for the request.
@@ -99,7 +219,21 @@ func New(ctx context.Context, cfg Config) (Orchestrator, error) {
 // Returns:
 //   - error: An error if any occurred during the initialization or updating of the email templates.
 func (o orchestrator) initTemplates(ctx context.Context) error {
-	for _, t := range templates {
+	ids := make([]string, 0, len(o.forms))
+	for id := range o.forms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var stored []emailTemplate
+	for _, id := range ids {
+		// Templates rendered in process are never referenced by name, so there is nothing to store in AWS SES.
+		if f := o.forms[id]; f.renderer == nil {
+			stored = append(stored, f.thankYou, f.forward)
+		}
+	}
+
+	for _, t := range stored {
 		_, err := o.ses.GetEmailTemplate(ctx, &sesv2.GetEmailTemplateInput{
 			TemplateName: &t.Name,
 		})
@@ -120,6 +254,10 @@ func (o orchestrator) initTemplates(ctx context.Context) error {
 			return fmt.Errorf("failed to initialize email template with aws ses: %w", err)
 		}
 
+		if o.preserve {
+			continue
+		}
+
 		_, err = o.ses.UpdateEmailTemplate(ctx, &sesv2.UpdateEmailTemplateInput{
 			TemplateName:    &t.Name,
 			TemplateContent: t.Content,
@@ -132,12 +270,19 @@ func (o orchestrator) initTemplates(ctx context.Context) error {
 	return nil
 }
 
-// SendMail sends an email based on the provided request. It performs two main actions:
-// 1. Forwards the email to a predefined address using a forward template.
-// 2. Sends a thank you email to the original sender using a thank you template.
+// SendMail sends an email based on the provided request. It resolves the form the request was made from and
+// performs two main actions with its settings:
+// 1. Forwards the email, along with its attachments, to the forward addresses of the form using its forward template.
+// 2. Sends a thank you email to the original sender using the thank you template of the form, when auto-replies are enabled.
 //
-// It first constructs the forward template data and sends the forward email.
-// Then, constructs the thank you template data and sends the thank you email.
+// It first constructs the forward template data and queues the forward email in the outbox.
+// Then, constructs the thank you template data and queues the thank you email. The thank you email is
+// best effort: it is skipped for suppressed or rate limited addresses, and a failure to queue it never
+// fails the request. The response reports what happened to it.
+// The emails are delivered asynchronously by the outbox, so a temporary provider outage never loses a submission.
+// Recipients on the suppression list are never mailed. Submissions from an origin the form does not allow are rejected.
+// The submitter's email address is validated and normalized first, and submissions with invalid fields are rejected
+// with an errdetails.BadRequest listing them.
 //
 // Parameters:
 //   - ctx: The context.Context object for the request.
@@ -147,78 +292,179 @@ func (o orchestrator) initTemplates(ctx context.Context) error {
 //   - *mailservice_v1.SendMailResponse: The response object indicating the result of the send mail operation.
 //   - error: An error if any occurred during the preparation of template data or sending of emails.
 func (o orchestrator) SendMail(ctx context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
-	forwardData, err := constructForwardTemplateData(req.Message, req.Email)
+	f, err := o.resolveForm(req.FormId)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to prepare forward template data: %v", err)
+		return nil, err
 	}
 
-	_, err = o.ses.SendEmail(ctx, &sesv2.SendEmailInput{
-		Content: &types.EmailContent{
-			Template: &types.Template{
-				TemplateName: &forwardName,
-				TemplateData: forwardData,
-			},
-		},
-		Destination: &types.Destination{
-			ToAddresses: []string{o.forwardEmail},
-		},
-		FromEmailAddress: &o.fromEmail,
-	})
+	if err := validateSubmission(req, f.schema); err != nil {
+		return nil, err
+	}
+
+	// Requests without an origin do not come from a browser, which is the only client the check protects against.
+	if origin := requestOrigin(ctx); origin != "" && !f.allowsOrigin(origin) {
+		o.logger.Warn("Submission rejected, the origin is not allowed", zap.String("form", f.id), zap.String("origin", origin))
+		return nil, status.Errorf(codes.PermissionDenied, "form %q cannot be submitted from %s", f.id, origin)
+	}
+
+	rcpt, route := f.recipients(req)
+	to := o.deliverable(rcpt.To)
+	if len(to) == 0 {
+		o.logger.Warn("Forward email not queued, the forward addresses are suppressed", zap.String("form", f.id), zap.String("route", route), zap.Strings("to", rcpt.To))
+		return nil, status.Error(codes.FailedPrecondition, "the forward address is on the suppression list")
+	}
+
+	attachments, err := f.attachments(req.Attachments)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to send forward email: %v", err)
-	}
-
-	o.logger.Info("Forward email sent", zap.String("to", o.forwardEmail))
-
-	// thankYouData, err := constructThankYouTemplateData(req.Message)
-	// if err != nil {
-	// 	return nil, status.Errorf(codes.Internal, "failed to prepare thank you template data: %v", err)
-	// }
-
-	// _, err = o.ses.SendEmail(ctx, &sesv2.SendEmailInput{
-	// 	Content: &types.EmailContent{
-	// 		Template: &types.Template{
-	// 			TemplateName: &thankYouTemplateName,
-	// 			TemplateData: thankYouData,
-	// 		},
-	// 	},
-	// 	Destination: &types.Destination{
-	// 		ToAddresses: []string{req.Email},
-	// 	},
-	// 	FromEmailAddress: &o.fromEmail,
-	// })
-	// if err != nil {
-	// 	return nil, status.Errorf(codes.Internal, "failed to send email thank you email: %v", err)
-	// }
-
-	return &mailservice_v1.SendMailResponse{}, nil
+		return nil, err
+	}
+
+	screened := o.screen(f, req)
+	if screened.Verdict == spam.VerdictDrop {
+		return o.decoy(f, req)
+	}
+
+	// The subject of a stored template cannot be replaced, so templates use the subject line as {{subject_line}}.
+	data := constructForwardTemplateData(req, f.schema)
+	subject := f.subjectLine(req, f.templateSubject(data))
+	data[subjectLineKey] = subject
+
+	forward, err := f.newMessage(f.forward, to, data)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to prepare forward email: %v", err)
+	}
+	forward.Cc = o.deliverable(rcpt.Cc)
+	forward.Bcc = o.deliverable(rcpt.Bcc)
+	forward.Attachments = attachments
+	forward.ReplyTo = []string{replyTo(req)}
+	if f.forwardSubject != "" {
+		forward.Subject = subject
+	}
+
+	switch {
+	case screened.Verdict == spam.VerdictQuarantine && o.quarantine != nil:
+		return o.hold(f, req, screened, forward)
+	case screened.Verdict == spam.VerdictTag || screened.Verdict == spam.VerdictQuarantine:
+		// Without a quarantine to hold them in, submissions that should be held for review are forwarded tagged.
+		tagSpam(forward)
+	}
+
+	forwardID, err := o.outbox.Enqueue(ctx, forward)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to queue forward email: %v", err)
+	}
+
+	o.logger.Info("Forward email queued", zap.String("form", f.id), zap.String("route", route), zap.Strings("to", to), zap.String("message_id", forwardID))
+
+	// Submissions that look like spam often carry forged addresses, which an auto-reply would send backscatter to.
+	autoReplyStatus, autoReplyID := mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED, ""
+	if screened.Verdict == spam.VerdictAccept {
+		autoReplyStatus, autoReplyID = o.sendAutoReply(ctx, f, req)
+	}
+
+	return &mailservice_v1.SendMailResponse{
+		MessageId:          forwardID,
+		AutoReplyStatus:    autoReplyStatus,
+		AutoReplyMessageId: autoReplyID,
+	}, nil
 }
 
-func constructForwardTemplateData(message string, from string) (*string, error) {
-	templateData := map[string]string{
-		"text": message,
-		"from": from,
+// newMessage builds a transport.Message for the given template of the form.
+// When the form has a renderer, the message only carries the template rendered in process. Otherwise the message
+// references the stored template for transports that render server side and carries a locally rendered copy of the
+// template for all other transports.
+//
+// Parameters:
+//   - t: The emailTemplate to render.
+//   - to: The recipients of the message.
+//   - data: The data used to render the template.
+//
+// Returns:
+//   - *transport.Message: The message ready to be delivered.
+//   - error: An error if the template could not be rendered or its data could not be encoded.
+func (f *form) newMessage(t emailTemplate, to []string, data map[string]any) (*transport.Message, error) {
+	if f.renderer != nil {
+		content, err := f.renderer.Render(t.Key, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+
+		return &transport.Message{
+			From:    f.fromEmail,
+			To:      to,
+			Subject: content.Subject,
+			HTML:    content.HTML,
+			Text:    content.Text,
+		}, nil
 	}
 
-	v, err := json.Marshal(&templateData)
+	v, err := json.Marshal(&data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal template data: %w", err)
 	}
 
-	templateDataString := string(v)
-	return &templateDataString, nil
+	subject, html, text := t.render(data)
+	return &transport.Message{
+		From:    f.fromEmail,
+		To:      to,
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+		Template: &transport.Template{
+			Name: t.Name,
+			Data: string(v),
+		},
+	}, nil
 }
 
-func constructThankYouTemplateData(name string) (*string, error) {
-	templateData := map[string]string{
+func constructForwardTemplateData(req *mailservice_v1.SendMailRequest, schema *fields.Schema) map[string]any {
+	return withFields(map[string]any{
+		"text":       req.Message,
+		"from":       req.Email,
+		"name":       req.Name,
+		"subject":    req.GetSubject(),
+		"department": req.Department,
+	}, req, schema)
+}
+
+func constructThankYouTemplateData(req *mailservice_v1.SendMailRequest, schema *fields.Schema) map[string]any {
+	name := req.Name
+	if name == "" {
+		name = "there"
+	}
+
+	return withFields(map[string]any{
 		"name": name,
+	}, req, schema)
+}
+
+// withFields adds the custom fields of a submission to the data of a template. "fields" holds the value of every
+// field the form declares, formatted as text and empty when not submitted, so templates can look them up by name, e.g.
+// {{fields.company}}. "field_list" holds the submitted fields in the order the form declares them, each with its
+// "name", "label" and "value", so templates can list them, e.g. {{#each field_list}}.
+//
+// Parameters:
+//   - data: The data of the template.
+//   - req: The mailservice_v1.SendMailRequest object containing the validated custom fields.
+//   - schema: The fields.Schema of the form.
+//
+// Returns:
+//   - map[string]any: The data, with the custom fields added.
+func withFields(data map[string]any, req *mailservice_v1.SendMailRequest, schema *fields.Schema) map[string]any {
+	values := map[string]string{}
+	for _, name := range schema.Names() {
+		values[name] = ""
 	}
 
-	v, err := json.Marshal(&templateData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal template data: %w", err)
+	entries := schema.Entries(req.Fields)
+	list := make([]map[string]string, 0, len(entries))
+	for _, e := range entries {
+		values[e.Name] = e.Value
+		list = append(list, map[string]string{"name": e.Name, "label": e.Label, "value": e.Value})
 	}
 
-	templateDataString := string(v)
-	return &templateDataString, nil
+	data["fields"] = values
+	data["field_list"] = list
+
+	return data
 }
@@ -3,20 +3,30 @@ package mail
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/render"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/brice-aldrich/mail-service/internal/transport"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestInitTemplatesUnit(t *testing.T) {
 	type input struct {
-		ses sesClient
+		ses      sesClient
+		preserve bool
 	}
 
 	type want struct {
@@ -72,6 +82,20 @@ func TestInitTemplatesUnit(t *testing.T) {
 				},
 			},
 		},
+		{
+			"does not update existing templates when preserved",
+			input{
+				ses: &mockSESClient{
+					updateEmailTemplateErr: "failed to update email template",
+				},
+				preserve: true,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
 		{
 			"is successful",
 			input{
@@ -88,10 +112,14 @@ func TestInitTemplatesUnit(t *testing.T) {
 		},
 	}
 
+	thankYou, forward := loadTemplates(t)
+
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			o := orchestrator{
-				ses: tt.input.ses,
+				ses:      tt.input.ses,
+				forms:    map[string]*form{DefaultFormID: {thankYou: thankYou, forward: forward}},
+				preserve: tt.input.preserve,
 			}
 
 			err := o.initTemplates(context.Background())
@@ -104,12 +132,19 @@ func TestSendMailUnit(t *testing.T) {
 	logger, err := zap.NewDevelopment()
 	require.Empty(t, err)
 
+	thankYou, forward := loadTemplates(t)
+
 	type input struct {
-		ses sesClient
+		outbox     *mockQueue
+		suppressed []string
+		autoReply  AutoReplyConfig
+		email      string
 	}
 
 	type want struct {
 		errAssertion func(t *testing.T, err error)
+		queued       []string
+		autoReply    mailservice_v1.AutoReplyStatus
 	}
 
 	cases := []struct {
@@ -118,71 +153,281 @@ func TestSendMailUnit(t *testing.T) {
 		want  want
 	}{
 		{
-			"handles failure to send forward email",
+			"handles failure to queue forward email",
 			input{
-				ses: &mockSESClient{
-					sendEmailErrors: []string{"error sending forward email"},
+				outbox: &mockQueue{
+					enqueueErrors: []string{"error queueing forward email"},
 				},
+				autoReply: AutoReplyConfig{Enabled: true},
 			},
 			want{
 				errAssertion: func(t *testing.T, err error) {
 					require.NotEmpty(t, err)
-					assert.Contains(t, err.Error(), "error sending forward email")
+					assert.Contains(t, err.Error(), "error queueing forward email")
 				},
 			},
 		},
-		// {
-		// 	"handles failure to send thank you email",
-		// 	input{
-		// 		ses: &mockSESClient{
-		// 			sendEmailErrors: []string{"", "error sending thank you email"},
-		// 		},
-		// 	},
-		// 	want{
-		// 		errAssertion: func(t *testing.T, err error) {
-		// 			require.NotEmpty(t, err)
-		// 			assert.Contains(t, err.Error(), "error sending thank you email")
-		// 		},
-		// 	},
-		// },
 		{
-			"is successful",
+			"does not queue email to suppressed forward address",
 			input{
-				ses: &mockSESClient{
-					sendEmailErrors: []string{"", ""},
+				outbox:     &mockQueue{},
+				suppressed: []string{"owner@example.com"},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+				},
+			},
+		},
+		{
+			"does not fail forward when thank you email cannot be queued",
+			input{
+				outbox: &mockQueue{
+					enqueueErrors: []string{"", "error queueing thank you email"},
+				},
+				autoReply: AutoReplyConfig{Enabled: true},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
 				},
+				queued:    []string{"owner@example.com"},
+				autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_FAILED,
+			},
+		},
+		{
+			"rejects invalid submitter address",
+			input{
+				outbox:    &mockQueue{},
+				autoReply: AutoReplyConfig{Enabled: true},
+				email:     "jane@example.com\r\nBcc: victim@example.com",
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+			},
+		},
+		{
+			"does not queue thank you email to suppressed address",
+			input{
+				outbox:     &mockQueue{},
+				suppressed: []string{"jane@example.com"},
+				autoReply:  AutoReplyConfig{Enabled: true},
 			},
 			want{
 				errAssertion: func(t *testing.T, err error) {
 					assert.Empty(t, err)
 				},
+				queued:    []string{"owner@example.com"},
+				autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_SUPPRESSED,
+			},
+		},
+		{
+			"is successful without thank you email",
+			input{
+				outbox:     &mockQueue{},
+				suppressed: []string{"someone-else@example.com"},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				queued:    []string{"owner@example.com"},
+				autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED,
+			},
+		},
+		{
+			"is successful with thank you email",
+			input{
+				outbox:    &mockQueue{},
+				autoReply: AutoReplyConfig{Enabled: true, Limit: 1, Window: time.Hour},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				queued:    []string{"owner@example.com", "jane@example.com"},
+				autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED,
 			},
 		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
+			suppressions, err := suppression.New(suppression.Config{})
+			require.Empty(t, err)
+			for _, addr := range tt.input.suppressed {
+				_, err := suppressions.Add(context.Background(), suppression.Entry{Address: addr})
+				require.Empty(t, err)
+			}
+
+			email := tt.input.email
+			if email == "" {
+				email = "jane@example.com"
+			}
+
 			o := orchestrator{
-				ses:    tt.input.ses,
-				logger: logger,
+				outbox:       tt.input.outbox,
+				suppressions: suppressions,
+				forms: map[string]*form{
+					DefaultFormID: {
+						id:            DefaultFormID,
+						thankYou:      thankYou,
+						forward:       forward,
+						forwardEmails: []string{"owner@example.com"},
+						fromEmail:     "noreply@example.com",
+						autoReply:     tt.input.autoReply,
+						replyLimiter:  newReplyLimiter(tt.input.autoReply.Limit, tt.input.autoReply.Window),
+					},
+				},
+				defaultForm: DefaultFormID,
+				logger:      logger,
 			}
 
-			_, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{})
+			resp, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+				Name:    "Jane",
+				Email:   email,
+				Message: "Hello there",
+			})
 			tt.want.errAssertion(t, err)
+			if err == nil {
+				assert.Equal(t, "message-1", resp.MessageId)
+				assert.Equal(t, tt.want.autoReply, resp.AutoReplyStatus)
+				if tt.want.autoReply == mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED {
+					assert.Equal(t, "message-2", resp.AutoReplyMessageId)
+				} else {
+					assert.Empty(t, resp.AutoReplyMessageId)
+				}
+			}
+
+			var queued []string
+			for _, msg := range tt.input.outbox.messages {
+				assert.Equal(t, "noreply@example.com", msg.From)
+				queued = append(queued, msg.To...)
+			}
+			assert.Equal(t, tt.want.queued, queued)
+
+			if len(tt.input.outbox.messages) > 0 {
+				forwarded := tt.input.outbox.messages[0]
+				assert.Equal(t, "ForwardTemplate", forwarded.Template.Name)
+				assert.Equal(t, "Name: Jane\nFrom: "+email+"\nSubject: \n\nHello there", forwarded.Text)
+			}
+
+			if len(tt.input.outbox.messages) > 1 {
+				reply := tt.input.outbox.messages[1]
+				assert.Equal(t, "ThankYouTemplate", reply.Template.Name)
+				assert.JSONEq(t, `{"name":"Jane","fields":{},"field_list":[]}`, reply.Template.Data)
+				assert.Contains(t, reply.HTML, "Hi Jane,")
+				assert.Contains(t, reply.Text, "Hi Jane,")
+			}
 		})
 	}
 }
 
+func TestReplyLimiterUnit(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	l := newReplyLimiter(2, time.Hour)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.allow("jane@example.com"))
+	assert.True(t, l.allow("JANE@example.com"))
+	assert.False(t, l.allow("jane@example.com"))
+	assert.True(t, l.allow("john@example.com"))
+
+	now = now.Add(61 * time.Minute)
+	assert.True(t, l.allow("jane@example.com"))
+
+	assert.True(t, newReplyLimiter(0, time.Hour).allow("jane@example.com"))
+}
+
+func TestNewUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	_, err = New(context.Background(), Config{Templates: map[string]templates.Template{templates.ThankYou: all[templates.ThankYou]}})
+	require.NotEmpty(t, err)
+	assert.Contains(t, err.Error(), "missing forward template")
+
+	o, err := New(context.Background(), Config{Templates: all, Logger: zap.NewNop()})
+	require.Empty(t, err)
+	assert.NotNil(t, o)
+}
+
+func TestNewMessageUnit(t *testing.T) {
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	engine, err := render.New(render.Config{Templates: all})
+	require.Empty(t, err)
+
+	// The SES client fails every call, so New only succeeds when templates are not stored in AWS SES.
+	o, err := New(context.Background(), Config{
+		SES:       &mockSESClient{getEmailTemplateErr: "failed to get email template"},
+		Templates: all,
+		Renderer:  engine,
+		FromEmail: "noreply@example.com",
+		Logger:    zap.NewNop(),
+	})
+	require.Empty(t, err)
+
+	f := o.(*orchestrator).forms[DefaultFormID]
+	data := constructForwardTemplateData(&mailservice_v1.SendMailRequest{Message: "<b>hi</b>", Email: "jane@example.com"}, nil)
+	data[subjectLineKey] = "You have an inquiry"
+	msg, err := f.newMessage(f.forward, []string{"me@example.com"}, data)
+	require.Empty(t, err)
+
+	assert.Nil(t, msg.Template)
+	assert.Equal(t, "noreply@example.com", msg.From)
+	assert.Equal(t, []string{"me@example.com"}, msg.To)
+	assert.Equal(t, "You have an inquiry", msg.Subject)
+	assert.Contains(t, msg.HTML, "&lt;b&gt;hi&lt;/b&gt;")
+	assert.Equal(t, "Name: \nFrom: jane@example.com\nSubject: \n\n<b>hi</b>", msg.Text)
+}
+
+func loadTemplates(t testing.TB) (emailTemplate, emailTemplate) {
+	t.Helper()
+
+	all, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	return newEmailTemplate(all[templates.ThankYou]), newEmailTemplate(all[templates.Forward])
+}
+
+type mockQueue struct {
+	// enqueueErrors is a slice of error messages returned, in order, by successive calls to Enqueue.
+	// An empty string means the call succeeds.
+	enqueueErrors []string
+	messages      []*transport.Message
+}
+
+func (m *mockQueue) Enqueue(ctx context.Context, msg *transport.Message) (string, error) {
+	if len(m.enqueueErrors) > 0 {
+		err := m.enqueueErrors[0]
+		m.enqueueErrors = m.enqueueErrors[1:]
+		if err != "" {
+			return "", errors.New(err)
+		}
+	}
+
+	m.messages = append(m.messages, msg)
+	return fmt.Sprintf("message-%d", len(m.messages)), nil
+}
+
+func (m *mockQueue) Get(id string) (outbox.Record, error) {
+	return outbox.Record{}, outbox.ErrNotFound
+}
+
+func (m *mockQueue) List(q outbox.Query) ([]outbox.Record, string, error) {
+	return nil, "", nil
+}
+
 var _ sesClient = &mockSESClient{}
 
 type mockSESClient struct {
 	getEmailTemplateErr    string
 	createEmailTemplateErr string
 	updateEmailTemplateErr string
-
-	// sendEmailErrors is a slice of boolean values that indicate whether an error should be returned when sending an email.
-	// In the SendEmail funciton two emails are sent with sesClient so this allows us to control the error for each email.
-	sendEmailErrors []string
 }
 
 func (m mockSESClient) GetEmailTemplate(ctx context.Context, params *sesv2.GetEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailTemplateOutput, error) {
@@ -214,14 +459,19 @@ func (m mockSESClient) UpdateEmailTemplate(ctx context.Context, params *sesv2.Up
 	return &sesv2.UpdateEmailTemplateOutput{}, nil
 }
 
-func (m *mockSESClient) SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
-	if len(m.sendEmailErrors) > 0 {
-		err := m.sendEmailErrors[0]
-		m.sendEmailErrors = m.sendEmailErrors[1:]
-		if err != "" {
-			return nil, errors.New(err)
-		}
-	}
+func TestRenderTemplateUnit(t *testing.T) {
+	subject, html, text := emailTemplate{
+		Content: &types.EmailTemplateContent{
+			Subject: aws.String("Hello {{name}}"),
+			Html:    aws.String("<p>{{ name }} said {{text}}</p>"),
+			Text:    aws.String("{{name}} said {{text}}{{missing}}"),
+		},
+	}.render(map[string]any{
+		"name": "Jane",
+		"text": "<b>hi</b>",
+	})
 
-	return &sesv2.SendEmailOutput{}, nil
+	assert.Equal(t, "Hello Jane", subject)
+	assert.Equal(t, "<p>Jane said &lt;b&gt;hi&lt;/b&gt;</p>", html)
+	assert.Equal(t, "Jane said <b>hi</b>", text)
 }
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// originMetadataKeys are the metadata keys the origin of a request is read from: the header forwarded by the
+// gRPC-Gateway, followed by the header set by native gRPC clients.
+var originMetadataKeys = []string{"grpcgateway-origin", "origin"}
+
+// requestOrigin returns the origin of the page a request was made from, or an empty string if it is unknown.
+func requestOrigin(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, key := range originMetadataKeys {
+		if v := md.Get(key); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// allowsOrigin reports whether the form may be submitted from the origin. Forms without origins may be submitted
+// from anywhere. An origin pattern may contain one "*" wildcard, e.g. "https://*.example.com", and "*" matches every origin.
+//
+// Parameters:
+//   - origin: The origin of the request, e.g. "https://www.example.com".
+//
+// Returns:
+//   - bool: Whether the origin matches one of the origins of the form.
+func (f *form) allowsOrigin(origin string) bool {
+	if len(f.origins) == 0 {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, pattern := range f.origins {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if origin == pattern {
+				return true
+			}
+			continue
+		}
+
+		if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
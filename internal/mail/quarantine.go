@@ -0,0 +1,239 @@
+package mail
+
+import (
+	"context"
+	"errors"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/quarantine"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// quarantineQueue is an interface that defines the methods from the quarantine.Queue that are used by the
+// Orchestrator to hold submissions back for review and to review them.
+type quarantineQueue interface {
+	Add(e quarantine.Entry) (quarantine.Entry, error)
+	Get(id string) (quarantine.Entry, error)
+	Remove(id string) error
+	Take(id string) (quarantine.Entry, error)
+	Restore(e quarantine.Entry) error
+	List(q quarantine.Query) ([]quarantine.Entry, string, error)
+}
+
+// hold quarantines a submission instead of forwarding it. The submitter gets the same response as for a forwarded
+// submission, but no auto-reply is sent.
+//
+// Parameters:
+//   - f: The form the submission was made to.
+//   - req: The mailservice_v1.SendMailRequest object containing the submission.
+//   - screened: The spam.Result the submission was quarantined for.
+//   - forward: The message forwarded when the submission is released.
+//
+// Returns:
+//   - *mailservice_v1.SendMailResponse: A response carrying a message ID that is not tracked by the outbox.
+//   - error: An Internal error if the submission could not be quarantined.
+func (o orchestrator) hold(f *form, req *mailservice_v1.SendMailRequest, screened spam.Result, forward *transport.Message) (*mailservice_v1.SendMailResponse, error) {
+	e, err := o.quarantine.Add(quarantine.Entry{
+		FormID:  f.id,
+		Name:    req.Name,
+		Email:   req.Email,
+		Subject: req.GetSubject(),
+		Message: req.Message,
+		Score:   screened.Score,
+		Reasons: screened.Reasons,
+		Forward: *forward,
+	})
+	if err != nil {
+		// The error matches the one of a failed forward, so the submitter does not learn of the quarantine.
+		return nil, status.Errorf(codes.Internal, "failed to queue forward email: %v", err)
+	}
+
+	o.logger.Info("Submission quarantined", zap.String("form", f.id), zap.String("quarantine_id", e.ID))
+
+	return o.decoy(f, req)
+}
+
+// ListQuarantine returns a page of the submissions held back for review, newest first.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ListQuarantineRequest object containing the form filter and the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListQuarantineResponse: The page of submissions and the token for the next page.
+//   - error: A FailedPrecondition error if the quarantine is disabled, or an InvalidArgument error if the page token is malformed.
+func (o orchestrator) ListQuarantine(_ context.Context, req *mailservice_v1.ListQuarantineRequest) (*mailservice_v1.ListQuarantineResponse, error) {
+	if o.quarantine == nil {
+		return nil, status.Error(codes.FailedPrecondition, "spam quarantine is disabled")
+	}
+
+	entries, next, err := o.quarantine.List(quarantine.Query{
+		FormID:    req.FormId,
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+	})
+	if err != nil {
+		if errors.Is(err, quarantine.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to list quarantine: %v", err)
+	}
+
+	resp := &mailservice_v1.ListQuarantineResponse{
+		NextPageToken: next,
+	}
+	for _, e := range entries {
+		resp.Submissions = append(resp.Submissions, toQuarantinedSubmission(e))
+	}
+
+	return resp, nil
+}
+
+// ReleaseQuarantined forwards a quarantined submission, removes it from the quarantine and trains the spam classifier
+// with it as ham. The submission is taken out of the quarantine before it is forwarded, so concurrent releases only
+// forward it once, and put back when it cannot be queued.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ReleaseQuarantinedRequest object containing the ID of the submission.
+//
+// Returns:
+//   - *mailservice_v1.ReleaseQuarantinedResponse: The ID of the forwarded message.
+//   - error: A NotFound error if the submission is not quarantined, or a FailedPrecondition error if the forward addresses are suppressed.
+func (o orchestrator) ReleaseQuarantined(ctx context.Context, req *mailservice_v1.ReleaseQuarantinedRequest) (*mailservice_v1.ReleaseQuarantinedResponse, error) {
+	e, err := o.quarantined(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	forward := e.Forward
+	forward.To = o.deliverable(forward.To)
+	if len(forward.To) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "the forward address is on the suppression list")
+	}
+	forward.Cc = o.deliverable(forward.Cc)
+	forward.Bcc = o.deliverable(forward.Bcc)
+
+	if e, err = o.quarantine.Take(e.ID); err != nil {
+		if errors.Is(err, quarantine.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "submission %q is not quarantined", req.Id)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to release submission: %v", err)
+	}
+
+	forwardID, err := o.outbox.Enqueue(ctx, &forward)
+	if err != nil {
+		if err := o.quarantine.Restore(e); err != nil {
+			o.logger.With(zap.Error(err)).Error("Failed to restore unreleased submission to quarantine.", zap.String("quarantine_id", e.ID))
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to queue forward email: %v", err)
+	}
+
+	o.logger.Info("Quarantined submission released", zap.String("form", e.FormID), zap.String("quarantine_id", e.ID), zap.String("message_id", forwardID))
+	o.learn(e, false)
+
+	return &mailservice_v1.ReleaseQuarantinedResponse{
+		MessageId: forwardID,
+	}, nil
+}
+
+// DiscardQuarantined deletes a quarantined submission and trains the spam classifier with it as spam.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.DiscardQuarantinedRequest object containing the ID of the submission.
+//
+// Returns:
+//   - *mailservice_v1.DiscardQuarantinedResponse: An empty response.
+//   - error: A NotFound error if the submission is not quarantined.
+func (o orchestrator) DiscardQuarantined(_ context.Context, req *mailservice_v1.DiscardQuarantinedRequest) (*mailservice_v1.DiscardQuarantinedResponse, error) {
+	e, err := o.quarantined(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.quarantine.Remove(e.ID); err != nil {
+		if errors.Is(err, quarantine.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "submission %q is not quarantined", e.ID)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to discard submission: %v", err)
+	}
+
+	o.logger.Info("Quarantined submission discarded", zap.String("form", e.FormID), zap.String("quarantine_id", e.ID))
+	o.learn(e, true)
+
+	return &mailservice_v1.DiscardQuarantinedResponse{}, nil
+}
+
+// quarantined returns a quarantined submission.
+//
+// Parameters:
+//   - id: The ID of the submission.
+//
+// Returns:
+//   - quarantine.Entry: The quarantined submission.
+//   - error: A FailedPrecondition error if the quarantine is disabled, or a NotFound error if the submission is not quarantined.
+func (o orchestrator) quarantined(id string) (quarantine.Entry, error) {
+	if o.quarantine == nil {
+		return quarantine.Entry{}, status.Error(codes.FailedPrecondition, "spam quarantine is disabled")
+	}
+
+	if id == "" {
+		return quarantine.Entry{}, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	e, err := o.quarantine.Get(id)
+	if err != nil {
+		if errors.Is(err, quarantine.ErrNotFound) {
+			return quarantine.Entry{}, status.Errorf(codes.NotFound, "submission %q is not quarantined", id)
+		}
+
+		return quarantine.Entry{}, status.Errorf(codes.Internal, "failed to get quarantined submission: %v", err)
+	}
+
+	return e, nil
+}
+
+// learn trains the spam classifier with a reviewed submission. Reviews are not undone when training fails.
+//
+// Parameters:
+//   - e: The reviewed submission.
+//   - isSpam: Whether the submission was found to be spam.
+func (o orchestrator) learn(e quarantine.Entry, isSpam bool) {
+	if o.spam == nil {
+		return
+	}
+
+	if _, err := o.spam.Train(spam.Submission{FormID: e.FormID, Subject: e.Subject, Message: e.Message}, isSpam); err != nil {
+		o.logger.With(zap.Error(err)).Warn("Failed to train spam filter with reviewed submission.", zap.String("quarantine_id", e.ID))
+	}
+}
+
+func toQuarantinedSubmission(e quarantine.Entry) *mailservice_v1.QuarantinedSubmission {
+	s := &mailservice_v1.QuarantinedSubmission{
+		Id:        e.ID,
+		FormId:    e.FormID,
+		Name:      e.Name,
+		Email:     e.Email,
+		Subject:   e.Subject,
+		Message:   e.Message,
+		Score:     e.Score,
+		Reasons:   e.Reasons,
+		CreatedAt: timestamppb.New(e.CreatedAt),
+	}
+
+	for _, a := range e.Forward.Attachments {
+		s.Attachments = append(s.Attachments, a.Filename)
+	}
+
+	return s
+}
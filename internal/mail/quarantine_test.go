@@ -0,0 +1,129 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/quarantine"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuarantineUnit(t *testing.T) {
+	thankYou, forward := loadTemplates(t)
+
+	classifier, err := spam.NewClassifier("")
+	require.Empty(t, err)
+
+	filter, err := spam.New(spam.Config{
+		Thresholds: spam.Thresholds{Tag: 2, Quarantine: 4, Drop: 6},
+		MaxLinks:   -1,
+		Keywords:   []string{"pills", "buy pills"},
+		Classifier: classifier,
+	})
+	require.Empty(t, err)
+
+	held, err := quarantine.New("")
+	require.Empty(t, err)
+
+	suppressions, err := suppression.New(suppression.Config{})
+	require.Empty(t, err)
+
+	outbox := &mockQueue{}
+	o := orchestrator{
+		outbox:       outbox,
+		suppressions: suppressions,
+		forms: map[string]*form{
+			DefaultFormID: {
+				id:               DefaultFormID,
+				thankYou:         thankYou,
+				forward:          forward,
+				forwardEmails:    []string{"owner@example.com"},
+				fromEmail:        "noreply@example.com",
+				autoReply:        AutoReplyConfig{Enabled: true},
+				attachmentLimits: AttachmentLimits{MaxCount: 1, MaxFileSize: 1 << 10, MaxTotalSize: 1 << 10},
+			},
+		},
+		defaultForm: DefaultFormID,
+		spam:        filter,
+		quarantine:  held,
+		logger:      zap.NewNop(),
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+			Name:        "Jane",
+			Email:       "jane@example.com",
+			Message:     "Cheap pills, buy pills",
+			Attachments: []*mailservice_v1.Attachment{{Filename: "notes.txt", Content: []byte("notes")}},
+		})
+		require.Empty(t, err)
+		assert.Len(t, resp.MessageId, 32)
+		assert.Equal(t, mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED, resp.AutoReplyStatus)
+		assert.Len(t, resp.AutoReplyMessageId, 32)
+	}
+	assert.Empty(t, outbox.messages, "quarantined submissions are not forwarded and get no auto-reply")
+
+	list, err := o.ListQuarantine(context.Background(), &mailservice_v1.ListQuarantineRequest{PageSize: 1})
+	require.Empty(t, err)
+	require.Len(t, list.Submissions, 1)
+	assert.NotEmpty(t, list.NextPageToken)
+
+	sub := list.Submissions[0]
+	assert.Equal(t, DefaultFormID, sub.FormId)
+	assert.Equal(t, "jane@example.com", sub.Email)
+	assert.Equal(t, "Cheap pills, buy pills", sub.Message)
+	assert.Equal(t, float64(4), sub.Score)
+	assert.Equal(t, []string{"notes.txt"}, sub.Attachments)
+
+	outbox.enqueueErrors = []string{"disk full"}
+	_, err = o.ReleaseQuarantined(context.Background(), &mailservice_v1.ReleaseQuarantinedRequest{Id: sub.Id})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	_, err = held.Get(sub.Id)
+	require.Empty(t, err, "submissions that could not be queued are put back")
+
+	released, err := o.ReleaseQuarantined(context.Background(), &mailservice_v1.ReleaseQuarantinedRequest{Id: sub.Id})
+	require.Empty(t, err)
+	assert.Equal(t, "message-1", released.MessageId)
+	require.Len(t, outbox.messages, 1)
+	assert.Equal(t, []string{"owner@example.com"}, outbox.messages[0].To)
+	assert.Equal(t, "You have an inquiry", outbox.messages[0].Subject)
+	assert.Len(t, outbox.messages[0].Attachments, 1)
+
+	_, err = o.ReleaseQuarantined(context.Background(), &mailservice_v1.ReleaseQuarantinedRequest{Id: sub.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err = o.ListQuarantine(context.Background(), &mailservice_v1.ListQuarantineRequest{})
+	require.Empty(t, err)
+	require.Len(t, list.Submissions, 1)
+
+	_, err = o.DiscardQuarantined(context.Background(), &mailservice_v1.DiscardQuarantinedRequest{Id: list.Submissions[0].Id})
+	require.Empty(t, err)
+	assert.Len(t, outbox.messages, 1, "discarded submissions are not forwarded")
+
+	_, err = o.DiscardQuarantined(context.Background(), &mailservice_v1.DiscardQuarantinedRequest{Id: list.Submissions[0].Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stats, err := o.TrainSpamFilter(context.Background(), &mailservice_v1.TrainSpamFilterRequest{Message: "Hello", Spam: false})
+	require.Empty(t, err)
+	assert.Equal(t, &mailservice_v1.TrainSpamFilterResponse{SpamMessages: 1, HamMessages: 2}, stats, "reviews train the classifier")
+}
+
+func TestQuarantineDisabledUnit(t *testing.T) {
+	o := orchestrator{logger: zap.NewNop()}
+
+	_, err := o.ListQuarantine(context.Background(), &mailservice_v1.ListQuarantineRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = o.ReleaseQuarantined(context.Background(), &mailservice_v1.ReleaseQuarantinedRequest{Id: "9f1c2b7e4a5d4c3b8e6f0a1b2c3d4e5f"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = o.DiscardQuarantined(context.Background(), &mailservice_v1.DiscardQuarantinedRequest{Id: "9f1c2b7e4a5d4c3b8e6f0a1b2c3d4e5f"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brice-aldrich/mail-service/internal/sanitize"
+)
+
+// placeholderPattern matches the simple {{variable}} placeholders supported by the SES template engine, including
+// paths into nested data such as {{fields.company}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+(?:\.[A-Za-z0-9_]+)*)\s*\}\}`)
+
+// eachPattern matches the {{#each list}}...{{/each}} blocks of the SES template engine, which render their body once
+// for every item of a list, e.g. the "field_list" of a submission.
+var eachPattern = regexp.MustCompile(`(?s)\{\{#each\s+([A-Za-z0-9_]+(?:\.[A-Za-z0-9_]+)*)\s*\}\}(.*?)\{\{/each\}\}`)
+
+// render renders the template locally by substituting its {{variable}} placeholders and expanding its {{#each list}}
+// blocks with the provided data.
+// Values substituted into the HTML part are HTML escaped, mirroring the behaviour of SES.
+//
+// Parameters:
+//   - data: The data used to render the template.
+//
+// Returns:
+//   - string: The rendered subject.
+//   - string: The rendered HTML part.
+//   - string: The rendered text part.
+func (t emailTemplate) render(data map[string]any) (string, string, string) {
+	return substitute(aws.ToString(t.Content.Subject), data, false),
+		substitute(aws.ToString(t.Content.Html), data, true),
+		substitute(aws.ToString(t.Content.Text), data, false)
+}
+
+func substitute(s string, data map[string]any, escape bool) string {
+	s = eachPattern.ReplaceAllStringFunc(s, func(m string) string {
+		match := eachPattern.FindStringSubmatch(m)
+
+		var b strings.Builder
+		for _, item := range items(value(data, match[1])) {
+			b.WriteString(substitute(match[2], item, escape))
+		}
+
+		return b.String()
+	})
+
+	return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		v := lookup(data, placeholderPattern.FindStringSubmatch(m)[1])
+		if escape {
+			return sanitize.HTML(v)
+		}
+
+		return v
+	})
+}
+
+// lookup returns the text at a dotted path of the data, e.g. "fields.company". Missing values and values that are not
+// text, such as lists, are empty.
+func lookup(data map[string]any, path string) string {
+	s, _ := value(data, path).(string)
+	return s
+}
+
+// value returns the value at a dotted path of the data, or nil when it is missing.
+func value(data map[string]any, path string) any {
+	var v any = data
+	for _, key := range strings.Split(path, ".") {
+		switch m := v.(type) {
+		case map[string]any:
+			v = m[key]
+		case map[string]string:
+			v = m[key]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+// items returns the items of a list as data to render the body of an {{#each}} block with. Values that are not lists
+// have no items, and items that are not objects are skipped.
+func items(v any) []map[string]any {
+	var list []map[string]any
+	switch l := v.(type) {
+	case []map[string]string:
+		for _, item := range l {
+			m := make(map[string]any, len(item))
+			for k, s := range item {
+				m[k] = s
+			}
+			list = append(list, m)
+		}
+	case []map[string]any:
+		list = l
+	case []any:
+		for _, item := range l {
+			if m, ok := item.(map[string]any); ok {
+				list = append(list, m)
+			}
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// spamTag is prepended to the subject of forwarded submissions that look like spam.
+const spamTag = "[SPAM?] "
+
+// spamFilter is an interface that defines the methods from the spam.Filter that are used by the Orchestrator to
+// screen submissions and train the spam classifier.
+type spamFilter interface {
+	Evaluate(sub spam.Submission) spam.Result
+	Train(sub spam.Submission, isSpam bool) (spam.Stats, error)
+}
+
+// screen scores a submission with the spam filter. Submissions are accepted when there is no spam filter.
+//
+// Parameters:
+//   - f: The form the submission was made to.
+//   - req: The mailservice_v1.SendMailRequest object containing the submission.
+//
+// Returns:
+//   - spam.Result: The score, the reasons and the verdict of the spam filter.
+func (o orchestrator) screen(f *form, req *mailservice_v1.SendMailRequest) spam.Result {
+	if o.spam == nil {
+		return spam.Result{}
+	}
+
+	res := o.spam.Evaluate(spam.Submission{
+		FormID:  f.id,
+		Name:    req.Name,
+		Email:   req.Email,
+		Subject: req.GetSubject(),
+		Message: req.Message,
+	})
+	if res.Verdict != spam.VerdictAccept {
+		o.logger.Info("Submission looks like spam",
+			zap.String("form", f.id),
+			zap.String("verdict", res.Verdict.String()),
+			zap.Float64("score", res.Score),
+			zap.Strings("reasons", res.Reasons),
+		)
+	}
+
+	return res
+}
+
+// tagSpam prepends the spam tag to the subject of a message. The stored template is dropped and the locally rendered
+// copy is sent instead, so the tag shows whatever subject the stored template has.
+func tagSpam(msg *transport.Message) {
+	msg.Subject = spamTag + msg.Subject
+	msg.Template = nil
+}
+
+// TrainSpamFilter trains the spam classifier with a message marked as spam or ham.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.TrainSpamFilterRequest object containing the message and whether it is spam.
+//
+// Returns:
+//   - *mailservice_v1.TrainSpamFilterResponse: The number of spam and ham messages the classifier was trained with.
+//   - error: A FailedPrecondition error if spam filtering is disabled, or an Internal error if the classifier could not be trained.
+func (o orchestrator) TrainSpamFilter(_ context.Context, req *mailservice_v1.TrainSpamFilterRequest) (*mailservice_v1.TrainSpamFilterResponse, error) {
+	if o.spam == nil {
+		return nil, status.Error(codes.FailedPrecondition, "spam filtering is disabled")
+	}
+
+	if req.Subject == "" && req.Message == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject or message is required")
+	}
+
+	stats, err := o.spam.Train(spam.Submission{Subject: req.Subject, Message: req.Message}, req.Spam)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to train spam filter: %v", err)
+	}
+
+	return &mailservice_v1.TrainSpamFilterResponse{
+		SpamMessages: stats.Spam,
+		HamMessages:  stats.Ham,
+	}, nil
+}
+
+// DecoyResponse builds the response returned for a submission to a form that is dropped without being sent. It looks
+// like the response of an accepted submission to the form, so senders of spam and bots do not learn they were caught.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.SendMailRequest object containing the dropped submission.
+//
+// Returns:
+//   - *mailservice_v1.SendMailResponse: The decoy response, carrying random message IDs.
+//   - error: An InvalidArgument error if the form does not exist, or an Internal error if no random message ID could
+//     be generated.
+func (o orchestrator) DecoyResponse(_ context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
+	f, err := o.resolveForm(req.FormId)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.decoy(f, req)
+}
+
+// decoy builds the decoy response of a submission to a form. The auto-reply is reported as queued with a random ID
+// when the form sends one, as it would be for an accepted submission, but never actually sent.
+func (o orchestrator) decoy(f *form, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
+	id, err := decoyID()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &mailservice_v1.SendMailResponse{
+		MessageId:       id,
+		AutoReplyStatus: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED,
+	}
+
+	switch {
+	case !f.autoReply.Enabled:
+	case len(o.deliverable([]string{req.Email})) == 0:
+		resp.AutoReplyStatus = mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_SUPPRESSED
+	default:
+		if resp.AutoReplyMessageId, err = decoyID(); err != nil {
+			return nil, err
+		}
+		resp.AutoReplyStatus = mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED
+	}
+
+	return resp, nil
+}
+
+// decoyID generates a random message ID shaped like the IDs of the outbox.
+func decoyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", status.Errorf(codes.Internal, "failed to queue forward email: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
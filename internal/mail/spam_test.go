@@ -0,0 +1,189 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSendMailSpamUnit(t *testing.T) {
+	thankYou, forward := loadTemplates(t)
+
+	type want struct {
+		queued    int
+		subject   string
+		tagged    bool
+		autoReply mailservice_v1.AutoReplyStatus
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"forwards clean submission",
+			"Hello there",
+			want{queued: 2, subject: "You have an inquiry", autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED},
+		},
+		{
+			"tags likely spam",
+			"Cheap pills here",
+			want{queued: 1, subject: "[SPAM?] You have an inquiry", tagged: true, autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED},
+		},
+		{
+			"tags submission held for review",
+			"Cheap pills, buy pills",
+			want{queued: 1, subject: "[SPAM?] You have an inquiry", tagged: true, autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED},
+		},
+		{
+			"drops spam",
+			"Cheap pills, buy pills at the casino",
+			want{},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := spam.New(spam.Config{
+				Thresholds: spam.Thresholds{Tag: 2, Quarantine: 4, Drop: 6},
+				MaxLinks:   -1,
+				Keywords:   []string{"pills", "buy pills", "casino"},
+			})
+			require.Empty(t, err)
+
+			outbox := &mockQueue{}
+			o := orchestrator{
+				outbox: outbox,
+				forms: map[string]*form{
+					DefaultFormID: {
+						id:            DefaultFormID,
+						thankYou:      thankYou,
+						forward:       forward,
+						forwardEmails: []string{"owner@example.com"},
+						fromEmail:     "noreply@example.com",
+						autoReply:     AutoReplyConfig{Enabled: true},
+					},
+				},
+				defaultForm: DefaultFormID,
+				spam:        filter,
+				logger:      zap.NewNop(),
+			}
+
+			resp, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{
+				Name:    "Jane",
+				Email:   "jane@example.com",
+				Message: tt.input,
+			})
+			require.Empty(t, err)
+			require.Len(t, outbox.messages, tt.want.queued)
+			if tt.want.queued == 0 {
+				assert.Len(t, resp.MessageId, 32, "dropped submissions get a decoy message id")
+				assert.Equal(t, mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED, resp.AutoReplyStatus, "dropped submissions look accepted")
+				assert.Len(t, resp.AutoReplyMessageId, 32)
+				return
+			}
+
+			assert.Equal(t, tt.want.subject, outbox.messages[0].Subject)
+			assert.Equal(t, tt.want.tagged, outbox.messages[0].Template == nil)
+			assert.Equal(t, tt.want.autoReply, resp.AutoReplyStatus, "only accepted submissions get an auto-reply")
+		})
+	}
+}
+
+func TestDecoyResponseUnit(t *testing.T) {
+	type want struct {
+		autoReply   mailservice_v1.AutoReplyStatus
+		autoReplyID bool
+		code        codes.Code
+	}
+
+	cases := []struct {
+		name  string
+		input *mailservice_v1.SendMailRequest
+		want  want
+	}{
+		{
+			"reports auto-reply as queued",
+			&mailservice_v1.SendMailRequest{Email: "jane@example.com"},
+			want{autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED, autoReplyID: true},
+		},
+		{
+			"reports auto-reply to suppressed address as suppressed",
+			&mailservice_v1.SendMailRequest{Email: "bounced@example.com"},
+			want{autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_SUPPRESSED},
+		},
+		{
+			"reports auto-reply of form without one as disabled",
+			&mailservice_v1.SendMailRequest{FormId: "shop", Email: "jane@example.com"},
+			want{autoReply: mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_DISABLED},
+		},
+		{
+			"rejects unknown form",
+			&mailservice_v1.SendMailRequest{FormId: "missing", Email: "jane@example.com"},
+			want{code: codes.InvalidArgument},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			suppressions, err := suppression.New(suppression.Config{})
+			require.Empty(t, err)
+			_, err = suppressions.Add(context.Background(), suppression.Entry{Address: "bounced@example.com"})
+			require.Empty(t, err)
+
+			o := orchestrator{
+				suppressions: suppressions,
+				forms: map[string]*form{
+					DefaultFormID: {id: DefaultFormID, autoReply: AutoReplyConfig{Enabled: true}},
+					"shop":        {id: "shop"},
+				},
+				defaultForm: DefaultFormID,
+				logger:      zap.NewNop(),
+			}
+
+			resp, err := o.DecoyResponse(context.Background(), tt.input)
+			if tt.want.code != codes.OK {
+				assert.Equal(t, tt.want.code, status.Code(err))
+				return
+			}
+
+			require.Empty(t, err)
+			assert.Len(t, resp.MessageId, 32)
+			assert.Equal(t, tt.want.autoReply, resp.AutoReplyStatus)
+			assert.Equal(t, tt.want.autoReplyID, len(resp.AutoReplyMessageId) == 32)
+		})
+	}
+}
+
+func TestTrainSpamFilterUnit(t *testing.T) {
+	classifier, err := spam.NewClassifier("")
+	require.Empty(t, err)
+
+	filter, err := spam.New(spam.Config{MaxLinks: -1, Classifier: classifier})
+	require.Empty(t, err)
+
+	o := orchestrator{spam: filter, logger: zap.NewNop()}
+
+	resp, err := o.TrainSpamFilter(context.Background(), &mailservice_v1.TrainSpamFilterRequest{Message: "Cheap pills", Spam: true})
+	require.Empty(t, err)
+	assert.Equal(t, &mailservice_v1.TrainSpamFilterResponse{SpamMessages: 1}, resp)
+
+	resp, err = o.TrainSpamFilter(context.Background(), &mailservice_v1.TrainSpamFilterRequest{Subject: "Project", Message: "Let's talk"})
+	require.Empty(t, err)
+	assert.Equal(t, &mailservice_v1.TrainSpamFilterResponse{SpamMessages: 1, HamMessages: 1}, resp)
+
+	_, err = o.TrainSpamFilter(context.Background(), &mailservice_v1.TrainSpamFilterRequest{Spam: true})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = orchestrator{}.TrainSpamFilter(context.Background(), &mailservice_v1.TrainSpamFilterRequest{Message: "Cheap pills"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
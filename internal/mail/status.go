@@ -0,0 +1,118 @@
+package mail
+
+import (
+	"context"
+	"errors"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// messageStates maps the outbox delivery states to their API representation.
+var messageStates = map[outbox.State]mailservice_v1.MessageState{
+	outbox.StateQueued:     mailservice_v1.MessageState_MESSAGE_STATE_QUEUED,
+	outbox.StateSent:       mailservice_v1.MessageState_MESSAGE_STATE_SENT,
+	outbox.StateDelivered:  mailservice_v1.MessageState_MESSAGE_STATE_DELIVERED,
+	outbox.StateBounced:    mailservice_v1.MessageState_MESSAGE_STATE_BOUNCED,
+	outbox.StateComplained: mailservice_v1.MessageState_MESSAGE_STATE_COMPLAINED,
+	outbox.StateFailed:     mailservice_v1.MessageState_MESSAGE_STATE_FAILED,
+}
+
+// GetMessageStatus returns the delivery status of a message previously returned by SendMail.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The GetMessageStatusRequest object containing the message ID.
+//
+// Returns:
+//   - *mailservice_v1.MessageStatus: The delivery status of the message.
+//   - error: A NotFound error if the message does not exist.
+func (o orchestrator) GetMessageStatus(ctx context.Context, req *mailservice_v1.GetMessageStatusRequest) (*mailservice_v1.MessageStatus, error) {
+	if req.MessageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "message_id is required")
+	}
+
+	r, err := o.outbox.Get(req.MessageId)
+	if err != nil {
+		if errors.Is(err, outbox.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "message %q not found", req.MessageId)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get message: %v", err)
+	}
+
+	return toMessageStatus(r), nil
+}
+
+// ListMessages returns a page of messages known to the outbox, newest first.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The ListMessagesRequest object containing the state filter and pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListMessagesResponse: The page of messages and the token for the next page.
+//   - error: An InvalidArgument error if the page token is malformed.
+func (o orchestrator) ListMessages(ctx context.Context, req *mailservice_v1.ListMessagesRequest) (*mailservice_v1.ListMessagesResponse, error) {
+	q := outbox.Query{
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+	}
+
+	if req.State != mailservice_v1.MessageState_MESSAGE_STATE_UNSPECIFIED {
+		for s, v := range messageStates {
+			if v == req.State {
+				q.State = s
+			}
+		}
+
+		// No outbox state maps to the requested state, so no message can match it.
+		if q.State == "" {
+			return &mailservice_v1.ListMessagesResponse{}, nil
+		}
+	}
+
+	records, next, err := o.outbox.List(q)
+	if err != nil {
+		if errors.Is(err, outbox.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to list messages: %v", err)
+	}
+
+	resp := &mailservice_v1.ListMessagesResponse{
+		NextPageToken: next,
+	}
+	for _, r := range records {
+		resp.Messages = append(resp.Messages, toMessageStatus(r))
+	}
+
+	return resp, nil
+}
+
+func toMessageStatus(r outbox.Record) *mailservice_v1.MessageStatus {
+	ms := &mailservice_v1.MessageStatus{
+		MessageId:         r.ID,
+		State:             messageStates[r.State],
+		ProviderMessageId: r.ProviderMessageID,
+		Attempts:          int32(r.Attempts),
+		LastError:         r.LastError,
+		CreatedAt:         timestamppb.New(r.CreatedAt),
+		UpdatedAt:         timestamppb.New(r.UpdatedAt),
+	}
+
+	for _, e := range r.Events {
+		ms.Events = append(ms.Events, &mailservice_v1.MessageEvent{
+			Type:       string(e.Type),
+			Timestamp:  timestamppb.New(e.Timestamp),
+			Recipients: e.Recipients,
+			Detail:     e.Detail,
+		})
+	}
+
+	return ms
+}
@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetMessageStatusUnit(t *testing.T) {
+	ob, err := outbox.New(outbox.Config{})
+	require.Empty(t, err)
+
+	id, err := ob.Enqueue(context.Background(), &transport.Message{To: []string{"owner@example.com"}})
+	require.Empty(t, err)
+
+	type want struct {
+		code  codes.Code
+		state mailservice_v1.MessageState
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"handles missing message id",
+			"",
+			want{code: codes.InvalidArgument},
+		},
+		{
+			"handles unknown message",
+			"unknown",
+			want{code: codes.NotFound},
+		},
+		{
+			"is successful",
+			id,
+			want{code: codes.OK, state: mailservice_v1.MessageState_MESSAGE_STATE_QUEUED},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			o := orchestrator{outbox: ob}
+
+			resp, err := o.GetMessageStatus(context.Background(), &mailservice_v1.GetMessageStatusRequest{MessageId: tt.input})
+			require.Equal(t, tt.want.code, status.Code(err))
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, id, resp.MessageId)
+			assert.Equal(t, tt.want.state, resp.State)
+			assert.NotNil(t, resp.CreatedAt)
+		})
+	}
+}
+
+func TestListMessagesUnit(t *testing.T) {
+	ob, err := outbox.New(outbox.Config{})
+	require.Empty(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := ob.Enqueue(context.Background(), &transport.Message{To: []string{"owner@example.com"}})
+		require.Empty(t, err)
+	}
+
+	o := orchestrator{outbox: ob}
+
+	first, err := o.ListMessages(context.Background(), &mailservice_v1.ListMessagesRequest{PageSize: 2})
+	require.Empty(t, err)
+	assert.Len(t, first.Messages, 2)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, err := o.ListMessages(context.Background(), &mailservice_v1.ListMessagesRequest{PageSize: 2, PageToken: first.NextPageToken})
+	require.Empty(t, err)
+	assert.Len(t, second.Messages, 1)
+	assert.Empty(t, second.NextPageToken)
+
+	sent, err := o.ListMessages(context.Background(), &mailservice_v1.ListMessagesRequest{State: mailservice_v1.MessageState_MESSAGE_STATE_SENT})
+	require.Empty(t, err)
+	assert.Empty(t, sent.Messages)
+
+	_, err = o.ListMessages(context.Background(), &mailservice_v1.ListMessagesRequest{PageToken: "%%%"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
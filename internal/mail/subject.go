@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/sanitize"
+)
+
+// subjectLineKey is the key of the template data holding the subject line of a message.
+const subjectLineKey = "subject_line"
+
+// defaultForwardSubject is the subject of forwarded submissions whose forward template has no subject of its own,
+// such as the compiled-in template, whose subject is {{subject_line}}.
+const defaultForwardSubject = "You have an inquiry"
+
+// subjectLine renders the forward subject pattern of the form for a submission. The pattern may use the {{site}},
+// {{subject}}, {{name}}, {{email}} and {{form}} placeholders. Submissions without a subject use the fallback, and
+// submitters without a name their email address.
+//
+// Parameters:
+//   - req: The mailservice_v1.SendMailRequest object containing the submission.
+//   - fallback: The subject of the forward template, see templateSubject.
+//
+// Returns:
+//   - string: The subject line of the forward email.
+func (f *form) subjectLine(req *mailservice_v1.SendMailRequest, fallback string) string {
+	if f.forwardSubject == "" {
+		return fallback
+	}
+
+	subject := req.GetSubject()
+	if subject == "" {
+		subject = fallback
+	}
+
+	name := req.Name
+	if name == "" {
+		name = req.Email
+	}
+
+	return sanitize.Header(substitute(f.forwardSubject, map[string]any{
+		"site":    f.site,
+		"subject": subject,
+		"name":    name,
+		"email":   req.Email,
+		"form":    f.id,
+	}, false))
+}
+
+// templateSubject renders the subject of the forward template of the form without the subject line. Templates whose
+// subject is only the subject line fall back to "You have an inquiry".
+//
+// Parameters:
+//   - data: The data used to render the forward template.
+//
+// Returns:
+//   - string: The subject of the forward template.
+func (f *form) templateSubject(data map[string]any) string {
+	if f.forward.Content == nil {
+		return defaultForwardSubject
+	}
+
+	if subject := strings.TrimSpace(substitute(aws.ToString(f.forward.Content.Subject), data, false)); subject != "" {
+		return subject
+	}
+
+	return defaultForwardSubject
+}
+
+// replyTo returns the address replies to a forwarded submission go to: the submitter, under their name if they gave one.
+func replyTo(req *mailservice_v1.SendMailRequest) string {
+	return (&mail.Address{Name: req.Name, Address: req.Email}).String()
+}
@@ -0,0 +1,129 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSubjectLineUnit(t *testing.T) {
+	type input struct {
+		pattern string
+		req     *mailservice_v1.SendMailRequest
+	}
+
+	subject := func(s string) *string { return &s }
+
+	cases := []struct {
+		name  string
+		input input
+		want  string
+	}{
+		{
+			"uses the template subject without a pattern",
+			input{req: &mailservice_v1.SendMailRequest{Name: "Jane", Email: "jane@example.com", Subject: subject("Project")}},
+			"You have an inquiry",
+		},
+		{
+			"renders the pattern",
+			input{
+				pattern: "[{{site}}] {{subject}} — {{name}}",
+				req:     &mailservice_v1.SendMailRequest{Name: "Jane", Email: "jane@example.com", Subject: subject("Project")},
+			},
+			"[Portfolio] Project — Jane",
+		},
+		{
+			"falls back to the template subject and the email address",
+			input{
+				pattern: "[{{site}}] {{subject}} — {{name}}",
+				req:     &mailservice_v1.SendMailRequest{Email: "jane@example.com"},
+			},
+			"[Portfolio] You have an inquiry — jane@example.com",
+		},
+		{
+			"renders the email address and form",
+			input{
+				pattern: "{{form}}: {{email}}",
+				req:     &mailservice_v1.SendMailRequest{Email: "jane@example.com"},
+			},
+			"shop: jane@example.com",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &form{id: "shop", site: "Portfolio", forwardSubject: tt.input.pattern}
+			assert.Equal(t, tt.want, f.subjectLine(tt.input.req, "You have an inquiry"))
+		})
+	}
+}
+
+func TestSendMailSubjectUnit(t *testing.T) {
+	thankYou, forward := loadTemplates(t)
+
+	type input struct {
+		pattern string
+		subject string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  string
+	}{
+		{"renders the default pattern", input{pattern: "{{subject}} — {{name}}", subject: "Project"}, "Project — Jane Doe"},
+		{"renders the default pattern without a subject", input{pattern: "{{subject}} — {{name}}"}, "You have an inquiry — Jane Doe"},
+		{"renders the site", input{pattern: "[{{site}}] {{subject}} - {{name}}", subject: "Project"}, "[Portfolio] Project - Jane Doe"},
+		{"uses the template subject without a pattern", input{subject: "Project"}, "You have an inquiry"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &mockQueue{}
+			o := orchestrator{
+				outbox: outbox,
+				forms: map[string]*form{
+					DefaultFormID: {
+						id:             DefaultFormID,
+						thankYou:       thankYou,
+						forward:        forward,
+						forwardEmails:  []string{"owner@example.com"},
+						fromEmail:      "noreply@example.com",
+						site:           "Portfolio",
+						forwardSubject: tt.input.pattern,
+					},
+				},
+				defaultForm: DefaultFormID,
+				logger:      zap.NewNop(),
+			}
+
+			req := &mailservice_v1.SendMailRequest{Name: "Jane Doe", Email: "jane@example.com", Message: "Hello there"}
+			if tt.input.subject != "" {
+				req.Subject = &tt.input.subject
+			}
+
+			_, err := o.SendMail(context.Background(), req)
+			require.Empty(t, err)
+			require.Len(t, outbox.messages, 1)
+
+			forwarded := outbox.messages[0]
+			assert.Equal(t, tt.want, forwarded.Subject)
+			assert.Equal(t, []string{`"Jane Doe" <jane@example.com>`}, forwarded.ReplyTo)
+			require.NotEmpty(t, forwarded.Template, "the stored template is kept when the subject changes")
+
+			var data map[string]any
+			require.Empty(t, json.Unmarshal([]byte(forwarded.Template.Data), &data))
+			assert.Equal(t, tt.want, data["subject_line"])
+			assert.Equal(t, "Jane Doe", data["name"])
+
+			// AWS SES renders the subject of the stored template from the template data.
+			assert.Equal(t, tt.want, substitute(aws.ToString(forward.Content.Subject), data, false))
+		})
+	}
+}
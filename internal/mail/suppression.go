@@ -0,0 +1,170 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// suppressionList is an interface that defines the methods from the suppression list that are used by the Orchestrator
+// to skip suppressed recipients and to manage the list.
+type suppressionList interface {
+	Check(address string) (suppression.Entry, bool)
+	Add(ctx context.Context, e suppression.Entry) (suppression.Entry, error)
+	Remove(ctx context.Context, address string) error
+	List(q suppression.Query) ([]suppression.Entry, string, error)
+}
+
+// suppressionReasons maps the suppression list reasons to their API representation.
+var suppressionReasons = map[suppression.Reason]mailservice_v1.SuppressionReason{
+	suppression.ReasonBounce:    mailservice_v1.SuppressionReason_SUPPRESSION_REASON_BOUNCE,
+	suppression.ReasonComplaint: mailservice_v1.SuppressionReason_SUPPRESSION_REASON_COMPLAINT,
+	suppression.ReasonManual:    mailservice_v1.SuppressionReason_SUPPRESSION_REASON_MANUAL,
+}
+
+// deliverable returns the recipients that are not on the suppression list.
+//
+// Parameters:
+//   - to: The recipients of a message.
+//
+// Returns:
+//   - []string: The recipients that may be mailed.
+func (o orchestrator) deliverable(to []string) []string {
+	if o.suppressions == nil {
+		return to
+	}
+
+	out := make([]string, 0, len(to))
+	for _, addr := range to {
+		if _, ok := o.suppressions.Check(addr); ok {
+			continue
+		}
+		out = append(out, addr)
+	}
+
+	return out
+}
+
+// AddSuppression adds an address to the suppression list.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The AddSuppressionRequest object containing the address, reason and optional expiry.
+//
+// Returns:
+//   - *mailservice_v1.Suppression: The stored suppression.
+//   - error: An InvalidArgument error if the address is malformed or the expiry is in the past.
+func (o orchestrator) AddSuppression(ctx context.Context, req *mailservice_v1.AddSuppressionRequest) (*mailservice_v1.Suppression, error) {
+	e := suppression.Entry{
+		Address: req.Address,
+		Reason:  suppression.ReasonManual,
+		Detail:  req.Detail,
+	}
+
+	if req.Reason != mailservice_v1.SuppressionReason_SUPPRESSION_REASON_UNSPECIFIED {
+		for r, v := range suppressionReasons {
+			if v == req.Reason {
+				e.Reason = r
+			}
+		}
+	}
+
+	if req.ExpiresAt != nil {
+		if err := req.ExpiresAt.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid expires_at")
+		}
+		e.ExpiresAt = req.ExpiresAt.AsTime()
+		if !e.ExpiresAt.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+		}
+	}
+
+	e, err := o.suppressions.Add(ctx, e)
+	if err != nil {
+		if errors.Is(err, suppression.ErrInvalidAddress) {
+			return nil, status.Error(codes.InvalidArgument, "address must be a valid email address")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to add suppression: %v", err)
+	}
+
+	return toSuppression(e), nil
+}
+
+// RemoveSuppression removes an address from the suppression list.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The RemoveSuppressionRequest object containing the address.
+//
+// Returns:
+//   - *mailservice_v1.RemoveSuppressionResponse: An empty response.
+//   - error: A NotFound error if the address is not suppressed.
+func (o orchestrator) RemoveSuppression(ctx context.Context, req *mailservice_v1.RemoveSuppressionRequest) (*mailservice_v1.RemoveSuppressionResponse, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	if err := o.suppressions.Remove(ctx, req.Address); err != nil {
+		if errors.Is(err, suppression.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "address %q is not suppressed", req.Address)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to remove suppression: %v", err)
+	}
+
+	return &mailservice_v1.RemoveSuppressionResponse{}, nil
+}
+
+// ListSuppressions returns a page of suppressed addresses, ordered by address.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The ListSuppressionsRequest object containing the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListSuppressionsResponse: The page of suppressions and the token for the next page.
+//   - error: An InvalidArgument error if the page token is malformed.
+func (o orchestrator) ListSuppressions(ctx context.Context, req *mailservice_v1.ListSuppressionsRequest) (*mailservice_v1.ListSuppressionsResponse, error) {
+	entries, next, err := o.suppressions.List(suppression.Query{
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+	})
+	if err != nil {
+		if errors.Is(err, suppression.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to list suppressions: %v", err)
+	}
+
+	resp := &mailservice_v1.ListSuppressionsResponse{
+		NextPageToken: next,
+	}
+	for _, e := range entries {
+		resp.Suppressions = append(resp.Suppressions, toSuppression(e))
+	}
+
+	return resp, nil
+}
+
+func toSuppression(e suppression.Entry) *mailservice_v1.Suppression {
+	s := &mailservice_v1.Suppression{
+		Address:   e.Address,
+		Reason:    suppressionReasons[e.Reason],
+		Detail:    e.Detail,
+		CreatedAt: timestamppb.New(e.CreatedAt),
+	}
+
+	if !e.ExpiresAt.IsZero() {
+		s.ExpiresAt = timestamppb.New(e.ExpiresAt)
+	}
+
+	return s
+}
@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestAddSuppressionUnit(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	type want struct {
+		code   codes.Code
+		reason mailservice_v1.SuppressionReason
+	}
+
+	cases := []struct {
+		name  string
+		input *mailservice_v1.AddSuppressionRequest
+		want  want
+	}{
+		{
+			"handles invalid address",
+			&mailservice_v1.AddSuppressionRequest{Address: "not an address"},
+			want{code: codes.InvalidArgument},
+		},
+		{
+			"handles invalid expiry",
+			&mailservice_v1.AddSuppressionRequest{Address: "jane@example.com", ExpiresAt: &timestamppb.Timestamp{Nanos: -1}},
+			want{code: codes.InvalidArgument},
+		},
+		{
+			"handles past expiry",
+			&mailservice_v1.AddSuppressionRequest{Address: "jane@example.com", ExpiresAt: timestamppb.New(time.Now().Add(-time.Minute))},
+			want{code: codes.InvalidArgument},
+		},
+		{
+			"defaults to manual reason",
+			&mailservice_v1.AddSuppressionRequest{Address: "jane@example.com"},
+			want{code: codes.OK, reason: mailservice_v1.SuppressionReason_SUPPRESSION_REASON_MANUAL},
+		},
+		{
+			"is successful",
+			&mailservice_v1.AddSuppressionRequest{
+				Address:   "jane@example.com",
+				Reason:    mailservice_v1.SuppressionReason_SUPPRESSION_REASON_COMPLAINT,
+				Detail:    "reported via support",
+				ExpiresAt: timestamppb.New(expiresAt),
+			},
+			want{code: codes.OK, reason: mailservice_v1.SuppressionReason_SUPPRESSION_REASON_COMPLAINT},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			suppressions, err := suppression.New(suppression.Config{})
+			require.Empty(t, err)
+
+			o := orchestrator{suppressions: suppressions}
+
+			resp, err := o.AddSuppression(context.Background(), tt.input)
+			require.Equal(t, tt.want.code, status.Code(err))
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, "jane@example.com", resp.Address)
+			assert.Equal(t, tt.want.reason, resp.Reason)
+			assert.Equal(t, tt.input.Detail, resp.Detail)
+			assert.Equal(t, tt.input.ExpiresAt.AsTime(), resp.ExpiresAt.AsTime())
+			assert.Equal(t, []string{}, o.deliverable([]string{"jane@example.com"}))
+		})
+	}
+}
+
+func TestRemoveSuppressionUnit(t *testing.T) {
+	suppressions, err := suppression.New(suppression.Config{})
+	require.Empty(t, err)
+
+	_, err = suppressions.Add(context.Background(), suppression.Entry{Address: "jane@example.com"})
+	require.Empty(t, err)
+
+	cases := []struct {
+		name  string
+		input string
+		want  codes.Code
+	}{
+		{"handles missing address", "", codes.InvalidArgument},
+		{"handles address that is not suppressed", "john@example.com", codes.NotFound},
+		{"is successful", "jane@example.com", codes.OK},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			o := orchestrator{suppressions: suppressions}
+
+			_, err := o.RemoveSuppression(context.Background(), &mailservice_v1.RemoveSuppressionRequest{Address: tt.input})
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+
+	assert.Equal(t, []string{"jane@example.com"}, orchestrator{suppressions: suppressions}.deliverable([]string{"jane@example.com"}))
+}
+
+func TestListSuppressionsUnit(t *testing.T) {
+	suppressions, err := suppression.New(suppression.Config{})
+	require.Empty(t, err)
+
+	for _, addr := range []string{"c@example.com", "a@example.com", "b@example.com"} {
+		_, err := suppressions.Add(context.Background(), suppression.Entry{Address: addr, Reason: suppression.ReasonBounce})
+		require.Empty(t, err)
+	}
+
+	o := orchestrator{suppressions: suppressions}
+
+	first, err := o.ListSuppressions(context.Background(), &mailservice_v1.ListSuppressionsRequest{PageSize: 2})
+	require.Empty(t, err)
+	require.Len(t, first.Suppressions, 2)
+	assert.Equal(t, "a@example.com", first.Suppressions[0].Address)
+	assert.Equal(t, mailservice_v1.SuppressionReason_SUPPRESSION_REASON_BOUNCE, first.Suppressions[0].Reason)
+	assert.Nil(t, first.Suppressions[0].ExpiresAt)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, err := o.ListSuppressions(context.Background(), &mailservice_v1.ListSuppressionsRequest{PageSize: 2, PageToken: first.NextPageToken})
+	require.Empty(t, err)
+	require.Len(t, second.Suppressions, 1)
+	assert.Equal(t, "c@example.com", second.Suppressions[0].Address)
+	assert.Empty(t, second.NextPageToken)
+
+	_, err = o.ListSuppressions(context.Background(), &mailservice_v1.ListSuppressionsRequest{PageToken: "%%%"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
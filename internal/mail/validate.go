@@ -0,0 +1,122 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/address"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/sanitize"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxNameLength is the maximum length of the name of the submitter, in characters.
+	maxNameLength = 128
+	// maxSubjectLength is the maximum length of the subject of a submission, in characters.
+	maxSubjectLength = 200
+	// maxMessageLength is the maximum length of the message of a submission, in characters.
+	maxMessageLength = 20000
+	// maxDepartmentLength is the maximum length of the department selected by the submitter, in characters.
+	maxDepartmentLength = 64
+)
+
+// validateSubmission validates the fields of a submission and normalizes them in place, so every later step, from the
+// forward template to the auto-reply recipient, works with the normalized values. Fields that may end up in a header,
+// the name, the subject and the department, are cleaned with sanitize.Header, and the message with sanitize.Text, before their length
+// is checked. The custom fields are validated against the schema of the form, and reported as "fields.<name>".
+//
+// Parameters:
+//   - req: The mailservice_v1.SendMailRequest object to validate.
+//   - schema: The fields.Schema declaring the custom fields of the form. Custom fields are rejected when nil.
+//
+// Returns:
+//   - error: An InvalidArgument error with an errdetails.BadRequest describing every invalid field.
+func validateSubmission(req *mailservice_v1.SendMailRequest, schema *fields.Schema) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	req.Name = sanitize.Header(req.Name)
+	if utf8.RuneCountInString(req.Name) > maxNameLength {
+		violations = append(violations, tooLong("name", maxNameLength))
+	}
+
+	if req.Subject != nil {
+		subject := sanitize.Header(req.GetSubject())
+		req.Subject = &subject
+		if utf8.RuneCountInString(subject) > maxSubjectLength {
+			violations = append(violations, tooLong("subject", maxSubjectLength))
+		}
+	}
+
+	req.Department = sanitize.Header(req.Department)
+	if utf8.RuneCountInString(req.Department) > maxDepartmentLength {
+		violations = append(violations, tooLong("department", maxDepartmentLength))
+	}
+
+	req.Message = sanitize.Text(req.Message)
+	if utf8.RuneCountInString(req.Message) > maxMessageLength {
+		violations = append(violations, tooLong("message", maxMessageLength))
+	}
+
+	if strings.TrimSpace(req.Email) == "" {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "email", Description: "email is required"})
+	} else if email, err := address.Normalize(req.Email); err != nil {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "email", Description: describe(err)})
+	} else {
+		req.Email = email
+	}
+
+	for _, v := range schema.Validate(req.Fields) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "fields." + v.Field, Description: v.Description})
+	}
+
+	return invalidArgument(violations)
+}
+
+// tooLong describes a field that is longer than allowed.
+func tooLong(field string, max int) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf("%s must be at most %d characters", field, max),
+	}
+}
+
+// describe returns the reason of a validation error, e.g. "the domain is empty" for an address.ErrInvalid.
+func describe(err error) string {
+	if errors.Is(err, address.ErrInvalid) {
+		return "must be a valid email address: " + strings.TrimPrefix(err.Error(), address.ErrInvalid.Error()+": ")
+	}
+
+	return err.Error()
+}
+
+// invalidArgument builds the error returned for a request with invalid fields. The errdetails.BadRequest lets
+// clients, and the gateway, point the submitter to every field to correct.
+//
+// Parameters:
+//   - violations: The description of every invalid field.
+//
+// Returns:
+//   - error: An InvalidArgument error listing the violations, or nil when there are none.
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(violations))
+	for _, v := range violations {
+		descriptions = append(descriptions, v.Field+": "+v.Description)
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid submission: "+strings.Join(descriptions, "; "))
+	if withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
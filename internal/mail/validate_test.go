@@ -0,0 +1,199 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"strings"
+	"testing"
+	"unicode"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateSubmissionUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		email        string
+		violations   []string
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"normalizes email address",
+			" Jane@Bücher.example ",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				email: "jane@xn--bcher-kva.example",
+			},
+		},
+		{
+			"handles missing email address",
+			"",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, status.Convert(err).Message(), "email is required")
+				},
+				violations: []string{"email: email is required"},
+			},
+		},
+		{
+			"handles invalid email address",
+			"jane@localhost",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+				email:      "jane@localhost",
+				violations: []string{"email: must be a valid email address: the domain must be fully qualified"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &mailservice_v1.SendMailRequest{Email: tt.input}
+			err := validateSubmission(req, nil)
+			tt.want.errAssertion(t, err)
+			assert.Equal(t, tt.want.email, req.Email)
+
+			var violations []string
+			for _, d := range status.Convert(err).Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.FieldViolations {
+						violations = append(violations, v.Field+": "+v.Description)
+					}
+				}
+			}
+			assert.Equal(t, tt.want.violations, violations)
+		})
+	}
+}
+
+func TestValidateSubmissionSanitizesUnit(t *testing.T) {
+	subject := "Hi\r\nBcc: victim@example.com"
+	req := &mailservice_v1.SendMailRequest{
+		Name:       "Jane\x00 Doe\u202e",
+		Email:      "jane@example.com",
+		Subject:    &subject,
+		Message:    "Hello\r\nthere\x1b",
+		Department: " Sales\n",
+	}
+	require.Empty(t, validateSubmission(req, nil))
+	assert.Equal(t, "Jane Doe", req.Name)
+	assert.Equal(t, "Hi  Bcc: victim@example.com", req.GetSubject())
+	assert.Equal(t, "Hello\nthere", req.Message)
+	assert.Equal(t, "Sales", req.Department)
+
+	long := strings.Repeat("a", maxSubjectLength+1)
+	err := validateSubmission(&mailservice_v1.SendMailRequest{
+		Name:       strings.Repeat("\u00e9", maxNameLength),
+		Email:      "jane@example.com",
+		Subject:    &long,
+		Message:    strings.Repeat("a", maxMessageLength+1),
+		Department: strings.Repeat("a", maxDepartmentLength+1),
+	}, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "invalid submission: subject: subject must be at most 200 characters; department: department must be at most 64 characters; message: message must be at most 20000 characters", status.Convert(err).Message())
+}
+
+func TestSendMailNormalizesEmailUnit(t *testing.T) {
+	thankYou, forward := loadTemplates(t)
+
+	outbox := &mockQueue{}
+	o := orchestrator{
+		outbox: outbox,
+		forms: map[string]*form{
+			DefaultFormID: {
+				id:            DefaultFormID,
+				thankYou:      thankYou,
+				forward:       forward,
+				forwardEmails: []string{"owner@example.com"},
+				fromEmail:     "noreply@example.com",
+				autoReply:     AutoReplyConfig{Enabled: true},
+				replyLimiter:  newReplyLimiter(0, 0),
+			},
+		},
+		defaultForm: DefaultFormID,
+		logger:      zap.NewNop(),
+	}
+
+	_, err := o.SendMail(context.Background(), &mailservice_v1.SendMailRequest{Name: "Jane", Email: " Jane@Example.COM ", Message: "Hello there"})
+	require.Empty(t, err)
+	require.Len(t, outbox.messages, 2)
+	assert.Equal(t, "Name: Jane\nFrom: jane@example.com\nSubject: \n\nHello there", outbox.messages[0].Text)
+	assert.Equal(t, []string{"jane@example.com"}, outbox.messages[1].To)
+}
+
+func FuzzSendMail(f *testing.F) {
+	f.Add("Jane", "jane@example.com", "Project inquiry", "Hello there")
+	f.Add("Jane\r\nBcc: victim@example.com", "jane@example.com\r\nBcc: victim@example.com", "Hi\r\nBcc: victim@example.com", "Hello\r\n\r\nBcc: victim@example.com")
+	f.Add("Jane\u2028Cc: x@example.com", "jane@example.com", "Hi\x00\x1b", "<script>alert(1)</script>")
+	f.Add("J\u202eane", "\"jane\\\r\"@example.com", "\u2029To: x@example.com", "")
+
+	thankYou, forward := loadTemplates(f)
+
+	f.Fuzz(func(t *testing.T, name, email, subject, message string) {
+		outbox := &mockQueue{}
+		o := orchestrator{
+			outbox: outbox,
+			forms: map[string]*form{
+				DefaultFormID: {
+					id:            DefaultFormID,
+					thankYou:      thankYou,
+					forward:       forward,
+					forwardEmails: []string{"owner@example.com"},
+					fromEmail:     "noreply@example.com",
+					autoReply:     AutoReplyConfig{Enabled: true},
+					replyLimiter:  newReplyLimiter(0, 0),
+				},
+			},
+			defaultForm: DefaultFormID,
+			logger:      zap.NewNop(),
+		}
+
+		req := &mailservice_v1.SendMailRequest{Name: name, Email: email, Subject: &subject, Message: message}
+		if _, err := o.SendMail(context.Background(), req); err != nil {
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+
+		for _, msg := range outbox.messages {
+			for _, v := range append(append([]string{msg.From, msg.Subject}, msg.To...), msg.ReplyTo...) {
+				if strings.IndexFunc(v, unicode.IsControl) >= 0 {
+					t.Fatalf("header value %q contains a control character", v)
+				}
+			}
+
+			var data map[string]any
+			if err := json.Unmarshal([]byte(msg.Template.Data), &data); err != nil {
+				t.Fatalf("invalid template data %q: %v", msg.Template.Data, err)
+			}
+			for key, v := range data {
+				if s, ok := v.(string); ok && key != "text" && strings.IndexFunc(s, unicode.IsControl) >= 0 {
+					t.Fatalf("template data %s = %q contains a control character", key, v)
+				}
+			}
+
+		}
+
+		if forwarded := outbox.messages[0]; !strings.Contains(forwarded.HTML, html.EscapeString(req.Message)) {
+			t.Fatalf("message %q is not escaped in the html part:\n%s", req.Message, forwarded.HTML)
+		}
+	})
+}
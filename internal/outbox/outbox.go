@@ -0,0 +1,719 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/store"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"go.uber.org/zap"
+)
+
+// State is the delivery state of a message in the outbox.
+type State string
+
+const (
+	// StateQueued means the message is waiting to be delivered, either for the first time or for a retry.
+	StateQueued State = "queued"
+	// StateSent means the message was accepted by the transport.
+	StateSent State = "sent"
+	// StateFailed means every delivery attempt failed and the message was moved to the dead-letter state.
+	StateFailed State = "failed"
+	// StateDelivered means the provider reported that the message was delivered to the recipient's mail server.
+	StateDelivered State = "delivered"
+	// StateBounced means the provider reported that the recipient's mail server rejected the message.
+	StateBounced State = "bounced"
+	// StateComplained means the provider reported that the recipient marked the message as spam.
+	StateComplained State = "complained"
+)
+
+// EventType is the type of a delivery event reported by the email provider after a message was sent.
+type EventType string
+
+const (
+	// EventDelivery is reported when the message was delivered to the recipient's mail server.
+	EventDelivery EventType = "Delivery"
+	// EventBounce is reported when the recipient's mail server rejected the message.
+	EventBounce EventType = "Bounce"
+	// EventComplaint is reported when the recipient marked the message as spam.
+	EventComplaint EventType = "Complaint"
+	// EventOpen is reported when the recipient opened the message.
+	EventOpen EventType = "Open"
+	// EventClick is reported when the recipient clicked a link in the message.
+	EventClick EventType = "Click"
+)
+
+// Event is a delivery event reported by the email provider for a sent message.
+//
+// Fields:
+//   - Type: The type of the event.
+//   - Timestamp: When the event occurred according to the provider.
+//   - Recipients: The recipients the event applies to.
+//   - Detail: A short human readable description, e.g. the bounce type or the clicked link.
+type Event struct {
+	Type       EventType
+	Timestamp  time.Time
+	Recipients []string
+	Detail     string
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	// defaultAttemptTimeout bounds a delivery attempt when the configuration does not.
+	defaultAttemptTimeout = time.Minute
+	// pruneInterval is how often records past the retention period are removed.
+	pruneInterval = time.Minute
+	// pendingEventWindow is how long an event for an unknown provider message ID is held, as the provider may report
+	// events before the outbox recorded the ID of the message it just sent.
+	pendingEventWindow = 5 * time.Minute
+)
+
+// pendingEvent is an event held until the message it was reported for is recorded as sent.
+type pendingEvent struct {
+	event      Event
+	receivedAt time.Time
+}
+
+var (
+	// ErrNotFound is returned when a message does not exist in the outbox.
+	ErrNotFound = errors.New("message not found")
+	// ErrInvalidPageToken is returned when a page token passed to List is malformed.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// Record is a message stored in the outbox along with its delivery state.
+//
+// Fields:
+//   - ID: The ID assigned to the message by the outbox.
+//   - Message: The message to deliver. The content of its attachments is stored apart from the record, so records
+//     stay small enough to be kept in memory, and is removed once the message is sent.
+//   - State: The current delivery state.
+//   - Attempts: The number of delivery attempts made so far.
+//   - NextAttemptAt: When the next delivery attempt is due.
+//   - LastError: The error returned by the most recent failed delivery attempt.
+//   - ProviderMessageID: The ID the transport assigned to the message once it was sent.
+//   - Events: The delivery events reported by the provider after the message was sent.
+//   - CreatedAt: When the message was enqueued.
+//   - UpdatedAt: When the record was last changed.
+type Record struct {
+	ID                string
+	Message           transport.Message
+	State             State
+	Attempts          int
+	NextAttemptAt     time.Time
+	LastError         string
+	ProviderMessageID string
+	Events            []Event
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// RetryPolicy controls how failed deliveries are retried.
+//
+// Fields:
+//   - MaxAttempts: The maximum number of delivery attempts before a message is dead-lettered.
+//   - InitialBackoff: The delay before the first retry. Every following retry doubles the delay.
+//   - MaxBackoff: The upper bound for the delay between two attempts.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Config holds the configuration required to initialize the Outbox.
+//
+// Fields:
+//   - Dir: The directory the outbox is persisted in. An empty directory keeps the outbox in memory.
+//   - Transport: The transport.Transport used to deliver messages.
+//   - Workers: The number of concurrent delivery workers.
+//   - PollInterval: How often the outbox looks for messages that are due for delivery.
+//   - AttemptTimeout: How long a single delivery attempt may take before it is abandoned and retried. Defaults to a minute.
+//   - Retention: How long sent and dead-lettered messages are kept after their last change. They are kept forever when zero.
+//   - Retry: The RetryPolicy applied to failed deliveries.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Dir            string
+	Transport      transport.Transport
+	Workers        int
+	PollInterval   time.Duration
+	AttemptTimeout time.Duration
+	Retention      time.Duration
+	Retry          RetryPolicy
+	Logger         *zap.Logger
+}
+
+// Outbox is a durable, file-backed queue of outgoing messages.
+// Messages are persisted when they are enqueued and delivered asynchronously by a pool of workers,
+// which retry failed deliveries with exponential backoff and jitter until the retry policy is exhausted.
+// Sent and dead-lettered messages are removed once the retention period has passed.
+type Outbox struct {
+	records        *store.Collection[Record]
+	attachments    *store.Blobs
+	transport      transport.Transport
+	workers        int
+	poll           time.Duration
+	attemptTimeout time.Duration
+	retention      time.Duration
+	retry          RetryPolicy
+	logger         *zap.Logger
+
+	mu        sync.Mutex
+	inflight  map[string]struct{}
+	pending   map[string][]pendingEvent
+	wake      chan struct{}
+	now       func() time.Time
+	lastPrune time.Time
+}
+
+// New creates a new Outbox and loads any messages persisted by a previous run.
+//
+// Parameters:
+//   - cfg: The Config object containing the storage directory, transport, worker count and retry policy.
+//
+// Returns:
+//   - *Outbox: The newly created Outbox instance.
+//   - error: An error if the persisted messages could not be loaded.
+func New(cfg Config) (*Outbox, error) {
+	records, err := store.Open[Record](cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+
+	attachmentsDir := ""
+	if cfg.Dir != "" {
+		attachmentsDir = filepath.Join(cfg.Dir, "attachments")
+	}
+
+	attachments, err := store.OpenBlobs(attachmentsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox attachments: %w", err)
+	}
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = defaultAttemptTimeout
+	}
+
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = 1
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	return &Outbox{
+		records:        records,
+		attachments:    attachments,
+		transport:      cfg.Transport,
+		workers:        cfg.Workers,
+		poll:           cfg.PollInterval,
+		attemptTimeout: cfg.AttemptTimeout,
+		retention:      cfg.Retention,
+		retry:          cfg.Retry,
+		logger:         cfg.Logger,
+		inflight:       map[string]struct{}{},
+		pending:        map[string][]pendingEvent{},
+		wake:           make(chan struct{}, 1),
+		now:            time.Now,
+	}, nil
+}
+
+// Enqueue persists the message and schedules it for immediate delivery.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - msg: The message to deliver.
+//
+// Returns:
+//   - string: The ID assigned to the message.
+//   - error: An error if the message could not be persisted.
+func (o *Outbox) Enqueue(ctx context.Context, msg *transport.Message) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	stored := *msg
+	if stored.Attachments, err = o.storeAttachments(id, msg.Attachments); err != nil {
+		return "", err
+	}
+
+	now := o.now()
+	if err := o.records.Put(id, Record{
+		ID:            id,
+		Message:       stored,
+		State:         StateQueued,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}); err != nil {
+		o.deleteAttachments(id, len(stored.Attachments))
+		return "", fmt.Errorf("failed to persist message: %w", err)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return id, nil
+}
+
+// Get returns the outbox record for the given message ID.
+//
+// Parameters:
+//   - id: The ID of the message.
+//
+// Returns:
+//   - Record: The outbox record.
+//   - error: ErrNotFound if the message does not exist.
+func (o *Outbox) Get(id string) (Record, error) {
+	r, err := o.records.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return r, ErrNotFound
+	}
+
+	return r, err
+}
+
+// RecordEvent applies a delivery event reported by the provider to the message it was reported for.
+// Delivery, bounce and complaint events advance the state of the message; a delivery never overrides a bounce or
+// complaint, and a bounce never overrides a complaint. Open and click events are only added to the event history.
+// Events for an unknown provider message ID are held for a few minutes, and applied if a message is recorded as sent
+// with that ID in the meantime, as the provider may report them before the outbox recorded the ID.
+//
+// Parameters:
+//   - providerMessageID: The ID the provider assigned to the message.
+//   - e: The event to apply.
+//
+// Returns:
+//   - Record: The updated record.
+//   - error: ErrNotFound if no message was sent with the given provider message ID yet.
+func (o *Outbox) RecordEvent(providerMessageID string, e Event) (Record, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	matches := o.records.List(func(_ string, r Record) bool {
+		return providerMessageID != "" && r.ProviderMessageID == providerMessageID
+	})
+	if len(matches) == 0 {
+		if providerMessageID != "" {
+			o.holdEvent(providerMessageID, e)
+		}
+		return Record{}, ErrNotFound
+	}
+
+	r := applyEvent(matches[0], e)
+	r.UpdatedAt = o.now()
+	if err := o.records.Put(r.ID, r); err != nil {
+		return Record{}, fmt.Errorf("failed to persist message event: %w", err)
+	}
+
+	return r, nil
+}
+
+// holdEvent holds an event for an unknown provider message ID and drops the held events that are too old. The caller
+// must hold o.mu.
+func (o *Outbox) holdEvent(providerMessageID string, e Event) {
+	now := o.now()
+	for id, events := range o.pending {
+		if now.Sub(events[0].receivedAt) >= pendingEventWindow {
+			delete(o.pending, id)
+		}
+	}
+
+	o.pending[providerMessageID] = append(o.pending[providerMessageID], pendingEvent{event: e, receivedAt: now})
+}
+
+// applyEvent applies a delivery event to a record, see RecordEvent.
+func applyEvent(r Record, e Event) Record {
+	switch e.Type {
+	case EventDelivery:
+		if r.State == StateSent {
+			r.State = StateDelivered
+		}
+	case EventBounce:
+		if r.State != StateComplained {
+			r.State = StateBounced
+		}
+	case EventComplaint:
+		r.State = StateComplained
+	}
+
+	r.Events = append(r.Events, e)
+	return r
+}
+
+// Query filters and paginates the messages returned by List.
+//
+// Fields:
+//   - State: Only return messages in this state. An empty state returns messages in every state.
+//   - PageSize: The maximum number of messages to return. Defaults to 50, capped at 500.
+//   - PageToken: The token returned by a previous call to continue listing from.
+type Query struct {
+	State     State
+	PageSize  int
+	PageToken string
+}
+
+// List returns the messages matching the query, newest first.
+//
+// Parameters:
+//   - q: The Query used to filter and paginate the messages.
+//
+// Returns:
+//   - []Record: The page of matching messages.
+//   - string: The token to pass to the next call, or an empty string when there are no more messages.
+//   - error: ErrInvalidPageToken if the page token is malformed.
+func (o *Outbox) List(q Query) ([]Record, string, error) {
+	pageSize := q.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	var (
+		after    time.Time
+		afterID  string
+		hasAfter bool
+	)
+	if q.PageToken != "" {
+		var err error
+		after, afterID, err = decodePageToken(q.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		hasAfter = true
+	}
+
+	records := o.records.List(func(_ string, r Record) bool {
+		if q.State != "" && r.State != q.State {
+			return false
+		}
+
+		return !hasAfter || newerFirst(after, afterID, r.CreatedAt, r.ID)
+	})
+
+	sort.Slice(records, func(i, j int) bool {
+		return newerFirst(records[i].CreatedAt, records[i].ID, records[j].CreatedAt, records[j].ID)
+	})
+
+	if len(records) <= pageSize {
+		return records, "", nil
+	}
+
+	records = records[:pageSize]
+	last := records[len(records)-1]
+	return records, encodePageToken(last.CreatedAt, last.ID), nil
+}
+
+// DeadLetters returns every message that exhausted its delivery attempts.
+//
+// Returns:
+//   - []Record: The dead-lettered messages.
+func (o *Outbox) DeadLetters() []Record {
+	return o.records.List(func(_ string, r Record) bool {
+		return r.State == StateFailed
+	})
+}
+
+// Run starts the delivery workers and blocks until the context is cancelled.
+// Messages left queued by a previous run are picked up again, so delivery is at-least-once.
+//
+// Parameters:
+//   - ctx: The context.Context object controlling the lifetime of the workers.
+func (o *Outbox) Run(ctx context.Context) {
+	jobs := make(chan Record)
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				o.deliver(ctx, r)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(o.poll)
+	defer ticker.Stop()
+
+	for {
+		o.maybePrune()
+
+		for _, r := range o.due() {
+			select {
+			case jobs <- r:
+			case <-ctx.Done():
+				close(jobs)
+				wg.Wait()
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// due returns the queued messages whose next attempt is due and marks them as in flight.
+func (o *Outbox) due() []Record {
+	now := o.now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	records := o.records.List(func(id string, r Record) bool {
+		_, busy := o.inflight[id]
+		return !busy && r.State == StateQueued && !r.NextAttemptAt.After(now)
+	})
+
+	for _, r := range records {
+		o.inflight[r.ID] = struct{}{}
+	}
+
+	return records
+}
+
+// deliver attempts to send a single message and records the outcome. The record is read again once the attempt is
+// over, as delivery events may have been recorded for it in the meantime.
+func (o *Outbox) deliver(ctx context.Context, r Record) {
+	defer func() {
+		o.mu.Lock()
+		delete(o.inflight, r.ID)
+		o.mu.Unlock()
+	}()
+
+	logger := o.logger.With(zap.String("message_id", r.ID), zap.Strings("to", r.Message.To))
+
+	providerID, sendErr := o.send(ctx, r)
+	now := o.now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	r, err := o.records.Get(r.ID)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("Failed to load outbox record.")
+		return
+	}
+
+	r.Attempts++
+	r.UpdatedAt = now
+
+	switch {
+	case sendErr == nil:
+		r.State = StateSent
+		r.ProviderMessageID = providerID
+		r.LastError = ""
+		for _, p := range o.pending[providerID] {
+			r = applyEvent(r, p.event)
+		}
+		delete(o.pending, providerID)
+		logger.Info("Email sent", zap.String("provider_message_id", providerID), zap.Int("attempts", r.Attempts))
+		if err := o.deleteAttachments(r.ID, len(r.Message.Attachments)); err != nil {
+			logger.With(zap.Error(err)).Warn("Failed to delete attachments of sent email.")
+		}
+	case r.Attempts >= o.retry.MaxAttempts:
+		r.State = StateFailed
+		r.LastError = sendErr.Error()
+		logger.With(zap.Error(sendErr), zap.Int("attempts", r.Attempts)).Error("Email delivery failed permanently, moved to dead-letter state.")
+	default:
+		r.LastError = sendErr.Error()
+		r.NextAttemptAt = now.Add(o.backoff(r.Attempts))
+		logger.With(zap.Error(sendErr), zap.Int("attempts", r.Attempts), zap.Time("next_attempt_at", r.NextAttemptAt)).Warn("Email delivery failed, will retry.")
+	}
+
+	if err := o.records.Put(r.ID, r); err != nil {
+		logger.With(zap.Error(err)).Error("Failed to persist outbox record.")
+	}
+}
+
+// send makes a single delivery attempt, bounded by the attempt timeout so a stalled provider does not block a worker.
+func (o *Outbox) send(ctx context.Context, r Record) (string, error) {
+	msg := r.Message
+	msg.Attachments = make([]transport.Attachment, len(r.Message.Attachments))
+	for i, a := range r.Message.Attachments {
+		// Records persisted before the content was stored apart carry it inline.
+		if a.Data == nil {
+			data, err := o.attachments.Get(attachmentID(r.ID, i))
+			if err != nil {
+				return "", fmt.Errorf("failed to load attachment %q: %w", a.Filename, err)
+			}
+			a.Data = data
+		}
+		msg.Attachments[i] = a
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.attemptTimeout)
+	defer cancel()
+
+	return o.transport.Send(ctx, &msg)
+}
+
+// storeAttachments stores the content of the attachments of a message and returns them without it.
+func (o *Outbox) storeAttachments(id string, attachments []transport.Attachment) ([]transport.Attachment, error) {
+	if len(attachments) == 0 {
+		return attachments, nil
+	}
+
+	stored := make([]transport.Attachment, len(attachments))
+	for i, a := range attachments {
+		if err := o.attachments.Put(attachmentID(id, i), a.Data); err != nil {
+			o.deleteAttachments(id, i)
+			return nil, fmt.Errorf("failed to persist attachment %q: %w", a.Filename, err)
+		}
+
+		a.Data = nil
+		stored[i] = a
+	}
+
+	return stored, nil
+}
+
+// deleteAttachments deletes the content of the first n attachments of a message.
+func (o *Outbox) deleteAttachments(id string, n int) error {
+	var errs []error
+	for i := 0; i < n; i++ {
+		if err := o.attachments.Delete(attachmentID(id, i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// maybePrune prunes the outbox when the prune interval has passed since the last run.
+func (o *Outbox) maybePrune() {
+	if now := o.now(); now.Sub(o.lastPrune) >= pruneInterval {
+		o.lastPrune = now
+		o.prune(now)
+	}
+}
+
+// prune removes the messages that are no longer queued and have not changed within the retention period.
+func (o *Outbox) prune(now time.Time) {
+	if o.retention <= 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	expired := o.records.List(func(_ string, r Record) bool {
+		return r.State != StateQueued && now.Sub(r.UpdatedAt) >= o.retention
+	})
+
+	for _, r := range expired {
+		logger := o.logger.With(zap.String("message_id", r.ID))
+		if err := o.deleteAttachments(r.ID, len(r.Message.Attachments)); err != nil {
+			logger.With(zap.Error(err)).Warn("Failed to delete attachments of expired email.")
+			continue
+		}
+
+		if err := o.records.Delete(r.ID); err != nil {
+			logger.With(zap.Error(err)).Warn("Failed to delete expired email.")
+		}
+	}
+
+	if len(expired) > 0 {
+		o.logger.Info("Pruned expired emails from the outbox.", zap.Int("count", len(expired)))
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of failed attempts.
+// The delay grows exponentially from the initial backoff up to the maximum backoff, and half of it
+// is randomised ("equal jitter") so retries from many messages do not line up.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.retry.InitialBackoff
+	for i := 1; i < attempts && (o.retry.MaxBackoff <= 0 || d < o.retry.MaxBackoff); i++ {
+		d *= 2
+	}
+
+	if o.retry.MaxBackoff > 0 && d > o.retry.MaxBackoff {
+		d = o.retry.MaxBackoff
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+	return half + time.Duration(mrand.Int64N(int64(d-half)))
+}
+
+// newerFirst reports whether the record (at, aID) sorts before the record (bt, bID) in a newest first listing.
+func newerFirst(at time.Time, aID string, bt time.Time, bID string) bool {
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+
+	return aID < bID
+}
+
+func encodePageToken(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id))
+}
+
+func decodePageToken(token string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	ts, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	return time.Unix(0, nanos), id, nil
+}
+
+// attachmentID returns the ID the content of the i-th attachment of a message is stored under.
+func attachmentID(id string, i int) string {
+	return id + "/" + strconv.Itoa(i)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,472 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/store"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxDeliveryUnit(t *testing.T) {
+	type input struct {
+		sendErrors  []string
+		maxAttempts int
+	}
+
+	type want struct {
+		state             State
+		attempts          int
+		lastError         string
+		providerMessageID string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"is successful on first attempt",
+			input{maxAttempts: 3},
+			want{state: StateSent, attempts: 1, providerMessageID: "provider-1"},
+		},
+		{
+			"retries until successful",
+			input{sendErrors: []string{"throttled", "throttled"}, maxAttempts: 3},
+			want{state: StateSent, attempts: 3, providerMessageID: "provider-3"},
+		},
+		{
+			"moves message to dead-letter state after max attempts",
+			input{sendErrors: []string{"throttled", "throttled", "rejected"}, maxAttempts: 3},
+			want{state: StateFailed, attempts: 3, lastError: "rejected"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := &mockTransport{errors: tt.input.sendErrors}
+			o, err := New(Config{
+				Transport:    mt,
+				Workers:      2,
+				PollInterval: time.Millisecond,
+				Retry: RetryPolicy{
+					MaxAttempts:    tt.input.maxAttempts,
+					InitialBackoff: time.Millisecond,
+					MaxBackoff:     2 * time.Millisecond,
+				},
+			})
+			require.Empty(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go o.Run(ctx)
+
+			id, err := o.Enqueue(ctx, &transport.Message{To: []string{"owner@example.com"}})
+			require.Empty(t, err)
+
+			require.Eventually(t, func() bool {
+				r, err := o.Get(id)
+				return err == nil && r.State != StateQueued
+			}, time.Second, time.Millisecond)
+
+			r, err := o.Get(id)
+			require.Empty(t, err)
+			assert.Equal(t, tt.want.state, r.State)
+			assert.Equal(t, tt.want.attempts, r.Attempts)
+			assert.Equal(t, tt.want.lastError, r.LastError)
+			assert.Equal(t, tt.want.providerMessageID, r.ProviderMessageID)
+
+			if tt.want.state == StateFailed {
+				assert.Len(t, o.DeadLetters(), 1)
+			} else {
+				assert.Empty(t, o.DeadLetters())
+			}
+		})
+	}
+}
+
+func TestOutboxResumesPersistedMessagesUnit(t *testing.T) {
+	dir := t.TempDir()
+
+	o, err := New(Config{Dir: dir, Transport: &mockTransport{}})
+	require.Empty(t, err)
+
+	// Enqueue without running the workers, simulating a restart before delivery.
+	id, err := o.Enqueue(context.Background(), &transport.Message{To: []string{"owner@example.com"}})
+	require.Empty(t, err)
+
+	mt := &mockTransport{}
+	restarted, err := New(Config{Dir: dir, Transport: mt, PollInterval: time.Millisecond})
+	require.Empty(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go restarted.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		r, err := restarted.Get(id)
+		return err == nil && r.State == StateSent
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, mt.calls())
+}
+
+func TestOutboxAttachmentsUnit(t *testing.T) {
+	mt := &mockTransport{}
+	o, err := New(Config{Dir: t.TempDir(), Transport: mt, PollInterval: time.Millisecond})
+	require.Empty(t, err)
+
+	id, err := o.Enqueue(context.Background(), &transport.Message{
+		To:          []string{"owner@example.com"},
+		Attachments: []transport.Attachment{{Filename: "resume.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}},
+	})
+	require.Empty(t, err)
+
+	r, err := o.Get(id)
+	require.Empty(t, err)
+	require.Len(t, r.Message.Attachments, 1)
+	assert.Equal(t, "resume.pdf", r.Message.Attachments[0].Filename)
+	assert.Nil(t, r.Message.Attachments[0].Data, "the content is not kept in memory")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		r, err := o.Get(id)
+		return err == nil && r.State == StateSent
+	}, time.Second, time.Millisecond)
+
+	mt.mu.Lock()
+	require.Len(t, mt.sent, 1)
+	assert.Equal(t, []byte("%PDF-1.4"), mt.sent[0].Attachments[0].Data)
+	mt.mu.Unlock()
+
+	_, err = o.attachments.Get(attachmentID(id, 0))
+	assert.ErrorIs(t, err, store.ErrNotFound, "the content is deleted once sent")
+}
+
+type blockingTransport struct{}
+
+func (blockingTransport) Send(ctx context.Context, _ *transport.Message) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestOutboxAttemptTimeoutUnit(t *testing.T) {
+	o, err := New(Config{
+		Transport:      blockingTransport{},
+		PollInterval:   time.Millisecond,
+		AttemptTimeout: 10 * time.Millisecond,
+		Retry:          RetryPolicy{MaxAttempts: 1},
+	})
+	require.Empty(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	id, err := o.Enqueue(ctx, &transport.Message{To: []string{"owner@example.com"}})
+	require.Empty(t, err)
+
+	require.Eventually(t, func() bool {
+		r, err := o.Get(id)
+		return err == nil && r.State == StateFailed
+	}, time.Second, time.Millisecond)
+
+	r, err := o.Get(id)
+	require.Empty(t, err)
+	assert.Contains(t, r.LastError, "deadline exceeded")
+}
+
+func TestOutboxPruneUnit(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		retention time.Duration
+		want      []string
+	}{
+		{"removes expired messages that are not queued", 7 * 24 * time.Hour, []string{"fresh", "queued"}},
+		{"keeps every message without retention", 0, []string{"bounced", "failed", "fresh", "queued", "sent"}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(Config{Transport: &mockTransport{}, Retention: tt.retention})
+			require.Empty(t, err)
+
+			for id, r := range map[string]Record{
+				"sent":    {State: StateSent, UpdatedAt: now.Add(-8 * 24 * time.Hour)},
+				"bounced": {State: StateBounced, UpdatedAt: now.Add(-30 * 24 * time.Hour)},
+				"failed":  {State: StateFailed, UpdatedAt: now.Add(-7 * 24 * time.Hour), Message: transport.Message{Attachments: []transport.Attachment{{Filename: "a.pdf"}}}},
+				"fresh":   {State: StateDelivered, UpdatedAt: now.Add(-time.Hour)},
+				"queued":  {State: StateQueued, UpdatedAt: now.Add(-30 * 24 * time.Hour)},
+			} {
+				r.ID = id
+				require.Empty(t, o.records.Put(id, r))
+			}
+			require.Empty(t, o.attachments.Put(attachmentID("failed", 0), []byte("%PDF-1.4")))
+
+			o.prune(now)
+
+			var ids []string
+			for _, r := range o.records.List(nil) {
+				ids = append(ids, r.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+
+			_, err = o.attachments.Get(attachmentID("failed", 0))
+			if tt.retention > 0 {
+				assert.ErrorIs(t, err, store.ErrNotFound)
+			} else {
+				assert.Empty(t, err)
+			}
+		})
+	}
+}
+
+func TestOutboxBackoffUnit(t *testing.T) {
+	o := &Outbox{retry: RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}}
+
+	for attempts, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		d := o.backoff(attempts)
+		assert.GreaterOrEqual(t, d, max/2)
+		assert.LessOrEqual(t, d, max)
+	}
+}
+
+func TestOutboxGetUnit(t *testing.T) {
+	o, err := New(Config{Transport: &mockTransport{}})
+	require.Empty(t, err)
+
+	_, err = o.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+type mockTransport struct {
+	mu     sync.Mutex
+	errors []string
+	n      int
+	sent   []transport.Message
+}
+
+func (m *mockTransport) Send(ctx context.Context, msg *transport.Message) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.n++
+	m.sent = append(m.sent, *msg)
+	if len(m.errors) > 0 {
+		err := m.errors[0]
+		m.errors = m.errors[1:]
+		if err != "" {
+			return "", errors.New(err)
+		}
+	}
+
+	return fmt.Sprintf("provider-%d", m.n), nil
+}
+
+func (m *mockTransport) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.n
+}
+
+func TestOutboxListUnit(t *testing.T) {
+	o, err := New(Config{Transport: &mockTransport{}})
+	require.Empty(t, err)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, state := range []State{StateSent, StateQueued, StateSent, StateFailed, StateSent} {
+		require.Empty(t, o.records.Put(fmt.Sprintf("m%d", i), Record{
+			ID:        fmt.Sprintf("m%d", i),
+			State:     state,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	var ids []string
+	token := ""
+	for {
+		page, next, err := o.List(Query{State: StateSent, PageSize: 2, PageToken: token})
+		require.Empty(t, err)
+		for _, r := range page {
+			ids = append(ids, r.ID)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"m4", "m2", "m0"}, ids)
+
+	all, next, err := o.List(Query{})
+	require.Empty(t, err)
+	assert.Len(t, all, 5)
+	assert.Empty(t, next)
+
+	_, _, err = o.List(Query{PageToken: "not-a-token"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestOutboxRecordEventUnit(t *testing.T) {
+	type input struct {
+		state  State
+		events []EventType
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  State
+	}{
+		{
+			"marks sent message delivered",
+			input{state: StateSent, events: []EventType{EventDelivery}},
+			StateDelivered,
+		},
+		{
+			"marks delivered message bounced",
+			input{state: StateSent, events: []EventType{EventDelivery, EventBounce}},
+			StateBounced,
+		},
+		{
+			"does not override bounce with delivery",
+			input{state: StateSent, events: []EventType{EventBounce, EventDelivery}},
+			StateBounced,
+		},
+		{
+			"does not override complaint with bounce",
+			input{state: StateSent, events: []EventType{EventComplaint, EventBounce}},
+			StateComplained,
+		},
+		{
+			"only records opens and clicks",
+			input{state: StateDelivered, events: []EventType{EventOpen, EventClick}},
+			StateDelivered,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(Config{Transport: &mockTransport{}})
+			require.Empty(t, err)
+			require.Empty(t, o.records.Put("m1", Record{ID: "m1", State: tt.input.state, ProviderMessageID: "provider-1"}))
+
+			for _, e := range tt.input.events {
+				_, err := o.RecordEvent("provider-1", Event{Type: e})
+				require.Empty(t, err)
+			}
+
+			r, err := o.Get("m1")
+			require.Empty(t, err)
+			assert.Equal(t, tt.want, r.State)
+			assert.Len(t, r.Events, len(tt.input.events))
+		})
+	}
+
+	o, err := New(Config{Transport: &mockTransport{}})
+	require.Empty(t, err)
+
+	_, err = o.RecordEvent("unknown", Event{Type: EventDelivery})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+type reportingTransport struct {
+	o      *Outbox
+	events []EventType
+}
+
+func (t *reportingTransport) Send(_ context.Context, _ *transport.Message) (string, error) {
+	// The provider reports the events before the outbox recorded the ID of the message.
+	for _, e := range t.events {
+		if _, err := t.o.RecordEvent("provider-1", Event{Type: e}); !errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	return "provider-1", nil
+}
+
+func TestOutboxEventsDuringSendUnit(t *testing.T) {
+	type input struct {
+		during  []EventType
+		held    []EventType
+		elapsed time.Duration
+	}
+
+	type want struct {
+		state  State
+		events int
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"applies event reported during send",
+			input{during: []EventType{EventDelivery}},
+			want{state: StateDelivered, events: 1},
+		},
+		{
+			"applies events in order",
+			input{during: []EventType{EventDelivery, EventComplaint}},
+			want{state: StateComplained, events: 2},
+		},
+		{
+			"applies events held within the window",
+			input{held: []EventType{EventDelivery}, elapsed: time.Minute},
+			want{state: StateDelivered, events: 1},
+		},
+		{
+			"drops events held too long",
+			input{held: []EventType{EventDelivery}, elapsed: pendingEventWindow},
+			want{state: StateSent},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			tr := &reportingTransport{events: tt.input.during}
+			o, err := New(Config{Transport: tr})
+			require.Empty(t, err)
+			o.now = func() time.Time { return now }
+			tr.o = o
+
+			id, err := o.Enqueue(context.Background(), &transport.Message{To: []string{"owner@example.com"}})
+			require.Empty(t, err)
+			r, err := o.Get(id)
+			require.Empty(t, err)
+
+			for _, e := range tt.input.held {
+				_, err := o.RecordEvent("provider-1", Event{Type: e})
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+			now = now.Add(tt.input.elapsed)
+			// Events for other messages drop the held events that are too old.
+			_, err = o.RecordEvent("provider-2", Event{Type: EventDelivery})
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			o.deliver(context.Background(), r)
+
+			r, err = o.Get(id)
+			require.Empty(t, err)
+			assert.Equal(t, "provider-1", r.ProviderMessageID)
+			assert.Equal(t, 1, r.Attempts)
+			assert.Equal(t, tt.want.state, r.State)
+			assert.Len(t, r.Events, tt.want.events)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package quarantine
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/store"
+	"github.com/brice-aldrich/mail-service/internal/transport"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var (
+	// ErrNotFound is returned when a submission is not in the quarantine.
+	ErrNotFound = errors.New("submission not quarantined")
+	// ErrInvalidPageToken is returned when a page token passed to List is malformed.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// Entry is a submission held back for review because it looks like spam.
+//
+// Fields:
+//   - ID: The ID assigned to the submission by the quarantine.
+//   - FormID: The ID of the form the submission was made to.
+//   - Name: The name of the submitter.
+//   - Email: The email address of the submitter.
+//   - Subject: The subject entered by the submitter.
+//   - Message: The message entered by the submitter.
+//   - Score: The spam score of the submission.
+//   - Reasons: The reasons of the rules that scored the submission.
+//   - Forward: The message forwarded when the submission is released.
+//   - CreatedAt: When the submission was quarantined.
+type Entry struct {
+	ID        string
+	FormID    string
+	Name      string
+	Email     string
+	Subject   string
+	Message   string
+	Score     float64
+	Reasons   []string
+	Forward   transport.Message
+	CreatedAt time.Time
+}
+
+// Query filters and paginates the submissions returned by List.
+//
+// Fields:
+//   - FormID: Only return submissions made to this form. Every form when empty.
+//   - PageSize: The maximum number of submissions to return. Defaults to 50, capped at 500.
+//   - PageToken: The token returned by a previous call to continue listing from.
+type Query struct {
+	FormID    string
+	PageSize  int
+	PageToken string
+}
+
+// Queue is a durable, file-backed queue of the submissions held back for review. Submissions are removed atomically,
+// so a submission reviewed concurrently is only released or discarded once.
+type Queue struct {
+	mu      sync.Mutex
+	entries *store.Collection[Entry]
+	now     func() time.Time
+}
+
+// New creates a new Queue and loads any submissions persisted by a previous run.
+//
+// Parameters:
+//   - dir: The directory the quarantine is persisted in. An empty directory keeps the quarantine in memory.
+//
+// Returns:
+//   - *Queue: The newly created Queue instance.
+//   - error: An error if the persisted submissions could not be loaded.
+func New(dir string) (*Queue, error) {
+	entries, err := store.Open[Entry](dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quarantine: %w", err)
+	}
+
+	return &Queue{
+		entries: entries,
+		now:     time.Now,
+	}, nil
+}
+
+// Add quarantines a submission.
+//
+// Parameters:
+//   - e: The Entry to quarantine. Its ID and creation time are assigned by the queue.
+//
+// Returns:
+//   - Entry: The stored entry.
+//   - error: An error if the entry could not be persisted.
+func (q *Queue) Add(e Entry) (Entry, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Entry{}, fmt.Errorf("failed to generate quarantine id: %w", err)
+	}
+
+	e.ID = hex.EncodeToString(b)
+	e.CreatedAt = q.now()
+	if err := q.entries.Put(e.ID, e); err != nil {
+		return Entry{}, fmt.Errorf("failed to quarantine submission: %w", err)
+	}
+
+	return e, nil
+}
+
+// Get returns a quarantined submission.
+//
+// Parameters:
+//   - id: The ID of the submission.
+//
+// Returns:
+//   - Entry: The quarantined submission.
+//   - error: ErrNotFound if the submission is not quarantined.
+func (q *Queue) Get(id string) (Entry, error) {
+	e, err := q.entries.Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return Entry{}, ErrNotFound
+	}
+
+	return e, err
+}
+
+// Remove removes a submission from the quarantine.
+//
+// Parameters:
+//   - id: The ID of the submission.
+//
+// Returns:
+//   - error: ErrNotFound if the submission is not quarantined, or an error if it could not be removed from disk.
+func (q *Queue) Remove(id string) error {
+	_, err := q.Take(id)
+	return err
+}
+
+// Take removes a submission from the quarantine and returns it. Of concurrent calls taking the same submission, only
+// one succeeds.
+//
+// Parameters:
+//   - id: The ID of the submission.
+//
+// Returns:
+//   - Entry: The removed submission.
+//   - error: ErrNotFound if the submission is not quarantined, or an error if it could not be removed from disk.
+func (q *Queue) Take(id string) (Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, err := q.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err := q.entries.Delete(id); err != nil {
+		return Entry{}, err
+	}
+
+	return e, nil
+}
+
+// Restore puts a submission taken from the quarantine back, e.g. when it could not be released.
+//
+// Parameters:
+//   - e: The Entry returned by Take.
+//
+// Returns:
+//   - error: An error if the entry could not be persisted.
+func (q *Queue) Restore(e Entry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.entries.Put(e.ID, e); err != nil {
+		return fmt.Errorf("failed to restore quarantined submission: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the quarantined submissions matching the query, newest first.
+//
+// Parameters:
+//   - query: The Query used to filter and paginate the submissions.
+//
+// Returns:
+//   - []Entry: The page of matching submissions.
+//   - string: The token to pass to the next call, or an empty string when there are no more submissions.
+//   - error: ErrInvalidPageToken if the page token is malformed.
+func (q *Queue) List(query Query) ([]Entry, string, error) {
+	pageSize := query.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	var (
+		after    time.Time
+		afterID  string
+		hasAfter bool
+	)
+	if query.PageToken != "" {
+		var err error
+		after, afterID, err = decodePageToken(query.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		hasAfter = true
+	}
+
+	entries := q.entries.List(func(_ string, e Entry) bool {
+		if query.FormID != "" && e.FormID != query.FormID {
+			return false
+		}
+
+		return !hasAfter || newerFirst(after, afterID, e.CreatedAt, e.ID)
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return newerFirst(entries[i].CreatedAt, entries[i].ID, entries[j].CreatedAt, entries[j].ID)
+	})
+
+	if len(entries) <= pageSize {
+		return entries, "", nil
+	}
+
+	entries = entries[:pageSize]
+	last := entries[len(entries)-1]
+	return entries, encodePageToken(last.CreatedAt, last.ID), nil
+}
+
+// newerFirst reports whether the entry (at, aID) sorts before the entry (bt, bID) in a newest first listing.
+func newerFirst(at time.Time, aID string, bt time.Time, bID string) bool {
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+
+	return aID < bID
+}
+
+func encodePageToken(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id))
+}
+
+func decodePageToken(token string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	ts, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidPageToken
+	}
+
+	return time.Unix(0, nanos), id, nil
+}
@@ -0,0 +1,104 @@
+package quarantine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueUnit(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := New(dir)
+	require.Empty(t, err)
+
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i, form := range []string{"portfolio", "shop", "portfolio"} {
+		q.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		e, err := q.Add(Entry{
+			FormID:  form,
+			Email:   "jane@example.com",
+			Message: "Cheap pills",
+			Score:   7,
+			Reasons: []string{`contains "pills"`},
+			Forward: transport.Message{To: []string{"owner@example.com"}, Subject: "You have an inquiry"},
+		})
+		require.Empty(t, err)
+		assert.Len(t, e.ID, 32)
+		ids = append(ids, e.ID)
+	}
+
+	reopened, err := New(dir)
+	require.Empty(t, err)
+
+	e, err := reopened.Get(ids[1])
+	require.Empty(t, err)
+	assert.Equal(t, "shop", e.FormID)
+	assert.Equal(t, []string{`contains "pills"`}, e.Reasons)
+	assert.Equal(t, []string{"owner@example.com"}, e.Forward.To)
+	assert.True(t, now.Add(time.Minute).Equal(e.CreatedAt))
+
+	page, next, err := reopened.List(Query{PageSize: 2})
+	require.Empty(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, []string{ids[2], ids[1]}, []string{page[0].ID, page[1].ID})
+	require.NotEmpty(t, next)
+
+	page, next, err = reopened.List(Query{PageSize: 2, PageToken: next})
+	require.Empty(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, ids[0], page[0].ID)
+	assert.Empty(t, next)
+
+	page, _, err = reopened.List(Query{FormID: "portfolio"})
+	require.Empty(t, err)
+	assert.Len(t, page, 2)
+
+	_, _, err = reopened.List(Query{PageToken: "not a token"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	require.Empty(t, reopened.Remove(ids[1]))
+	_, err = reopened.Get(ids[1])
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, reopened.Remove(ids[1]), ErrNotFound)
+}
+
+func TestQueueTakeUnit(t *testing.T) {
+	q, err := New(t.TempDir())
+	require.Empty(t, err)
+
+	added, err := q.Add(Entry{FormID: "portfolio", Email: "jane@example.com"})
+	require.Empty(t, err)
+
+	var (
+		wg    sync.WaitGroup
+		taken atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := q.Take(added.ID); err == nil {
+				taken.Add(1)
+			} else {
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), taken.Load(), "a submission is only taken once")
+	_, err = q.Get(added.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.Empty(t, q.Restore(added))
+	e, err := q.Get(added.ID)
+	require.Empty(t, err)
+	assert.True(t, added.CreatedAt.Equal(e.CreatedAt))
+}
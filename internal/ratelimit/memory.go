@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the buckets that have refilled.
+const sweepInterval = time.Minute
+
+// memoryStore holds the token buckets in process. Its limits only hold within a single replica.
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	per     time.Duration
+}
+
+// NewMemoryStore creates a Store holding the token buckets in process.
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take takes a token from the bucket of the key.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key of the bucket, e.g. "ip:203.0.113.7".
+//   - limit: The Limit of the bucket.
+//   - now: The current time.
+//
+// Returns:
+//   - bool: Whether a token was available.
+//   - time.Duration: The time until a token is available, when none was.
+//   - error: Always nil.
+func (s *memoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+
+	ok, retryAfter := take(&b.tokens, &b.updated, limit, now)
+	return ok, retryAfter, nil
+}
+
+// sweep drops the buckets that have been idle long enough to refill, as they are equivalent to a new bucket.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// take refills a bucket for the time elapsed since its last update and takes a token from it.
+func take(tokens *float64, updated *time.Time, limit Limit, now time.Time) (bool, time.Duration) {
+	capacity := float64(limit.Requests)
+	rate := capacity / float64(limit.Per)
+
+	if elapsed := now.Sub(*updated); elapsed > 0 {
+		*tokens = min(capacity, *tokens+float64(elapsed)*rate)
+		*updated = now
+	}
+
+	if *tokens >= 1 {
+		*tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - *tokens) / rate)
+}
@@ -0,0 +1,243 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Limit is a token bucket holding Requests tokens, refilled at a rate of Requests per Per. A request takes one token,
+// so a client may burst up to Requests requests and then continue at the refill rate.
+//
+// Fields:
+//   - Requests: The capacity of the bucket. The limit is disabled when zero.
+//   - Per: The time it takes to refill an empty bucket.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as "<requests>/<duration>", e.g. "10/1h". An empty string disables the limit.
+//
+// Parameters:
+//   - s: The limit to parse.
+//
+// Returns:
+//   - Limit: The parsed Limit.
+//   - error: An error if the limit is malformed.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: must be <requests>/<duration>, e.g. 10/1h", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: duration must be positive, e.g. 1h", s)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// Store holds the token buckets. Stores shared between replicas, e.g. Redis, enforce the limits across all of them.
+//
+// Methods:
+//   - Take: Takes a token from the bucket of the key, creating a full bucket if it does not exist. It reports whether a
+//     token was available and, when it was not, how long until one is.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error)
+}
+
+// Config holds the configuration of the rate limiting interceptor.
+//
+// Fields:
+//   - Store: The Store holding the token buckets.
+//   - IP: The Limit of every client IP address.
+//   - Email: The Limit of every submitter email address.
+//   - Form: The Limit of every form, shared by all of its submitters.
+//   - TrustedHops: The number of proxies in front of the service, e.g. 1 behind an ingress controller. The client IP is
+//     read from the X-Forwarded-For entry added by the outermost trusted proxy, so clients cannot spoof it.
+//   - DefaultForm: The ID of the form used by submissions that do not select one.
+//   - Methods: The full gRPC method names, e.g. "/mailservice.MailService/SendMail", that are rate limited.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Store       Store
+	IP          Limit
+	Email       Limit
+	Form        Limit
+	TrustedHops int
+	DefaultForm string
+	Methods     []string
+	Logger      *zap.Logger
+}
+
+// submission is implemented by the requests that carry a submitter email address and a form ID.
+type submission interface {
+	GetEmail() string
+	GetFormId() string
+}
+
+type limiter struct {
+	cfg     Config
+	methods map[string]struct{}
+	now     func() time.Time
+}
+
+// Interceptor returns a unary interceptor that rate limits calls to the given methods by client IP address, by
+// submitter email address and by form. Calls over a limit fail with a ResourceExhausted error carrying an
+// errdetails.RetryInfo with the time until the call may be retried. Calls pass through when the Store fails, so an
+// outage of a shared store does not take the service down. Calls to all other methods pass through untouched.
+//
+// Parameters:
+//   - cfg: The Config object containing the store, the limits and the rate limited methods.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: The interceptor enforcing the limits.
+func Interceptor(cfg Config) grpc.UnaryServerInterceptor {
+	l := &limiter{
+		cfg:     cfg,
+		methods: make(map[string]struct{}, len(cfg.Methods)),
+		now:     time.Now,
+	}
+	for _, m := range cfg.Methods {
+		l.methods[m] = struct{}{}
+	}
+
+	return l.intercept
+}
+
+func (l *limiter) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if _, ok := l.methods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+
+	if err := l.check(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// check takes a token from the bucket of the client IP address, the submitter email address and the form in turn,
+// stopping at the first bucket that is empty.
+func (l *limiter) check(ctx context.Context, req any) error {
+	type bucket struct {
+		subject string
+		key     string
+		limit   Limit
+	}
+
+	var buckets []bucket
+	if ip := ClientIP(ctx, l.cfg.TrustedHops); ip != "" {
+		buckets = append(buckets, bucket{"ip address", "ip:" + ip, l.cfg.IP})
+	}
+
+	if s, ok := req.(submission); ok {
+		if email := strings.ToLower(strings.TrimSpace(s.GetEmail())); email != "" {
+			buckets = append(buckets, bucket{"email address", "email:" + email, l.cfg.Email})
+		}
+
+		form := s.GetFormId()
+		if form == "" {
+			form = l.cfg.DefaultForm
+		}
+		buckets = append(buckets, bucket{"form", "form:" + form, l.cfg.Form})
+	}
+
+	for _, b := range buckets {
+		if !b.limit.enabled() {
+			continue
+		}
+
+		ok, retryAfter, err := l.cfg.Store.Take(ctx, b.key, b.limit, l.now())
+		if err != nil {
+			l.cfg.Logger.Warn("Rate limit not enforced, the store failed", zap.String("key", b.key), zap.Error(err))
+			continue
+		}
+
+		if !ok {
+			return exhausted(b.subject, retryAfter)
+		}
+	}
+
+	return nil
+}
+
+// exhausted builds the ResourceExhausted error returned to a client over a limit.
+func exhausted(subject string, retryAfter time.Duration) error {
+	retryAfter = time.Duration(math.Ceil(retryAfter.Seconds())) * time.Second
+
+	st := status.Newf(codes.ResourceExhausted, "too many submissions from this %s, retry in %s", subject, retryAfter)
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
+
+// ClientIP returns the IP address of the client of a request. Requests forwarded by the gRPC-Gateway carry an
+// X-Forwarded-For list ending with the address the gateway was called from. Each of the trusted proxies in front of
+// the gateway adds the address it was called from, so the client address is the entry trustedHops from the end.
+// Entries further left are set by the client and cannot be trusted. Requests made directly over gRPC use the peer address.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - trustedHops: The number of proxies in front of the gateway.
+//
+// Returns:
+//   - string: The IP address of the client, or an empty string if it is unknown.
+func ClientIP(ctx context.Context, trustedHops int) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		var hops []string
+		for _, v := range md.Get("x-forwarded-for") {
+			for _, hop := range strings.Split(v, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+
+		if len(hops) > 0 {
+			i := max(len(hops)-1-trustedHops, 0)
+			return hops[i]
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,306 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseLimitUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		limit        Limit
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  want
+	}{
+		{
+			"parses limit",
+			"10/1h",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				limit: Limit{Requests: 10, Per: time.Hour},
+			},
+		},
+		{
+			"disables empty limit",
+			"",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"handles missing duration",
+			"10",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "must be <requests>/<duration>")
+				},
+			},
+		},
+		{
+			"handles invalid requests",
+			"0/1h",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "requests must be a positive integer")
+				},
+			},
+		},
+		{
+			"handles invalid duration",
+			"10/hour",
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorContains(t, err, "duration must be positive")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.input)
+			tt.want.errAssertion(t, err)
+			assert.Equal(t, tt.want.limit, got)
+		})
+	}
+}
+
+func TestStoreUnit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, "test:"),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := Limit{Requests: 2, Per: time.Minute}
+			now := time.Unix(1700000000, 0)
+
+			for i := 0; i < 2; i++ {
+				ok, _, err := store.Take(ctx, "ip:203.0.113.7", limit, now)
+				require.Empty(t, err)
+				assert.True(t, ok)
+			}
+
+			ok, retryAfter, err := store.Take(ctx, "ip:203.0.113.7", limit, now)
+			require.Empty(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, 30*time.Second, retryAfter)
+
+			ok, _, err = store.Take(ctx, "ip:198.51.100.4", limit, now)
+			require.Empty(t, err)
+			assert.True(t, ok, "buckets are kept per key")
+
+			ok, _, err = store.Take(ctx, "ip:203.0.113.7", limit, now.Add(30*time.Second))
+			require.Empty(t, err)
+			assert.True(t, ok, "a token is refilled after 30s")
+
+			ok, retryAfter, err = store.Take(ctx, "ip:203.0.113.7", limit, now.Add(40*time.Second))
+			require.Empty(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, 20*time.Second, retryAfter)
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestInterceptorUnit(t *testing.T) {
+	type input struct {
+		store Store
+		calls []*mailservice_v1.SendMailRequest
+	}
+
+	type want struct {
+		code    codes.Code
+		message string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"accepts submissions under the limits",
+			input{
+				calls: []*mailservice_v1.SendMailRequest{{Email: "a@example.com", FormId: "shop"}, {Email: "b@example.com"}},
+			},
+			want{code: codes.OK},
+		},
+		{
+			"limits client ip address",
+			input{
+				calls: []*mailservice_v1.SendMailRequest{
+					{Email: "a@example.com", FormId: "a"},
+					{Email: "b@example.com", FormId: "b"},
+					{Email: "c@example.com", FormId: "c"},
+					{Email: "d@example.com", FormId: "d"},
+				},
+			},
+			want{code: codes.ResourceExhausted, message: "too many submissions from this ip address"},
+		},
+		{
+			"limits submitter email address",
+			input{
+				calls: []*mailservice_v1.SendMailRequest{{Email: "a@example.com"}, {Email: " A@Example.com"}},
+			},
+			want{code: codes.ResourceExhausted, message: "too many submissions from this email address"},
+		},
+		{
+			"limits form",
+			input{
+				calls: []*mailservice_v1.SendMailRequest{
+					{Email: "a@example.com", FormId: "shop"},
+					{Email: "b@example.com", FormId: "shop"},
+					{Email: "c@example.com", FormId: "shop"},
+				},
+			},
+			want{code: codes.ResourceExhausted, message: "too many submissions from this form"},
+		},
+		{
+			"counts submissions without form against the default form",
+			input{
+				calls: []*mailservice_v1.SendMailRequest{{Email: "a@example.com", FormId: "portfolio"}, {Email: "b@example.com"}, {Email: "c@example.com"}},
+			},
+			want{code: codes.ResourceExhausted, message: "too many submissions from this form"},
+		},
+		{
+			"accepts submissions when the store fails",
+			input{
+				store: failingStore{},
+				calls: []*mailservice_v1.SendMailRequest{{Email: "a@example.com"}, {Email: "a@example.com"}, {Email: "a@example.com"}},
+			},
+			want{code: codes.OK},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.input.store
+			if store == nil {
+				store = NewMemoryStore()
+			}
+
+			intercept := Interceptor(Config{
+				Store:       store,
+				IP:          Limit{Requests: 3, Per: time.Hour},
+				Email:       Limit{Requests: 1, Per: time.Hour},
+				Form:        Limit{Requests: 2, Per: time.Hour},
+				DefaultForm: "portfolio",
+				Methods:     []string{"/mailservice.MailService/SendMail"},
+				Logger:      zap.NewNop(),
+			})
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+			info := &grpc.UnaryServerInfo{FullMethod: "/mailservice.MailService/SendMail"}
+			handler := func(context.Context, any) (any, error) { return &mailservice_v1.SendMailResponse{}, nil }
+
+			var err error
+			for _, req := range tt.input.calls {
+				if _, err = intercept(ctx, req, info, handler); err != nil {
+					break
+				}
+			}
+
+			assert.Equal(t, tt.want.code, status.Code(err))
+			if tt.want.code == codes.OK {
+				return
+			}
+
+			st := status.Convert(err)
+			assert.Contains(t, st.Message(), tt.want.message)
+			require.Len(t, st.Details(), 1)
+			retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+			require.True(t, ok)
+			assert.Positive(t, retry.RetryDelay.AsDuration())
+		})
+	}
+}
+
+func TestInterceptorSkipsOtherMethodsUnit(t *testing.T) {
+	intercept := Interceptor(Config{
+		Store:   NewMemoryStore(),
+		IP:      Limit{Requests: 1, Per: time.Hour},
+		Methods: []string{"/mailservice.MailService/SendMail"},
+		Logger:  zap.NewNop(),
+	})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/mailservice.MailService/GetMessageStatus"}
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	for i := 0; i < 3; i++ {
+		_, err := intercept(ctx, &mailservice_v1.GetMessageStatusRequest{}, info, handler)
+		assert.Empty(t, err)
+	}
+}
+
+func TestClientIPUnit(t *testing.T) {
+	type input struct {
+		forwardedFor []string
+		trustedHops  int
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  string
+	}{
+		{"uses the address the gateway was called from", input{[]string{"198.51.100.1, 203.0.113.7"}, 0}, "203.0.113.7"},
+		{"uses the address added by the trusted proxy", input{[]string{"203.0.113.7, 10.0.0.2"}, 1}, "203.0.113.7"},
+		{"ignores addresses set by the client", input{[]string{"198.51.100.1, 203.0.113.7, 10.0.0.2"}, 1}, "203.0.113.7"},
+		{"uses the first address with fewer hops than trusted", input{[]string{"203.0.113.7"}, 2}, "203.0.113.7"},
+		{"reads every header value", input{[]string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, 1}, "203.0.113.7"},
+		{"falls back to the peer address", input{nil, 1}, "192.0.2.10"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 5000}})
+
+			md := metadata.MD{}
+			for _, v := range tt.input.forwardedFor {
+				md.Append("x-forwarded-for", v)
+			}
+			ctx = metadata.NewIncomingContext(ctx, md)
+
+			assert.Equal(t, tt.want, ClientIP(ctx, tt.input.trustedHops))
+		})
+	}
+
+	assert.Equal(t, "", ClientIP(context.Background(), 0))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript takes a token from a bucket stored as a hash of its tokens and the time of its last update, in
+// milliseconds. It runs atomically, so concurrent replicas cannot both take the last token. Buckets expire once they
+// would have refilled, as they are equivalent to a new bucket.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+
+local rate = capacity / per
+if now > updated then
+  tokens = math.min(capacity, tokens + (now - updated) * rate)
+  updated = now
+end
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(updated))
+redis.call("PEXPIRE", KEYS[1], per)
+
+return {allowed, wait}
+`)
+
+// redisStore holds the token buckets in Redis, so the limits hold across replicas.
+type redisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore creates a Store holding the token buckets in Redis.
+//
+// Parameters:
+//   - client: The Redis client, e.g. a *redis.Client.
+//   - prefix: The prefix of the keys of the buckets, e.g. "mail-service:ratelimit:".
+//
+// Returns:
+//   - Store: The newly created Store.
+func NewRedisStore(client redis.Scripter, prefix string) Store {
+	return &redisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take takes a token from the bucket of the key.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - key: The key of the bucket, e.g. "ip:203.0.113.7".
+//   - limit: The Limit of the bucket.
+//   - now: The current time.
+//
+// Returns:
+//   - bool: Whether a token was available.
+//   - time.Duration: The time until a token is available, when none was.
+//   - error: An error if Redis could not be reached or the script failed.
+func (s *redisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	per := max(limit.Per.Milliseconds(), 1)
+
+	res, err := takeScript.Run(ctx, s.client, []string{s.prefix + key}, limit.Requests, per, now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take token from %q: %w", key, err)
+	}
+
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("failed to take token from %q: unexpected result %v", key, res)
+	}
+
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
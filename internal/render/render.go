@@ -0,0 +1,321 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/templates"
+)
+
+// placeholderPattern matches the simple {{variable}} placeholders of the SES template engine, including paths into
+// nested data such as {{fields.company}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)\s*\}\}`)
+
+// eachPattern matches the {{#each list}} and {{/each}} tags of the SES template engine, which iterate over a list
+// like the range action of Go templates.
+var eachPattern = regexp.MustCompile(`\{\{#each\s+([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)\s*\}\}|\{\{/each\}\}`)
+
+// keywords are the Go template actions that must not be rewritten into field lookups.
+var keywords = map[string]bool{
+	"end":      true,
+	"else":     true,
+	"break":    true,
+	"continue": true,
+	"nil":      true,
+	"true":     true,
+	"false":    true,
+}
+
+// Content is a rendered email.
+//
+// Fields:
+//   - Subject: The rendered subject line.
+//   - HTML: The rendered HTML body. Empty if the template has no HTML part.
+//   - Text: The rendered plain text body. Empty if the template has no text part.
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Config holds the configuration used to build the Engine.
+//
+// Fields:
+//   - Templates: The templates to render, keyed by template key.
+//   - PartialsDir: An optional directory of layouts and partials. Files ending in ".html" are available to every
+//     HTML part and files ending in ".txt" to every subject and text part, under their file name.
+type Config struct {
+	Templates   map[string]templates.Template
+	PartialsDir string
+}
+
+// Engine renders email templates in process. Template parts use the Go template syntax, e.g. {{.name}}, along
+// with the helper functions listed in Funcs. Simple SES style {{name}} placeholders are still understood, so the
+// same templates can be rendered by either engine.
+//
+// A part can be wrapped in a layout by defining the blocks the layout expects and then invoking it:
+//
+//	{{define "content"}}<p>Hi {{.name}}</p>{{end}}{{template "layout.html" .}}
+type Engine struct {
+	sets map[string]set
+}
+
+type set struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// New parses every template along with the layouts and partials.
+//
+// Parameters:
+//   - cfg: The Config object containing the templates and the partials directory.
+//
+// Returns:
+//   - *Engine: The newly created Engine.
+//   - error: An error if a template or partial could not be parsed.
+func New(cfg Config) (*Engine, error) {
+	var partials fs.FS
+	if cfg.PartialsDir != "" {
+		partials = os.DirFS(cfg.PartialsDir)
+	}
+
+	htmlPartials, err := globPartials(partials, "*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	textPartials, err := globPartials(partials, "*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{sets: make(map[string]set, len(cfg.Templates))}
+	for key, t := range cfg.Templates {
+		var s set
+
+		if s.subject, err = parseText(key+".subject", t.Subject, partials, textPartials); err != nil {
+			return nil, err
+		}
+
+		if t.HTML != "" {
+			if s.html, err = parseHTML(key+".html", t.HTML, partials, htmlPartials); err != nil {
+				return nil, err
+			}
+		}
+
+		if t.Text != "" {
+			if s.text, err = parseText(key+".txt", t.Text, partials, textPartials); err != nil {
+				return nil, err
+			}
+		}
+
+		e.sets[key] = s
+	}
+
+	return e, nil
+}
+
+// Render renders the template with the given key.
+//
+// Parameters:
+//   - key: The key of the template, e.g. templates.ThankYou.
+//   - data: The data the template is executed with.
+//
+// Returns:
+//   - Content: The rendered subject, HTML and text parts.
+//   - error: An error if the template does not exist or could not be executed.
+func (e *Engine) Render(key string, data any) (Content, error) {
+	s, ok := e.sets[key]
+	if !ok {
+		return Content{}, fmt.Errorf("unknown template %q", key)
+	}
+
+	var (
+		c   Content
+		buf bytes.Buffer
+	)
+
+	if err := s.subject.Execute(&buf, data); err != nil {
+		return Content{}, fmt.Errorf("failed to render %s subject: %w", key, err)
+	}
+	// Header values cannot span lines, so a subject rendered from a multi-line template is folded into one.
+	c.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	if s.html != nil {
+		buf.Reset()
+		if err := s.html.Execute(&buf, data); err != nil {
+			return Content{}, fmt.Errorf("failed to render %s html: %w", key, err)
+		}
+		c.HTML = buf.String()
+	}
+
+	if s.text != nil {
+		buf.Reset()
+		if err := s.text.Execute(&buf, data); err != nil {
+			return Content{}, fmt.Errorf("failed to render %s text: %w", key, err)
+		}
+		c.Text = buf.String()
+	}
+
+	return c, nil
+}
+
+// Funcs returns the helper functions available to every template.
+//
+//   - default: Returns the first argument when the second is empty, e.g. {{.name | default "there"}}.
+//   - upper, lower, trim: Wrap the strings package functions of the same name.
+//   - truncate: Shortens a string to at most n characters, e.g. {{.message | truncate 80}}.
+//   - join: Joins a list of strings, e.g. {{.tags | join ", "}}.
+//   - now: Returns the current time.
+//   - date: Formats a time.Time with a Go layout, e.g. {{now | date "2 January 2006"}}.
+//   - nl2br: Escapes a string and turns its line breaks into <br> elements. Intended for HTML parts.
+//
+// Returns:
+//   - map[string]any: The helper functions keyed by name.
+func Funcs() map[string]any {
+	return map[string]any{
+		"default":  defaultValue,
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"truncate": truncate,
+		"join":     join,
+		"now":      time.Now,
+		"date":     date,
+		"nl2br":    nl2br,
+	}
+}
+
+func parseHTML(name, body string, partials fs.FS, patterns []string) (*htmltemplate.Template, error) {
+	t := htmltemplate.New(name).Funcs(Funcs()).Option("missingkey=zero")
+
+	// Partials are parsed first, so that blocks defined by the body take precedence over the defaults of a layout.
+	if len(patterns) > 0 {
+		if _, err := t.ParseFS(partials, patterns...); err != nil {
+			return nil, fmt.Errorf("failed to parse html partials: %w", err)
+		}
+	}
+
+	if _, err := t.Parse(compat(body)); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+func parseText(name, body string, partials fs.FS, patterns []string) (*texttemplate.Template, error) {
+	t := texttemplate.New(name).Funcs(Funcs()).Option("missingkey=zero")
+
+	if len(patterns) > 0 {
+		if _, err := t.ParseFS(partials, patterns...); err != nil {
+			return nil, fmt.Errorf("failed to parse text partials: %w", err)
+		}
+	}
+
+	if _, err := t.Parse(compat(body)); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// globPartials returns the pattern when it matches at least one file, as ParseFS fails on patterns without matches.
+func globPartials(fsys fs.FS, pattern string) ([]string, error) {
+	if fsys == nil {
+		return nil, nil
+	}
+
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	return []string{pattern}, nil
+}
+
+// compat rewrites SES style {{variable}} placeholders into Go template field lookups, and {{#each list}} blocks into
+// range actions over the list, so the placeholders inside a block look up the fields of each item. Like SES, missing values render
+// as nothing, where Go templates would print "<no value>" for the missing keys of a map[string]any.
+// Keywords and helper functions are left untouched.
+func compat(s string) string {
+	s = eachPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "{{/each}}" {
+			return "{{end}}{{end}}"
+		}
+
+		// Missing lists render nothing, where range fails on them.
+		return "{{with ." + eachPattern.FindStringSubmatch(m)[1] + "}}{{range .}}"
+	})
+
+	funcs := Funcs()
+	return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderPattern.FindStringSubmatch(m)[1]
+		if keywords[name] {
+			return m
+		}
+
+		if _, ok := funcs[name]; ok {
+			return m
+		}
+
+		return "{{or ." + name + ` ""}}`
+	})
+}
+
+func defaultValue(def, v any) any {
+	switch x := v.(type) {
+	case nil:
+		return def
+	case string:
+		if strings.TrimSpace(x) == "" {
+			return def
+		}
+	}
+
+	return v
+}
+
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if n < 0 || len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
+
+func join(sep string, elems any) string {
+	switch x := elems.(type) {
+	case []string:
+		return strings.Join(x, sep)
+	case []any:
+		parts := make([]string, len(x))
+		for i, v := range x {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep)
+	default:
+		return fmt.Sprint(elems)
+	}
+}
+
+func date(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+func nl2br(s string) htmltemplate.HTML {
+	escaped := htmltemplate.HTMLEscapeString(strings.ReplaceAll(s, "\r\n", "\n"))
+	return htmltemplate.HTML(strings.ReplaceAll(escaped, "\n", "<br>\n"))
+}
@@ -0,0 +1,217 @@
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestRenderGoldenUnit(t *testing.T) {
+	defaults, err := templates.Load(templates.Config{})
+	require.Empty(t, err)
+
+	defaults["welcome"] = templates.Template{
+		Key:     "welcome",
+		Subject: "Welcome, {{.name | default \"friend\"}}",
+		HTML:    `{{define "content"}}<h1>Hi {{.name}}</h1><p>{{nl2br .message}}</p>{{end}}{{template "layout.html" .}}`,
+		Text:    "Hi {{.name | upper}},\n\n{{.message}}\n{{template \"footer.txt\" .}}",
+	}
+
+	engine, err := New(Config{
+		Templates:   defaults,
+		PartialsDir: filepath.Join("testdata", "partials"),
+	})
+	require.Empty(t, err)
+
+	cases := []struct {
+		name  string
+		key   string
+		input map[string]string
+	}{
+		{
+			"thank you",
+			templates.ThankYou,
+			map[string]string{"name": "Jane <Doe>"},
+		},
+		{
+			"forward",
+			templates.Forward,
+			map[string]string{"from": "jane@example.com", "name": "Jane Doe", "subject": "Project", "subject_line": "Project — Jane Doe", "text": "Hello\n<script>alert(1)</script>"},
+		},
+		{
+			"welcome",
+			"welcome",
+			map[string]string{"name": "Jane", "message": "Line one\nLine <two>", "site": "example.com"},
+		},
+		{
+			"welcome defaults",
+			"welcome",
+			map[string]string{},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Render(tt.key, tt.input)
+			require.Empty(t, err)
+
+			rendered := "Subject: " + got.Subject + "\n\n--- html ---\n" + got.HTML + "\n--- text ---\n" + got.Text + "\n"
+			golden := filepath.Join("testdata", "golden", filepath.Base(t.Name())+".golden")
+			if *update {
+				require.Empty(t, os.WriteFile(golden, []byte(rendered), 0o600))
+			}
+
+			want, err := os.ReadFile(golden)
+			require.Empty(t, err)
+			assert.Equal(t, string(want), rendered)
+		})
+	}
+}
+
+func TestRenderUnit(t *testing.T) {
+	type input struct {
+		template templates.Template
+		data     any
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		content      Content
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"understands ses placeholders",
+			input{
+				template: templates.Template{Subject: "Hello {{ name }}", HTML: "<p>{{name}}</p>", Text: "{{name}}{{missing}}"},
+				data:     map[string]string{"name": "<b>Jane</b>"},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "Hello <b>Jane</b>", HTML: "<p>&lt;b&gt;Jane&lt;/b&gt;</p>", Text: "<b>Jane</b>"},
+			},
+		},
+		{
+			"renders nested and missing ses placeholders",
+			input{
+				template: templates.Template{Subject: "{{fields.company}} {{missing}}", HTML: "<p>{{fields.company}}{{fields.missing}}</p>", Text: "{{ fields.budget }}|{{missing}}|{{fields.missing}}"},
+				data:     map[string]any{"fields": map[string]string{"company": "<Acme>", "budget": "5000"}},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "<Acme>", HTML: "<p>&lt;Acme&gt;</p>", Text: "5000||"},
+			},
+		},
+		{
+			"iterates ses each blocks",
+			input{
+				template: templates.Template{Subject: "Inquiry", HTML: "<ul>{{#each field_list}}<li>{{label}}: {{value}}</li>{{/each}}</ul>", Text: "{{#each field_list}}{{label}}: {{value}}{{missing}}\n{{/each}}"},
+				data: map[string]any{"field_list": []map[string]string{
+					{"name": "company", "label": "Company", "value": "<Acme>"},
+					{"name": "budget", "label": "Budget", "value": "5000"},
+				}},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "Inquiry", HTML: "<ul><li>Company: &lt;Acme&gt;</li><li>Budget: 5000</li></ul>", Text: "Company: <Acme>\nBudget: 5000\n"},
+			},
+		},
+		{
+			"leaves keywords and helpers untouched",
+			input{
+				template: templates.Template{Subject: "{{if .vip}}VIP{{else}}Regular{{end}} {{now | date \"2006\" | len}}", Text: "{{range .tags}}{{.}};{{end}}"},
+				data:     map[string]any{"vip": true, "tags": []string{"a", "b"}},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "VIP 4", Text: "a;b;"},
+			},
+		},
+		{
+			"folds multi-line subjects",
+			input{
+				template: templates.Template{Subject: "Hello\n  {{.name}}\n", Text: "Hi"},
+				data:     map[string]string{"name": "Jane"},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "Hello Jane", Text: "Hi"},
+			},
+		},
+		{
+			"applies helpers",
+			input{
+				template: templates.Template{Subject: "{{.name | trim | lower}}", Text: "{{.message | truncate 5}}|{{.tags | join \", \"}}"},
+				data:     map[string]any{"name": "  JANE ", "message": "Hello there", "tags": []any{"a", 1}},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				content: Content{Subject: "jane", Text: "Hello|a, 1"},
+			},
+		},
+		{
+			"handles execution failure",
+			input{
+				template: templates.Template{Subject: "{{.name.first}}", Text: "Hi"},
+				data:     map[string]any{"name": 1},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "failed to render test subject")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New(Config{Templates: map[string]templates.Template{"test": tt.input.template}})
+			require.Empty(t, err)
+
+			got, err := engine.Render("test", tt.input.data)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.content, got)
+		})
+	}
+}
+
+func TestNewUnit(t *testing.T) {
+	_, err := New(Config{Templates: map[string]templates.Template{"test": {Subject: "Hi", HTML: "{{if .name}}"}}})
+	require.NotEmpty(t, err)
+	assert.Contains(t, err.Error(), "failed to parse test.html")
+
+	engine, err := New(Config{})
+	require.Empty(t, err)
+
+	_, err = engine.Render("missing", nil)
+	require.NotEmpty(t, err)
+	assert.Contains(t, err.Error(), `unknown template "missing"`)
+}
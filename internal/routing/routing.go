@@ -0,0 +1,203 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Recipients holds the addresses a submission is forwarded to.
+//
+// Fields:
+//   - To: The addresses the submission is sent to.
+//   - Cc: The addresses the submission is copied to.
+//   - Bcc: The addresses the submission is blind copied to.
+type Recipients struct {
+	To  []string
+	Cc  []string
+	Bcc []string
+}
+
+// Match holds the conditions a submission must meet for a Rule to apply. A submission must meet every condition
+// that is set, and meets a condition when it matches any of its values. A Match without conditions matches every
+// submission.
+//
+// Fields:
+//   - SubjectKeywords: The words and phrases, matched case-insensitively, one of which the subject must contain.
+//   - Departments: The departments, matched case-insensitively, one of which the submitter must have selected.
+//   - EmailDomains: The domains, e.g. "example.com", the address of the submitter must belong to. Subdomains belong to their parent domain.
+type Match struct {
+	SubjectKeywords []string
+	Departments     []string
+	EmailDomains    []string
+}
+
+// Rule forwards the submissions it matches to its own recipients.
+//
+// Fields:
+//   - Name: The name of the rule, logged with the submissions it routes.
+//   - Match: The conditions a submission must meet for the rule to apply.
+//   - Recipients: The Recipients of the submissions the rule matches. Rules must have at least one To address.
+type Rule struct {
+	Name       string
+	Match      Match
+	Recipients Recipients
+}
+
+// Submission is the content of a submission the rules match.
+//
+// Fields:
+//   - Subject: The subject entered by the submitter.
+//   - Department: The department selected by the submitter.
+//   - Email: The email address of the submitter.
+type Submission struct {
+	Subject    string
+	Department string
+	Email      string
+}
+
+// Router picks the recipients of submissions with an ordered list of rules. The first rule a submission matches
+// decides its recipients. A nil Router matches no submission.
+type Router struct {
+	rules []rule
+}
+
+// rule is a Rule with its conditions normalized for matching.
+type rule struct {
+	Rule
+	keywords    []string
+	departments []string
+	domains     []string
+}
+
+// New creates a Router with the given rules, in the order they are matched.
+//
+// Parameters:
+//   - rules: The rules of the router.
+//
+// Returns:
+//   - *Router: The newly created Router.
+//   - error: An error if a rule has no To address or an email domain is invalid.
+func New(rules ...Rule) (*Router, error) {
+	r := &Router{rules: make([]rule, 0, len(rules))}
+	for i, rl := range rules {
+		name := rl.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+
+		if len(rl.Recipients.To) == 0 {
+			return nil, fmt.Errorf("routing rule %q has no forward address", name)
+		}
+
+		compiled := rule{
+			Rule:        rl,
+			keywords:    lower(rl.Match.SubjectKeywords),
+			departments: lower(rl.Match.Departments),
+		}
+		compiled.Name = name
+
+		for _, d := range rl.Match.EmailDomains {
+			domain, err := idna.Lookup.ToASCII(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+			if err == nil && domain == "" {
+				err = errors.New("domain is empty")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("routing rule %q has an invalid email domain %q: %w", name, d, err)
+			}
+			compiled.domains = append(compiled.domains, domain)
+		}
+
+		r.rules = append(r.rules, compiled)
+	}
+
+	return r, nil
+}
+
+// Route returns the first rule a submission matches.
+//
+// Parameters:
+//   - sub: The Submission to route.
+//
+// Returns:
+//   - Rule: The rule the submission matches.
+//   - bool: Whether the submission matches a rule.
+func (r *Router) Route(sub Submission) (Rule, bool) {
+	if r == nil {
+		return Rule{}, false
+	}
+
+	subject := strings.ToLower(sub.Subject)
+	department := strings.ToLower(strings.TrimSpace(sub.Department))
+	domain := strings.ToLower(sub.Email[strings.LastIndexByte(sub.Email, '@')+1:])
+
+	for _, rl := range r.rules {
+		if rl.matches(subject, department, domain) {
+			return rl.Rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// matches reports whether a submission, with its fields lowercased, meets every condition of the rule.
+func (rl rule) matches(subject, department, domain string) bool {
+	if len(rl.keywords) > 0 && !containsAny(subject, rl.keywords) {
+		return false
+	}
+
+	if len(rl.departments) > 0 && !equalsAny(department, rl.departments) {
+		return false
+	}
+
+	if len(rl.domains) > 0 && !inAnyDomain(domain, rl.domains) {
+		return false
+	}
+
+	return true
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalsAny(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inAnyDomain(domain string, parents []string) bool {
+	for _, parent := range parents {
+		if domain == parent || strings.HasSuffix(domain, "."+parent) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lower returns the values lowercased and trimmed, leaving out the empty ones.
+func lower(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
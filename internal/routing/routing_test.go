@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteUnit(t *testing.T) {
+	rules := []Rule{
+		{
+			Name:       "enterprise",
+			Match:      Match{EmailDomains: []string{"@BigCorp.example", "bücher.example"}},
+			Recipients: Recipients{To: []string{"accounts@example.com"}, Bcc: []string{"crm@example.com"}},
+		},
+		{
+			Name:       "sales",
+			Match:      Match{SubjectKeywords: []string{"pricing", "Quote"}},
+			Recipients: Recipients{To: []string{"sales@example.com"}, Cc: []string{"lead@example.com"}},
+		},
+		{
+			Name:       "support",
+			Match:      Match{Departments: []string{"Support"}, SubjectKeywords: []string{"bug", "broken"}},
+			Recipients: Recipients{To: []string{"support@example.com"}},
+		},
+		{
+			Match:      Match{Departments: []string{"careers"}},
+			Recipients: Recipients{To: []string{"jobs@example.com"}},
+		},
+	}
+
+	type want struct {
+		rule    string
+		to      []string
+		matched bool
+	}
+
+	cases := []struct {
+		name  string
+		input Submission
+		want  want
+	}{
+		{
+			"matches subject keyword case-insensitively",
+			Submission{Subject: "Asking for a QUOTE", Email: "jane@example.com"},
+			want{rule: "sales", to: []string{"sales@example.com"}, matched: true},
+		},
+		{
+			"matches email domain",
+			Submission{Subject: "Pricing", Email: "jane@bigcorp.example"},
+			want{rule: "enterprise", to: []string{"accounts@example.com"}, matched: true},
+		},
+		{
+			"matches subdomain of email domain",
+			Submission{Email: "jane@eu.bigcorp.example"},
+			want{rule: "enterprise", to: []string{"accounts@example.com"}, matched: true},
+		},
+		{
+			"matches internationalized email domain",
+			Submission{Email: "jane@xn--bcher-kva.example"},
+			want{rule: "enterprise", to: []string{"accounts@example.com"}, matched: true},
+		},
+		{
+			"does not match domain suffix of another domain",
+			Submission{Email: "jane@notbigcorp.example"},
+			want{},
+		},
+		{
+			"requires every condition",
+			Submission{Department: "support", Subject: "A question", Email: "jane@example.com"},
+			want{},
+		},
+		{
+			"matches department and keyword",
+			Submission{Department: " Support ", Subject: "Something is broken", Email: "jane@example.com"},
+			want{rule: "support", to: []string{"support@example.com"}, matched: true},
+		},
+		{
+			"names unnamed rules by position",
+			Submission{Department: "careers", Email: "jane@example.com"},
+			want{rule: "#4", to: []string{"jobs@example.com"}, matched: true},
+		},
+		{
+			"does not match other submissions",
+			Submission{Subject: "Hello", Email: "jane@example.com"},
+			want{},
+		},
+	}
+
+	router, err := New(rules...)
+	require.Empty(t, err)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := router.Route(tt.input)
+			assert.Equal(t, tt.want.matched, ok)
+			assert.Equal(t, tt.want.rule, rule.Name)
+			assert.Equal(t, tt.want.to, rule.Recipients.To)
+		})
+	}
+}
+
+func TestRouteNilUnit(t *testing.T) {
+	var router *Router
+	_, ok := router.Route(Submission{Email: "jane@example.com"})
+	assert.False(t, ok)
+}
+
+func TestNewUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input Rule
+		want  string
+	}{
+		{
+			"is successful",
+			Rule{Name: "sales", Match: Match{EmailDomains: []string{"example.com"}}, Recipients: Recipients{To: []string{"sales@example.com"}}},
+			"",
+		},
+		{
+			"handles missing to address",
+			Rule{Name: "sales", Recipients: Recipients{Cc: []string{"sales@example.com"}}},
+			`routing rule "sales" has no forward address`,
+		},
+		{
+			"handles empty email domain",
+			Rule{Name: "sales", Match: Match{EmailDomains: []string{"@"}}, Recipients: Recipients{To: []string{"sales@example.com"}}},
+			`routing rule "sales" has an invalid email domain "@"`,
+		},
+		{
+			"handles invalid email domain",
+			Rule{Name: "sales", Match: Match{EmailDomains: []string{"exa mple.com"}}, Recipients: Recipients{To: []string{"sales@example.com"}}},
+			`routing rule "sales" has an invalid email domain "exa mple.com"`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.input)
+			if tt.want == "" {
+				assert.Empty(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
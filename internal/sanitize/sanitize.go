@@ -0,0 +1,78 @@
+package sanitize
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Header cleans a value bound for a header field, e.g. a subject or a display name. Line breaks, tabs and Unicode
+// line separators become spaces, so the value cannot fold into or inject another field, and every other control
+// character is removed, along with the explicit bidirectional formatting characters that can make a value display
+// differently from what it contains. The value is converted to valid UTF-8 in Unicode normalization form C and
+// trimmed.
+//
+// Parameters:
+//   - v: The value to clean.
+//
+// Returns:
+//   - string: The cleaned value.
+func Header(v string) string {
+	v = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r' || r == '\n' || r == '\t' || r == '\u2028' || r == '\u2029':
+			return ' '
+		case unicode.IsControl(r) || isBidiControl(r):
+			return -1
+		}
+
+		return r
+	}, strings.ToValidUTF8(v, "\ufffd"))
+
+	return strings.TrimSpace(norm.NFC.String(v))
+}
+
+// Text cleans free text, e.g. a message. Line breaks are normalized to "\n" and tabs are kept, while every other
+// control character and the explicit bidirectional formatting characters are removed. The text is converted to
+// valid UTF-8 in Unicode normalization form C.
+//
+// Parameters:
+//   - v: The text to clean.
+//
+// Returns:
+//   - string: The cleaned text.
+func Text(v string) string {
+	v = strings.ReplaceAll(strings.ToValidUTF8(v, "\ufffd"), "\r\n", "\n")
+	v = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r':
+			return '\n'
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r) || isBidiControl(r):
+			return -1
+		}
+
+		return r
+	}, v)
+
+	return norm.NFC.String(v)
+}
+
+// HTML escapes user content for HTML templates, so it is displayed as text and never interpreted as markup.
+//
+// Parameters:
+//   - v: The content to escape.
+//
+// Returns:
+//   - string: The escaped content.
+func HTML(v string) string {
+	return html.EscapeString(v)
+}
+
+// isBidiControl reports whether a character is an explicit bidirectional embedding, override or isolate.
+func isBidiControl(r rune) bool {
+	return '\u202a' <= r && r <= '\u202e' || '\u2066' <= r && r <= '\u2069'
+}
@@ -0,0 +1,104 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestHeaderUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"keeps plain value", "Project inquiry", "Project inquiry"},
+		{"replaces line breaks", "Hi\r\nBcc: victim@example.com", "Hi  Bcc: victim@example.com"},
+		{"replaces unicode line separators", "Hi\u2028Bcc: victim@example.com", "Hi Bcc: victim@example.com"},
+		{"removes control characters", "Hi\x00\x1b[31m there\x7f", "Hi[31m there"},
+		{"removes bidirectional overrides", "invoice\u202egpj.exe", "invoicegpj.exe"},
+		{"normalizes unicode", "Ju\u0308rgen", "J\u00fcrgen"},
+		{"replaces invalid utf-8", "Hi \xff", "Hi \ufffd"},
+		{"trims whitespace", " \tHi\n", "Hi"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Header(tt.input))
+		})
+	}
+}
+
+func TestTextUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"keeps plain text", "Hello,\n\tthere", "Hello,\n\tthere"},
+		{"normalizes line breaks", "one\r\ntwo\rthree", "one\ntwo\nthree"},
+		{"removes control characters", "Hi\x00\x1b[31m there", "Hi[31m there"},
+		{"removes bidirectional isolates", "a\u2066b\u2069c", "abc"},
+		{"normalizes unicode", "Ju\u0308rgen", "J\u00fcrgen"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Text(tt.input))
+		})
+	}
+}
+
+func TestHTMLUnit(t *testing.T) {
+	assert.Equal(t, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt; &amp; more", HTML(`<script>alert("hi")</script> & more`))
+}
+
+func FuzzHeader(f *testing.F) {
+	for _, seed := range []string{"Project inquiry", "Hi\r\nBcc: victim@example.com", "Hi\u2028To: a@b.c", "\xff\xfe", "a\u0308\u202e"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, v string) {
+		got := Header(v)
+		if !utf8.ValidString(got) {
+			t.Fatalf("Header(%q) = %q is not valid UTF-8", v, got)
+		}
+
+		if !norm.NFC.IsNormalString(got) {
+			t.Fatalf("Header(%q) = %q is not in normalization form C", v, got)
+		}
+
+		for _, r := range got {
+			if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' || isBidiControl(r) {
+				t.Fatalf("Header(%q) = %q contains %U", v, got, r)
+			}
+		}
+
+		if got != strings.TrimSpace(got) {
+			t.Fatalf("Header(%q) = %q is not trimmed", v, got)
+		}
+	})
+}
+
+func FuzzText(f *testing.F) {
+	for _, seed := range []string{"Hello,\r\nthere", "one\rtwo", "\x00\x1b", "a\u0308\u2066"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, v string) {
+		got := Text(v)
+		if !utf8.ValidString(got) || !norm.NFC.IsNormalString(got) {
+			t.Fatalf("Text(%q) = %q is not valid UTF-8 in normalization form C", v, got)
+		}
+
+		for _, r := range got {
+			if r != '\n' && r != '\t' && (unicode.IsControl(r) || isBidiControl(r)) {
+				t.Fatalf("Text(%q) = %q contains %U", v, got, r)
+			}
+		}
+	})
+}
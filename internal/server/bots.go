@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/formtoken"
+	"github.com/brice-aldrich/mail-service/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// IssueFormToken issues the signed token a form embeds when it is rendered.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.IssueFormTokenRequest object containing the form ID.
+//
+// Returns:
+//   - *mailservice_v1.FormToken: The token and the time after which it is no longer accepted.
+//   - error: A FailedPrecondition error if form tokens are disabled, or an Internal error if the token could not be issued.
+func (s server) IssueFormToken(_ context.Context, req *mailservice_v1.IssueFormTokenRequest) (*mailservice_v1.FormToken, error) {
+	if s.formTokens == nil {
+		return nil, status.Error(codes.FailedPrecondition, "form tokens are disabled")
+	}
+
+	token, expiresAt, err := s.formTokens.Issue(s.formID(req.FormId))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to issue form token: %v", err)
+	}
+
+	return &mailservice_v1.FormToken{
+		Token:     token,
+		ExpiresAt: timestamppb.New(expiresAt),
+	}, nil
+}
+
+// automated reports whether a submission was made by a bot: it fills the honeypot field, or, when form tokens are
+// enabled, its token is missing, invalid, expired, replayed or shows the form was submitted faster than a person could.
+// The token is only verified here, and claimed by claimFormToken right before the submission is sent. Submissions are
+// not dropped when the used tokens cannot be looked up.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.SendMailRequest object containing the honeypot field and the form token.
+//
+// Returns:
+//   - string: Why the submission was considered automated, or an empty string if it was not.
+func (s server) automated(ctx context.Context, req *mailservice_v1.SendMailRequest) string {
+	if req.Honeypot != "" {
+		return "honeypot filled"
+	}
+
+	if s.formTokens == nil {
+		return ""
+	}
+
+	err := s.formTokens.Verify(ctx, req.FormRenderedAt, s.formID(req.FormId))
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, formtoken.ErrInvalid), errors.Is(err, formtoken.ErrTooFast),
+		errors.Is(err, formtoken.ErrExpired), errors.Is(err, formtoken.ErrReplayed):
+		return err.Error()
+	default:
+		s.logger.Warn("Form token could not be checked for replays", zap.String("form", s.formID(req.FormId)), zap.Error(err))
+		return ""
+	}
+}
+
+// claimFormToken marks the form token of a submission as used before it is sent. Only one of concurrent submissions
+// with the same token claims it; the others are replays. Submissions are not dropped when the token cannot be claimed
+// because the used tokens cannot be reached.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.SendMailRequest object containing the form token.
+//
+// Returns:
+//   - bool: Whether the token was claimed and should be released if the submission fails.
+//   - error: formtoken.ErrReplayed if another submission already claimed the token.
+func (s server) claimFormToken(ctx context.Context, req *mailservice_v1.SendMailRequest) (bool, error) {
+	if s.formTokens == nil {
+		return false, nil
+	}
+
+	err := s.formTokens.Use(ctx, req.FormRenderedAt)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, formtoken.ErrReplayed):
+		return false, err
+	default:
+		s.logger.Warn("Form token could not be marked as used", zap.String("form", s.formID(req.FormId)), zap.Error(err))
+		return false, nil
+	}
+}
+
+// releaseFormToken releases the form token of a submission that failed, so it can be corrected and submitted again.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.SendMailRequest object containing the form token.
+func (s server) releaseFormToken(ctx context.Context, req *mailservice_v1.SendMailRequest) {
+	if err := s.formTokens.Release(ctx, req.FormRenderedAt); err != nil {
+		s.logger.Warn("Form token could not be released", zap.String("form", s.formID(req.FormId)), zap.Error(err))
+	}
+}
+
+// dropAutomated logs a submission dropped as automated.
+func (s server) dropAutomated(ctx context.Context, req *mailservice_v1.SendMailRequest, reason string) {
+	s.logger.Info("Submission dropped, it looks automated",
+		zap.String("form", s.formID(req.FormId)),
+		zap.String("reason", reason),
+		zap.String("remote_ip", ratelimit.ClientIP(ctx, s.trustedHops)),
+	)
+}
+
+// formID returns the ID of the form a request selects, or the default form when it selects none.
+func (s server) formID(id string) string {
+	if id == "" {
+		return s.defaultForm
+	}
+
+	return id
+}
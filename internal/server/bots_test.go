@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/formtoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mockSigner struct {
+	err      error
+	useErr   error
+	formID   string
+	used     []string
+	released []string
+}
+
+func (m *mockSigner) Issue(formID string) (string, time.Time, error) {
+	m.formID = formID
+	return "token", time.Unix(1700000000, 0), nil
+}
+
+func (m *mockSigner) Verify(_ context.Context, _, formID string) error {
+	m.formID = formID
+	return m.err
+}
+
+func (m *mockSigner) Use(_ context.Context, token string) error {
+	if m.useErr != nil {
+		return m.useErr
+	}
+	m.used = append(m.used, token)
+	return nil
+}
+
+func (m *mockSigner) Release(_ context.Context, token string) error {
+	m.released = append(m.released, token)
+	return nil
+}
+
+func TestSendMailAutomatedUnit(t *testing.T) {
+	type input struct {
+		req        *mailservice_v1.SendMailRequest
+		formTokens *mockSigner
+		sendErr    error
+	}
+
+	type want struct {
+		sent     bool
+		formID   string
+		used     []string
+		released []string
+		code     codes.Code
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"sends submission without form tokens",
+			input{req: &mailservice_v1.SendMailRequest{Email: "jane@example.com"}},
+			want{sent: true},
+		},
+		{
+			"sends submission with valid form token",
+			input{req: &mailservice_v1.SendMailRequest{FormRenderedAt: "token"}, formTokens: &mockSigner{}},
+			want{sent: true, formID: "portfolio", used: []string{"token"}},
+		},
+		{
+			"releases token of failed submission",
+			input{
+				req:        &mailservice_v1.SendMailRequest{FormRenderedAt: "token"},
+				formTokens: &mockSigner{},
+				sendErr:    status.Error(codes.InvalidArgument, "email is required"),
+			},
+			want{sent: true, formID: "portfolio", used: []string{"token"}, released: []string{"token"}, code: codes.InvalidArgument},
+		},
+		{
+			"drops submission whose token was claimed concurrently",
+			input{req: &mailservice_v1.SendMailRequest{FormRenderedAt: "token"}, formTokens: &mockSigner{useErr: formtoken.ErrReplayed}},
+			want{formID: "portfolio"},
+		},
+		{
+			"sends submission when token cannot be claimed",
+			input{
+				req:        &mailservice_v1.SendMailRequest{FormRenderedAt: "token"},
+				formTokens: &mockSigner{useErr: errors.New("connection refused")},
+				sendErr:    status.Error(codes.InvalidArgument, "email is required"),
+			},
+			want{sent: true, formID: "portfolio", code: codes.InvalidArgument},
+		},
+		{
+			"sends submission when used tokens cannot be looked up",
+			input{req: &mailservice_v1.SendMailRequest{FormRenderedAt: "token"}, formTokens: &mockSigner{err: errors.New("connection refused")}},
+			want{sent: true, formID: "portfolio", used: []string{"token"}},
+		},
+		{
+			"drops submission filling the honeypot",
+			input{req: &mailservice_v1.SendMailRequest{Honeypot: "https://spam.example"}},
+			want{},
+		},
+		{
+			"drops submission submitted too fast",
+			input{req: &mailservice_v1.SendMailRequest{FormId: "shop", FormRenderedAt: "token"}, formTokens: &mockSigner{err: formtoken.ErrTooFast}},
+			want{formID: "shop"},
+		},
+		{
+			"drops submission with replayed token",
+			input{req: &mailservice_v1.SendMailRequest{FormRenderedAt: "token"}, formTokens: &mockSigner{err: formtoken.ErrReplayed}},
+			want{formID: "portfolio"},
+		},
+		{
+			"drops submission without token",
+			input{req: &mailservice_v1.SendMailRequest{}, formTokens: &mockSigner{err: formtoken.ErrInvalid}},
+			want{formID: "portfolio"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &mockOrchestrator{err: tt.input.sendErr}
+			cfg := Config{DefaultForm: "portfolio", Logger: zap.NewNop()}
+			if tt.input.formTokens != nil {
+				cfg.FormTokens = tt.input.formTokens
+			}
+
+			resp, err := New(orch, cfg).SendMail(context.Background(), tt.input.req)
+			if tt.want.code != codes.OK {
+				assert.Equal(t, tt.want.code, status.Code(err))
+			} else {
+				require.Empty(t, err)
+				assert.Len(t, resp.MessageId, 32)
+			}
+
+			if tt.want.sent {
+				assert.Len(t, orch.requests, 1)
+			} else {
+				assert.Empty(t, orch.requests)
+				assert.Equal(t, mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED, resp.AutoReplyStatus, "dropped submissions get the decoy of the form")
+			}
+
+			if tt.input.formTokens != nil {
+				assert.Equal(t, tt.want.formID, tt.input.formTokens.formID)
+				assert.Equal(t, tt.want.used, tt.input.formTokens.used)
+				assert.Equal(t, tt.want.released, tt.input.formTokens.released)
+			}
+		})
+	}
+}
+
+func TestSendMailConcurrentReplayUnit(t *testing.T) {
+	signer, err := formtoken.New(formtoken.Config{Secret: []byte("secret"), MaxAge: time.Hour, Store: formtoken.NewMemoryStore()})
+	require.Empty(t, err)
+
+	token, _, err := signer.Issue("portfolio")
+	require.Empty(t, err)
+
+	orch := &mockOrchestrator{}
+	s := New(orch, Config{FormTokens: signer, DefaultForm: "portfolio", Logger: zap.NewNop()})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := s.SendMail(context.Background(), &mailservice_v1.SendMailRequest{FormRenderedAt: token})
+			assert.Empty(t, err)
+			assert.Len(t, resp.MessageId, 32)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, orch.requests, 1)
+}
+
+func TestIssueFormTokenUnit(t *testing.T) {
+	signer := &mockSigner{}
+	s := New(&mockOrchestrator{}, Config{FormTokens: signer, DefaultForm: "portfolio"})
+
+	token, err := s.IssueFormToken(context.Background(), &mailservice_v1.IssueFormTokenRequest{})
+	require.Empty(t, err)
+	assert.Equal(t, "token", token.Token)
+	assert.Equal(t, int64(1700000000), token.ExpiresAt.GetSeconds())
+	assert.Equal(t, "portfolio", signer.formID)
+
+	_, err = New(&mockOrchestrator{}, Config{}).IssueFormToken(context.Background(), &mailservice_v1.IssueFormTokenRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
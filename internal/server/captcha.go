@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/captcha"
+	"github.com/brice-aldrich/mail-service/internal/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// verifyCaptcha verifies the CAPTCHA token of a submission with the verifier of its form. Forms without a verifier,
+// including unknown forms which the mail orchestrator rejects, are not verified.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.SendMailRequest object containing the form ID and the CAPTCHA token.
+//
+// Returns:
+//   - error: A PermissionDenied error if the token is missing or rejected, or an Unavailable error if it could not be verified.
+func (s server) verifyCaptcha(ctx context.Context, req *mailservice_v1.SendMailRequest) error {
+	v, ok := s.captchas[s.formID(req.FormId)]
+	if !ok || v == nil {
+		return nil
+	}
+
+	err := v.Verify(ctx, req.CaptchaToken, ratelimit.ClientIP(ctx, s.trustedHops))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, captcha.ErrRejected):
+		return status.Errorf(codes.PermissionDenied, "captcha verification failed: %v", err)
+	default:
+		return status.Errorf(codes.Unavailable, "captcha could not be verified: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/captcha"
+	"github.com/brice-aldrich/mail-service/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockOrchestrator struct {
+	mail.Orchestrator
+	mu       sync.Mutex
+	err      error
+	requests []*mailservice_v1.SendMailRequest
+}
+
+func (m *mockOrchestrator) SendMail(_ context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &mailservice_v1.SendMailResponse{MessageId: "9f1c2b7e4a5d4c3b8e6f0a1b2c3d4e5f"}, nil
+}
+
+func (m *mockOrchestrator) DecoyResponse(_ context.Context, _ *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
+	return &mailservice_v1.SendMailResponse{
+		MessageId:          "0a1b2c3d4e5f60718293a4b5c6d7e8f9",
+		AutoReplyStatus:    mailservice_v1.AutoReplyStatus_AUTO_REPLY_STATUS_QUEUED,
+		AutoReplyMessageId: "f9e8d7c6b5a4938271605f4e3d2c1b0a",
+	}, nil
+}
+
+type mockVerifier struct {
+	err      error
+	token    string
+	remoteIP string
+}
+
+func (m *mockVerifier) Verify(_ context.Context, token, remoteIP string) error {
+	m.token, m.remoteIP = token, remoteIP
+	return m.err
+}
+
+func TestSendMailCaptchaUnit(t *testing.T) {
+	type input struct {
+		formID string
+		err    error
+	}
+
+	type want struct {
+		code     codes.Code
+		verified bool
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{"accepts verified token", input{}, want{codes.OK, true}},
+		{"accepts verified token of the selected form", input{formID: "shop"}, want{codes.OK, true}},
+		{"skips forms without a captcha", input{formID: "blog"}, want{codes.OK, false}},
+		{"rejects rejected token", input{err: fmt.Errorf("%w: no token", captcha.ErrRejected)}, want{codes.PermissionDenied, true}},
+		{"handles provider failure", input{err: errors.New("connection refused")}, want{codes.Unavailable, true}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &mockOrchestrator{}
+			verifier := &mockVerifier{err: tt.input.err}
+			s := New(orch, Config{
+				Captchas:    map[string]captcha.Verifier{"portfolio": verifier, "shop": verifier, "blog": nil},
+				TrustedHops: 1,
+				DefaultForm: "portfolio",
+			})
+
+			// The leftmost entry is set by the client, the last one by the trusted proxy.
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-for", "198.51.100.9, 203.0.113.7, 10.0.0.1"))
+			_, err := s.SendMail(ctx, &mailservice_v1.SendMailRequest{FormId: tt.input.formID, CaptchaToken: "token"})
+			assert.Equal(t, tt.want.code, status.Code(err))
+
+			if tt.want.verified {
+				assert.Equal(t, "token", verifier.token)
+				assert.Equal(t, "203.0.113.7", verifier.remoteIP)
+			}
+
+			if tt.want.code == codes.OK {
+				assert.Len(t, orch.requests, 1)
+			} else {
+				assert.Empty(t, orch.requests)
+			}
+		})
+	}
+}
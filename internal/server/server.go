@@ -4,32 +4,66 @@ import (
 	"context"
 
 	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/captcha"
+	"github.com/brice-aldrich/mail-service/internal/formtoken"
 	"github.com/brice-aldrich/mail-service/internal/mail"
+	"go.uber.org/zap"
 )
 
 // server implements the mailservice_v1.MailServiceServer interface.
 // It holds a reference to the mail orchestrator which is used to handle email sending operations.
 type server struct {
-	mailOrch mail.Orchestrator
+	mailOrch    mail.Orchestrator
+	captchas    map[string]captcha.Verifier
+	formTokens  formtoken.Signer
+	trustedHops int
+	defaultForm string
+	logger      *zap.Logger
 	mailservice_v1.UnimplementedMailServiceServer
 }
 
+// Config holds the configuration of the server.
+//
+// Fields:
+//   - Captchas: The captcha.Verifier of every form that requires a CAPTCHA, keyed by form ID. Submissions to other forms are not verified.
+//   - FormTokens: The formtoken.Signer issuing and verifying the tokens forms embed when rendered. Submissions do not need a token when nil.
+//   - TrustedHops: The number of proxies in front of the gRPC-Gateway, whose X-Forwarded-For entries are trusted when looking up the IP address of a submitter.
+//   - DefaultForm: The ID of the form used by submissions that do not select one.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Captchas    map[string]captcha.Verifier
+	FormTokens  formtoken.Signer
+	TrustedHops int
+	DefaultForm string
+	Logger      *zap.Logger
+}
+
 // New creates a new instance of the server with the provided mail orchestrator.
 // It returns an implementation of the mailservice_v1.MailServiceServer interface.
 //
 // Parameters:
 //   - mailOrch: The mail.Orchestrator object used to handle email sending operations.
+//   - cfg: The Config object containing the CAPTCHA verifiers of the forms.
 //
 // Returns:
 //   - mailservice_v1.MailServiceServer: The newly created server instance.
-func New(mailOrch mail.Orchestrator) mailservice_v1.MailServiceServer {
+func New(mailOrch mail.Orchestrator, cfg Config) mailservice_v1.MailServiceServer {
 	return &server{
-		mailOrch: mailOrch,
+		mailOrch:    mailOrch,
+		captchas:    cfg.Captchas,
+		formTokens:  cfg.FormTokens,
+		trustedHops: cfg.TrustedHops,
+		defaultForm: cfg.DefaultForm,
+		logger:      cfg.Logger,
 	}
 }
 
 // SendMail handles the SendMail request by delegating the operation to the mail orchestrator.
-// It sends an email based on the provided request and returns the response.
+// Submissions that look automated are dropped, answering with a decoy response so bots do not learn they were caught.
+// It then verifies the CAPTCHA token of the submission when the form requires one, sends an email based on the
+// provided request and returns the response. The form token of the submission is claimed before it is sent, so
+// concurrent submissions with the same token are not all sent, and released when the send fails, so a submission that
+// failed, e.g. with a validation error, can be corrected and submitted again.
 //
 // Parameters:
 //   - ctx: The context.Context object for the request.
@@ -39,5 +73,145 @@ func New(mailOrch mail.Orchestrator) mailservice_v1.MailServiceServer {
 //   - *mailservice_v1.SendMailResponse: The response object indicating the result of the send mail operation.
 //   - error: An error if any occurred during the sending of the email.
 func (s server) SendMail(ctx context.Context, req *mailservice_v1.SendMailRequest) (*mailservice_v1.SendMailResponse, error) {
-	return s.mailOrch.SendMail(ctx, req)
+	if reason := s.automated(ctx, req); reason != "" {
+		s.dropAutomated(ctx, req, reason)
+		return s.mailOrch.DecoyResponse(ctx, req)
+	}
+
+	if err := s.verifyCaptcha(ctx, req); err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.claimFormToken(ctx, req)
+	if err != nil {
+		s.dropAutomated(ctx, req, err.Error())
+		return s.mailOrch.DecoyResponse(ctx, req)
+	}
+
+	resp, err := s.mailOrch.SendMail(ctx, req)
+	if err != nil {
+		if claimed {
+			s.releaseFormToken(ctx, req)
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetMessageStatus handles the GetMessageStatus request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.GetMessageStatusRequest object containing the message ID.
+//
+// Returns:
+//   - *mailservice_v1.MessageStatus: The delivery status of the message.
+//   - error: An error if the message does not exist or its status could not be retrieved.
+func (s server) GetMessageStatus(ctx context.Context, req *mailservice_v1.GetMessageStatusRequest) (*mailservice_v1.MessageStatus, error) {
+	return s.mailOrch.GetMessageStatus(ctx, req)
+}
+
+// ListMessages handles the ListMessages request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ListMessagesRequest object containing the state filter and pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListMessagesResponse: The page of messages and the token for the next page.
+//   - error: An error if the messages could not be listed.
+func (s server) ListMessages(ctx context.Context, req *mailservice_v1.ListMessagesRequest) (*mailservice_v1.ListMessagesResponse, error) {
+	return s.mailOrch.ListMessages(ctx, req)
+}
+
+// AddSuppression handles the AddSuppression request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.AddSuppressionRequest object containing the address to suppress.
+//
+// Returns:
+//   - *mailservice_v1.Suppression: The stored suppression.
+//   - error: An error if the address could not be suppressed.
+func (s server) AddSuppression(ctx context.Context, req *mailservice_v1.AddSuppressionRequest) (*mailservice_v1.Suppression, error) {
+	return s.mailOrch.AddSuppression(ctx, req)
+}
+
+// RemoveSuppression handles the RemoveSuppression request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.RemoveSuppressionRequest object containing the address to remove.
+//
+// Returns:
+//   - *mailservice_v1.RemoveSuppressionResponse: An empty response.
+//   - error: An error if the address is not suppressed or could not be removed.
+func (s server) RemoveSuppression(ctx context.Context, req *mailservice_v1.RemoveSuppressionRequest) (*mailservice_v1.RemoveSuppressionResponse, error) {
+	return s.mailOrch.RemoveSuppression(ctx, req)
+}
+
+// ListSuppressions handles the ListSuppressions request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ListSuppressionsRequest object containing the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListSuppressionsResponse: The page of suppressions and the token for the next page.
+//   - error: An error if the suppressions could not be listed.
+func (s server) ListSuppressions(ctx context.Context, req *mailservice_v1.ListSuppressionsRequest) (*mailservice_v1.ListSuppressionsResponse, error) {
+	return s.mailOrch.ListSuppressions(ctx, req)
+}
+
+// TrainSpamFilter handles the TrainSpamFilter request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.TrainSpamFilterRequest object containing the message and whether it is spam.
+//
+// Returns:
+//   - *mailservice_v1.TrainSpamFilterResponse: The number of spam and ham messages the classifier was trained with.
+//   - error: An error if spam filtering is disabled or the classifier could not be trained.
+func (s server) TrainSpamFilter(ctx context.Context, req *mailservice_v1.TrainSpamFilterRequest) (*mailservice_v1.TrainSpamFilterResponse, error) {
+	return s.mailOrch.TrainSpamFilter(ctx, req)
+}
+
+// ListQuarantine handles the ListQuarantine request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ListQuarantineRequest object containing the form filter and the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListQuarantineResponse: The page of quarantined submissions and the token for the next page.
+//   - error: An error if the quarantine is disabled or the page token is malformed.
+func (s server) ListQuarantine(ctx context.Context, req *mailservice_v1.ListQuarantineRequest) (*mailservice_v1.ListQuarantineResponse, error) {
+	return s.mailOrch.ListQuarantine(ctx, req)
+}
+
+// ReleaseQuarantined handles the ReleaseQuarantined request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ReleaseQuarantinedRequest object containing the ID of the submission.
+//
+// Returns:
+//   - *mailservice_v1.ReleaseQuarantinedResponse: The ID of the forwarded message.
+//   - error: An error if the submission is not quarantined or could not be forwarded.
+func (s server) ReleaseQuarantined(ctx context.Context, req *mailservice_v1.ReleaseQuarantinedRequest) (*mailservice_v1.ReleaseQuarantinedResponse, error) {
+	return s.mailOrch.ReleaseQuarantined(ctx, req)
+}
+
+// DiscardQuarantined handles the DiscardQuarantined request by delegating the operation to the mail orchestrator.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.DiscardQuarantinedRequest object containing the ID of the submission.
+//
+// Returns:
+//   - *mailservice_v1.DiscardQuarantinedResponse: An empty response.
+//   - error: An error if the submission is not quarantined.
+func (s server) DiscardQuarantined(ctx context.Context, req *mailservice_v1.DiscardQuarantinedRequest) (*mailservice_v1.DiscardQuarantinedResponse, error) {
+	return s.mailOrch.DiscardQuarantined(ctx, req)
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+)
+
+// templateServer implements the mailservice_v1.TemplateServiceServer interface.
+// It holds a reference to the template manager which is used to manage the email templates.
+type templateServer struct {
+	manager templates.Manager
+	mailservice_v1.UnimplementedTemplateServiceServer
+}
+
+// NewTemplateServer creates a new instance of the template server with the provided template manager.
+// It returns an implementation of the mailservice_v1.TemplateServiceServer interface.
+//
+// Parameters:
+//   - manager: The templates.Manager object used to manage the email templates.
+//
+// Returns:
+//   - mailservice_v1.TemplateServiceServer: The newly created server instance.
+func NewTemplateServer(manager templates.Manager) mailservice_v1.TemplateServiceServer {
+	return &templateServer{
+		manager: manager,
+	}
+}
+
+// CreateTemplate handles the CreateTemplate request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.CreateTemplateRequest object containing the template.
+//
+// Returns:
+//   - *mailservice_v1.Template: The stored template.
+//   - error: An error if the template could not be created.
+func (s templateServer) CreateTemplate(ctx context.Context, req *mailservice_v1.CreateTemplateRequest) (*mailservice_v1.Template, error) {
+	return s.manager.CreateTemplate(ctx, req)
+}
+
+// UpdateTemplate handles the UpdateTemplate request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.UpdateTemplateRequest object containing the template.
+//
+// Returns:
+//   - *mailservice_v1.Template: The stored template.
+//   - error: An error if the template could not be updated.
+func (s templateServer) UpdateTemplate(ctx context.Context, req *mailservice_v1.UpdateTemplateRequest) (*mailservice_v1.Template, error) {
+	return s.manager.UpdateTemplate(ctx, req)
+}
+
+// GetTemplate handles the GetTemplate request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.GetTemplateRequest object containing the template name.
+//
+// Returns:
+//   - *mailservice_v1.Template: The template.
+//   - error: An error if the template does not exist or could not be retrieved.
+func (s templateServer) GetTemplate(ctx context.Context, req *mailservice_v1.GetTemplateRequest) (*mailservice_v1.Template, error) {
+	return s.manager.GetTemplate(ctx, req)
+}
+
+// ListTemplates handles the ListTemplates request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.ListTemplatesRequest object containing the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListTemplatesResponse: The page of templates and the token for the next page.
+//   - error: An error if the templates could not be listed.
+func (s templateServer) ListTemplates(ctx context.Context, req *mailservice_v1.ListTemplatesRequest) (*mailservice_v1.ListTemplatesResponse, error) {
+	return s.manager.ListTemplates(ctx, req)
+}
+
+// DeleteTemplate handles the DeleteTemplate request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.DeleteTemplateRequest object containing the template name.
+//
+// Returns:
+//   - *mailservice_v1.DeleteTemplateResponse: An empty response.
+//   - error: An error if the template does not exist or could not be deleted.
+func (s templateServer) DeleteTemplate(ctx context.Context, req *mailservice_v1.DeleteTemplateRequest) (*mailservice_v1.DeleteTemplateResponse, error) {
+	return s.manager.DeleteTemplate(ctx, req)
+}
+
+// RenderPreview handles the RenderPreview request by delegating the operation to the template manager.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The mailservice_v1.RenderPreviewRequest object containing the template name and data.
+//
+// Returns:
+//   - *mailservice_v1.RenderPreviewResponse: The rendered email.
+//   - error: An error if the template does not exist or could not be rendered.
+func (s templateServer) RenderPreview(ctx context.Context, req *mailservice_v1.RenderPreviewRequest) (*mailservice_v1.RenderPreviewResponse, error) {
+	return s.manager.RenderPreview(ctx, req)
+}
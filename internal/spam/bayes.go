@@ -0,0 +1,170 @@
+package spam
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/brice-aldrich/mail-service/internal/store"
+)
+
+const (
+	// modelID is the ID of the record holding the model of the classifier.
+	modelID = "model"
+	// minTraining is the number of spam and of ham messages the classifier needs before it scores submissions.
+	minTraining = 5
+	// bayesScore is the score of a submission the classifier is certain is spam.
+	bayesScore = 6
+)
+
+// Stats holds the number of messages a Classifier was trained with.
+//
+// Fields:
+//   - Spam: The number of spam messages.
+//   - Ham: The number of legitimate messages.
+type Stats struct {
+	Spam int64
+	Ham  int64
+}
+
+// model holds the number of spam and ham messages every word appeared in.
+type model struct {
+	SpamMessages int64            `json:"spam_messages"`
+	HamMessages  int64            `json:"ham_messages"`
+	Spam         map[string]int64 `json:"spam"`
+	Ham          map[string]int64 `json:"ham"`
+}
+
+// Classifier is a naive Bayes classifier learning which words spam is made of from the messages it is trained with.
+// Its model is persisted, so training survives a restart.
+type Classifier struct {
+	mu     sync.RWMutex
+	model  model
+	models *store.Collection[model]
+}
+
+// NewClassifier opens the model of a Classifier stored in dir.
+//
+// Parameters:
+//   - dir: The directory the model is stored in. An empty dir keeps the model in memory.
+//
+// Returns:
+//   - *Classifier: The Classifier, trained with the stored model if there is one.
+//   - error: An error if the model could not be read.
+func NewClassifier(dir string) (*Classifier, error) {
+	models, err := store.Open[model](dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spam model: %w", err)
+	}
+
+	m, err := models.Get(modelID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("failed to read spam model: %w", err)
+	}
+
+	if m.Spam == nil {
+		m.Spam = map[string]int64{}
+	}
+	if m.Ham == nil {
+		m.Ham = map[string]int64{}
+	}
+
+	return &Classifier{
+		model:  m,
+		models: models,
+	}, nil
+}
+
+// Train learns from a message marked as spam or ham and saves the model.
+//
+// Parameters:
+//   - text: The text of the message.
+//   - spam: Whether the message is spam.
+//
+// Returns:
+//   - Stats: The number of spam and ham messages the classifier was trained with.
+//   - error: An error if the model could not be saved.
+func (c *Classifier) Train(text string, spam bool) (Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := c.model.Ham
+	if spam {
+		counts = c.model.Spam
+		c.model.SpamMessages++
+	} else {
+		c.model.HamMessages++
+	}
+
+	for word := range words(text) {
+		counts[word]++
+	}
+
+	if err := c.models.Put(modelID, c.model); err != nil {
+		return Stats{}, fmt.Errorf("failed to save spam model: %w", err)
+	}
+
+	return Stats{Spam: c.model.SpamMessages, Ham: c.model.HamMessages}, nil
+}
+
+// Probability returns the probability that a message is spam, between 0 and 1. It returns 0.5 until the classifier
+// was trained with enough spam and ham messages to tell them apart.
+//
+// Parameters:
+//   - text: The text of the message.
+//
+// Returns:
+//   - float64: The probability that the message is spam.
+func (c *Classifier) Probability(text string) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m := c.model
+	if m.SpamMessages < minTraining || m.HamMessages < minTraining {
+		return 0.5
+	}
+
+	total := float64(m.SpamMessages + m.HamMessages)
+	logSpam := math.Log(float64(m.SpamMessages) / total)
+	logHam := math.Log(float64(m.HamMessages) / total)
+
+	for word := range words(text) {
+		spam, ham := m.Spam[word], m.Ham[word]
+		if spam == 0 && ham == 0 {
+			continue
+		}
+
+		// Laplace smoothing keeps words seen in only one class from deciding on their own.
+		logSpam += math.Log((float64(spam) + 1) / (float64(m.SpamMessages) + 2))
+		logHam += math.Log((float64(ham) + 1) / (float64(m.HamMessages) + 2))
+	}
+
+	return 1 / (1 + math.Exp(logHam-logSpam))
+}
+
+// Check scores submissions the classifier considers more likely spam than not, up to the score of a certain spam.
+func (c *Classifier) Check(sub Submission) (float64, string) {
+	p := c.Probability(sub.text())
+	if p <= 0.5 {
+		return 0, ""
+	}
+
+	return math.Round((p-0.5)*2*bayesScore*100) / 100, fmt.Sprintf("classified as spam with probability %.2f", p)
+}
+
+// words returns the distinct words of a text, lowercased. Very short and very long words carry no signal.
+func words(text string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if n := len([]rune(w)); n >= 2 && n <= 32 {
+			set[w] = struct{}{}
+		}
+	}
+
+	return set
+}
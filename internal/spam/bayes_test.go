@@ -0,0 +1,54 @@
+package spam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifierUnit(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewClassifier(dir)
+	require.Empty(t, err)
+
+	spam := []string{
+		"Boost your SEO ranking with our backlinks",
+		"Cheap SEO backlinks, guaranteed first page ranking",
+		"We sell backlinks to boost your Google ranking",
+		"Get guaranteed traffic and SEO ranking today",
+		"Buy backlinks now, cheap SEO packages",
+	}
+	ham := []string{
+		"Hi, I'd like to discuss a project with you",
+		"Loved your talk, could we meet for coffee?",
+		"Are you available for a freelance project next month?",
+		"Thanks for the article, I have a question about it",
+		"Could you send me your resume for a role on our team?",
+	}
+
+	assert.Equal(t, 0.5, c.Probability("cheap backlinks"), "untrained classifiers are undecided")
+
+	for i := range spam {
+		_, err := c.Train(spam[i], true)
+		require.Empty(t, err)
+
+		stats, err := c.Train(ham[i], false)
+		require.Empty(t, err)
+		assert.Equal(t, Stats{Spam: int64(i + 1), Ham: int64(i + 1)}, stats)
+	}
+
+	assert.Greater(t, c.Probability("Cheap backlinks for your SEO"), 0.9)
+	assert.Less(t, c.Probability("I have a project, are you available?"), 0.1)
+
+	score, reason := c.Check(Submission{Subject: "SEO", Message: "Cheap backlinks and ranking"})
+	assert.Greater(t, score, 5.0)
+	assert.Contains(t, reason, "classified as spam")
+
+	score, _ = c.Check(Submission{Message: "Could we meet about a project?"})
+	assert.Zero(t, score)
+
+	reopened, err := NewClassifier(dir)
+	require.Empty(t, err)
+	assert.Equal(t, c.Probability("Cheap backlinks for your SEO"), reopened.Probability("Cheap backlinks for your SEO"))
+}
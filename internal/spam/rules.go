@@ -0,0 +1,228 @@
+package spam
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The scores of the built-in rules. Thresholds are set relative to them.
+const (
+	linkScore      = 1
+	shortenerScore = 3
+	keywordScore   = 2
+	patternScore   = 3
+	scriptScore    = 3
+	repeatScore    = 5
+)
+
+// linkPattern matches the links of a text, with or without a scheme.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// links returns the links of a text.
+func links(text string) []string {
+	return linkPattern.FindAllString(text, -1)
+}
+
+// linkRule scores every link beyond the first max links of a submission.
+type linkRule struct {
+	max int
+}
+
+func (r linkRule) Check(sub Submission) (float64, string) {
+	n := len(links(sub.text()))
+	if n <= r.max {
+		return 0, ""
+	}
+
+	return float64(n-r.max) * linkScore, fmt.Sprintf("contains %d links", n)
+}
+
+// shortenerRule scores every URL shortener a submission links to. Shorteners hide where a link leads.
+type shortenerRule struct {
+	domains map[string]struct{}
+}
+
+func newShortenerRule(domains []string) shortenerRule {
+	r := shortenerRule{domains: make(map[string]struct{}, len(domains))}
+	for _, d := range domains {
+		r.domains[strings.ToLower(strings.TrimSpace(d))] = struct{}{}
+	}
+
+	return r
+}
+
+func (r shortenerRule) Check(sub Submission) (float64, string) {
+	found := map[string]struct{}{}
+	for _, link := range links(sub.text()) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		if _, ok := r.domains[host]; ok {
+			found[host] = struct{}{}
+		}
+	}
+
+	if len(found) == 0 {
+		return 0, ""
+	}
+
+	return float64(len(found)) * shortenerScore, "links to a url shortener"
+}
+
+// keywordRule scores every blocked keyword and pattern a submission matches.
+type keywordRule struct {
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+func newKeywordRule(keywords, patterns []string) (keywordRule, error) {
+	r := keywordRule{}
+	for _, k := range keywords {
+		if k = normalize(k); k != "" {
+			r.keywords = append(r.keywords, k)
+		}
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return keywordRule{}, fmt.Errorf("invalid spam pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+func (r keywordRule) Check(sub Submission) (float64, string) {
+	text := normalize(sub.text())
+
+	var score float64
+	var matched []string
+	for _, k := range r.keywords {
+		if strings.Contains(text, k) {
+			score += keywordScore
+			matched = append(matched, fmt.Sprintf("%q", k))
+		}
+	}
+
+	for _, re := range r.patterns {
+		if re.MatchString(sub.text()) {
+			score += patternScore
+			matched = append(matched, re.String()[len("(?i)"):])
+		}
+	}
+
+	if score == 0 {
+		return 0, ""
+	}
+
+	return score, "matches blocked content " + strings.Join(matched, ", ")
+}
+
+// scriptRule scores submissions mostly written in scripts other than the expected ones, e.g. Cyrillic spam sent to
+// an English site.
+type scriptRule struct {
+	scripts []*unicode.RangeTable
+	names   []string
+}
+
+func newScriptRule(names []string) (scriptRule, error) {
+	r := scriptRule{}
+	for _, name := range names {
+		table, ok := unicode.Scripts[name]
+		if !ok {
+			return scriptRule{}, fmt.Errorf("unknown unicode script %q", name)
+		}
+		r.scripts = append(r.scripts, table)
+		r.names = append(r.names, name)
+	}
+
+	return r, nil
+}
+
+func (r scriptRule) Check(sub Submission) (float64, string) {
+	var letters, foreign int
+	for _, c := range sub.text() {
+		if !unicode.IsLetter(c) {
+			continue
+		}
+
+		letters++
+		if !unicode.In(c, r.scripts...) {
+			foreign++
+		}
+	}
+
+	if letters == 0 || foreign*2 <= letters {
+		return 0, ""
+	}
+
+	return scriptScore, "not written in " + strings.Join(r.names, " or ")
+}
+
+// repeatRule scores messages identical to one submitted within the window, e.g. the same pitch sent to every form.
+type repeatRule struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[[sha256.Size]byte]time.Time
+	lastSweep time.Time
+}
+
+func newRepeatRule(window time.Duration) *repeatRule {
+	return &repeatRule{
+		window: window,
+		now:    time.Now,
+		seen:   make(map[[sha256.Size]byte]time.Time),
+	}
+}
+
+func (r *repeatRule) Check(sub Submission) (float64, string) {
+	message := normalize(sub.Message)
+	if message == "" {
+		return 0, ""
+	}
+
+	sum := sha256.Sum256([]byte(message))
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= time.Minute {
+		r.lastSweep = now
+		for k, at := range r.seen {
+			if now.Sub(at) >= r.window {
+				delete(r.seen, k)
+			}
+		}
+	}
+
+	last, ok := r.seen[sum]
+	r.seen[sum] = now
+	if !ok || now.Sub(last) >= r.window {
+		return 0, ""
+	}
+
+	return repeatScore, "repeats a recent message"
+}
+
+// normalize lowercases a text and collapses its whitespace, so trivially different copies compare equal.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), unicode.IsSpace), " ")
+}
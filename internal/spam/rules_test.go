@@ -0,0 +1,68 @@
+package spam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesUnit(t *testing.T) {
+	keywords, err := newKeywordRule([]string{"Casino", "backlinks  for sale"}, []string{`\bv[i1]agra\b`})
+	require.Empty(t, err)
+
+	scripts, err := newScriptRule([]string{"Latin"})
+	require.Empty(t, err)
+
+	type want struct {
+		score  float64
+		reason string
+	}
+
+	cases := []struct {
+		name  string
+		rule  Rule
+		input Submission
+		want  want
+	}{
+		{"accepts links up to the maximum", linkRule{max: 1}, Submission{Message: "See https://example.com"}, want{}},
+		{"scores links beyond the maximum", linkRule{max: 1}, Submission{Subject: "www.a.example", Message: "https://b.example http://c.example"}, want{2, "contains 3 links"}},
+		{"scores url shorteners", newShortenerRule([]string{"bit.ly", "t.co"}), Submission{Message: "https://BIT.ly/x www.bit.ly/y https://t.co/z"}, want{6, "links to a url shortener"}},
+		{"ignores other domains", newShortenerRule([]string{"bit.ly"}), Submission{Message: "https://notbit.ly/x https://example.com/bit.ly"}, want{}},
+		{"scores keywords", keywords, Submission{Subject: "CASINO", Message: "Backlinks\nfor sale"}, want{4, `matches blocked content "casino", "backlinks for sale"`}},
+		{"scores patterns", keywords, Submission{Message: "Buy V1agra now"}, want{3, `matches blocked content \bv[i1]agra\b`}},
+		{"accepts expected script", scripts, Submission{Message: "Hello, I'd like a quote. Привет"}, want{}},
+		{"scores foreign script", scripts, Submission{Message: "Здравствуйте, предлагаем продвижение сайта"}, want{3, "not written in Latin"}},
+		{"ignores text without letters", scripts, Submission{Message: "123 !!!"}, want{}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reason := tt.rule.Check(tt.input)
+			assert.Equal(t, tt.want.score, score)
+			assert.Equal(t, tt.want.reason, reason)
+		})
+	}
+}
+
+func TestRepeatRuleUnit(t *testing.T) {
+	r := newRepeatRule(time.Hour)
+	now := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return now }
+
+	score, _ := r.Check(Submission{Message: "Hello there"})
+	assert.Zero(t, score)
+
+	now = now.Add(time.Minute)
+	score, reason := r.Check(Submission{Message: "  HELLO\nthere "})
+	assert.Equal(t, float64(repeatScore), score)
+	assert.Equal(t, "repeats a recent message", reason)
+
+	score, _ = r.Check(Submission{Message: "Something else"})
+	assert.Zero(t, score)
+
+	now = now.Add(2 * time.Hour)
+	score, _ = r.Check(Submission{Message: "Hello there"})
+	assert.Zero(t, score, "messages are forgotten after the window")
+}
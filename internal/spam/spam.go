@@ -0,0 +1,219 @@
+package spam
+
+import (
+	"errors"
+	"time"
+)
+
+// Verdict is the action a Filter decides on for a submission.
+type Verdict int
+
+const (
+	// VerdictAccept forwards the submission as is.
+	VerdictAccept Verdict = iota
+	// VerdictTag forwards the submission with its subject tagged with "[SPAM?]".
+	VerdictTag
+	// VerdictQuarantine holds the submission back for review.
+	VerdictQuarantine
+	// VerdictDrop drops the submission without forwarding it.
+	VerdictDrop
+)
+
+// String returns the name of the verdict, e.g. "quarantine".
+func (v Verdict) String() string {
+	switch v {
+	case VerdictTag:
+		return "tag"
+	case VerdictQuarantine:
+		return "quarantine"
+	case VerdictDrop:
+		return "drop"
+	default:
+		return "accept"
+	}
+}
+
+// Submission is the content of a submission the rules score.
+//
+// Fields:
+//   - FormID: The ID of the form the submission was made to.
+//   - Name: The name of the submitter.
+//   - Email: The email address of the submitter.
+//   - Subject: The subject entered by the submitter.
+//   - Message: The message entered by the submitter.
+type Submission struct {
+	FormID  string
+	Name    string
+	Email   string
+	Subject string
+	Message string
+}
+
+// text returns the free text of the submission, which the rules look for spam in.
+func (s Submission) text() string {
+	return s.Subject + "\n" + s.Message
+}
+
+// Rule scores a submission. Filters sum the scores of their rules, so rules can be added to a Filter without knowing
+// of each other.
+//
+// Methods:
+//   - Check: Scores a submission. It returns the score, 0 when the rule does not apply, and the reason for the score.
+type Rule interface {
+	Check(sub Submission) (float64, string)
+}
+
+// Thresholds are the scores from which a Filter tags, quarantines or drops a submission. A threshold of zero
+// disables its verdict.
+//
+// Fields:
+//   - Tag: The score from which the subject of a submission is tagged with "[SPAM?]".
+//   - Quarantine: The score from which a submission is quarantined.
+//   - Drop: The score from which a submission is dropped.
+type Thresholds struct {
+	Tag        float64
+	Quarantine float64
+	Drop       float64
+}
+
+// Result is the outcome of scoring a submission.
+//
+// Fields:
+//   - Score: The sum of the scores of the rules.
+//   - Reasons: The reasons of the rules that scored the submission.
+//   - Verdict: The action decided on by the thresholds.
+type Result struct {
+	Score   float64
+	Reasons []string
+	Verdict Verdict
+}
+
+// Config holds the configuration of a Filter. Built-in rules are disabled when their settings are empty.
+//
+// Fields:
+//   - Thresholds: The Thresholds deciding on the verdict of a submission.
+//   - MaxLinks: The number of links a submission may contain before every further link adds to its score. Negative disables the rule.
+//   - Shorteners: The domains of URL shorteners, e.g. "bit.ly", whose links add to the score.
+//   - Keywords: The words and phrases, matched case-insensitively, that add to the score.
+//   - Patterns: The regular expressions that add to the score.
+//   - Scripts: The Unicode scripts submissions are expected in, e.g. "Latin". Submissions mostly written in other scripts add to the score.
+//   - RepeatWindow: How long identical messages are remembered. Messages repeated within the window add to the score.
+//   - Classifier: The naive Bayes Classifier trained with messages marked as spam or ham. Leave nil to disable.
+//   - Rules: Additional rules scoring the submissions.
+type Config struct {
+	Thresholds   Thresholds
+	MaxLinks     int
+	Shorteners   []string
+	Keywords     []string
+	Patterns     []string
+	Scripts      []string
+	RepeatWindow time.Duration
+	Classifier   *Classifier
+	Rules        []Rule
+}
+
+// Filter scores submissions with a pipeline of rules and decides what to do with them.
+type Filter struct {
+	rules      []Rule
+	thresholds Thresholds
+	classifier *Classifier
+}
+
+// New creates a Filter with the built-in rules enabled by the configuration, followed by the additional rules.
+//
+// Parameters:
+//   - cfg: The Config object containing the thresholds and the settings of the rules.
+//
+// Returns:
+//   - *Filter: The newly created Filter.
+//   - error: An error if a pattern or script is invalid.
+func New(cfg Config) (*Filter, error) {
+	f := &Filter{
+		thresholds: cfg.Thresholds,
+		classifier: cfg.Classifier,
+	}
+
+	if cfg.MaxLinks >= 0 {
+		f.rules = append(f.rules, linkRule{max: cfg.MaxLinks})
+	}
+
+	if len(cfg.Shorteners) > 0 {
+		f.rules = append(f.rules, newShortenerRule(cfg.Shorteners))
+	}
+
+	if len(cfg.Keywords) > 0 || len(cfg.Patterns) > 0 {
+		rule, err := newKeywordRule(cfg.Keywords, cfg.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		f.rules = append(f.rules, rule)
+	}
+
+	if len(cfg.Scripts) > 0 {
+		rule, err := newScriptRule(cfg.Scripts)
+		if err != nil {
+			return nil, err
+		}
+		f.rules = append(f.rules, rule)
+	}
+
+	if cfg.RepeatWindow > 0 {
+		f.rules = append(f.rules, newRepeatRule(cfg.RepeatWindow))
+	}
+
+	if cfg.Classifier != nil {
+		f.rules = append(f.rules, cfg.Classifier)
+	}
+
+	f.rules = append(f.rules, cfg.Rules...)
+
+	return f, nil
+}
+
+// Evaluate scores a submission with every rule and decides on its verdict.
+//
+// Parameters:
+//   - sub: The Submission to score.
+//
+// Returns:
+//   - Result: The score, the reasons and the verdict.
+func (f *Filter) Evaluate(sub Submission) Result {
+	var res Result
+	for _, rule := range f.rules {
+		score, reason := rule.Check(sub)
+		if score == 0 {
+			continue
+		}
+
+		res.Score += score
+		res.Reasons = append(res.Reasons, reason)
+	}
+
+	switch {
+	case f.thresholds.Drop > 0 && res.Score >= f.thresholds.Drop:
+		res.Verdict = VerdictDrop
+	case f.thresholds.Quarantine > 0 && res.Score >= f.thresholds.Quarantine:
+		res.Verdict = VerdictQuarantine
+	case f.thresholds.Tag > 0 && res.Score >= f.thresholds.Tag:
+		res.Verdict = VerdictTag
+	}
+
+	return res
+}
+
+// Train trains the classifier of the filter with a submission marked as spam or ham.
+//
+// Parameters:
+//   - sub: The Submission to learn from.
+//   - spam: Whether the submission is spam.
+//
+// Returns:
+//   - Stats: The number of spam and ham messages the classifier was trained with.
+//   - error: An error if the filter has no classifier or the model could not be saved.
+func (f *Filter) Train(sub Submission, spam bool) (Stats, error) {
+	if f.classifier == nil {
+		return Stats{}, errors.New("spam filter has no classifier")
+	}
+
+	return f.classifier.Train(sub.text(), spam)
+}
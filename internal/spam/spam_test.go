@@ -0,0 +1,90 @@
+package spam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedRule float64
+
+func (r fixedRule) Check(Submission) (float64, string) {
+	return float64(r), "fixed"
+}
+
+func TestEvaluateUnit(t *testing.T) {
+	thresholds := Thresholds{Tag: 3, Quarantine: 6, Drop: 10}
+
+	cases := []struct {
+		name  string
+		input Config
+		want  Result
+	}{
+		{
+			"accepts submission below the thresholds",
+			Config{Thresholds: thresholds, MaxLinks: 2},
+			Result{Score: 1, Reasons: []string{"contains 3 links"}, Verdict: VerdictAccept},
+		},
+		{
+			"tags suspicious submission",
+			Config{Thresholds: thresholds, MaxLinks: 2, Keywords: []string{"SEO services"}},
+			Result{Score: 3, Reasons: []string{"contains 3 links", `matches blocked content "seo services"`}, Verdict: VerdictTag},
+		},
+		{
+			"quarantines likely spam",
+			Config{Thresholds: thresholds, MaxLinks: 0, Shorteners: []string{"bit.ly"}},
+			Result{Score: 6, Reasons: []string{"contains 3 links", "links to a url shortener"}, Verdict: VerdictQuarantine},
+		},
+		{
+			"drops certain spam",
+			Config{Thresholds: thresholds, MaxLinks: -1, Rules: []Rule{fixedRule(4), fixedRule(7)}},
+			Result{Score: 11, Reasons: []string{"fixed", "fixed"}, Verdict: VerdictDrop},
+		},
+		{
+			"skips disabled verdicts",
+			Config{Thresholds: Thresholds{Tag: 3}, MaxLinks: -1, Rules: []Rule{fixedRule(11)}},
+			Result{Score: 11, Reasons: []string{"fixed"}, Verdict: VerdictTag},
+		},
+	}
+
+	sub := Submission{
+		Subject: "Grow your traffic",
+		Message: "We offer SEO   services: https://bit.ly/seo www.example.com and http://example.org/rank",
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.input)
+			require.Empty(t, err)
+
+			assert.Equal(t, tt.want, f.Evaluate(sub))
+		})
+	}
+}
+
+func TestNewUnit(t *testing.T) {
+	_, err := New(Config{Patterns: []string{"(unclosed"}})
+	assert.ErrorContains(t, err, `invalid spam pattern "(unclosed"`)
+
+	_, err = New(Config{Scripts: []string{"Klingon"}})
+	assert.ErrorContains(t, err, `unknown unicode script "Klingon"`)
+}
+
+func TestTrainUnit(t *testing.T) {
+	f, err := New(Config{})
+	require.Empty(t, err)
+
+	_, err = f.Train(Submission{Message: "Cheap pills"}, true)
+	assert.ErrorContains(t, err, "no classifier")
+
+	c, err := NewClassifier("")
+	require.Empty(t, err)
+
+	f, err = New(Config{Classifier: c})
+	require.Empty(t, err)
+
+	stats, err := f.Train(Submission{Message: "Cheap pills"}, true)
+	require.Empty(t, err)
+	assert.Equal(t, Stats{Spam: 1}, stats)
+}
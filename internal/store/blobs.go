@@ -0,0 +1,129 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const blobExt = ".bin"
+
+// Blobs is an embedded, file-backed set of binary blobs keyed by an ID, e.g. the content of attachments.
+//
+// Unlike a Collection, blobs stored on disk are not kept in memory: they are read from disk every time they are
+// requested, so large content does not add to the memory used by the service. Blobs are written atomically like the
+// records of a Collection. Blobs opened without a directory only live in memory.
+type Blobs struct {
+	dir   string
+	mu    sync.RWMutex
+	items map[string][]byte
+}
+
+// OpenBlobs opens the blobs stored in dir, creating the directory if needed. An empty dir opens a set of blobs that is
+// only kept in memory.
+//
+// Parameters:
+//   - dir: The directory the blobs are stored in.
+//
+// Returns:
+//   - *Blobs: The opened set of blobs.
+//   - error: An error if the directory could not be created.
+func OpenBlobs(dir string) (*Blobs, error) {
+	b := &Blobs{dir: dir}
+	if dir == "" {
+		b.items = map[string][]byte{}
+		return b, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	return b, nil
+}
+
+// Put creates or replaces the blob with the given ID.
+//
+// Parameters:
+//   - id: The ID of the blob.
+//   - data: The content of the blob.
+//
+// Returns:
+//   - error: An error if the blob could not be persisted.
+func (b *Blobs) Put(id string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dir == "" {
+		b.items[id] = append([]byte(nil), data...)
+		return nil
+	}
+
+	if err := writeFile(b.dir, b.path(id), data); err != nil {
+		return fmt.Errorf("failed to write blob %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// Get returns the content of the blob with the given ID.
+//
+// Parameters:
+//   - id: The ID of the blob.
+//
+// Returns:
+//   - []byte: The content of the blob.
+//   - error: ErrNotFound if the blob does not exist, or an error if it could not be read.
+func (b *Blobs) Get(id string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.dir == "" {
+		data, ok := b.items[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+
+		return append([]byte(nil), data...), nil
+	}
+
+	data, err := os.ReadFile(b.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %q: %w", id, err)
+	}
+
+	return data, nil
+}
+
+// Delete removes the blob with the given ID. Deleting a blob that does not exist is not an error.
+//
+// Parameters:
+//   - id: The ID of the blob.
+//
+// Returns:
+//   - error: An error if the blob could not be removed from disk.
+func (b *Blobs) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dir == "" {
+		delete(b.items, id)
+		return nil
+	}
+
+	if err := os.Remove(b.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob %q: %w", id, err)
+	}
+
+	return nil
+}
+
+func (b *Blobs) path(id string) string {
+	return filepath.Join(b.dir, base64.RawURLEncoding.EncodeToString([]byte(id))+blobExt)
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when a record does not exist in the collection.
+var ErrNotFound = errors.New("record not found")
+
+const fileExt = ".json"
+
+// Collection is an embedded, file-backed set of records of type T keyed by an ID.
+//
+// Every record is persisted as its own JSON document inside the collection directory and is
+// written atomically (write to a temporary file followed by a rename), so a crash never leaves a
+// partially written record behind. All records are also kept in memory, which makes reads cheap.
+// A Collection opened without a directory only lives in memory.
+type Collection[T any] struct {
+	dir   string
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+// Open opens the collection stored in dir, creating the directory if needed, and loads every record into memory.
+// An empty dir opens a collection that is only kept in memory.
+//
+// Parameters:
+//   - dir: The directory the records are stored in.
+//
+// Returns:
+//   - *Collection[T]: The opened collection.
+//   - error: An error if the directory could not be created or a record could not be read.
+func Open[T any](dir string) (*Collection[T], error) {
+	c := &Collection[T]{
+		dir:   dir,
+		items: map[string]T{},
+	}
+
+	if dir == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileExt) {
+			continue
+		}
+
+		id, err := decodeID(strings.TrimSuffix(e.Name(), fileExt))
+		if err != nil {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record %q: %w", id, err)
+		}
+
+		var v T
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode record %q: %w", id, err)
+		}
+
+		c.items[id] = v
+	}
+
+	return c, nil
+}
+
+// Put creates or replaces the record with the given ID.
+//
+// Parameters:
+//   - id: The ID of the record.
+//   - v: The record to store.
+//
+// Returns:
+//   - error: An error if the record could not be persisted.
+func (c *Collection[T]) Put(id string, v T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dir != "" {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode record %q: %w", id, err)
+		}
+
+		if err := writeFile(c.dir, c.path(id), b); err != nil {
+			return fmt.Errorf("failed to write record %q: %w", id, err)
+		}
+	}
+
+	c.items[id] = v
+	return nil
+}
+
+// Get returns the record with the given ID.
+//
+// Parameters:
+//   - id: The ID of the record.
+//
+// Returns:
+//   - T: The record.
+//   - error: ErrNotFound if the record does not exist.
+func (c *Collection[T]) Get(id string) (T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v, ok := c.items[id]
+	if !ok {
+		return v, ErrNotFound
+	}
+
+	return v, nil
+}
+
+// Delete removes the record with the given ID. Deleting a record that does not exist is not an error.
+//
+// Parameters:
+//   - id: The ID of the record.
+//
+// Returns:
+//   - error: An error if the record could not be removed from disk.
+func (c *Collection[T]) Delete(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dir != "" {
+		if err := os.Remove(c.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete record %q: %w", id, err)
+		}
+	}
+
+	delete(c.items, id)
+	return nil
+}
+
+// List returns every record for which keep returns true, ordered by ID. A nil keep function returns all records.
+//
+// Parameters:
+//   - keep: An optional filter function.
+//
+// Returns:
+//   - []T: The matching records.
+func (c *Collection[T]) List(keep func(id string, v T) bool) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.items))
+	for id, v := range c.items {
+		if keep == nil || keep(id, v) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	out := make([]T, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, c.items[id])
+	}
+
+	return out
+}
+
+func (c *Collection[T]) path(id string) string {
+	return filepath.Join(c.dir, base64.RawURLEncoding.EncodeToString([]byte(id))+fileExt)
+}
+
+// writeFile writes b to path atomically: it is written to a temporary file in dir, synced, and renamed over path.
+func writeFile(dir, path string, b []byte) error {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func decodeID(name string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
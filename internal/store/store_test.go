@@ -0,0 +1,120 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	Name  string
+	Count int
+}
+
+func TestCollectionUnit(t *testing.T) {
+	cases := []struct {
+		name string
+		dir  func(t *testing.T) string
+	}{
+		{
+			"is successful in memory",
+			func(t *testing.T) string { return "" },
+		},
+		{
+			"is successful on disk",
+			func(t *testing.T) string { return t.TempDir() },
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Open[record](tt.dir(t))
+			require.Empty(t, err)
+
+			_, err = c.Get("missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.Empty(t, c.Put("b", record{Name: "b", Count: 2}))
+			require.Empty(t, c.Put("a/../a", record{Name: "a", Count: 1}))
+
+			v, err := c.Get("a/../a")
+			require.Empty(t, err)
+			assert.Equal(t, record{Name: "a", Count: 1}, v)
+
+			assert.Equal(t, []record{{Name: "a", Count: 1}, {Name: "b", Count: 2}}, c.List(nil))
+			assert.Equal(t, []record{{Name: "b", Count: 2}}, c.List(func(_ string, r record) bool { return r.Count > 1 }))
+
+			require.Empty(t, c.Delete("b"))
+			require.Empty(t, c.Delete("b"))
+			assert.Equal(t, []record{{Name: "a", Count: 1}}, c.List(nil))
+		})
+	}
+}
+
+func TestCollectionReopenUnit(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open[record](dir)
+	require.Empty(t, err)
+	require.Empty(t, c.Put("someone@example.com", record{Name: "persisted"}))
+
+	// Stray files must not break loading the collection.
+	require.Empty(t, os.WriteFile(filepath.Join(dir, "README"), []byte("ignore me"), 0o600))
+
+	reopened, err := Open[record](dir)
+	require.Empty(t, err)
+
+	v, err := reopened.Get("someone@example.com")
+	require.Empty(t, err)
+	assert.Equal(t, "persisted", v.Name)
+}
+
+func TestBlobsUnit(t *testing.T) {
+	cases := []struct {
+		name string
+		dir  func(t *testing.T) string
+	}{
+		{
+			"is successful in memory",
+			func(t *testing.T) string { return "" },
+		},
+		{
+			"is successful on disk",
+			func(t *testing.T) string { return t.TempDir() },
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir(t)
+			b, err := OpenBlobs(dir)
+			require.Empty(t, err)
+
+			_, err = b.Get("missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.Empty(t, b.Put("m1/0", []byte("%PDF-1.4")))
+
+			data, err := b.Get("m1/0")
+			require.Empty(t, err)
+			assert.Equal(t, []byte("%PDF-1.4"), data)
+
+			if dir != "" {
+				reopened, err := OpenBlobs(dir)
+				require.Empty(t, err)
+
+				data, err = reopened.Get("m1/0")
+				require.Empty(t, err)
+				assert.Equal(t, []byte("%PDF-1.4"), data)
+			}
+
+			require.Empty(t, b.Delete("m1/0"))
+			require.Empty(t, b.Delete("m1/0"))
+			_, err = b.Get("m1/0")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
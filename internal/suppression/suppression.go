@@ -0,0 +1,395 @@
+package suppression
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/brice-aldrich/mail-service/internal/store"
+	"go.uber.org/zap"
+)
+
+// Reason is the reason an address was added to the suppression list.
+type Reason string
+
+const (
+	// ReasonBounce means mail to the address hard-bounced.
+	ReasonBounce Reason = "bounce"
+	// ReasonComplaint means the recipient marked a message as spam.
+	ReasonComplaint Reason = "complaint"
+	// ReasonManual means the address was added by an administrator.
+	ReasonManual Reason = "manual"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var (
+	// ErrNotFound is returned when an address is not on the suppression list.
+	ErrNotFound = errors.New("address not suppressed")
+	// ErrInvalidAddress is returned when an entry does not hold a valid email address.
+	ErrInvalidAddress = errors.New("invalid email address")
+	// ErrInvalidPageToken is returned when a page token passed to List is malformed.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// Entry is an address on the suppression list.
+//
+// Fields:
+//   - Address: The suppressed email address, normalized to lower case.
+//   - Reason: Why the address was suppressed.
+//   - Detail: A short human readable description, e.g. the bounce type or a note from an administrator.
+//   - CreatedAt: When the address was suppressed.
+//   - ExpiresAt: When the suppression ends. A zero value never expires.
+type Entry struct {
+	Address   string
+	Reason    Reason
+	Detail    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Active reports whether the entry still suppresses its address at the given time.
+//
+// Parameters:
+//   - now: The time to check the entry at.
+//
+// Returns:
+//   - bool: True if the entry has not expired.
+func (e Entry) Active(now time.Time) bool {
+	return e.ExpiresAt.IsZero() || now.Before(e.ExpiresAt)
+}
+
+// sesClient is an interface that defines the methods from the AWS SES client that are used to keep the
+// suppression list in sync with the SES account-level suppression list.
+type sesClient interface {
+	PutSuppressedDestination(ctx context.Context, params *sesv2.PutSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.PutSuppressedDestinationOutput, error)
+	DeleteSuppressedDestination(ctx context.Context, params *sesv2.DeleteSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.DeleteSuppressedDestinationOutput, error)
+	ListSuppressedDestinations(ctx context.Context, params *sesv2.ListSuppressedDestinationsInput, optFns ...func(*sesv2.Options)) (*sesv2.ListSuppressedDestinationsOutput, error)
+}
+
+// Config holds the configuration required to initialize the suppression List.
+//
+// Fields:
+//   - Dir: The directory the suppression list is persisted in. An empty directory keeps the list in memory.
+//   - SES: An optional SES client. When set, entries are mirrored to and imported from the SES account-level suppression list.
+//   - SyncInterval: How often expired entries are pruned and the SES account-level suppression list is imported. Defaults to one hour.
+//   - Logger: The zap.Logger object used for logging.
+type Config struct {
+	Dir          string
+	SES          sesClient
+	SyncInterval time.Duration
+	Logger       *zap.Logger
+}
+
+// List is a durable, file-backed list of addresses that must not be mailed.
+type List struct {
+	entries  *store.Collection[Entry]
+	ses      sesClient
+	interval time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// New creates a new suppression List and loads any entries persisted by a previous run.
+//
+// Parameters:
+//   - cfg: The Config object containing the storage directory and the optional SES client.
+//
+// Returns:
+//   - *List: The newly created List instance.
+//   - error: An error if the persisted entries could not be loaded.
+func New(cfg Config) (*List, error) {
+	entries, err := store.Open[Entry](cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open suppression list: %w", err)
+	}
+
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Hour
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	return &List{
+		entries:  entries,
+		ses:      cfg.SES,
+		interval: cfg.SyncInterval,
+		logger:   cfg.Logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Check reports whether the address is currently suppressed.
+//
+// Parameters:
+//   - address: The email address to check.
+//
+// Returns:
+//   - Entry: The suppression entry for the address.
+//   - bool: True if the address is suppressed and the entry has not expired.
+func (l *List) Check(address string) (Entry, bool) {
+	e, err := l.entries.Get(normalize(address))
+	if err != nil || !e.Active(l.now()) {
+		return Entry{}, false
+	}
+
+	return e, true
+}
+
+// Add adds an address to the suppression list, replacing any existing entry for it.
+// Permanent entries are mirrored to the SES account-level suppression list when SES sync is enabled;
+// entries with an expiry stay local because SES suppressions never expire.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - e: The entry to add. CreatedAt defaults to the current time.
+//
+// Returns:
+//   - Entry: The stored entry.
+//   - error: ErrInvalidAddress if the address is malformed, or an error if the entry could not be persisted or synced.
+func (l *List) Add(ctx context.Context, e Entry) (Entry, error) {
+	addr, err := mail.ParseAddress(e.Address)
+	if err != nil || addr.Name != "" {
+		return Entry{}, ErrInvalidAddress
+	}
+
+	e.Address = normalize(addr.Address)
+	if e.Reason == "" {
+		e.Reason = ReasonManual
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = l.now()
+	}
+
+	if l.ses != nil && e.ExpiresAt.IsZero() {
+		if _, err := l.ses.PutSuppressedDestination(ctx, &sesv2.PutSuppressedDestinationInput{
+			EmailAddress: aws.String(e.Address),
+			Reason:       sesReason(e.Reason),
+		}); err != nil {
+			return Entry{}, fmt.Errorf("failed to add address to the aws ses suppression list: %w", err)
+		}
+	}
+
+	if err := l.entries.Put(e.Address, e); err != nil {
+		return Entry{}, fmt.Errorf("failed to persist suppression: %w", err)
+	}
+
+	return e, nil
+}
+
+// Remove removes an address from the suppression list, and from the SES account-level suppression list when SES sync is enabled.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - address: The email address to remove.
+//
+// Returns:
+//   - error: ErrNotFound if the address is not suppressed, or an error if it could not be removed.
+func (l *List) Remove(ctx context.Context, address string) error {
+	address = normalize(address)
+	if _, err := l.entries.Get(address); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if l.ses != nil {
+		if _, err := l.ses.DeleteSuppressedDestination(ctx, &sesv2.DeleteSuppressedDestinationInput{
+			EmailAddress: aws.String(address),
+		}); err != nil {
+			var notFoundErr *types.NotFoundException
+			if !errors.As(err, &notFoundErr) {
+				return fmt.Errorf("failed to remove address from the aws ses suppression list: %w", err)
+			}
+		}
+	}
+
+	if err := l.entries.Delete(address); err != nil {
+		return fmt.Errorf("failed to delete suppression: %w", err)
+	}
+
+	return nil
+}
+
+// Query paginates the entries returned by List.
+//
+// Fields:
+//   - PageSize: The maximum number of entries to return. Defaults to 50, capped at 500.
+//   - PageToken: The token returned by a previous call to continue listing from.
+type Query struct {
+	PageSize  int
+	PageToken string
+}
+
+// List returns the active entries on the suppression list, ordered by address.
+//
+// Parameters:
+//   - q: The Query used to paginate the entries.
+//
+// Returns:
+//   - []Entry: The page of entries.
+//   - string: The token to pass to the next call, or an empty string when there are no more entries.
+//   - error: ErrInvalidPageToken if the page token is malformed.
+func (l *List) List(q Query) ([]Entry, string, error) {
+	pageSize := q.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	var after string
+	if q.PageToken != "" {
+		b, err := base64.RawURLEncoding.DecodeString(q.PageToken)
+		if err != nil || len(b) == 0 {
+			return nil, "", ErrInvalidPageToken
+		}
+		after = string(b)
+	}
+
+	now := l.now()
+	entries := l.entries.List(func(id string, e Entry) bool {
+		return e.Active(now) && id > after
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Address < entries[j].Address
+	})
+
+	if len(entries) <= pageSize {
+		return entries, "", nil
+	}
+
+	entries = entries[:pageSize]
+	return entries, base64.RawURLEncoding.EncodeToString([]byte(entries[len(entries)-1].Address)), nil
+}
+
+// Run prunes expired entries and imports the SES account-level suppression list, once immediately and then
+// on every sync interval, until the context is cancelled.
+//
+// Parameters:
+//   - ctx: The context.Context object controlling the lifetime of the sync loop.
+func (l *List) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.prune()
+
+		if l.ses != nil {
+			if err := l.Sync(ctx); err != nil {
+				l.logger.With(zap.Error(err)).Error("Failed to sync the aws ses suppression list.")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync imports the addresses on the SES account-level suppression list that are not yet on the local list.
+// Existing local entries are left untouched.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//
+// Returns:
+//   - error: An error if the SES suppression list could not be listed or an entry could not be persisted.
+func (l *List) Sync(ctx context.Context) error {
+	if l.ses == nil {
+		return nil
+	}
+
+	imported := 0
+	input := &sesv2.ListSuppressedDestinationsInput{}
+	for {
+		out, err := l.ses.ListSuppressedDestinations(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to list the aws ses suppression list: %w", err)
+		}
+
+		for _, d := range out.SuppressedDestinationSummaries {
+			address := normalize(aws.ToString(d.EmailAddress))
+			if address == "" {
+				continue
+			}
+
+			if _, err := l.entries.Get(address); err == nil {
+				continue
+			}
+
+			e := Entry{
+				Address:   address,
+				Reason:    localReason(d.Reason),
+				Detail:    "imported from aws ses",
+				CreatedAt: aws.ToTime(d.LastUpdateTime),
+			}
+			if err := l.entries.Put(address, e); err != nil {
+				return fmt.Errorf("failed to persist suppression: %w", err)
+			}
+			imported++
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	if imported > 0 {
+		l.logger.With(zap.Int("imported", imported)).Info("Imported addresses from the aws ses suppression list.")
+	}
+
+	return nil
+}
+
+// prune deletes the entries that have expired.
+func (l *List) prune() {
+	now := l.now()
+	for _, e := range l.entries.List(func(_ string, e Entry) bool { return !e.Active(now) }) {
+		if err := l.entries.Delete(e.Address); err != nil {
+			l.logger.With(zap.Error(err), zap.String("address", e.Address)).Error("Failed to delete expired suppression.")
+		}
+	}
+}
+
+func normalize(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// sesReason maps a local reason to its SES equivalent. SES only knows bounces and complaints, so manual
+// suppressions are stored as bounces.
+func sesReason(r Reason) types.SuppressionListReason {
+	if r == ReasonComplaint {
+		return types.SuppressionListReasonComplaint
+	}
+
+	return types.SuppressionListReasonBounce
+}
+
+func localReason(r types.SuppressionListReason) Reason {
+	if r == types.SuppressionListReasonComplaint {
+		return ReasonComplaint
+	}
+
+	return ReasonBounce
+}
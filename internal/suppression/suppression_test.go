@@ -0,0 +1,333 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAddUnit(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	type input struct {
+		entry  Entry
+		sesErr string
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		entry        Entry
+		sesPut       []string
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"handles invalid address",
+			input{entry: Entry{Address: "not an address"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrInvalidAddress)
+				},
+			},
+		},
+		{
+			"handles address with display name",
+			input{entry: Entry{Address: "Jane <jane@example.com>"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.ErrorIs(t, err, ErrInvalidAddress)
+				},
+			},
+		},
+		{
+			"handles failure to sync with aws ses",
+			input{entry: Entry{Address: "jane@example.com"}, sesErr: "throttled"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "throttled")
+				},
+			},
+		},
+		{
+			"is successful with defaults",
+			input{entry: Entry{Address: " Jane@Example.com "}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				entry:  Entry{Address: "jane@example.com", Reason: ReasonManual, CreatedAt: now},
+				sesPut: []string{"jane@example.com:BOUNCE"},
+			},
+		},
+		{
+			"mirrors complaints to aws ses",
+			input{entry: Entry{Address: "jane@example.com", Reason: ReasonComplaint, Detail: "abuse"}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				entry:  Entry{Address: "jane@example.com", Reason: ReasonComplaint, Detail: "abuse", CreatedAt: now},
+				sesPut: []string{"jane@example.com:COMPLAINT"},
+			},
+		},
+		{
+			"keeps expiring suppressions local",
+			input{entry: Entry{Address: "jane@example.com", ExpiresAt: now.Add(time.Hour)}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				entry: Entry{Address: "jane@example.com", Reason: ReasonManual, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := &mockSESClient{putErr: tt.input.sesErr}
+			l, err := New(Config{SES: ses})
+			require.Empty(t, err)
+			l.now = func() time.Time { return now }
+
+			e, err := l.Add(context.Background(), tt.input.entry)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				_, ok := l.Check(tt.input.entry.Address)
+				assert.False(t, ok)
+				return
+			}
+
+			assert.Equal(t, tt.want.entry, e)
+			assert.Equal(t, tt.want.sesPut, ses.put)
+
+			got, ok := l.Check("JANE@example.com")
+			assert.True(t, ok)
+			assert.Equal(t, tt.want.entry, got)
+		})
+	}
+}
+
+func TestListExpiryUnit(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	l, err := New(Config{Dir: t.TempDir()})
+	require.Empty(t, err)
+	l.now = func() time.Time { return now }
+
+	_, err = l.Add(context.Background(), Entry{Address: "temporary@example.com", ExpiresAt: now.Add(time.Hour)})
+	require.Empty(t, err)
+	_, err = l.Add(context.Background(), Entry{Address: "permanent@example.com"})
+	require.Empty(t, err)
+
+	_, ok := l.Check("temporary@example.com")
+	assert.True(t, ok)
+
+	l.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	_, ok = l.Check("temporary@example.com")
+	assert.False(t, ok)
+
+	entries, _, err := l.List(Query{})
+	require.Empty(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "permanent@example.com", entries[0].Address)
+
+	l.prune()
+	_, err = l.entries.Get("temporary@example.com")
+	assert.Error(t, err)
+	_, err = l.entries.Get("permanent@example.com")
+	assert.Empty(t, err)
+}
+
+func TestListRemoveUnit(t *testing.T) {
+	type input struct {
+		sesErr error
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		suppressed   bool
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"handles failure to remove from aws ses",
+			input{sesErr: errors.New("throttled")},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "throttled")
+				},
+				suppressed: true,
+			},
+		},
+		{
+			"ignores addresses missing from aws ses",
+			input{sesErr: &types.NotFoundException{Message: aws.String("not found")}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful",
+			input{},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := &mockSESClient{deleteErr: tt.input.sesErr}
+			l, err := New(Config{SES: ses})
+			require.Empty(t, err)
+
+			_, err = l.Add(context.Background(), Entry{Address: "jane@example.com"})
+			require.Empty(t, err)
+
+			tt.want.errAssertion(t, l.Remove(context.Background(), "Jane@example.com"))
+
+			_, ok := l.Check("jane@example.com")
+			assert.Equal(t, tt.want.suppressed, ok)
+		})
+	}
+
+	l, err := New(Config{})
+	require.Empty(t, err)
+	assert.ErrorIs(t, l.Remove(context.Background(), "missing@example.com"), ErrNotFound)
+}
+
+func TestListPaginationUnit(t *testing.T) {
+	l, err := New(Config{})
+	require.Empty(t, err)
+
+	for _, addr := range []string{"c@example.com", "a@example.com", "e@example.com", "b@example.com", "d@example.com"} {
+		_, err := l.Add(context.Background(), Entry{Address: addr})
+		require.Empty(t, err)
+	}
+
+	var addrs []string
+	token := ""
+	for {
+		page, next, err := l.List(Query{PageSize: 2, PageToken: token})
+		require.Empty(t, err)
+		for _, e := range page {
+			addrs = append(addrs, e.Address)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}, addrs)
+
+	_, _, err = l.List(Query{PageToken: "%%%"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestListSyncUnit(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ses := &mockSESClient{
+		pages: [][]types.SuppressedDestinationSummary{
+			{
+				{EmailAddress: aws.String("Bounced@example.com"), Reason: types.SuppressionListReasonBounce, LastUpdateTime: &updated},
+				{EmailAddress: aws.String("local@example.com"), Reason: types.SuppressionListReasonBounce, LastUpdateTime: &updated},
+			},
+			{
+				{EmailAddress: aws.String("complained@example.com"), Reason: types.SuppressionListReasonComplaint, LastUpdateTime: &updated},
+			},
+		},
+	}
+
+	l, err := New(Config{SES: ses})
+	require.Empty(t, err)
+
+	_, err = l.Add(context.Background(), Entry{Address: "local@example.com", Detail: "added by hand"})
+	require.Empty(t, err)
+
+	require.Empty(t, l.Sync(context.Background()))
+
+	e, ok := l.Check("bounced@example.com")
+	require.True(t, ok)
+	assert.Equal(t, ReasonBounce, e.Reason)
+	assert.Equal(t, updated, e.CreatedAt)
+
+	e, ok = l.Check("complained@example.com")
+	require.True(t, ok)
+	assert.Equal(t, ReasonComplaint, e.Reason)
+
+	e, ok = l.Check("local@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "added by hand", e.Detail)
+
+	ses.listErr = "throttled"
+	assert.ErrorContains(t, l.Sync(context.Background()), "throttled")
+}
+
+type mockSESClient struct {
+	putErr    string
+	deleteErr error
+	listErr   string
+	pages     [][]types.SuppressedDestinationSummary
+	put       []string
+}
+
+func (m *mockSESClient) PutSuppressedDestination(ctx context.Context, params *sesv2.PutSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.PutSuppressedDestinationOutput, error) {
+	if m.putErr != "" {
+		return nil, errors.New(m.putErr)
+	}
+
+	m.put = append(m.put, aws.ToString(params.EmailAddress)+":"+string(params.Reason))
+	return &sesv2.PutSuppressedDestinationOutput{}, nil
+}
+
+func (m *mockSESClient) DeleteSuppressedDestination(ctx context.Context, params *sesv2.DeleteSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.DeleteSuppressedDestinationOutput, error) {
+	if m.deleteErr != nil {
+		return nil, m.deleteErr
+	}
+
+	return &sesv2.DeleteSuppressedDestinationOutput{}, nil
+}
+
+func (m *mockSESClient) ListSuppressedDestinations(ctx context.Context, params *sesv2.ListSuppressedDestinationsInput, optFns ...func(*sesv2.Options)) (*sesv2.ListSuppressedDestinationsOutput, error) {
+	if m.listErr != "" {
+		return nil, errors.New(m.listErr)
+	}
+
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(aws.ToString(params.NextToken), "page-%d", &page)
+	}
+
+	out := &sesv2.ListSuppressedDestinationsOutput{}
+	if page < len(m.pages) {
+		out.SuppressedDestinationSummaries = m.pages[page]
+	}
+	if page+1 < len(m.pages) {
+		out.NextToken = aws.String(fmt.Sprintf("page-%d", page+1))
+	}
+
+	return out, nil
+}
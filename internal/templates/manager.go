@@ -0,0 +1,400 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// sesClient is an interface that defines the methods from the AWS SES client that are used by the Manager
+// to manage the email templates stored in AWS SES.
+type sesClient interface {
+	CreateEmailTemplate(ctx context.Context, params *sesv2.CreateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.CreateEmailTemplateOutput, error)
+	UpdateEmailTemplate(ctx context.Context, params *sesv2.UpdateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.UpdateEmailTemplateOutput, error)
+	GetEmailTemplate(ctx context.Context, params *sesv2.GetEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailTemplateOutput, error)
+	ListEmailTemplates(ctx context.Context, params *sesv2.ListEmailTemplatesInput, optFns ...func(*sesv2.Options)) (*sesv2.ListEmailTemplatesOutput, error)
+	DeleteEmailTemplate(ctx context.Context, params *sesv2.DeleteEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.DeleteEmailTemplateOutput, error)
+	TestRenderEmailTemplate(ctx context.Context, params *sesv2.TestRenderEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.TestRenderEmailTemplateOutput, error)
+}
+
+// Manager defines the interface for managing the email templates stored in AWS SES at runtime.
+//
+// Methods:
+//   - CreateTemplate: Stores a new template.
+//   - UpdateTemplate: Replaces the content of an existing template.
+//   - GetTemplate: Returns a template along with its content.
+//   - ListTemplates: Returns a page of templates, without their content.
+//   - DeleteTemplate: Deletes a template.
+//   - RenderPreview: Renders a template with the given data without sending it.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The request object for the method.
+//
+// Returns:
+//   - The response object for the method.
+//   - error: An error if any occurred while handling the request.
+type Manager interface {
+	CreateTemplate(ctx context.Context, req *mailservice_v1.CreateTemplateRequest) (*mailservice_v1.Template, error)
+	UpdateTemplate(ctx context.Context, req *mailservice_v1.UpdateTemplateRequest) (*mailservice_v1.Template, error)
+	GetTemplate(ctx context.Context, req *mailservice_v1.GetTemplateRequest) (*mailservice_v1.Template, error)
+	ListTemplates(ctx context.Context, req *mailservice_v1.ListTemplatesRequest) (*mailservice_v1.ListTemplatesResponse, error)
+	DeleteTemplate(ctx context.Context, req *mailservice_v1.DeleteTemplateRequest) (*mailservice_v1.DeleteTemplateResponse, error)
+	RenderPreview(ctx context.Context, req *mailservice_v1.RenderPreviewRequest) (*mailservice_v1.RenderPreviewResponse, error)
+}
+
+type manager struct {
+	ses sesClient
+}
+
+// NewManager creates a new Manager backed by the AWS SES template APIs.
+//
+// Parameters:
+//   - ses: The sesv2.Client object used to manage the templates.
+//
+// Returns:
+//   - Manager: The newly created Manager instance.
+func NewManager(ses sesClient) Manager {
+	return manager{ses: ses}
+}
+
+// CreateTemplate stores a new template in AWS SES.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The CreateTemplateRequest object containing the template.
+//
+// Returns:
+//   - *mailservice_v1.Template: The stored template.
+//   - error: An AlreadyExists error if a template with the same name exists, or an InvalidArgument error if the template is incomplete.
+func (m manager) CreateTemplate(ctx context.Context, req *mailservice_v1.CreateTemplateRequest) (*mailservice_v1.Template, error) {
+	content, err := templateContent(req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.ses.CreateEmailTemplate(ctx, &sesv2.CreateEmailTemplateInput{
+		TemplateName:    aws.String(req.Template.Name),
+		TemplateContent: content,
+	}); err != nil {
+		return nil, sesError(err, "failed to create template")
+	}
+
+	return req.Template, nil
+}
+
+// UpdateTemplate replaces the content of an existing template in AWS SES.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The UpdateTemplateRequest object containing the template.
+//
+// Returns:
+//   - *mailservice_v1.Template: The stored template.
+//   - error: A NotFound error if the template does not exist, or an InvalidArgument error if the template is incomplete.
+func (m manager) UpdateTemplate(ctx context.Context, req *mailservice_v1.UpdateTemplateRequest) (*mailservice_v1.Template, error) {
+	content, err := templateContent(req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.ses.UpdateEmailTemplate(ctx, &sesv2.UpdateEmailTemplateInput{
+		TemplateName:    aws.String(req.Template.Name),
+		TemplateContent: content,
+	}); err != nil {
+		return nil, sesError(err, "failed to update template")
+	}
+
+	return req.Template, nil
+}
+
+// GetTemplate returns a template stored in AWS SES along with its content.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The GetTemplateRequest object containing the template name.
+//
+// Returns:
+//   - *mailservice_v1.Template: The template.
+//   - error: A NotFound error if the template does not exist.
+func (m manager) GetTemplate(ctx context.Context, req *mailservice_v1.GetTemplateRequest) (*mailservice_v1.Template, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	out, err := m.ses.GetEmailTemplate(ctx, &sesv2.GetEmailTemplateInput{
+		TemplateName: aws.String(req.Name),
+	})
+	if err != nil {
+		return nil, sesError(err, "failed to get template")
+	}
+
+	t := &mailservice_v1.Template{
+		Name: aws.ToString(out.TemplateName),
+	}
+	if out.TemplateContent != nil {
+		t.Subject = aws.ToString(out.TemplateContent.Subject)
+		t.Html = aws.ToString(out.TemplateContent.Html)
+		t.Text = aws.ToString(out.TemplateContent.Text)
+	}
+
+	return t, nil
+}
+
+// ListTemplates returns a page of the templates stored in AWS SES. Only the name and creation time of each template are set.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The ListTemplatesRequest object containing the pagination options.
+//
+// Returns:
+//   - *mailservice_v1.ListTemplatesResponse: The page of templates and the token for the next page.
+//   - error: An error if the templates could not be listed.
+func (m manager) ListTemplates(ctx context.Context, req *mailservice_v1.ListTemplatesRequest) (*mailservice_v1.ListTemplatesResponse, error) {
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	input := &sesv2.ListEmailTemplatesInput{
+		PageSize: aws.Int32(pageSize),
+	}
+	if req.PageToken != "" {
+		input.NextToken = aws.String(req.PageToken)
+	}
+
+	out, err := m.ses.ListEmailTemplates(ctx, input)
+	if err != nil {
+		return nil, sesError(err, "failed to list templates")
+	}
+
+	resp := &mailservice_v1.ListTemplatesResponse{
+		NextPageToken: aws.ToString(out.NextToken),
+	}
+	for _, t := range out.TemplatesMetadata {
+		v := &mailservice_v1.Template{
+			Name: aws.ToString(t.TemplateName),
+		}
+		if t.CreatedTimestamp != nil {
+			v.CreatedAt = timestamppb.New(*t.CreatedTimestamp)
+		}
+		resp.Templates = append(resp.Templates, v)
+	}
+
+	return resp, nil
+}
+
+// DeleteTemplate deletes a template from AWS SES.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The DeleteTemplateRequest object containing the template name.
+//
+// Returns:
+//   - *mailservice_v1.DeleteTemplateResponse: An empty response.
+//   - error: A NotFound error if the template does not exist.
+func (m manager) DeleteTemplate(ctx context.Context, req *mailservice_v1.DeleteTemplateRequest) (*mailservice_v1.DeleteTemplateResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	if _, err := m.ses.DeleteEmailTemplate(ctx, &sesv2.DeleteEmailTemplateInput{
+		TemplateName: aws.String(req.Name),
+	}); err != nil {
+		return nil, sesError(err, "failed to delete template")
+	}
+
+	return &mailservice_v1.DeleteTemplateResponse{}, nil
+}
+
+// RenderPreview renders a template stored in AWS SES with the given data, without sending it.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - req: The RenderPreviewRequest object containing the template name and data.
+//
+// Returns:
+//   - *mailservice_v1.RenderPreviewResponse: The rendered subject, HTML and text parts along with the raw MIME message.
+//   - error: A NotFound error if the template does not exist, or an InvalidArgument error if the data does not render.
+func (m manager) RenderPreview(ctx context.Context, req *mailservice_v1.RenderPreviewRequest) (*mailservice_v1.RenderPreviewResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	data := []byte("{}")
+	if req.Data != nil {
+		var err error
+		data, err = protojson.Marshal(req.Data)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid data: %v", err)
+		}
+	}
+
+	out, err := m.ses.TestRenderEmailTemplate(ctx, &sesv2.TestRenderEmailTemplateInput{
+		TemplateName: aws.String(req.Name),
+		TemplateData: aws.String(string(data)),
+	})
+	if err != nil {
+		return nil, sesError(err, "failed to render template")
+	}
+
+	raw := aws.ToString(out.RenderedTemplate)
+	resp, err := parseRendered(raw)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse rendered template: %v", err)
+	}
+	resp.RawMessage = raw
+
+	return resp, nil
+}
+
+// templateContent validates the template and converts it into its AWS SES representation.
+func templateContent(t *mailservice_v1.Template) (*types.EmailTemplateContent, error) {
+	switch {
+	case t == nil || t.Name == "":
+		return nil, status.Error(codes.InvalidArgument, "template.name is required")
+	case t.Subject == "":
+		return nil, status.Error(codes.InvalidArgument, "template.subject is required")
+	case t.Html == "" && t.Text == "":
+		return nil, status.Error(codes.InvalidArgument, "template.html or template.text is required")
+	}
+
+	content := &types.EmailTemplateContent{
+		Subject: aws.String(t.Subject),
+	}
+	if t.Html != "" {
+		content.Html = aws.String(t.Html)
+	}
+	if t.Text != "" {
+		content.Text = aws.String(t.Text)
+	}
+
+	return content, nil
+}
+
+// sesError converts an AWS SES error into a gRPC status error.
+func sesError(err error, msg string) error {
+	var (
+		notFound      *types.NotFoundException
+		alreadyExists *types.AlreadyExistsException
+		badRequest    *types.BadRequestException
+		tooMany       *types.TooManyRequestsException
+		limitExceeded *types.LimitExceededException
+	)
+
+	switch {
+	case errors.As(err, &notFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, notFound.ErrorMessage())
+	case errors.As(err, &alreadyExists):
+		return status.Errorf(codes.AlreadyExists, "%s: %s", msg, alreadyExists.ErrorMessage())
+	case errors.As(err, &badRequest):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, badRequest.ErrorMessage())
+	case errors.As(err, &tooMany):
+		return status.Errorf(codes.ResourceExhausted, "%s: %s", msg, tooMany.ErrorMessage())
+	case errors.As(err, &limitExceeded):
+		return status.Errorf(codes.ResourceExhausted, "%s: %s", msg, limitExceeded.ErrorMessage())
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", msg, err)
+	}
+}
+
+// parseRendered extracts the subject and the HTML and text parts from a MIME message rendered by AWS SES.
+func parseRendered(raw string) (*mailservice_v1.RenderPreviewResponse, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &mailservice_v1.RenderPreviewResponse{}
+
+	dec := new(mime.WordDecoder)
+	resp.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		resp.Subject = msg.Header.Get("Subject")
+	}
+
+	if err := collectParts(resp, msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// collectParts walks a (possibly nested multipart) MIME body and stores the first HTML and text parts in the response.
+func collectParts(resp *mailservice_v1.RenderPreviewResponse, contentType, encoding string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := collectParts(resp, p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p); err != nil {
+				return err
+			}
+		}
+	}
+
+	b, err := decodeBody(encoding, body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case mediaType == "text/html" && resp.Html == "":
+		resp.Html = string(b)
+	case mediaType == "text/plain" && resp.Text == "":
+		resp.Text = string(b)
+	}
+
+	return nil
+}
+
+func decodeBody(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(body))
+	case "base64":
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(b), nil)))
+	default:
+		return io.ReadAll(body)
+	}
+}
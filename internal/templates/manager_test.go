@@ -0,0 +1,398 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCreateTemplateUnit(t *testing.T) {
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+	}
+
+	cases := []struct {
+		name  string
+		input *mailservice_v1.Template
+		want  want
+	}{
+		{
+			"handles missing name",
+			&mailservice_v1.Template{Subject: "Hi", Text: "Hello"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+			},
+		},
+		{
+			"handles missing subject",
+			&mailservice_v1.Template{Name: "Welcome", Text: "Hello"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+			},
+		},
+		{
+			"handles missing body",
+			&mailservice_v1.Template{Name: "Welcome", Subject: "Hi"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+			},
+		},
+		{
+			"handles existing template",
+			&mailservice_v1.Template{Name: "Existing", Subject: "Hi", Text: "Hello"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.AlreadyExists, status.Code(err))
+				},
+			},
+		},
+		{
+			"is successful",
+			&mailservice_v1.Template{Name: "Welcome", Subject: "Hi {{name}}", Html: "<p>Hello</p>"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := newMockSESClient()
+			ses.templates["Existing"] = &types.EmailTemplateContent{Subject: aws.String("Hi"), Text: aws.String("Hello")}
+
+			_, err := NewManager(ses).CreateTemplate(context.Background(), &mailservice_v1.CreateTemplateRequest{Template: tt.input})
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			got, err := NewManager(ses).GetTemplate(context.Background(), &mailservice_v1.GetTemplateRequest{Name: tt.input.Name})
+			require.Empty(t, err)
+			assert.Equal(t, tt.input.Subject, got.Subject)
+			assert.Equal(t, tt.input.Html, got.Html)
+			assert.Equal(t, tt.input.Text, got.Text)
+		})
+	}
+}
+
+func TestManagerLifecycleUnit(t *testing.T) {
+	ctx := context.Background()
+	ses := newMockSESClient()
+	m := NewManager(ses)
+
+	for _, name := range []string{"B", "A", "C"} {
+		_, err := m.CreateTemplate(ctx, &mailservice_v1.CreateTemplateRequest{
+			Template: &mailservice_v1.Template{Name: name, Subject: "Subject " + name, Text: "Text " + name},
+		})
+		require.Empty(t, err)
+	}
+
+	_, err := m.UpdateTemplate(ctx, &mailservice_v1.UpdateTemplateRequest{
+		Template: &mailservice_v1.Template{Name: "A", Subject: "Updated", Html: "<p>Updated</p>"},
+	})
+	require.Empty(t, err)
+
+	got, err := m.GetTemplate(ctx, &mailservice_v1.GetTemplateRequest{Name: "A"})
+	require.Empty(t, err)
+	assert.Equal(t, "Updated", got.Subject)
+	assert.Equal(t, "<p>Updated</p>", got.Html)
+	assert.Empty(t, got.Text)
+
+	_, err = m.UpdateTemplate(ctx, &mailservice_v1.UpdateTemplateRequest{
+		Template: &mailservice_v1.Template{Name: "Missing", Subject: "Updated", Text: "Updated"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	page, err := m.ListTemplates(ctx, &mailservice_v1.ListTemplatesRequest{PageSize: 2})
+	require.Empty(t, err)
+	require.Len(t, page.Templates, 2)
+	assert.Equal(t, "A", page.Templates[0].Name)
+	assert.Equal(t, "B", page.Templates[1].Name)
+	assert.NotEmpty(t, page.Templates[0].CreatedAt)
+	require.NotEmpty(t, page.NextPageToken)
+
+	page, err = m.ListTemplates(ctx, &mailservice_v1.ListTemplatesRequest{PageSize: 2, PageToken: page.NextPageToken})
+	require.Empty(t, err)
+	require.Len(t, page.Templates, 1)
+	assert.Equal(t, "C", page.Templates[0].Name)
+	assert.Empty(t, page.NextPageToken)
+
+	_, err = m.DeleteTemplate(ctx, &mailservice_v1.DeleteTemplateRequest{Name: "A"})
+	require.Empty(t, err)
+
+	_, err = m.GetTemplate(ctx, &mailservice_v1.GetTemplateRequest{Name: "A"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = m.DeleteTemplate(ctx, &mailservice_v1.DeleteTemplateRequest{Name: "A"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestListTemplatesPageSizeUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input int32
+		want  int32
+	}{
+		{"defaults page size", 0, defaultPageSize},
+		{"caps page size", 1000, maxPageSize},
+		{"keeps page size", 25, 25},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := newMockSESClient()
+			_, err := NewManager(ses).ListTemplates(context.Background(), &mailservice_v1.ListTemplatesRequest{PageSize: tt.input})
+			require.Empty(t, err)
+			assert.Equal(t, tt.want, ses.lastPageSize)
+		})
+	}
+}
+
+func TestRenderPreviewUnit(t *testing.T) {
+	multipartMessage := "Subject: =?UTF-8?Q?Hello_J=C3=BCrgen?=\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"Hello J=C3=BCrgen\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"PHA+SGVsbG8gSsO8cmdlbjwvcD4=\r\n" +
+		"--b1--\r\n"
+
+	type input struct {
+		req      *mailservice_v1.RenderPreviewRequest
+		rendered string
+		err      error
+	}
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		resp         *mailservice_v1.RenderPreviewResponse
+		data         string
+	}
+
+	data, err := structpb.NewStruct(map[string]any{"name": "Jürgen"})
+	require.Empty(t, err)
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"handles missing name",
+			input{
+				req: &mailservice_v1.RenderPreviewRequest{},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				},
+			},
+		},
+		{
+			"handles render failure",
+			input{
+				req: &mailservice_v1.RenderPreviewRequest{Name: "Welcome"},
+				err: &types.BadRequestException{Message: aws.String("Attribute 'name' is not present in the rendering data")},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+					assert.Contains(t, err.Error(), "Attribute 'name'")
+				},
+			},
+		},
+		{
+			"parses multipart message",
+			input{
+				req:      &mailservice_v1.RenderPreviewRequest{Name: "Welcome", Data: data},
+				rendered: multipartMessage,
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				resp: &mailservice_v1.RenderPreviewResponse{
+					Subject:    "Hello Jürgen",
+					Html:       "<p>Hello Jürgen</p>",
+					Text:       "Hello Jürgen",
+					RawMessage: multipartMessage,
+				},
+				data: `{"name":"Jürgen"}`,
+			},
+		},
+		{
+			"parses single part message with empty data",
+			input{
+				req:      &mailservice_v1.RenderPreviewRequest{Name: "Welcome"},
+				rendered: "Subject: Hello\r\nContent-Type: text/plain\r\n\r\nHello there",
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				resp: &mailservice_v1.RenderPreviewResponse{
+					Subject:    "Hello",
+					Text:       "Hello there",
+					RawMessage: "Subject: Hello\r\nContent-Type: text/plain\r\n\r\nHello there",
+				},
+				data: `{}`,
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ses := newMockSESClient()
+			ses.rendered = tt.input.rendered
+			ses.renderErr = tt.input.err
+
+			got, err := NewManager(ses).RenderPreview(context.Background(), tt.input.req)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want.resp.Subject, got.Subject)
+			assert.Equal(t, tt.want.resp.Html, got.Html)
+			assert.Equal(t, tt.want.resp.Text, got.Text)
+			assert.Equal(t, tt.want.resp.RawMessage, got.RawMessage)
+			assert.JSONEq(t, tt.want.data, ses.lastData)
+		})
+	}
+}
+
+func TestSESErrorUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input error
+		want  codes.Code
+	}{
+		{"maps not found", &types.NotFoundException{}, codes.NotFound},
+		{"maps already exists", &types.AlreadyExistsException{}, codes.AlreadyExists},
+		{"maps bad request", &types.BadRequestException{}, codes.InvalidArgument},
+		{"maps too many requests", &types.TooManyRequestsException{}, codes.ResourceExhausted},
+		{"maps limit exceeded", &types.LimitExceededException{}, codes.ResourceExhausted},
+		{"maps unknown errors", errors.New("boom"), codes.Internal},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, status.Code(sesError(tt.input, "failed")))
+		})
+	}
+}
+
+var _ sesClient = &mockSESClient{}
+
+// mockSESClient is an in-memory implementation of the AWS SES template APIs.
+type mockSESClient struct {
+	templates    map[string]*types.EmailTemplateContent
+	rendered     string
+	renderErr    error
+	lastData     string
+	lastPageSize int32
+}
+
+func newMockSESClient() *mockSESClient {
+	return &mockSESClient{templates: map[string]*types.EmailTemplateContent{}}
+}
+
+func (m *mockSESClient) CreateEmailTemplate(ctx context.Context, params *sesv2.CreateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.CreateEmailTemplateOutput, error) {
+	if _, ok := m.templates[*params.TemplateName]; ok {
+		return nil, &types.AlreadyExistsException{Message: aws.String("Template already exists")}
+	}
+
+	m.templates[*params.TemplateName] = params.TemplateContent
+	return &sesv2.CreateEmailTemplateOutput{}, nil
+}
+
+func (m *mockSESClient) UpdateEmailTemplate(ctx context.Context, params *sesv2.UpdateEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.UpdateEmailTemplateOutput, error) {
+	if _, ok := m.templates[*params.TemplateName]; !ok {
+		return nil, &types.NotFoundException{Message: aws.String("Template not found")}
+	}
+
+	m.templates[*params.TemplateName] = params.TemplateContent
+	return &sesv2.UpdateEmailTemplateOutput{}, nil
+}
+
+func (m *mockSESClient) GetEmailTemplate(ctx context.Context, params *sesv2.GetEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.GetEmailTemplateOutput, error) {
+	content, ok := m.templates[*params.TemplateName]
+	if !ok {
+		return nil, &types.NotFoundException{Message: aws.String("Template not found")}
+	}
+
+	return &sesv2.GetEmailTemplateOutput{TemplateName: params.TemplateName, TemplateContent: content}, nil
+}
+
+func (m *mockSESClient) ListEmailTemplates(ctx context.Context, params *sesv2.ListEmailTemplatesInput, optFns ...func(*sesv2.Options)) (*sesv2.ListEmailTemplatesOutput, error) {
+	m.lastPageSize = aws.ToInt32(params.PageSize)
+
+	names := make([]string, 0, len(m.templates))
+	for name := range m.templates {
+		if name > aws.ToString(params.NextToken) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := &sesv2.ListEmailTemplatesOutput{}
+	for i, name := range names {
+		if int32(i) == m.lastPageSize {
+			out.NextToken = aws.String(names[i-1])
+			break
+		}
+
+		out.TemplatesMetadata = append(out.TemplatesMetadata, types.EmailTemplateMetadata{
+			TemplateName:     aws.String(name),
+			CreatedTimestamp: aws.Time(time.Now()),
+		})
+	}
+
+	return out, nil
+}
+
+func (m *mockSESClient) DeleteEmailTemplate(ctx context.Context, params *sesv2.DeleteEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.DeleteEmailTemplateOutput, error) {
+	if _, ok := m.templates[*params.TemplateName]; !ok {
+		return nil, &types.NotFoundException{Message: aws.String("Template not found")}
+	}
+
+	delete(m.templates, *params.TemplateName)
+	return &sesv2.DeleteEmailTemplateOutput{}, nil
+}
+
+func (m *mockSESClient) TestRenderEmailTemplate(ctx context.Context, params *sesv2.TestRenderEmailTemplateInput, optFns ...func(*sesv2.Options)) (*sesv2.TestRenderEmailTemplateOutput, error) {
+	m.lastData = aws.ToString(params.TemplateData)
+	if m.renderErr != nil {
+		return nil, m.renderErr
+	}
+
+	return &sesv2.TestRenderEmailTemplateOutput{RenderedTemplate: aws.String(m.rendered)}, nil
+}
@@ -0,0 +1,194 @@
+package templates
+
+import (
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const (
+	// ThankYou is the key of the thank you email sent to the sender of a submission.
+	ThankYou = "thank_you"
+	// Forward is the key of the email that forwards a submission to the configured address.
+	Forward = "forward"
+)
+
+const (
+	subjectExt = ".subject.txt"
+	htmlExt    = ".html"
+	textExt    = ".txt"
+)
+
+// names maps the template keys to the names the templates are stored under in AWS SES.
+var names = map[string]string{
+	ThankYou: "ThankYouTemplate",
+	Forward:  "ForwardTemplate",
+}
+
+// defaults holds the compiled-in templates, used for every part that is not supplied through configuration.
+//
+//go:embed defaults
+var defaults embed.FS
+
+// Template is an email template with its subject, HTML and text parts. The parts use {{variable}} placeholders.
+//
+// Fields:
+//   - Key: The key of the template, e.g. ThankYou.
+//   - Name: The name the template is stored under in AWS SES.
+//   - Subject: The subject line.
+//   - HTML: The HTML body. Optional if Text is set.
+//   - Text: The plain text body. Optional if HTML is set.
+type Template struct {
+	Key     string
+	Name    string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Source holds the configured parts of a single template. Inline parts take precedence over files.
+//
+// Fields:
+//   - Subject: The subject line.
+//   - HTML: The base64 standard encoded HTML body.
+//   - Text: The base64 standard encoded plain text body.
+//   - SubjectFile: The path of a file holding the subject line.
+//   - HTMLFile: The path of a file holding the HTML body.
+//   - TextFile: The path of a file holding the plain text body.
+type Source struct {
+	Subject     string
+	HTML        string
+	Text        string
+	SubjectFile string
+	HTMLFile    string
+	TextFile    string
+}
+
+// Config holds the configuration used to load the templates.
+//
+// Fields:
+//   - Dir: An optional directory holding template files named "<key>.subject.txt", "<key>.html" and "<key>.txt".
+//   - Sources: The Source of each template, keyed by template key.
+//   - NameSuffix: An optional suffix appended to the names the templates are stored under in AWS SES, so the templates
+//     of several forms can be stored side by side, e.g. "_sales" stores "ThankYouTemplate_sales".
+type Config struct {
+	Dir        string
+	Sources    map[string]Source
+	NameSuffix string
+}
+
+// Load loads every template. Each part of a template is taken from the first of the following that provides it:
+// the inline Source value, the Source file, the template directory, and finally the compiled-in default.
+//
+// Parameters:
+//   - cfg: The Config object containing the template directory and sources.
+//
+// Returns:
+//   - map[string]Template: The templates keyed by template key.
+//   - error: An error if a configured part could not be read or decoded, or a template has no subject or body.
+func Load(cfg Config) (map[string]Template, error) {
+	for key := range cfg.Sources {
+		if _, ok := names[key]; !ok {
+			return nil, fmt.Errorf("unknown template %q", key)
+		}
+	}
+
+	defaultsFS, err := fs.Sub(defaults, "defaults")
+	if err != nil {
+		return nil, err
+	}
+
+	var dirFS fs.FS
+	if cfg.Dir != "" {
+		dirFS = os.DirFS(cfg.Dir)
+	}
+
+	out := make(map[string]Template, len(names))
+	for key, name := range names {
+		t := Template{Key: key, Name: name + cfg.NameSuffix}
+		src := cfg.Sources[key]
+
+		parts := []struct {
+			dst        *string
+			inline     string
+			base64     bool
+			file       string
+			ext        string
+			trimSpaces bool
+		}{
+			{dst: &t.Subject, inline: src.Subject, file: src.SubjectFile, ext: subjectExt, trimSpaces: true},
+			{dst: &t.HTML, inline: src.HTML, base64: true, file: src.HTMLFile, ext: htmlExt},
+			{dst: &t.Text, inline: src.Text, base64: true, file: src.TextFile, ext: textExt},
+		}
+
+		for _, p := range parts {
+			v, err := resolve(p.inline, p.base64, p.file, dirFS, defaultsFS, key+p.ext)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s template: %w", key, err)
+			}
+
+			if p.trimSpaces {
+				v = strings.TrimSpace(v)
+			}
+			*p.dst = v
+		}
+
+		if t.Subject == "" {
+			return nil, fmt.Errorf("%s template has no subject", key)
+		}
+
+		if t.HTML == "" && t.Text == "" {
+			return nil, fmt.Errorf("%s template has neither an html nor a text part", key)
+		}
+
+		out[key] = t
+	}
+
+	return out, nil
+}
+
+// resolve returns the first available value of a template part.
+func resolve(inline string, isBase64 bool, file string, dirFS, defaultsFS fs.FS, name string) (string, error) {
+	if inline != "" {
+		if !isBase64 {
+			return inline, nil
+		}
+
+		b, err := base64.StdEncoding.DecodeString(inline)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+
+		return string(b), nil
+	}
+
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		return string(b), nil
+	}
+
+	for _, fsys := range []fs.FS{dirFS, defaultsFS} {
+		if fsys == nil {
+			continue
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err == nil {
+			return string(b), nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,151 @@
+package templates
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadUnit(t *testing.T) {
+	dir := t.TempDir()
+	require.Empty(t, os.WriteFile(filepath.Join(dir, "thank_you.subject.txt"), []byte("Subject from dir\n"), 0o600))
+	require.Empty(t, os.WriteFile(filepath.Join(dir, "thank_you.html"), []byte("<p>HTML from dir</p>"), 0o600))
+	require.Empty(t, os.WriteFile(filepath.Join(dir, "forward.txt"), []byte("Text from dir"), 0o600))
+
+	file := filepath.Join(t.TempDir(), "thank_you.txt")
+	require.Empty(t, os.WriteFile(file, []byte("Text from file"), 0o600))
+
+	type want struct {
+		errAssertion func(t *testing.T, err error)
+		templates    map[string]Template
+	}
+
+	cases := []struct {
+		name  string
+		input Config
+		want  want
+	}{
+		{
+			"falls back to compiled-in defaults",
+			Config{},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				templates: map[string]Template{
+					ThankYou: {Key: ThankYou, Name: "ThankYouTemplate", Subject: "Thank you for your interest"},
+					Forward:  {Key: Forward, Name: "ForwardTemplate", Subject: "{{subject_line}}", Text: "Name: {{name}}\nFrom: {{from}}\nSubject: {{subject}}\n{{#each field_list}}{{label}}: {{value}}\n{{/each}}\n{{text}}"},
+				},
+			},
+		},
+		{
+			"prefers inline values over files over the template directory",
+			Config{
+				Dir: dir,
+				Sources: map[string]Source{
+					ThankYou: {
+						HTML:     base64.StdEncoding.EncodeToString([]byte("<p>Inline HTML</p>")),
+						HTMLFile: filepath.Join(dir, "missing.html"),
+						TextFile: file,
+					},
+					Forward: {
+						Subject: "Inline subject",
+					},
+				},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				templates: map[string]Template{
+					ThankYou: {Key: ThankYou, Name: "ThankYouTemplate", Subject: "Subject from dir", HTML: "<p>Inline HTML</p>", Text: "Text from file"},
+					Forward:  {Key: Forward, Name: "ForwardTemplate", Subject: "Inline subject", Text: "Text from dir"},
+				},
+			},
+		},
+		{
+			"appends the name suffix",
+			Config{NameSuffix: "_sales"},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				templates: map[string]Template{
+					ThankYou: {Key: ThankYou, Name: "ThankYouTemplate_sales", Subject: "Thank you for your interest"},
+					Forward:  {Key: Forward, Name: "ForwardTemplate_sales", Subject: "{{subject_line}}"},
+				},
+			},
+		},
+		{
+			"handles invalid base64",
+			Config{Sources: map[string]Source{ThankYou: {HTML: "not base64!"}}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "failed to decode thank_you.html")
+				},
+			},
+		},
+		{
+			"handles missing file",
+			Config{Sources: map[string]Source{Forward: {TextFile: filepath.Join(dir, "missing.txt")}}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "failed to read forward.txt")
+				},
+			},
+		},
+		{
+			"handles unknown template",
+			Config{Sources: map[string]Source{"welcome": {Subject: "Welcome"}}},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), `unknown template "welcome"`)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.input)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			require.Len(t, got, len(tt.want.templates))
+			for key, want := range tt.want.templates {
+				assert.Equal(t, want.Key, got[key].Key)
+				assert.Equal(t, want.Name, got[key].Name)
+				assert.Equal(t, want.Subject, got[key].Subject)
+				if want.HTML != "" {
+					assert.Equal(t, want.HTML, got[key].HTML)
+				}
+				if want.Text != "" {
+					assert.Equal(t, want.Text, got[key].Text)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDefaultsUnit(t *testing.T) {
+	got, err := Load(Config{})
+	require.Empty(t, err)
+
+	for key, tmpl := range got {
+		assert.NotEmpty(t, tmpl.HTML, key)
+		assert.NotEmpty(t, tmpl.Text, key)
+	}
+
+	assert.Contains(t, got[ThankYou].HTML, "{{name}}")
+	assert.Contains(t, got[ThankYou].Text, "{{name}}")
+	assert.Contains(t, got[Forward].HTML, "{{text}}")
+}
@@ -0,0 +1,328 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/brice-aldrich/mail-service/internal/sanitize"
+)
+
+const (
+	// maxHeaderLineLength is the line length headers are folded at (RFC 5322, section 2.1.1).
+	maxHeaderLineLength = 78
+	// base64LineLength is the maximum line length of base64 encoded bodies (RFC 2045, section 6.8).
+	base64LineLength = 76
+	// defaultAttachmentType is the media type of attachments without a valid content type.
+	defaultAttachmentType = "application/octet-stream"
+)
+
+// header is a single MIME header field. Headers are kept in order, as the order of the top level fields is significant to some clients.
+type header struct {
+	key   string
+	value string
+}
+
+// entity is a MIME entity: the header fields describing its content and a function writing its encoded body.
+type entity struct {
+	header []header
+	write  func(w io.Writer) error
+}
+
+// buildMessage composes msg into an RFC 5322 message.
+//
+// The body is nested as follows, leaving out every level that is not needed:
+//
+//	multipart/mixed
+//	├── multipart/related
+//	│   ├── multipart/alternative
+//	│   │   ├── text/plain
+//	│   │   └── text/html
+//	│   └── inline attachments
+//	└── attachments
+//
+// Header values are RFC 2047 encoded when they are not plain ASCII and folded at 78 characters.
+//
+// Parameters:
+//   - msg: The message to compose.
+//   - messageID: The Message-ID header of the message. Left out when empty, e.g. when the provider assigns its own.
+//
+// Returns:
+//   - []byte: The composed message.
+//   - error: An error if a boundary could not be generated or a part could not be encoded.
+func buildMessage(msg *Message, messageID string) ([]byte, error) {
+	root, err := bodyEntity(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	top := []header{
+		{"From", formatAddressList([]string{msg.From})},
+		{"To", formatAddressList(msg.To)},
+		{"Cc", formatAddressList(msg.Cc)},
+		{"Reply-To", formatAddressList(msg.ReplyTo)},
+		{"Subject", encodeHeader(msg.Subject)},
+		{"Date", time.Now().UTC().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+	}
+
+	var out bytes.Buffer
+	if err := writeHeaders(&out, append(top, root.header...)); err != nil {
+		return nil, err
+	}
+
+	if err := root.write(&out); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func bodyEntity(msg *Message) (entity, error) {
+	var body entity
+	switch {
+	case msg.HTML != "" && msg.Text != "":
+		var err error
+		body, err = multipartEntity("alternative", textEntity("text/plain", msg.Text), textEntity("text/html", msg.HTML))
+		if err != nil {
+			return entity{}, err
+		}
+	case msg.HTML != "":
+		body = textEntity("text/html", msg.HTML)
+	default:
+		body = textEntity("text/plain", msg.Text)
+	}
+
+	var inline, attached []entity
+	for _, a := range msg.Attachments {
+		if a.ContentID != "" {
+			inline = append(inline, attachmentEntity(a))
+		} else {
+			attached = append(attached, attachmentEntity(a))
+		}
+	}
+
+	var err error
+	if len(inline) > 0 {
+		body, err = multipartEntity("related", append([]entity{body}, inline...)...)
+		if err != nil {
+			return entity{}, err
+		}
+	}
+
+	if len(attached) > 0 {
+		body, err = multipartEntity("mixed", append([]entity{body}, attached...)...)
+		if err != nil {
+			return entity{}, err
+		}
+	}
+
+	return body, nil
+}
+
+func textEntity(contentType, body string) entity {
+	return entity{
+		header: []header{
+			{"Content-Type", contentType + "; charset=UTF-8"},
+			{"Content-Transfer-Encoding", "quoted-printable"},
+		},
+		write: func(w io.Writer) error {
+			return writeQuotedPrintable(w, body)
+		},
+	}
+}
+
+func attachmentEntity(a Attachment) entity {
+	filename := attachmentFilename(a.Filename)
+
+	mediaType, params, err := mime.ParseMediaType(a.ContentType)
+	if err != nil || !strings.Contains(mediaType, "/") || strings.HasPrefix(mediaType, "multipart/") {
+		mediaType, params = defaultAttachmentType, nil
+	}
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["name"] = filename
+
+	contentType := mime.FormatMediaType(mediaType, params)
+	if contentType == "" {
+		contentType = defaultAttachmentType
+	}
+
+	disposition := "attachment"
+	h := []header{
+		{"Content-Type", contentType},
+		{"Content-Transfer-Encoding", "base64"},
+	}
+	if a.ContentID != "" {
+		disposition = "inline"
+		h = append(h, header{"Content-ID", "<" + strings.Trim(sanitizeHeader(a.ContentID), "<>") + ">"})
+	}
+	h = append(h, header{"Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename})})
+
+	return entity{
+		header: h,
+		write: func(w io.Writer) error {
+			return writeBase64(w, a.Data)
+		},
+	}
+}
+
+func multipartEntity(subtype string, parts ...entity) (entity, error) {
+	boundary, err := newBoundary()
+	if err != nil {
+		return entity{}, err
+	}
+
+	return entity{
+		header: []header{
+			{"Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": boundary})},
+		},
+		write: func(w io.Writer) error {
+			for _, p := range parts {
+				if _, err := fmt.Fprintf(w, "\r\n--%s\r\n", boundary); err != nil {
+					return err
+				}
+
+				if err := writeHeaders(w, p.header); err != nil {
+					return err
+				}
+
+				if err := p.write(w); err != nil {
+					return err
+				}
+			}
+
+			_, err := fmt.Fprintf(w, "\r\n--%s--\r\n", boundary)
+			return err
+		},
+	}, nil
+}
+
+func newBoundary() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate mime boundary: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// writeHeaders writes the non-empty header fields, folded, followed by the blank line that ends the header section.
+func writeHeaders(w io.Writer, headers []header) error {
+	for _, h := range headers {
+		if h.value == "" {
+			continue
+		}
+
+		if _, err := io.WriteString(w, foldHeader(h.key, sanitizeHeader(h.value))); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// foldHeader formats a header field, folding it at whitespace so that no line exceeds maxHeaderLineLength where possible.
+// Words longer than a line, such as long addresses, are kept whole, as folding is only allowed at whitespace.
+func foldHeader(key, value string) string {
+	var b strings.Builder
+	b.WriteString(key)
+	b.WriteString(":")
+
+	lineLength := len(key) + 1
+	for _, word := range strings.Split(value, " ") {
+		if lineLength+1+len(word) > maxHeaderLineLength {
+			b.WriteString("\r\n")
+			lineLength = 0
+		}
+
+		b.WriteString(" ")
+		b.WriteString(word)
+		lineLength += 1 + len(word)
+	}
+	b.WriteString("\r\n")
+
+	return b.String()
+}
+
+// sanitizeHeader replaces line breaks and removes control characters with sanitize.Header, so header values cannot
+// inject additional fields.
+func sanitizeHeader(v string) string {
+	return sanitize.Header(v)
+}
+
+// encodeHeader RFC 2047 encodes an unstructured header value when it is not plain ASCII.
+func encodeHeader(v string) string {
+	return mime.QEncoding.Encode("utf-8", sanitizeHeader(v))
+}
+
+// formatAddressList formats a list of addresses, RFC 2047 encoding display names when needed.
+// Addresses that cannot be parsed are written as given.
+func formatAddressList(addrs []string) string {
+	formatted := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if a == "" {
+			continue
+		}
+
+		if addr, err := mail.ParseAddress(a); err == nil {
+			formatted = append(formatted, addr.String())
+		} else {
+			formatted = append(formatted, sanitizeHeader(a))
+		}
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// attachmentFilename strips any directory from a file name and removes control characters.
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+
+	return name
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(base64LineLength, len(encoded))
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+
+	return nil
+}
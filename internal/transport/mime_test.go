@@ -0,0 +1,277 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mimePart is a decoded leaf part of a MIME message.
+type mimePart struct {
+	contentType string
+	disposition string
+	filename    string
+	contentID   string
+	body        string
+}
+
+func TestBuildMessageUnit(t *testing.T) {
+	type want struct {
+		contentType string
+		parts       []mimePart
+	}
+
+	cases := []struct {
+		name  string
+		input *Message
+		want  want
+	}{
+		{
+			"builds single part text message",
+			&Message{Text: "Hello"},
+			want{
+				contentType: "text/plain",
+				parts:       []mimePart{{contentType: "text/plain", body: "Hello"}},
+			},
+		},
+		{
+			"builds alternative message",
+			&Message{Text: "Hello", HTML: "<p>Hello</p>"},
+			want{
+				contentType: "multipart/alternative",
+				parts: []mimePart{
+					{contentType: "text/plain", body: "Hello"},
+					{contentType: "text/html", body: "<p>Hello</p>"},
+				},
+			},
+		},
+		{
+			"builds mixed message with inline and attached parts",
+			&Message{
+				Text: "Hello",
+				HTML: `<p>Hello <img src="cid:logo"></p>`,
+				Attachments: []Attachment{
+					{Filename: "logo.png", ContentType: "image/png", Data: []byte("png"), ContentID: "logo"},
+					{Filename: `C:\Users\jane\Lebenslauf Jürgen.pdf`, ContentType: "application/pdf", Data: bytes.Repeat([]byte("pdf"), 100)},
+					{Filename: "notes", ContentType: "not a media type", Data: []byte("notes")},
+				},
+			},
+			want{
+				contentType: "multipart/mixed",
+				parts: []mimePart{
+					{contentType: "text/plain", body: "Hello"},
+					{contentType: "text/html", body: `<p>Hello <img src="cid:logo"></p>`},
+					{contentType: "image/png", disposition: "inline", filename: "logo.png", contentID: "<logo>", body: "png"},
+					{contentType: "application/pdf", disposition: "attachment", filename: "Lebenslauf Jürgen.pdf", body: strings.Repeat("pdf", 100)},
+					{contentType: "application/octet-stream", disposition: "attachment", filename: "notes", body: "notes"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.From = "Jürgen Müller <noreply@example.com>"
+			tt.input.To = []string{"owner@example.com"}
+			tt.input.Subject = "A rather long subject line with ümlauts that has to be encoded and folded over several lines"
+
+			raw, err := buildMessage(tt.input, "<id@example.com>")
+			require.Empty(t, err)
+
+			for _, line := range strings.Split(string(raw), "\r\n") {
+				assert.LessOrEqual(t, len(line), maxHeaderLineLength, line)
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			require.Empty(t, err)
+
+			dec := new(mime.WordDecoder)
+			subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+			require.Empty(t, err)
+			assert.Equal(t, tt.input.Subject, subject)
+
+			from, err := msg.Header.AddressList("From")
+			require.Empty(t, err)
+			assert.Equal(t, "Jürgen Müller", from[0].Name)
+			assert.Equal(t, "<id@example.com>", msg.Header.Get("Message-ID"))
+
+			mediaType, _, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			require.Empty(t, err)
+			assert.Equal(t, tt.want.contentType, mediaType)
+
+			var got []mimePart
+			collect(t, msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", "", msg.Body, &got)
+			require.Len(t, got, len(tt.want.parts))
+			for i, want := range tt.want.parts {
+				assert.Equal(t, want.contentType, got[i].contentType)
+				assert.Equal(t, want.disposition, got[i].disposition)
+				assert.Equal(t, want.filename, got[i].filename)
+				assert.Equal(t, want.contentID, got[i].contentID)
+				assert.Equal(t, want.body, got[i].body)
+			}
+		})
+	}
+}
+
+func TestFoldHeaderUnit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"keeps short headers", "short value", "Subject: short value\r\n"},
+		{"folds at whitespace", strings.Repeat("word ", 20), "Subject: word word word word word word word word word word word word word word\r\n word word word word word word \r\n"},
+		{"keeps long words whole", strings.Repeat("x", 100), "Subject:\r\n " + strings.Repeat("x", 100) + "\r\n"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, foldHeader("Subject", tt.input))
+		})
+	}
+}
+
+func TestSanitizeHeaderUnit(t *testing.T) {
+	raw, err := buildMessage(&Message{From: "noreply@example.com", To: []string{"owner@example.com"}, Subject: "Hi\r\nBcc: victim@example.com", Text: "Hello"}, "")
+	require.Empty(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.Empty(t, err)
+	assert.Empty(t, msg.Header.Get("Bcc"))
+}
+
+func TestReplyToUnit(t *testing.T) {
+	raw, err := buildMessage(&Message{From: "noreply@example.com", To: []string{"owner@example.com"}, ReplyTo: []string{`"Jürgen" <jurgen@example.com>`}, Text: "Hello"}, "")
+	require.Empty(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.Empty(t, err)
+
+	replyTo, err := msg.Header.AddressList("Reply-To")
+	require.Empty(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "Jürgen", Address: "jurgen@example.com"}}, replyTo)
+
+	raw, err = buildMessage(&Message{From: "noreply@example.com", To: []string{"owner@example.com"}, Text: "Hello"}, "")
+	require.Empty(t, err)
+	assert.NotContains(t, string(raw), "Reply-To")
+}
+
+func TestCopyRecipientsUnit(t *testing.T) {
+	raw, err := buildMessage(&Message{
+		From: "noreply@example.com",
+		To:   []string{"owner@example.com"},
+		Cc:   []string{"sales@example.com", "support@example.com"},
+		Bcc:  []string{"archive@example.com"},
+		Text: "Hello",
+	}, "")
+	require.Empty(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.Empty(t, err)
+
+	cc, err := msg.Header.AddressList("Cc")
+	require.Empty(t, err)
+	assert.Equal(t, []*mail.Address{{Address: "sales@example.com"}, {Address: "support@example.com"}}, cc)
+	assert.Empty(t, msg.Header.Get("Bcc"))
+	assert.NotContains(t, string(raw), "archive@example.com")
+}
+
+func FuzzBuildMessageHeaders(f *testing.F) {
+	f.Add("Jane <jane@example.com>", "owner@example.com", "Jane <jane@example.com>", "Project inquiry", "Hello")
+	f.Add("jane@example.com\r\nBcc: victim@example.com", "owner@example.com", "jane@example.com\r\nBcc: victim@example.com", "Hi\r\nBcc: victim@example.com", "Hello\r\n\r\nBcc: victim@example.com")
+	f.Add("\"Jane\nBcc: x@example.com\" <jane@example.com>", "owner@example.com\nCc: x@example.com", "\"Jane\rCc: x@example.com\" <jane@example.com>", "Hi\rCc: x@example.com", "")
+	f.Add("noreply@example.com", "owner@example.com", "", "Grüße\u2028X-Injected: yes", "Hi")
+
+	allowed := map[string]bool{
+		"From": true, "To": true, "Cc": true, "Reply-To": true, "Subject": true, "Date": true, "Mime-Version": true,
+		"Content-Type": true, "Content-Transfer-Encoding": true,
+	}
+
+	f.Fuzz(func(t *testing.T, from, to, replyTo, subject, text string) {
+		raw, err := buildMessage(&Message{From: from, To: []string{to}, ReplyTo: []string{replyTo}, Subject: subject, Text: text}, "")
+		if err != nil {
+			t.Fatalf("failed to build message: %v", err)
+		}
+
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("failed to parse message: %v\n%s", err, raw)
+		}
+
+		for key := range msg.Header {
+			if !allowed[key] {
+				t.Fatalf("injected header %q in message:\n%s", key, raw)
+			}
+		}
+
+		if got := len(msg.Header["Subject"]); got > 1 {
+			t.Fatalf("message has %d subjects:\n%s", got, raw)
+		}
+	})
+}
+
+// collect walks a MIME body and appends every leaf part along with its decoded content.
+func collect(t *testing.T, contentType, encoding, disposition, contentID string, body io.Reader, parts *[]mimePart) {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.Empty(t, err)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return
+			}
+			require.Empty(t, err)
+
+			collect(t, p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p.Header.Get("Content-Disposition"), p.Header.Get("Content-ID"), p, parts)
+		}
+	}
+
+	var b []byte
+	switch encoding {
+	case "base64":
+		b, err = io.ReadAll(base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body}))
+	default:
+		b, err = io.ReadAll(quotedprintable.NewReader(body))
+	}
+	require.Empty(t, err)
+
+	var filename, kind string
+	if disposition != "" {
+		var dparams map[string]string
+		kind, dparams, err = mime.ParseMediaType(disposition)
+		require.Empty(t, err)
+		filename = dparams["filename"]
+	}
+
+	*parts = append(*parts, mimePart{mediaType, kind, filename, contentID, string(b)})
+}
+
+// newlineStripper drops line breaks, which the base64 decoder does not accept.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	out := p[:0]
+	for _, c := range p[:n] {
+		if c != '\r' && c != '\n' {
+			out = append(out, c)
+		}
+	}
+
+	return len(out), err
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// sesSender is an interface that defines the methods from the AWS SES client that are used by the SES transport.
+type sesSender interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+type ses struct {
+	client sesSender
+}
+
+// NewSES creates a Transport that delivers messages through AWS SES.
+// Messages with attachments are sent as raw MIME messages, messages that reference a stored template are sent as
+// SES templated emails, and all others are sent as simple content.
+//
+// Parameters:
+//   - client: The SES client used to send emails.
+//
+// Returns:
+//   - Transport: The newly created SES transport.
+func NewSES(client sesSender) Transport {
+	return &ses{
+		client: client,
+	}
+}
+
+// Send delivers the message with the SES SendEmail API.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - msg: The message to deliver.
+//
+// Returns:
+//   - string: The message ID assigned by SES.
+//   - error: An error if SES rejected the message.
+func (s ses) Send(ctx context.Context, msg *Message) (string, error) {
+	input := &sesv2.SendEmailInput{
+		Destination: &types.Destination{
+			ToAddresses:  msg.To,
+			CcAddresses:  msg.Cc,
+			BccAddresses: msg.Bcc,
+		},
+		FromEmailAddress: aws.String(msg.From),
+		ReplyToAddresses: msg.ReplyTo,
+	}
+
+	switch {
+	case len(msg.Attachments) > 0:
+		// SES assigns its own Message-ID to raw messages.
+		raw, err := buildMessage(msg, "")
+		if err != nil {
+			return "", fmt.Errorf("failed to build raw message: %w", err)
+		}
+
+		input.Content = &types.EmailContent{
+			Raw: &types.RawMessage{Data: raw},
+		}
+	case msg.Template != nil:
+		input.Content = &types.EmailContent{
+			Template: &types.Template{
+				TemplateName: aws.String(msg.Template.Name),
+				TemplateData: aws.String(msg.Template.Data),
+			},
+		}
+	default:
+		input.Content = &types.EmailContent{
+			Simple: simpleContent(msg),
+		}
+	}
+
+	out, err := s.client.SendEmail(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to send email with aws ses: %w", err)
+	}
+
+	return aws.ToString(out.MessageId), nil
+}
+
+func simpleContent(msg *Message) *types.Message {
+	body := &types.Body{}
+	if msg.HTML != "" {
+		body.Html = &types.Content{Data: aws.String(msg.HTML), Charset: aws.String("UTF-8")}
+	}
+	if msg.Text != "" {
+		body.Text = &types.Content{Data: aws.String(msg.Text), Charset: aws.String("UTF-8")}
+	}
+
+	return &types.Message{
+		Subject: &types.Content{Data: aws.String(msg.Subject), Charset: aws.String("UTF-8")},
+		Body:    body,
+	}
+}
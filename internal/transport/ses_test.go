@@ -0,0 +1,151 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSESSendUnit(t *testing.T) {
+	type input struct {
+		msg *Message
+		err string
+	}
+
+	type want struct {
+		errAssertion   func(t *testing.T, err error)
+		inputAssertion func(t *testing.T, in *sesv2.SendEmailInput)
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"handles failure to send email",
+			input{
+				msg: &Message{From: "noreply@example.com", To: []string{"owner@example.com"}},
+				err: "throttled",
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "throttled")
+				},
+			},
+		},
+		{
+			"sends templated email when a template is referenced",
+			input{
+				msg: &Message{
+					From:     "noreply@example.com",
+					To:       []string{"owner@example.com"},
+					Subject:  "rendered",
+					Template: &Template{Name: "ForwardTemplate", Data: `{"text":"hi"}`},
+				},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				inputAssertion: func(t *testing.T, in *sesv2.SendEmailInput) {
+					require.NotNil(t, in.Content.Template)
+					assert.Nil(t, in.Content.Simple)
+					assert.Equal(t, "ForwardTemplate", aws.ToString(in.Content.Template.TemplateName))
+					assert.Equal(t, `{"text":"hi"}`, aws.ToString(in.Content.Template.TemplateData))
+				},
+			},
+		},
+		{
+			"sends raw email when the message has attachments",
+			input{
+				msg: &Message{
+					From:        "noreply@example.com",
+					To:          []string{"owner@example.com"},
+					Subject:     "rendered",
+					Text:        "Body",
+					Attachments: []Attachment{{Filename: "resume.pdf", ContentType: "application/pdf", Data: []byte("%PDF")}},
+					Template:    &Template{Name: "ForwardTemplate", Data: `{"text":"hi"}`},
+				},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				inputAssertion: func(t *testing.T, in *sesv2.SendEmailInput) {
+					require.NotNil(t, in.Content.Raw)
+					assert.Nil(t, in.Content.Template)
+					assert.Contains(t, string(in.Content.Raw.Data), "multipart/mixed")
+					assert.Contains(t, string(in.Content.Raw.Data), `filename=resume.pdf`)
+					assert.NotContains(t, string(in.Content.Raw.Data), "Message-ID")
+				},
+			},
+		},
+		{
+			"sends simple email without a template",
+			input{
+				msg: &Message{
+					From:    "noreply@example.com",
+					To:      []string{"owner@example.com"},
+					Cc:      []string{"sales@example.com"},
+					Bcc:     []string{"archive@example.com"},
+					ReplyTo: []string{"jane@example.com"},
+					Subject: "Hello",
+					Text:    "Body",
+				},
+			},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+				inputAssertion: func(t *testing.T, in *sesv2.SendEmailInput) {
+					require.NotNil(t, in.Content.Simple)
+					assert.Equal(t, "Hello", aws.ToString(in.Content.Simple.Subject.Data))
+					assert.Equal(t, "Body", aws.ToString(in.Content.Simple.Body.Text.Data))
+					assert.Nil(t, in.Content.Simple.Body.Html)
+					assert.Equal(t, []string{"jane@example.com"}, in.ReplyToAddresses)
+					assert.Equal(t, []string{"owner@example.com"}, in.Destination.ToAddresses)
+					assert.Equal(t, []string{"sales@example.com"}, in.Destination.CcAddresses)
+					assert.Equal(t, []string{"archive@example.com"}, in.Destination.BccAddresses)
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockSESSender{err: tt.input.err}
+
+			id, err := NewSES(client).Send(context.Background(), tt.input.msg)
+			tt.want.errAssertion(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, "ses-message-id", id)
+			assert.Equal(t, tt.input.msg.To, client.input.Destination.ToAddresses)
+			assert.Equal(t, tt.input.msg.From, aws.ToString(client.input.FromEmailAddress))
+			tt.want.inputAssertion(t, client.input)
+		})
+	}
+}
+
+type mockSESSender struct {
+	err   string
+	input *sesv2.SendEmailInput
+}
+
+func (m *mockSESSender) SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	m.input = params
+	if m.err != "" {
+		return nil, errors.New(m.err)
+	}
+
+	return &sesv2.SendEmailOutput{MessageId: aws.String("ses-message-id")}, nil
+}
@@ -0,0 +1,299 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SMTPAuthNone disables SMTP authentication.
+	SMTPAuthNone = "none"
+	// SMTPAuthPlain authenticates with the PLAIN mechanism (RFC 4616).
+	SMTPAuthPlain = "plain"
+	// SMTPAuthLogin authenticates with the LOGIN mechanism.
+	SMTPAuthLogin = "login"
+	// SMTPAuthCRAMMD5 authenticates with the CRAM-MD5 mechanism (RFC 2195).
+	SMTPAuthCRAMMD5 = "cram-md5"
+
+	// SMTPTLSNone sends mail over a plain text connection.
+	SMTPTLSNone = "none"
+	// SMTPTLSStartTLS upgrades a plain text connection with the STARTTLS command.
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSImplicit connects with TLS from the start, typically on port 465.
+	SMTPTLSImplicit = "tls"
+)
+
+// SMTPConfig holds the configuration for the SMTP transport.
+//
+// Fields:
+//   - Host: The hostname of the SMTP server.
+//   - Port: The port of the SMTP server.
+//   - Username: The username used to authenticate with the SMTP server.
+//   - Password: The password used to authenticate with the SMTP server.
+//   - Auth: The authentication mechanism. One of "none", "plain", "login" or "cram-md5".
+//   - TLS: The TLS mode. One of "none", "starttls" or "tls".
+//   - TLSConfig: An optional tls.Config used for STARTTLS and implicit TLS connections.
+//   - LocalName: The hostname sent with the EHLO command. Defaults to "localhost".
+//   - Timeout: The timeout for a whole SMTP session, from establishing the connection to closing it. A deadline of the context passed to Send that is earlier takes precedence.
+type SMTPConfig struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	Auth      string
+	TLS       string
+	TLSConfig *tls.Config
+	LocalName string
+	Timeout   time.Duration
+}
+
+type smtpTransport struct {
+	cfg SMTPConfig
+}
+
+// NewSMTP creates a Transport that delivers messages to an SMTP server.
+//
+// Parameters:
+//   - cfg: The SMTPConfig object containing the server address, credentials and TLS settings.
+//
+// Returns:
+//   - Transport: The newly created SMTP transport.
+//   - error: An error if the configuration contains an unsupported authentication or TLS mode.
+func NewSMTP(cfg SMTPConfig) (Transport, error) {
+	switch cfg.Auth {
+	case "":
+		cfg.Auth = SMTPAuthNone
+	case SMTPAuthNone, SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5:
+	default:
+		return nil, fmt.Errorf("unsupported smtp auth mechanism %q", cfg.Auth)
+	}
+
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = SMTPTLSStartTLS
+	case SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unsupported smtp tls mode %q", cfg.TLS)
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{}
+	}
+
+	if cfg.TLSConfig.ServerName == "" {
+		cfg.TLSConfig = cfg.TLSConfig.Clone()
+		cfg.TLSConfig.ServerName = cfg.Host
+	}
+
+	return &smtpTransport{
+		cfg: cfg,
+	}, nil
+}
+
+// Send delivers the message to the configured SMTP server.
+// It connects, optionally upgrades the connection to TLS, authenticates and submits the message in a single SMTP session.
+//
+// Parameters:
+//   - ctx: The context.Context object for the request.
+//   - msg: The message to deliver.
+//
+// Returns:
+//   - string: The Message-ID header assigned to the message.
+//   - error: An error if any occurred during the SMTP session.
+func (s smtpTransport) Send(ctx context.Context, msg *Message) (string, error) {
+	messageID, err := newMessageID(s.cfg.Host)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := buildMessage(msg, messageID)
+	if err != nil {
+		return "", fmt.Errorf("failed to build smtp message: %w", err)
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if err := s.authenticate(client); err != nil {
+		return "", err
+	}
+
+	if err := client.Mail(msg.From); err != nil {
+		return "", fmt.Errorf("smtp server rejected sender: %w", err)
+	}
+
+	for _, to := range recipients(msg) {
+		if err := client.Rcpt(to); err != nil {
+			return "", fmt.Errorf("smtp server rejected recipient %q: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp server rejected data command: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write smtp message: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return "", fmt.Errorf("failed to close smtp session: %w", err)
+	}
+
+	return messageID, nil
+}
+
+func (s smtpTransport) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.cfg.TLS == SMTPTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.cfg.TLSConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	// The deadline bounds the whole session, so a server that stalls mid-conversation cannot block delivery.
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set smtp deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start smtp session: %w", err)
+	}
+
+	localName := s.cfg.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+
+	if err := client.Hello(localName); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("smtp server rejected greeting: %w", err)
+	}
+
+	if s.cfg.TLS == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := client.StartTLS(s.cfg.TLSConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to start tls with smtp server: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+func (s smtpTransport) authenticate(client *smtp.Client) error {
+	var auth smtp.Auth
+	switch s.cfg.Auth {
+	case SMTPAuthPlain:
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	case SMTPAuthLogin:
+		auth = &loginAuth{username: s.cfg.Username, password: s.cfg.Password, host: s.cfg.Host}
+	case SMTPAuthCRAMMD5:
+		auth = smtp.CRAMMD5Auth(s.cfg.Username, s.cfg.Password)
+	default:
+		return nil
+	}
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+	}
+
+	return nil
+}
+
+// loginAuth implements the non-standard but widely deployed LOGIN authentication mechanism,
+// which net/smtp does not provide.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected smtp login challenge %q", fromServer)
+	}
+}
+
+// recipients returns the envelope recipients of a message. Blind copy recipients are only ever part of the envelope.
+func recipients(msg *Message) []string {
+	out := make([]string, 0, len(msg.To)+len(msg.Cc)+len(msg.Bcc))
+	out = append(out, msg.To...)
+	out = append(out, msg.Cc...)
+	return append(out, msg.Bcc...)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+func newMessageID(host string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), host), nil
+}
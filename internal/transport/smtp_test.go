@@ -0,0 +1,386 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPSendUnit(t *testing.T) {
+	tlsConfig := newTestTLSConfig(t)
+
+	type input struct {
+		auth     string
+		tls      string
+		password string
+	}
+
+	type want struct {
+		mechanism    string
+		errAssertion func(t *testing.T, err error)
+	}
+
+	cases := []struct {
+		name  string
+		input input
+		want  want
+	}{
+		{
+			"is successful without auth",
+			input{auth: SMTPAuthNone, tls: SMTPTLSNone},
+			want{
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful with plain auth",
+			input{auth: SMTPAuthPlain, tls: SMTPTLSNone, password: "secret"},
+			want{
+				mechanism: "PLAIN",
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful with login auth",
+			input{auth: SMTPAuthLogin, tls: SMTPTLSNone, password: "secret"},
+			want{
+				mechanism: "LOGIN",
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful with cram-md5 auth",
+			input{auth: SMTPAuthCRAMMD5, tls: SMTPTLSNone, password: "secret"},
+			want{
+				mechanism: "CRAM-MD5",
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful with starttls",
+			input{auth: SMTPAuthPlain, tls: SMTPTLSStartTLS, password: "secret"},
+			want{
+				mechanism: "PLAIN",
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"is successful with implicit tls",
+			input{auth: SMTPAuthLogin, tls: SMTPTLSImplicit, password: "secret"},
+			want{
+				mechanism: "LOGIN",
+				errAssertion: func(t *testing.T, err error) {
+					assert.Empty(t, err)
+				},
+			},
+		},
+		{
+			"handles failure to authenticate",
+			input{auth: SMTPAuthPlain, tls: SMTPTLSNone, password: "wrong"},
+			want{
+				mechanism: "PLAIN",
+				errAssertion: func(t *testing.T, err error) {
+					require.NotEmpty(t, err)
+					assert.Contains(t, err.Error(), "failed to authenticate with smtp server")
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newMockSMTPServer(t, tlsConfig, tt.input.tls == SMTPTLSImplicit)
+
+			smtpTransport, err := NewSMTP(SMTPConfig{
+				Host:      "127.0.0.1",
+				Port:      srv.port(),
+				Username:  "user",
+				Password:  tt.input.password,
+				Auth:      tt.input.auth,
+				TLS:       tt.input.tls,
+				TLSConfig: &tls.Config{InsecureSkipVerify: true},
+			})
+			require.Empty(t, err)
+
+			id, err := smtpTransport.Send(context.Background(), &Message{
+				From:    "noreply@example.com",
+				To:      []string{"owner@example.com", "team@example.com"},
+				Cc:      []string{"sales@example.com"},
+				Bcc:     []string{"archive@example.com"},
+				Subject: "Grüße",
+				HTML:    "<p>Hello</p>",
+				Text:    "Hello",
+			})
+			tt.want.errAssertion(t, err)
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			assert.Equal(t, tt.want.mechanism, srv.mechanism)
+			if err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, id)
+			assert.Equal(t, "noreply@example.com", srv.from)
+			assert.Equal(t, []string{"owner@example.com", "team@example.com", "sales@example.com", "archive@example.com"}, srv.rcpts)
+			assert.Contains(t, srv.data, "Cc: <sales@example.com>")
+			assert.NotContains(t, srv.data, "archive@example.com")
+			assert.Contains(t, srv.data, "Message-ID: "+id)
+			assert.Contains(t, srv.data, "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=")
+			assert.Contains(t, srv.data, "multipart/alternative")
+			assert.Equal(t, tt.input.tls != SMTPTLSNone, srv.tls)
+		})
+	}
+}
+
+func TestSMTPSendTimeoutUnit(t *testing.T) {
+	// The listener completes the connection but never greets the client, like a stalled server.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Empty(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+	}{
+		{
+			"times out a stalled session",
+			100 * time.Millisecond,
+			func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+		},
+		{
+			"uses an earlier context deadline",
+			time.Minute,
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewSMTP(SMTPConfig{
+				Host:    "127.0.0.1",
+				Port:    lis.Addr().(*net.TCPAddr).Port,
+				Auth:    SMTPAuthNone,
+				TLS:     SMTPTLSNone,
+				Timeout: tt.timeout,
+			})
+			require.Empty(t, err)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, err = tr.Send(ctx, &Message{From: "noreply@example.com", To: []string{"owner@example.com"}, Subject: "Hi", Text: "Hello"})
+			require.NotEmpty(t, err)
+			assert.Less(t, time.Since(start), 2*time.Second)
+		})
+	}
+}
+
+func TestNewSMTPUnit(t *testing.T) {
+	_, err := NewSMTP(SMTPConfig{Auth: "xoauth2"})
+	require.NotEmpty(t, err)
+	assert.Contains(t, err.Error(), "unsupported smtp auth mechanism")
+
+	_, err = NewSMTP(SMTPConfig{TLS: "ssl"})
+	require.NotEmpty(t, err)
+	assert.Contains(t, err.Error(), "unsupported smtp tls mode")
+}
+
+// mockSMTPServer is a minimal SMTP server used as a local stand-in for a real mail server.
+// It accepts a single session and records the envelope, the message and the auth mechanism used.
+type mockSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu        sync.Mutex
+	mechanism string
+	from      string
+	rcpts     []string
+	data      string
+	tls       bool
+}
+
+func newMockSMTPServer(t *testing.T, tlsConfig *tls.Config, implicitTLS bool) *mockSMTPServer {
+	var (
+		lis net.Listener
+		err error
+	)
+	if implicitTLS {
+		lis, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		lis, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	require.Empty(t, err)
+
+	s := &mockSMTPServer{listener: lis, tlsConfig: tlsConfig, tls: implicitTLS}
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		s.serve(conn)
+	}()
+
+	return s
+}
+
+func (s *mockSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *mockSMTPServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	readLine := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line := readLine()
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			s.mu.Lock()
+			if !s.tls {
+				reply("250-STARTTLS")
+			}
+			s.mu.Unlock()
+			reply("250 AUTH PLAIN LOGIN CRAM-MD5")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+			s.mu.Lock()
+			s.tls = true
+			s.mu.Unlock()
+		case "AUTH":
+			parts := strings.Fields(line)
+			s.mu.Lock()
+			s.mechanism = parts[1]
+			s.mu.Unlock()
+
+			var ok bool
+			switch parts[1] {
+			case "PLAIN":
+				v, _ := base64.StdEncoding.DecodeString(parts[2])
+				ok = string(v) == "\x00user\x00secret"
+			case "LOGIN":
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				user, _ := base64.StdEncoding.DecodeString(readLine())
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				pass, _ := base64.StdEncoding.DecodeString(readLine())
+				ok = string(user) == "user" && string(pass) == "secret"
+			case "CRAM-MD5":
+				challenge := "<1896.697170952@localhost>"
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+				v, _ := base64.StdEncoding.DecodeString(readLine())
+				mac := hmac.New(md5.New, []byte("secret"))
+				mac.Write([]byte(challenge))
+				ok = string(v) == "user "+hex.EncodeToString(mac.Sum(nil))
+			}
+
+			if ok {
+				reply("235 authenticated")
+			} else {
+				reply("535 authentication failed")
+			}
+		case "MAIL":
+			s.mu.Lock()
+			s.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l := readLine()
+				if l == "." {
+					break
+				}
+				data.WriteString(l + "\r\n")
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		case "":
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Empty(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Empty(t, err)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
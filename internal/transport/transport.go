@@ -0,0 +1,66 @@
+package transport
+
+import "context"
+
+// Transport defines a provider-neutral way of delivering a single email message.
+// Implementations are responsible for turning a Message into whatever the underlying
+// provider expects (an SES API call, an SMTP conversation, ...).
+//
+// Methods:
+//   - Send: Delivers the message and returns the identifier the provider assigned to it.
+type Transport interface {
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// Message is a provider-neutral representation of an outgoing email.
+//
+// A message always carries its rendered Subject, HTML and Text parts so it can be delivered by any transport.
+// Transports that store templates server side (such as SES) may instead use the Template reference when one is set.
+//
+// Fields:
+//   - From: The email address the message is sent from.
+//   - To: The recipients of the message.
+//   - Cc: The recipients the message is copied to.
+//   - Bcc: The recipients the message is blind copied to. They are never written to the message headers.
+//   - ReplyTo: The addresses replies to the message are sent to. Replies go to From when empty.
+//   - Subject: The rendered subject line.
+//   - HTML: The rendered HTML body. May be empty.
+//   - Text: The rendered plain text body. May be empty.
+//   - Attachments: Files sent along with the message. Messages with attachments are always sent as rendered content.
+//   - Template: An optional reference to a provider stored template and the data used to render it.
+type Message struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     []string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []Attachment
+	Template    *Template
+}
+
+// Attachment is a file sent along with a message.
+//
+// Fields:
+//   - Filename: The name of the file.
+//   - ContentType: The media type of the file. Defaults to "application/octet-stream".
+//   - Data: The content of the file.
+//   - ContentID: An optional Content-ID. When set, the file is embedded inline and can be referenced from the HTML body as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	ContentID   string
+}
+
+// Template references a template stored with the email provider.
+//
+// Fields:
+//   - Name: The name of the stored template.
+//   - Data: The JSON encoded data used to render the template.
+type Template struct {
+	Name string
+	Data string
+}
@@ -5,17 +5,37 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/brice-aldrich/mail-service/config"
 	mailservice_v1 "github.com/brice-aldrich/mail-service/gen/go/mailservice.v1"
+	"github.com/brice-aldrich/mail-service/internal/auth"
+	"github.com/brice-aldrich/mail-service/internal/captcha"
+	"github.com/brice-aldrich/mail-service/internal/events"
+	"github.com/brice-aldrich/mail-service/internal/fields"
+	"github.com/brice-aldrich/mail-service/internal/forms"
+	"github.com/brice-aldrich/mail-service/internal/formtoken"
 	"github.com/brice-aldrich/mail-service/internal/gateway"
+	"github.com/brice-aldrich/mail-service/internal/idempotency"
 	"github.com/brice-aldrich/mail-service/internal/mail"
+	"github.com/brice-aldrich/mail-service/internal/outbox"
+	"github.com/brice-aldrich/mail-service/internal/quarantine"
+	"github.com/brice-aldrich/mail-service/internal/ratelimit"
+	"github.com/brice-aldrich/mail-service/internal/render"
+	"github.com/brice-aldrich/mail-service/internal/routing"
 	"github.com/brice-aldrich/mail-service/internal/server"
+	"github.com/brice-aldrich/mail-service/internal/spam"
+	"github.com/brice-aldrich/mail-service/internal/suppression"
+	"github.com/brice-aldrich/mail-service/internal/templates"
+	"github.com/brice-aldrich/mail-service/internal/transport"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -32,43 +52,401 @@ func main() {
 		zlog.With(zap.Error(err)).Fatal("Failed to load application configuration.")
 	}
 
-	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion("us-east-1"))
+	emailTemplates, err := templates.Load(templates.Config{
+		Dir: cfg.Templates.Dir,
+		Sources: map[string]templates.Source{
+			templates.ThankYou: templates.Source(cfg.Templates.ThankYou),
+			templates.Forward:  templates.Source(cfg.Templates.Forward),
+		},
+	})
 	if err != nil {
-		zlog.With(zap.Error(err)).Fatal("Failed to load AWS configuration.")
+		zlog.With(zap.Error(err)).Fatal("Failed to load email templates.")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-	mailOrch, err := mail.New(ctx, mail.Config{
-		SES:          sesv2.NewFromConfig(awsConfig),
-		ForwardEmail: cfg.Email.Forward,
-		FromEmail:    cfg.Email.From,
+	mailCfg := mail.Config{
+		ForwardEmails:  cfg.Email.Forward,
+		CcEmails:       cfg.Email.Cc,
+		BccEmails:      cfg.Email.Bcc,
+		FromEmail:      cfg.Email.From,
+		Site:           cfg.Email.Site,
+		ForwardSubject: cfg.Email.ForwardSubject,
+		AutoReply: mail.AutoReplyConfig{
+			Enabled: cfg.Email.AutoReply.Enabled,
+			Limit:   cfg.Email.AutoReply.Limit,
+			Window:  cfg.Email.AutoReply.Window,
+		},
+		Templates: emailTemplates,
+		Attachments: mail.AttachmentLimits{
+			MaxCount:     cfg.Attachments.MaxCount,
+			MaxFileSize:  cfg.Attachments.MaxFileSize,
+			MaxTotalSize: cfg.Attachments.MaxTotalSize,
+		},
+		PreserveTemplates: cfg.Templates.Preserve,
+		Logger:            zlog,
+	}
+
+	switch cfg.Templates.Engine {
+	case config.TemplateEngineSES:
+	case config.TemplateEngineLocal:
+		engine, err := render.New(render.Config{
+			Templates:   emailTemplates,
+			PartialsDir: cfg.Templates.PartialsDir,
+		})
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to parse email templates.")
+		}
+		mailCfg.Renderer = engine
+	default:
+		zlog.With(zap.String("engine", cfg.Templates.Engine)).Fatal("Unsupported template engine.")
+	}
+
+	// Every form registers its own origins, so the gateway accepts cross-origin requests from all of them.
+	corsOrigins := slices.Clone(cfg.Service.CORS.AllowedOrigins)
+	formCaptchas := map[string]forms.Captcha{mail.DefaultFormID: baseForm(cfg).Captcha}
+	serverCfg := server.Config{TrustedHops: cfg.RateLimit.TrustedHops, DefaultForm: mail.DefaultFormID, Logger: zlog}
+	if cfg.Forms.File != "" {
+		registry, err := forms.Load(cfg.Forms.File, baseForm(cfg))
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to load forms.")
+		}
+
+		for _, f := range registry.Forms() {
+			formCfg, err := newFormConfig(cfg, f)
+			if err != nil {
+				zlog.With(zap.Error(err), zap.String("form", f.ID)).Fatal("Failed to setup form.")
+			}
+			mailCfg.Forms = append(mailCfg.Forms, formCfg)
+			formCaptchas[f.ID] = f.Captcha
+
+			for _, origin := range f.Origins {
+				if !slices.Contains(corsOrigins, origin) {
+					corsOrigins = append(corsOrigins, origin)
+				}
+			}
+		}
+		mailCfg.DefaultForm = registry.Default()
+		serverCfg.DefaultForm = registry.Default()
+		delete(formCaptchas, mail.DefaultFormID)
+	}
+
+	serverCfg.Captchas = make(map[string]captcha.Verifier, len(formCaptchas))
+	for id, c := range formCaptchas {
+		verifier, err := captcha.New(captcha.Config{
+			Provider:  c.Provider,
+			Secret:    c.Secret,
+			Endpoint:  c.Endpoint,
+			MinScore:  c.MinScore,
+			Action:    c.Action,
+			Hostnames: c.Hostnames,
+			Timeout:   cfg.Captcha.Timeout,
+		})
+		if err != nil {
+			zlog.With(zap.Error(err), zap.String("form", id)).Fatal("Failed to setup captcha verification.")
+		}
+		serverCfg.Captchas[id] = verifier
+	}
+
+	suppressionCfg := suppression.Config{
+		Dir:          filepath.Join(cfg.Storage.DataDir, "suppressions"),
+		SyncInterval: cfg.Suppression.SyncInterval,
 		Logger:       zlog,
+	}
+
+	var (
+		mailTransport   transport.Transport
+		templateManager templates.Manager
+	)
+	switch cfg.Email.Transport {
+	case config.TransportSES:
+		awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion("us-east-1"))
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to load AWS configuration.")
+		}
+
+		sesClient := sesv2.NewFromConfig(awsConfig)
+		mailCfg.SES = sesClient
+		mailTransport = transport.NewSES(sesClient)
+		templateManager = templates.NewManager(sesClient)
+		if cfg.Suppression.SyncSES {
+			suppressionCfg.SES = sesClient
+		}
+	case config.TransportSMTP:
+		mailTransport, err = transport.NewSMTP(transport.SMTPConfig{
+			Host:      cfg.Email.SMTP.Host,
+			Port:      cfg.Email.SMTP.Port,
+			Username:  cfg.Email.SMTP.Username,
+			Password:  cfg.Email.SMTP.Password,
+			Auth:      cfg.Email.SMTP.Auth,
+			TLS:       cfg.Email.SMTP.TLS,
+			LocalName: cfg.Email.SMTP.LocalName,
+			Timeout:   cfg.Email.SMTP.Timeout,
+		})
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup SMTP transport.")
+		}
+	default:
+		zlog.With(zap.String("transport", cfg.Email.Transport)).Fatal("Unsupported email transport.")
+	}
+
+	suppressions, err := suppression.New(suppressionCfg)
+	if err != nil {
+		zlog.With(zap.Error(err)).Fatal("Failed to setup suppression list.")
+	}
+	mailCfg.Suppressions = suppressions
+
+	go suppressions.Run(context.Background())
+
+	mailOutbox, err := outbox.New(outbox.Config{
+		Dir:            filepath.Join(cfg.Storage.DataDir, "outbox"),
+		Transport:      mailTransport,
+		Workers:        cfg.Outbox.Workers,
+		PollInterval:   cfg.Outbox.PollInterval,
+		AttemptTimeout: cfg.Outbox.AttemptTimeout,
+		Retention:      cfg.Outbox.Retention,
+		Retry: outbox.RetryPolicy{
+			MaxAttempts:    cfg.Outbox.MaxAttempts,
+			InitialBackoff: cfg.Outbox.InitialBackoff,
+			MaxBackoff:     cfg.Outbox.MaxBackoff,
+		},
+		Logger: zlog,
 	})
+	if err != nil {
+		zlog.With(zap.Error(err)).Fatal("Failed to setup outbox.")
+	}
+	mailCfg.Outbox = mailOutbox
+
+	go mailOutbox.Run(context.Background())
+
+	if cfg.Spam.Enabled {
+		classifier, err := spam.NewClassifier(filepath.Join(cfg.Storage.DataDir, "spam"))
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup spam classifier.")
+		}
+
+		filter, err := spam.New(spam.Config{
+			Thresholds: spam.Thresholds{
+				Tag:        cfg.Spam.TagThreshold,
+				Quarantine: cfg.Spam.QuarantineThreshold,
+				Drop:       cfg.Spam.DropThreshold,
+			},
+			MaxLinks:     cfg.Spam.MaxLinks,
+			Shorteners:   cfg.Spam.Shorteners,
+			Keywords:     cfg.Spam.Keywords,
+			Patterns:     cfg.Spam.Patterns,
+			Scripts:      cfg.Spam.Scripts,
+			RepeatWindow: cfg.Spam.RepeatWindow,
+			Classifier:   classifier,
+		})
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup spam filter.")
+		}
+		mailCfg.Spam = filter
+
+		held, err := quarantine.New(filepath.Join(cfg.Storage.DataDir, "quarantine"))
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup spam quarantine.")
+		}
+		mailCfg.Quarantine = held
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	mailOrch, err := mail.New(ctx, mailCfg)
 	if err != nil {
 		zlog.With(zap.Error(err)).Fatal("Failed to setup mail orchestrator.")
 	}
 
+	if cfg.FormTokens.Secret != "" {
+		formTokenCfg := formtoken.Config{
+			Secret: []byte(cfg.FormTokens.Secret),
+			MinAge: cfg.FormTokens.MinAge,
+			MaxAge: cfg.FormTokens.MaxAge,
+		}
+		switch cfg.FormTokens.Backend {
+		case config.FormTokenBackendMemory:
+			formTokenCfg.Store = formtoken.NewMemoryStore()
+		case config.FormTokenBackendRedis:
+			opts, err := redis.ParseURL(cfg.FormTokens.RedisURL)
+			if err != nil {
+				zlog.With(zap.Error(err)).Fatal("Failed to parse form token redis url.")
+			}
+			formTokenCfg.Store = formtoken.NewRedisStore(redis.NewClient(opts), "mail-service:formtoken:")
+		default:
+			zlog.With(zap.String("backend", cfg.FormTokens.Backend)).Fatal("Unsupported form token backend.")
+		}
+
+		serverCfg.FormTokens, err = formtoken.New(formTokenCfg)
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup form tokens.")
+		}
+	}
+
+	limiterCfg := ratelimit.Config{
+		TrustedHops: cfg.RateLimit.TrustedHops,
+		DefaultForm: serverCfg.DefaultForm,
+		Methods:     []string{"/mailservice.MailService/SendMail"},
+		Logger:      zlog,
+	}
+	for _, l := range []struct {
+		limit *ratelimit.Limit
+		value string
+	}{
+		{&limiterCfg.IP, cfg.RateLimit.IP},
+		{&limiterCfg.Email, cfg.RateLimit.Email},
+		{&limiterCfg.Form, cfg.RateLimit.Form},
+	} {
+		if *l.limit, err = ratelimit.ParseLimit(l.value); err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to parse rate limit.")
+		}
+	}
+
+	switch cfg.RateLimit.Backend {
+	case config.RateLimitBackendMemory:
+		limiterCfg.Store = ratelimit.NewMemoryStore()
+	case config.RateLimitBackendRedis:
+		opts, err := redis.ParseURL(cfg.RateLimit.RedisURL)
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to parse rate limit redis url.")
+		}
+		limiterCfg.Store = ratelimit.NewRedisStore(redis.NewClient(opts), "mail-service:ratelimit:")
+	default:
+		zlog.With(zap.String("backend", cfg.RateLimit.Backend)).Fatal("Unsupported rate limit backend.")
+	}
+
+	idempotencyCfg := idempotency.Config{
+		Window:  cfg.Idempotency.Window,
+		Methods: []string{"/mailservice.MailService/SendMail"},
+		Logger:  zlog,
+	}
+	switch cfg.Idempotency.Backend {
+	case config.IdempotencyBackendMemory:
+		idempotencyCfg.Store = idempotency.NewMemoryStore()
+	case config.IdempotencyBackendRedis:
+		opts, err := redis.ParseURL(cfg.Idempotency.RedisURL)
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to parse idempotency redis url.")
+		}
+		idempotencyCfg.Store = idempotency.NewRedisStore(redis.NewClient(opts), "mail-service:idempotency:")
+	default:
+		zlog.With(zap.String("backend", cfg.Idempotency.Backend)).Fatal("Unsupported idempotency backend.")
+	}
+
 	grpcServer := grpc.NewServer(
+		// Submissions carry their attachments, so the receive limit leaves room for them on top of the default 4 MiB.
+		grpc.MaxRecvMsgSize(int(cfg.Attachments.MaxTotalSize)+4<<20),
 		grpc.ChainUnaryInterceptor(
 			grpc_zap.UnaryServerInterceptor(zlog),
+			// Retries are answered before the rate limits, so they do not use up the tokens of the submitter.
+			idempotency.Interceptor(idempotencyCfg),
+			ratelimit.Interceptor(limiterCfg),
+			auth.AdminInterceptor(cfg.Service.AdminToken,
+				// Delivery events name the private forward, Cc and Bcc addresses.
+				"/mailservice.MailService/GetMessageStatus",
+				"/mailservice.MailService/ListMessages",
+				"/mailservice.MailService/AddSuppression",
+				"/mailservice.MailService/RemoveSuppression",
+				"/mailservice.MailService/ListSuppressions",
+				"/mailservice.MailService/TrainSpamFilter",
+				"/mailservice.MailService/ListQuarantine",
+				"/mailservice.MailService/ReleaseQuarantined",
+				"/mailservice.MailService/DiscardQuarantined",
+				"/mailservice.TemplateService/CreateTemplate",
+				"/mailservice.TemplateService/UpdateTemplate",
+				"/mailservice.TemplateService/GetTemplate",
+				"/mailservice.TemplateService/ListTemplates",
+				"/mailservice.TemplateService/DeleteTemplate",
+				"/mailservice.TemplateService/RenderPreview",
+			),
 		),
 	)
 
-	mailService := server.New(mailOrch)
+	mailService := server.New(mailOrch, serverCfg)
 	mailservice_v1.RegisterMailServiceServer(grpcServer, mailService)
 
+	// Templates are managed in AWS SES, so the TemplateService is only available with the SES transport.
+	if templateManager != nil {
+		mailservice_v1.RegisterTemplateServiceServer(grpcServer, server.NewTemplateServer(templateManager))
+	}
+
 	gw := gateway.New(gateway.Config{
 		Host:     cfg.Service.ListenAddress,
 		Port:     cfg.Service.Port,
 		GRPCHost: cfg.Service.GRPCHost,
 		GRPCPort: cfg.Service.GRPCPort,
+		CORS: gateway.CORSConfig{
+			AllowedOrigins:   corsOrigins,
+			AllowedMethods:   cfg.Service.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.Service.CORS.AllowedHeaders,
+			AllowCredentials: cfg.Service.CORS.AllowCredentials,
+			MaxAge:           cfg.Service.CORS.MaxAge,
+		},
 	})
 
 	if err := gw.Register(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
 		zlog.With(zap.Error(err)).Fatal("Failed to register gRPC gateway.")
 	}
 
+	uploadCfg := gateway.UploadConfig{
+		Path:         cfg.Attachments.UploadPath,
+		AllowedTypes: cfg.Attachments.AllowedTypes,
+		MaxFiles:     cfg.Attachments.MaxCount,
+		MaxFileSize:  cfg.Attachments.MaxFileSize,
+		MaxTotalSize: cfg.Attachments.MaxTotalSize,
+	}
+	if err := gw.RegisterUpload(context.Background(), uploadCfg, grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
+		zlog.With(zap.Error(err)).Fatal("Failed to register upload route.")
+	}
+
+	formSites, err := gateway.ParseFormSites(cfg.HTMLForm.Sites)
+	if err != nil {
+		zlog.With(zap.Error(err)).Fatal("Failed to parse HTML form sites.")
+	}
+
+	formCfg := gateway.FormConfig{
+		Path:  cfg.HTMLForm.Path,
+		Sites: formSites,
+		Default: gateway.FormRedirect{
+			SuccessURL: cfg.HTMLForm.SuccessURL,
+			ErrorURL:   cfg.HTMLForm.ErrorURL,
+		},
+	}
+	if err := gw.RegisterForm(context.Background(), formCfg, grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
+		zlog.With(zap.Error(err)).Fatal("Failed to register HTML form route.")
+	}
+
+	if cfg.Events.Enabled {
+		snsClient := &http.Client{Timeout: cfg.Events.Timeout}
+
+		var certs events.CertificateSource
+		if cfg.Events.CertFile != "" {
+			certs, err = events.NewFileCertificateSource(cfg.Events.CertFile)
+		} else {
+			certs, err = events.NewHTTPCertificateSource(snsClient, cfg.Events.CertHostPattern)
+		}
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup SNS certificate source.")
+		}
+
+		eventsHandler, err := events.NewHandler(events.Config{
+			Certificates: certs,
+			Recorder:     mailOutbox,
+			Suppressions: suppressions,
+			TopicARNs:    cfg.Events.TopicARNs,
+			HostPattern:  cfg.Events.CertHostPattern,
+			AutoConfirm:  cfg.Events.AutoConfirm,
+			MaxAge:       cfg.Events.MaxAge,
+			Client:       snsClient,
+			Logger:       zlog,
+		})
+		if err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to setup SES events handler.")
+		}
+
+		if err := gw.Handle(http.MethodPost, cfg.Events.Path, eventsHandler); err != nil {
+			zlog.With(zap.Error(err)).Fatal("Failed to register SES events handler.")
+		}
+	}
+
 	go func() {
 		if err := gw.Serve(); err != nil {
 			zlog.With(zap.Error(err)).Fatal("Failed to service gRPC gateway.")
@@ -85,3 +463,127 @@ func main() {
 		zlog.With(zap.Error(err), zap.Int("port", cfg.Service.Port), zap.String("host", cfg.Service.ListenAddress)).Fatal("Failed to start email service.")
 	}
 }
+
+// baseForm builds the form holding the service-wide settings, which the forms of the registry inherit from.
+func baseForm(cfg *config.Config) forms.Form {
+	return forms.Form{
+		Forward:        cfg.Email.Forward,
+		Cc:             cfg.Email.Cc,
+		Bcc:            cfg.Email.Bcc,
+		From:           cfg.Email.From,
+		Site:           cfg.Email.Site,
+		ForwardSubject: cfg.Email.ForwardSubject,
+		Origins:        cfg.Service.CORS.AllowedOrigins,
+		Templates: forms.Templates{
+			Dir:      cfg.Templates.Dir,
+			ThankYou: forms.TemplateSource(cfg.Templates.ThankYou),
+			Forward:  forms.TemplateSource(cfg.Templates.Forward),
+		},
+		AutoReply: forms.AutoReply{
+			Enabled: cfg.Email.AutoReply.Enabled,
+			Limit:   cfg.Email.AutoReply.Limit,
+			Window:  cfg.Email.AutoReply.Window,
+		},
+		Attachments: forms.Attachments{
+			MaxCount:     cfg.Attachments.MaxCount,
+			MaxFileSize:  cfg.Attachments.MaxFileSize,
+			MaxTotalSize: cfg.Attachments.MaxTotalSize,
+		},
+		Captcha: forms.Captcha{
+			Provider:  cfg.Captcha.Provider,
+			Secret:    cfg.Captcha.Secret,
+			Endpoint:  cfg.Captcha.Endpoint,
+			MinScore:  cfg.Captcha.MinScore,
+			Action:    cfg.Captcha.Action,
+			Hostnames: cfg.Captcha.Hostnames,
+		},
+	}
+}
+
+// routingRules converts the routes of a form of the registry to the routing rules of its mail.FormConfig.
+func routingRules(routes []forms.Route) []routing.Rule {
+	rules := make([]routing.Rule, 0, len(routes))
+	for _, r := range routes {
+		rules = append(rules, routing.Rule{
+			Name: r.Name,
+			Match: routing.Match{
+				SubjectKeywords: r.Match.SubjectKeywords,
+				Departments:     r.Match.Departments,
+				EmailDomains:    r.Match.EmailDomains,
+			},
+			Recipients: routing.Recipients{To: r.Forward, Cc: r.Cc, Bcc: r.Bcc},
+		})
+	}
+
+	return rules
+}
+
+// customFields converts the field declarations of a form of the registry to those of its mail.FormConfig.
+func customFields(declared []forms.Field) []fields.Field {
+	out := make([]fields.Field, 0, len(declared))
+	for _, f := range declared {
+		out = append(out, fields.Field{
+			Name:      f.Name,
+			Label:     f.Label,
+			Type:      fields.Type(f.Type),
+			Required:  f.Required,
+			Pattern:   f.Pattern,
+			MaxLength: f.MaxLength,
+		})
+	}
+
+	return out
+}
+
+// newFormConfig loads the templates of a form of the registry. Their AWS SES names are suffixed with the form ID,
+// so the templates of every form are stored side by side.
+func newFormConfig(cfg *config.Config, f forms.Form) (mail.FormConfig, error) {
+	formTemplates, err := templates.Load(templates.Config{
+		Dir: f.Templates.Dir,
+		Sources: map[string]templates.Source{
+			templates.ThankYou: templates.Source(f.Templates.ThankYou),
+			templates.Forward:  templates.Source(f.Templates.Forward),
+		},
+		NameSuffix: "_" + f.ID,
+	})
+	if err != nil {
+		return mail.FormConfig{}, err
+	}
+
+	formCfg := mail.FormConfig{
+		ID:             f.ID,
+		ForwardEmails:  f.Forward,
+		CcEmails:       f.Cc,
+		BccEmails:      f.Bcc,
+		Routes:         routingRules(f.Routes),
+		Fields:         customFields(f.Fields),
+		FromEmail:      f.From,
+		Site:           f.Site,
+		ForwardSubject: f.ForwardSubject,
+		Origins:        f.Origins,
+		AutoReply: mail.AutoReplyConfig{
+			Enabled: f.AutoReply.Enabled,
+			Limit:   f.AutoReply.Limit,
+			Window:  f.AutoReply.Window,
+		},
+		Templates: formTemplates,
+		Attachments: mail.AttachmentLimits{
+			MaxCount:     f.Attachments.MaxCount,
+			MaxFileSize:  f.Attachments.MaxFileSize,
+			MaxTotalSize: f.Attachments.MaxTotalSize,
+		},
+	}
+
+	if cfg.Templates.Engine == config.TemplateEngineLocal {
+		engine, err := render.New(render.Config{
+			Templates:   formTemplates,
+			PartialsDir: cfg.Templates.PartialsDir,
+		})
+		if err != nil {
+			return mail.FormConfig{}, err
+		}
+		formCfg.Renderer = engine
+	}
+
+	return formCfg, nil
+}